@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func cherryPickCmd() *cobra.Command {
+	var abort bool
+	var cont bool
+
+	cmd := &cobra.Command{
+		Use:   "cherry-pick <commit>",
+		Short: "Apply a commit to the current branch of all repositories",
+		Long: `Apply a commit, given by its hash, to the current branch of every
+repository that has it, e.g. a fix to code the repos share.
+
+Repos without the commit report "commit not found", and repos where it
+changes nothing are skipped as already applied. A repo where it conflicts
+is left mid-cherry-pick, with the conflicted files listed. Resolve them and
+'git add' the files, then run mergeish cherry-pick --continue, or run
+mergeish cherry-pick --abort to back out in every repo still
+mid-cherry-pick.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if abort || cont {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if abort && cont {
+				return fmt.Errorf("--abort and --continue can't be used together")
+			}
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			if abort {
+				fmt.Println("Aborting cherry-picks...")
+				return printStoppableResults(ws.CherryPickAbort(), "cherry-pick", "abort the cherry-pick in")
+			}
+			if cont {
+				fmt.Println("Continuing cherry-picks...")
+				return printStoppableResults(ws.CherryPickContinue(), "cherry-pick", "continue the cherry-pick in")
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("Warning: repositories are on different branches")
+			}
+
+			fmt.Printf("Cherry-picking %s onto %s...\n", args[0], branch)
+			return printStoppableResults(ws.CherryPick(args[0]), "cherry-pick", "cherry-pick into")
+		},
+	}
+
+	cmd.Flags().BoolVar(&abort, "abort", false, "abort the cherry-pick in progress in every repo")
+	cmd.Flags().BoolVar(&cont, "continue", false, "continue the cherry-pick in progress in every repo after resolving conflicts")
+	return cmd
+}
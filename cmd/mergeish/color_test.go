@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeTerminal makes stdout count as a terminal, or not, for the test
+func fakeTerminal(t *testing.T, terminal bool) {
+	t.Helper()
+	orig := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return terminal }
+	t.Cleanup(func() { stdoutIsTerminal = orig })
+	t.Setenv("NO_COLOR", "")
+}
+
+func TestGitColor(t *testing.T) {
+	scopeWorkspace(t)
+	for _, tt := range []struct {
+		name     string
+		terminal bool
+		args     []string
+		color    bool
+	}{
+		{"terminal", true, []string{"log", "--oneline", "--decorate", "-1"}, true},
+		{"terminal with JSON", true, []string{"--json", "log", "--oneline", "--decorate", "-1"}, false},
+		{"terminal with JSON and --color", true, []string{"--json", "log", "--color", "--oneline", "-1"}, false},
+		{"pipe", false, []string{"log", "--oneline", "--decorate", "-1"}, false},
+		{"pipe with --color", false, []string{"log", "--color", "--oneline", "--decorate", "-1"}, true},
+		{"pipe with JSON and --color", false, []string{"--json", "log", "--color", "--oneline", "-1"}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeTerminal(t, tt.terminal)
+			out, err := runCommand(t, gitCmd, tt.args...)
+			if err != nil {
+				t.Fatalf("git %v = %v\n%s", tt.args, err, out)
+			}
+			// git colors the hash yellow; mergeish's own headers may
+			// have other colors
+			if got := strings.Contains(out, "\x1b[33m"); got != tt.color {
+				t.Errorf("git %v: color = %v, want %v\n%q", tt.args, got, tt.color, out)
+			}
+			if tt.args[0] == "--json" && strings.Contains(out, "\x1b") {
+				t.Errorf("git %v: JSON output has escape codes\n%q", tt.args, out)
+			}
+		})
+	}
+}
+
+func TestGitColorNoColorEnv(t *testing.T) {
+	scopeWorkspace(t)
+	fakeTerminal(t, true)
+	t.Setenv("NO_COLOR", "1")
+
+	out, err := runCommand(t, gitCmd, "log", "--oneline", "--decorate", "-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("NO_COLOR output has color codes:\n%q", out)
+	}
+}
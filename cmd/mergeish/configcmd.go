@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the global config and inspect the workspace config",
+		Long: `Manage user-level defaults in the global config file,
+$XDG_CONFIG_HOME/mergeish/config.yml (or ~/.config/mergeish/config.yml).
+
+Keys are dotted paths, for example:
+  settings.parallel        default for every workspace
+  gh_token                 token for gh when GH_TOKEN is not set
+  workspaces.<name>        directory used by --workspace <name>
+
+Workspace configs override these settings. Repos cannot be set globally.
+
+config show --resolve <repo> prints a workspace repo's effective config.`,
+	}
+
+	cmd.AddCommand(configGetCmd())
+	cmd.AddCommand(configSetCmd())
+	cmd.AddCommand(configPathCmd())
+	cmd.AddCommand(configShowCmd())
+
+	return cmd
+}
+
+func configGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a global config value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := config.GetGlobal(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(value)
+			return nil
+		},
+	}
+}
+
+func configSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a global config value",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.SetGlobal(args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("Set %s = %s\n", args[0], args[1])
+			return nil
+		},
+	}
+}
+
+func configPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Print the global config file path",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := config.GlobalConfigPath()
+			if err != nil {
+				return err
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+}
+
+func configShowCmd() *cobra.Command {
+	var resolve string
+
+	cmd := &cobra.Command{
+		Use:   "show --resolve <repo>",
+		Short: "Print a workspace repo's effective config",
+		Long: `Print the config a repo in the workspace ends up with, as YAML: its own
+settings on top of its preset on top of repo_defaults, with variables in
+its url expanded.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := openWorkspace(true)
+			if err != nil {
+				return err
+			}
+			if ws, err = ws.FilterByName(resolve); err != nil {
+				return err
+			}
+			if len(ws.Repos) == 0 {
+				return fmt.Errorf("%s is not among the selected repos", resolve)
+			}
+
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(ws.Repos[0].Config); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	}
+
+	cmd.Flags().StringVar(&resolve, "resolve", "", "the repo to print, by path or name")
+	cmd.MarkFlagRequired("resolve")
+	cmd.RegisterFlagCompletionFunc("resolve", completeRepoNames)
+	return cmd
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func branchDescribeCmd() *cobra.Command {
+	var ticket string
+	var desc string
+	var owner string
+	var clear bool
+	var prune bool
+
+	cmd := &cobra.Command{
+		Use:   "describe [name]",
+		Short: "Show or set the ticket, description, and owner of a branch",
+		Long: `Show or set metadata for a cross-repo branch: a ticket ID, a one-line
+description, and an owner. The branch defaults to the current branch.
+
+The metadata is kept in .mergeish/state.json, is shown by mergeish status
+and mergeish branch, and is added to PRs by mergeish pr create: the title
+goes through settings.pr.title_template (default "{ticket}: {title}") and
+the body starts with the ticket, linked through settings.pr.ticket_url if
+set, and the description.
+
+Without flags, prints the metadata. Only the given flags are changed;
+--clear removes all of it. --prune removes the metadata of branches that
+no repo has any more; mergeish branch -d and mergeish branch --prune do
+this after deleting branches.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			flags := cmd.Flags()
+			setting := clear || flags.Changed("ticket") || flags.Changed("desc") || flags.Changed("owner")
+
+			if prune {
+				if len(args) > 0 || setting {
+					return fmt.Errorf("--prune takes no branch or other flags")
+				}
+				return pruneBranchInfo(ws)
+			}
+
+			var branch string
+			if len(args) > 0 {
+				branch = args[0]
+			} else {
+				current, consistent, err := ws.CheckBranchConsistency()
+				if err != nil {
+					return err
+				}
+				if !consistent {
+					return fmt.Errorf("repositories are on different branches, give a branch name")
+				}
+				if current == "" {
+					return fmt.Errorf("no repository is cloned, give a branch name")
+				}
+				branch = current
+			}
+
+			info, err := ws.BranchInfo(branch)
+			if err != nil {
+				return err
+			}
+
+			if !setting {
+				if info.IsZero() {
+					fmt.Printf("%s: no description\n", branch)
+					return nil
+				}
+				fmt.Printf("%s:\n", branch)
+				if info.Ticket != "" {
+					fmt.Printf("  ticket: %s\n", info.Ticket)
+				}
+				if info.Description != "" {
+					fmt.Printf("  description: %s\n", info.Description)
+				}
+				if info.Owner != "" {
+					fmt.Printf("  owner: %s\n", info.Owner)
+				}
+				return nil
+			}
+
+			if clear {
+				info = workspace.BranchInfo{}
+			}
+			if flags.Changed("ticket") {
+				info.Ticket = ticket
+			}
+			if flags.Changed("desc") {
+				info.Description = desc
+			}
+			if flags.Changed("owner") {
+				info.Owner = owner
+			}
+
+			if err := ws.SetBranchInfo(branch, info); err != nil {
+				return err
+			}
+			if info.IsZero() {
+				fmt.Printf("✓ Cleared description of %s\n", branch)
+			} else {
+				fmt.Printf("✓ %s: %s\n", branch, info)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&ticket, "ticket", "", "ticket ID, e.g. JIRA-123")
+	cmd.Flags().StringVar(&desc, "desc", "", "one-line description")
+	cmd.Flags().StringVar(&owner, "owner", "", "who owns the branch")
+	cmd.Flags().BoolVar(&clear, "clear", false, "remove the branch's metadata")
+	cmd.Flags().BoolVar(&prune, "prune", false, "remove metadata of branches no repo has")
+	return cmd
+}
+
+func pruneBranchInfo(ws *workspace.Workspace) error {
+	pruned, err := ws.PruneBranchInfo()
+	if err != nil {
+		return err
+	}
+	if len(pruned) == 0 {
+		fmt.Println("No branch metadata to prune")
+		return nil
+	}
+	for _, branch := range pruned {
+		fmt.Printf("  ✓ pruned %s\n", branch)
+	}
+	return nil
+}
+
+// branchInfoLines summarizes the metadata of each branch that has any
+func branchInfoLines(ws *workspace.Workspace, branches []string) []string {
+	sort.Strings(branches)
+	var lines []string
+	for _, branch := range branches {
+		info, err := ws.BranchInfo(branch)
+		if err != nil {
+			return []string{fmt.Sprintf("⚠ Warning: reading branch metadata: %v", err)}
+		}
+		if !info.IsZero() {
+			lines = append(lines, fmt.Sprintf("%s: %s", branch, info))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+)
+
+func diffCmd() *cobra.Command {
+	var staged bool
+	var stat bool
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "diff [ref | range]",
+		Short: "Show changes across all repositories",
+		Long: `Show changes across all repositories, followed by a combined summary.
+
+By default the working tree is compared with the index. Use --staged (or
+--cached) to show staged changes, or pass a ref to compare against it or a
+revision range such as main..HEAD. Use --stat to show a diffstat per repo
+instead of the full patch.
+
+Repos with no changes are skipped unless --all is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			opts := git.DiffOptions{Staged: staged, Stat: stat}
+			if len(args) == 1 {
+				opts.Ref = args[0]
+			}
+
+			results := ws.Diff(opts)
+
+			hasErrors := false
+			for _, r := range results {
+				if r.Error != nil {
+					fmt.Printf("── %s ──\n", r.Repo.Name())
+					fmt.Printf("error: %v\n\n", r.Error)
+					hasErrors = true
+					continue
+				}
+
+				if r.Diff.Patch == "" && !all {
+					continue
+				}
+
+				fmt.Printf("── %s ──\n", r.Repo.Name())
+				if r.Diff.Patch == "" {
+					fmt.Println("(no changes)")
+				} else {
+					fmt.Println(r.Diff.Patch)
+				}
+				fmt.Println()
+			}
+
+			// Combined summary
+			files, insertions, deletions := 0, 0, 0
+			fmt.Println("Summary:")
+			for _, r := range results {
+				if r.Error != nil || (len(r.Diff.Files) == 0 && !all) {
+					continue
+				}
+				d := r.Diff
+				fmt.Printf("  %s: %d file(s) changed, %d insertion(s)(+), %d deletion(s)(-)\n",
+					r.Repo.Name(), len(d.Files), d.Insertions(), d.Deletions())
+				files += len(d.Files)
+				insertions += d.Insertions()
+				deletions += d.Deletions()
+			}
+			fmt.Printf("  total: %d file(s) changed, %d insertion(s)(+), %d deletion(s)(-)\n",
+				files, insertions, deletions)
+
+			if hasErrors {
+				return fmt.Errorf("diff failed on some repositories")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&staged, "staged", false, "show staged changes")
+	cmd.Flags().BoolVar(&staged, "cached", false, "same as --staged")
+	cmd.Flags().BoolVar(&stat, "stat", false, "show a diffstat instead of the patch")
+	cmd.Flags().BoolVar(&all, "all", false, "include repos with no changes")
+	return cmd
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func doctorCmd() *cobra.Command {
+	var gitConfig bool
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that git, gh, and the workspace are set up correctly",
+		Long: `Check the environment mergeish depends on:
+
+  - git is installed
+  - gh is installed and authenticated (only needed for mergeish pr, and
+    skipped when no repo has PRs)
+  - the config file parses
+  - every repo URL is reachable
+
+With --git-config, also audits git config across repos (see
+mergeish git-config audit); differences are reported as warnings.
+
+Exits non-zero if a required check fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			failed := false
+
+			if v, err := git.Version(); err != nil {
+				fmt.Printf("  ✗ git: %v\n", err)
+				failed = true
+			} else {
+				fmt.Printf("  ✓ git %s\n", v)
+			}
+
+			ws, wsErr := loadWorkspace()
+
+			// gh is only needed for PR commands, so problems are warnings.
+			// Workspaces without any GitHub repos don't need it at all.
+			if wsErr == nil && !anyHasPRs(ws) {
+				fmt.Println("  - gh: not needed (no repo has PRs)")
+			} else if v, err := git.GHVersion(); err != nil {
+				fmt.Printf("  ! gh: %v (needed for mergeish pr)\n", err)
+			} else {
+				fmt.Printf("  ✓ gh %s\n", v)
+				if err := git.GHAuthStatus(); err != nil {
+					fmt.Printf("  ! gh: not authenticated, run `gh auth login` (needed for mergeish pr)\n")
+				} else {
+					fmt.Println("  ✓ gh authenticated")
+				}
+			}
+
+			if wsErr != nil {
+				fmt.Printf("  ✗ config: %v\n", wsErr)
+				return fmt.Errorf("some required checks failed")
+			}
+			fmt.Printf("  ✓ config: %s (%d repos)\n", ws.ConfigPath, len(ws.Repos))
+
+			if !printRemoteChecks(ws.CheckRemotes()) {
+				failed = true
+			}
+
+			if gitConfig {
+				fmt.Println("Git config:")
+				printConfigAudit(ws.AuditGitConfig())
+			}
+
+			if failed {
+				return fmt.Errorf("some required checks failed")
+			}
+
+			fmt.Println("All checks passed")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&gitConfig, "git-config", false, "also audit git config for differences between repos")
+
+	return cmd
+}
+
+// anyHasPRs reports whether any repo in ws has a PR provider
+func anyHasPRs(ws *workspace.Workspace) bool {
+	for _, r := range ws.Repos {
+		if r.Config.HasPRs() {
+			return true
+		}
+	}
+	return false
+}
+
+// printRemoteChecks prints whether each repo's URL is reachable and
+// reports whether all were
+func printRemoteChecks(results []workspace.Result) bool {
+	ok := true
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %s unreachable: %v\n", r.Repo.Name(), r.Repo.Config.URL, r.Error)
+			ok = false
+		} else {
+			fmt.Printf("  ✓ %s: %s reachable\n", r.Repo.Name(), r.Repo.Config.URL)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func foreachCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "foreach [--stop-on-error] [--only-output] [--only-failed] [--only-matching regex] [--json] [--sort] [--timing] [--repos list] [--exclude list] -- command...",
+		Short: "Run a shell command in each repository",
+		Long: `Run a shell command with sh -c in the directory of every repository, and
+print each repo's output under its name. MERGEISH_REPO is set to the repo's
+path in the config and MERGEISH_ROOT to the workspace root.
+
+Options go before the command, which can follow -- so that its own flags
+are left alone:
+  --stop-on-error          run in one repo at a time, and stop at the
+                           first repo the command fails in
+  --only-output, --only-failed, --only-matching, --json, --sort,
+  --timing, --repos, --exclude
+                           as for mergeish git
+
+Examples:
+  mergeish foreach -- make test
+  mergeish foreach --stop-on-error -- go mod tidy
+  mergeish foreach -- 'ls *.md | wc -l'
+  mergeish foreach --only-failed -- test -f LICENSE`,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stopOnError, help, args := parseForeachOptions(args)
+			if help {
+				return cmd.Help()
+			}
+			filter, args, err := parseGitFilter(args)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("command required")
+			}
+			command := strings.Join(args, " ")
+
+			// The identity line would break JSON output
+			load := loadWorkspaceForChange
+			if filter.json {
+				load = loadWorkspace
+			}
+			ws, err := load()
+			if err != nil {
+				return err
+			}
+
+			if !filter.json {
+				fmt.Printf("Running: %s\n\n", command)
+			}
+			results := ws.ForEachShell(command, stopOnError)
+			if filter.sort {
+				sortByRepo(results, func(r workspace.GitResult) string { return r.Repo.Name() })
+			}
+			if filter.json {
+				if err := printGitJSON([]string{command}, results, filter); err != nil {
+					return err
+				}
+				for _, r := range results {
+					if r.Error != nil {
+						return fmt.Errorf("command failed on some repositories")
+					}
+				}
+				return nil
+			}
+
+			if printGitResults(results, filter) {
+				return fmt.Errorf("command failed on some repositories")
+			}
+			return nil
+		},
+	}
+}
+
+// parseForeachOptions takes --stop-on-error and --help out of the options
+// before the command, leaving the rest for parseGitFilter
+func parseForeachOptions(args []string) (stopOnError, help bool, rest []string) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--stop-on-error":
+			stopOnError = true
+		case arg == "-h" || arg == "--help":
+			help = true
+		case arg == "--repos" || arg == "--exclude" || arg == "--only-matching":
+			// The option's value could look like the command
+			rest = append(rest, args[i:min(i+2, len(args))]...)
+			i++
+		case arg == "--" || !strings.HasPrefix(arg, "-"):
+			return stopOnError, help, append(rest, args[i:]...)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return stopOnError, help, rest
+}
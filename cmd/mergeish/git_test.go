@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDestructiveGit(t *testing.T) {
+	for _, tt := range []struct {
+		args        string
+		destructive bool
+	}{
+		{"reset --hard", true},
+		{"reset --hard origin/main", true},
+		{"-C sub reset --hard", true},
+		{"-c core.pager=cat reset --hard", true},
+		{"reset --soft HEAD~1", false},
+		{"reset", false},
+		{"clean -f", true},
+		{"clean -fdx", true},
+		{"clean -xdf", true},
+		{"clean --force -d", true},
+		{"clean -n", false},
+		{"clean -n -- -f", false},
+		{"checkout -- README.md", true},
+		{"checkout main -- .", true},
+		{"checkout main", false},
+		{"checkout -b feat", false},
+		{"branch -D old", true},
+		{"branch --delete --force old", true},
+		{"branch -d -f old", true},
+		{"branch -d old", false},
+		{"branch -f old HEAD", false},
+		{"status", false},
+		{"log --oneline -5", false},
+		{"-C sub", false},
+	} {
+		got := destructiveGit(strings.Fields(tt.args)) != ""
+		if got != tt.destructive {
+			t.Errorf("destructiveGit(%q) = %v, want %v", tt.args, got, tt.destructive)
+		}
+	}
+}
+
+func TestParseGitFilterTiming(t *testing.T) {
+	for _, flag := range []string{"--timing", "--timings"} {
+		timing = false
+		_, rest, err := parseGitFilter([]string{flag, "status"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !timing || len(rest) != 1 || rest[0] != "status" {
+			t.Errorf("parseGitFilter(%s status) left timing = %v and %q for git", flag, timing, rest)
+		}
+	}
+	timing = false
+}
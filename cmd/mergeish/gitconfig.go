@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/repo"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func gitConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git-config",
+		Short: "Inspect git config across all repositories",
+	}
+
+	cmd.AddCommand(gitConfigAuditCmd())
+
+	return cmd
+}
+
+func gitConfigAuditCmd() *cobra.Command {
+	var fixes []string
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Find repos whose git config differs from the rest",
+		Long: `Compare git config keys that change how commands behave (pull.rebase,
+push.default, core.autocrlf, commit.gpgsign, ...) across all repositories.
+
+Repos are flagged when their effective value differs from most other repos
+or is a value mergeish can't work with. Add keys with
+settings.config_audit_keys.
+
+--fix key=value sets a repo-local value in every repo that doesn't already
+have it, or in every repo named with --repo. With --repo, only the named
+repos are audited.
+
+Exits non-zero if any repo is flagged.`,
+		Example: `  mergeish git-config audit
+  mergeish git-config audit --fix pull.rebase=true
+  mergeish git-config audit --fix core.autocrlf=input --repo services/api`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			type fix struct{ key, value string }
+			var parsed []fix
+			for _, f := range fixes {
+				key, value, ok := strings.Cut(f, "=")
+				if !ok || key == "" {
+					return fmt.Errorf("invalid --fix %q, expected key=value", f)
+				}
+				parsed = append(parsed, fix{key, value})
+			}
+			load := loadWorkspace
+			if len(parsed) > 0 {
+				load = loadWorkspaceForChange
+			}
+			ws, err := load()
+			if err != nil {
+				return err
+			}
+
+			for _, f := range parsed {
+				target := ws
+				if len(repoNames) == 0 && len(includeRepos) == 0 {
+					target = ws.Filter(func(r *repo.Repo) bool {
+						v, err := r.GetConfig(f.key)
+						return err != nil || !v.Set() || v.Value != f.value
+					})
+				}
+				if len(target.Repos) == 0 {
+					fmt.Printf("%s is already %s everywhere\n", f.key, f.value)
+					continue
+				}
+
+				fmt.Printf("Setting %s=%s...\n", f.key, f.value)
+				if err := printResults(target.SetGitConfig(f.key, f.value), "failed to set git config on some repositories"); err != nil {
+					return err
+				}
+			}
+
+			fmt.Println("Auditing git config...")
+			if !printConfigAudit(ws.AuditGitConfig()) {
+				return fmt.Errorf("git config differs across repositories")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&fixes, "fix", nil, "set key=value in the repos' local git config (repeatable)")
+
+	return cmd
+}
+
+// printConfigAudit prints each audited key, listing the repos flagged for
+// it, and reports whether no repo was flagged
+func printConfigAudit(audit workspace.ConfigAudit) bool {
+	ok := true
+	for _, res := range audit.Results {
+		if res.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", res.Repo.Name(), res.Error)
+			ok = false
+		}
+	}
+
+	for _, key := range audit.Keys {
+		var findings []workspace.ConfigFinding
+		for _, f := range audit.Findings {
+			if f.Key == key {
+				findings = append(findings, f)
+			}
+		}
+
+		if len(findings) == 0 {
+			majority := audit.Majority[key]
+			if majority == "" {
+				majority = "unset"
+			}
+			fmt.Printf("  ✓ %s: %s\n", key, majority)
+			continue
+		}
+
+		ok = false
+		fmt.Printf("  ! %s:\n", key)
+		for _, f := range findings {
+			source := ""
+			if f.Value.Set() {
+				source = fmt.Sprintf(" (%s, %s)", f.Value.Scope, f.Value.Origin)
+			}
+			fmt.Printf("      %s: %s%s - %s\n", f.Repo.Name(), f.Value, source, f.Reason)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+// Every --json output is rendered through printJSON and follows the same
+// rules, so runs can be diffed:
+//
+//   - repos are listed in config order, or by path with --sort
+//   - objects are structs with fixed keys; maps are written with sorted keys
+//   - times are UTC RFC 3339 strings (jsonTime)
+//   - durations are integer milliseconds
+//   - no floats
+//   - empty lists are [] rather than null
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// jsonTime formats t for JSON output
+func jsonTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// sortByRepo orders items by the repo path name returns, keeping config
+// order among equal names
+func sortByRepo[T any](items []T, name func(T) string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return name(items[i]) < name(items[j])
+	})
+}
+
+// errorString returns err's message, or "" for nil
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+type statusJSON struct {
+	Repo       string           `json:"repo"`
+	Branch     string           `json:"branch"`
+	Detached   bool             `json:"detached"`
+	Worktree   bool             `json:"worktree"`
+	State      string           `json:"state"`
+	Upstream   string           `json:"upstream"`
+	Ahead      int              `json:"ahead"`
+	Behind     int              `json:"behind"`
+	Files      []fileStatusJSON `json:"files"`
+	LastCommit *commitJSON      `json:"last_commit"` // null with --no-commit-info
+	Fetch      *fetchJSON       `json:"fetch"`       // null without --fetch
+	Error      string           `json:"error,omitempty"`
+}
+
+type fileStatusJSON struct {
+	Path       string `json:"path"`
+	OrigPath   string `json:"orig_path,omitempty"`
+	Status     string `json:"status"`
+	Conflicted bool   `json:"conflicted"`
+}
+
+type commitJSON struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Time    string `json:"time"`
+	Subject string `json:"subject"`
+}
+
+type fetchJSON struct {
+	OK         bool   `json:"ok"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// printStatusJSON prints mergeish status --json. fetched holds the fetch
+// results by repo name when --fetch was given.
+func printStatusJSON(results []workspace.StatusResult, fetched map[string]workspace.Result) error {
+	out := make([]statusJSON, len(results))
+	for i, r := range results {
+		o := statusJSON{Repo: r.Repo.Name(), Files: []fileStatusJSON{}, Error: errorString(r.Error)}
+		if f, ok := fetched[r.Repo.Name()]; ok {
+			o.Fetch = &fetchJSON{OK: f.Error == nil, DurationMS: f.Duration.Milliseconds(), Error: errorString(f.Error)}
+		}
+		if s := r.Status; s != nil {
+			o.Branch, o.Detached, o.State, o.Upstream = s.Branch, s.Detached, s.State, s.Upstream
+			o.Worktree = s.Worktree
+			o.Ahead, o.Behind = s.Ahead, s.Behind
+			if c := s.LastCommit; c != nil {
+				o.LastCommit = &commitJSON{Hash: c.Hash, Author: c.Author, Time: jsonTime(c.Time), Subject: c.Subject}
+			}
+			for _, f := range s.Files {
+				o.Files = append(o.Files, fileStatusJSON{Path: f.Path, OrigPath: f.OrigPath, Status: f.Status, Conflicted: f.Conflicted})
+			}
+		}
+		out[i] = o
+	}
+	return printJSON(out)
+}
+
+type prStatusJSON struct {
+	Repo  string  `json:"repo"`
+	HasPR bool    `json:"has_prs"` // false for repos with provider: none
+	PR    *prJSON `json:"pr"`      // null when the branch has no PR
+	Error string  `json:"error,omitempty"`
+}
+
+type prJSON struct {
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	State     string   `json:"state"`
+	Branch    string   `json:"branch"`
+	Checks    string   `json:"checks"`
+	Review    string   `json:"review"`
+	Mergeable string   `json:"mergeable"`
+	Draft     bool     `json:"draft"`
+	Author    string   `json:"author"`
+	Labels    []string `json:"labels"`
+}
+
+// newPRJSON converts pr for JSON output
+func newPRJSON(pr *git.PRInfo) *prJSON {
+	labels := append([]string{}, pr.Labels...)
+	return &prJSON{
+		Number:    pr.Number,
+		Title:     pr.Title,
+		URL:       pr.URL,
+		State:     pr.State,
+		Branch:    pr.Branch,
+		Checks:    pr.Checks,
+		Review:    pr.Review,
+		Mergeable: pr.Mergeable,
+		Draft:     pr.Draft,
+		Author:    pr.Author,
+		Labels:    labels,
+	}
+}
+
+// printPRStatusJSON prints mergeish pr status --json
+func printPRStatusJSON(results []workspace.PRResult) error {
+	out := make([]prStatusJSON, len(results))
+	for i, r := range results {
+		o := prStatusJSON{Repo: r.Repo.Name(), HasPR: !r.NoPRs, Error: errorString(r.Error)}
+		if r.PR != nil {
+			o.PR = newPRJSON(r.PR)
+		}
+		out[i] = o
+	}
+	return printJSON(out)
+}
+
+type prListJSON struct {
+	Repo  string    `json:"repo"`
+	HasPR bool      `json:"has_prs"` // false for repos with provider: none
+	PRs   []*prJSON `json:"prs"`
+	Error string    `json:"error,omitempty"`
+}
+
+// printPRListJSON prints mergeish pr list --json
+func printPRListJSON(results []workspace.PRListResult) error {
+	out := make([]prListJSON, len(results))
+	for i, r := range results {
+		o := prListJSON{Repo: r.Repo.Name(), HasPR: !r.NoPRs, PRs: []*prJSON{}, Error: errorString(r.Error)}
+		for j := range r.PRs {
+			o.PRs = append(o.PRs, newPRJSON(&r.PRs[j]))
+		}
+		out[i] = o
+	}
+	return printJSON(out)
+}
+
+type gitJSON struct {
+	Command []string        `json:"command"`
+	Repos   []gitResultJSON `json:"repos"`
+	Hidden  int             `json:"hidden"` // repos left out by the --only-* filters
+}
+
+type gitResultJSON struct {
+	Repo     string `json:"repo"`
+	ExitCode *int   `json:"exit_code"` // null if git didn't run to completion
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Error    string `json:"error,omitempty"`
+}
+
+// printGitJSON prints mergeish git --json. Repos hidden by the filter are
+// left out and counted.
+func printGitJSON(args []string, results []workspace.GitResult, filter gitFilter) error {
+	out := gitJSON{Command: args, Repos: []gitResultJSON{}}
+	for _, r := range results {
+		if !filter.show(r) {
+			out.Hidden++
+			continue
+		}
+		o := gitResultJSON{Repo: r.Repo.Name(), Stdout: r.Stdout, Stderr: r.Stderr, Error: errorString(r.Error)}
+		var exitErr *exec.ExitError
+		switch {
+		case r.Error == nil:
+			o.ExitCode = new(int)
+		case errors.As(r.Error, &exitErr):
+			code := exitErr.ExitCode()
+			o.ExitCode = &code
+		}
+		out.Repos = append(out.Repos, o)
+	}
+	return printJSON(out)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/willnewby/mergeish/internal/config"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// captureStdout returns what fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func() error) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fnErr := fn()
+	w.Close()
+	out := <-done
+	if fnErr != nil {
+		t.Fatal(fnErr)
+	}
+	return out
+}
+
+// assertGolden compares got with testdata/name, or rewrites the file with
+// -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// testRepo returns a repo at path for building results; it is never cloned
+func testRepo(path string) *repo.Repo {
+	return repo.New(config.RepoConfig{URL: "git@github.com:org/" + path + ".git", Path: path}, "/workspace")
+}
+
+func TestStatusJSONGolden(t *testing.T) {
+	results := []workspace.StatusResult{
+		{
+			Repo: testRepo("api"),
+			Status: &git.Status{
+				Branch:   "feat",
+				Upstream: "origin/feat",
+				Ahead:    2,
+				Behind:   1,
+				Files: []git.FileStatus{
+					{Path: "main.go", Status: "M"},
+					{Path: "new.go", OrigPath: "old.go", Status: "R"},
+					{Path: "conflict.go", Status: "UU", Conflicted: true},
+					{Path: "notes.txt", Status: "??"},
+				},
+				LastCommit: &git.Commit{
+					Hash:    "0123456789abcdef0123456789abcdef01234567",
+					Author:  "Ada",
+					Time:    time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600)),
+					Subject: "Add <things> & \"stuff\"",
+				},
+			},
+		},
+		{
+			Repo:   testRepo("web"),
+			Status: &git.Status{Branch: "main", Detached: true, State: git.StateRebase, Worktree: true},
+		},
+		{Repo: testRepo("docs"), Error: errors.New("not cloned")},
+	}
+	fetched := map[string]workspace.Result{
+		"api": {Duration: 1500 * time.Millisecond},
+		"web": {Error: errors.New("could not resolve host"), Duration: 20 * time.Millisecond},
+	}
+
+	got := captureStdout(t, func() error { return printStatusJSON(results, fetched) })
+	assertGolden(t, "status.golden", got)
+}
+
+func TestPRStatusJSONGolden(t *testing.T) {
+	results := []workspace.PRResult{
+		{
+			Repo: testRepo("api"),
+			PR: &git.PRInfo{
+				Number:    42,
+				Title:     "Add the thing",
+				URL:       "https://github.com/org/api/pull/42",
+				State:     "OPEN",
+				Branch:    "feat",
+				Checks:    "pass",
+				Review:    "APPROVED",
+				Mergeable: "MERGEABLE",
+				Author:    "ada",
+				Labels:    []string{"enhancement"},
+			},
+		},
+		{
+			Repo: testRepo("web"),
+			PR:   &git.PRInfo{Number: 7, State: "OPEN", Draft: true, Branch: "feat"},
+		},
+		{Repo: testRepo("docs")},
+		{Repo: testRepo("local"), NoPRs: true},
+		{Repo: testRepo("broken"), Error: errors.New("gh: not logged in")},
+	}
+
+	got := captureStdout(t, func() error { return printPRStatusJSON(results) })
+	assertGolden(t, "pr_status.golden", got)
+}
+
+func TestGitJSONGolden(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	results := []workspace.GitResult{
+		{Repo: testRepo("api"), Stdout: "## feat...origin/feat\n M main.go\n"},
+		{Repo: testRepo("web"), Stdout: "", Stderr: "fatal: bad revision\n", Error: exitErr},
+		{Repo: testRepo("docs"), Error: errors.New("not cloned")},
+		{Repo: testRepo("quiet")},
+	}
+
+	got := captureStdout(t, func() error {
+		return printGitJSON([]string{"status", "-sb"}, results, gitFilter{json: true})
+	})
+	assertGolden(t, "git.golden", got)
+
+	// Hidden repos are counted, not listed
+	got = captureStdout(t, func() error {
+		return printGitJSON([]string{"status", "-sb"}, results, gitFilter{json: true, onlyFailed: true})
+	})
+	assertGolden(t, "git_only_failed.golden", got)
+}
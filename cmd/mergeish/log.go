@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func logCmd() *cobra.Command {
+	var limit int
+	var since string
+	var branchOnly bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show an interleaved commit log across all repositories",
+		Long: `Show recent commits from all repositories merged chronologically,
+each prefixed with the repo name.
+
+Examples:
+  mergeish log
+  mergeish log -n 50 --since "2 weeks ago"
+  mergeish log --branch-only
+  mergeish log --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			opts := git.LogOptions{Limit: limit, Since: since}
+			results := ws.Log(opts, branchOnly)
+
+			hasErrors := false
+			for _, r := range results {
+				if r.Error != nil {
+					fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+				}
+			}
+
+			entries := workspace.MergeLogs(results)
+			if limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+
+			if jsonOutput {
+				if err := printLogJSON(entries); err != nil {
+					return err
+				}
+			} else {
+				printLog(entries)
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to read log for some repositories")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "number", "n", 20, "number of commits to show")
+	cmd.Flags().StringVar(&since, "since", "", "show commits more recent than a date")
+	cmd.Flags().BoolVar(&branchOnly, "branch-only", false, "only show commits not on the base branch")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func printLog(entries []workspace.LogEntry) {
+	width := 0
+	for _, e := range entries {
+		if n := len(e.Repo.Name()); n > width {
+			width = n
+		}
+	}
+
+	for _, e := range entries {
+		c := e.Commit
+		fmt.Printf("%-*s  %s  %s  %s (%s)\n",
+			width, e.Repo.Name(), shortHash(c.Hash), c.Time.Format("2006-01-02 15:04"), c.Subject, c.Author)
+	}
+}
+
+func printLogJSON(entries []workspace.LogEntry) error {
+	type logJSON struct {
+		Repo    string `json:"repo"`
+		Hash    string `json:"hash"`
+		Author  string `json:"author"`
+		Time    string `json:"time"`
+		Subject string `json:"subject"`
+		Body    string `json:"body"`
+	}
+
+	out := make([]logJSON, len(entries))
+	for i, e := range entries {
+		out[i] = logJSON{
+			Repo:    e.Repo.Name(),
+			Hash:    e.Commit.Hash,
+			Author:  e.Commit.Author,
+			Time:    jsonTime(e.Commit.Time),
+			Subject: e.Commit.Subject,
+			Body:    e.Commit.Body,
+		}
+	}
+
+	return printJSON(out)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
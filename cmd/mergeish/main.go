@@ -1,14 +1,26 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/willnewby/mergeish/internal/config"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/jsonlog"
+	"github.com/willnewby/mergeish/internal/metrics"
+	"github.com/willnewby/mergeish/internal/repo"
 	"github.com/willnewby/mergeish/internal/workspace"
 )
 
@@ -18,7 +30,23 @@ var (
 	commit  = "none"
 	date    = "unknown"
 
-	configPath string
+	configPath    string
+	workspaceName string
+	repoNames     []string
+	includeRepos  []string
+	excludeRepos  []string
+	jobs          int
+	assumeYes     bool
+	timing        bool
+	noFetch       bool
+	timeout       time.Duration
+	timeoutSet    bool
+	retries       int
+	retriesSet    bool
+
+	metricsFile   string
+	metricsFormat string
+	logJSON       bool
 )
 
 func main() {
@@ -30,29 +58,171 @@ func main() {
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "path to config file")
+	rootCmd.PersistentFlags().StringVarP(&workspaceName, "workspace", "w", "", "use a workspace shortcut from the global config")
+	rootCmd.PersistentFlags().StringArrayVarP(&repoNames, "repo", "r", nil, "only act on this repo, by path or name (repeatable)")
+	rootCmd.PersistentFlags().StringSliceVar(&includeRepos, "repos", nil, "only act on repos matching these comma-separated names or globs, e.g. api,web")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeRepos, "exclude", nil, "don't act on repos matching these comma-separated names or globs, e.g. 'legacy-*'")
+	for _, flag := range []string{"repo", "repos", "exclude"} {
+		rootCmd.RegisterFlagCompletionFunc(flag, completeRepoNames)
+	}
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "run at most this many repos at once (overrides settings.max_jobs)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "skip confirmation prompts")
+	rootCmd.PersistentFlags().BoolVar(&noFetch, "no-fetch", false, "never fetch implicitly; trust existing remote refs")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "kill any git or gh process running longer than this (overrides settings.timeout)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 0, "retry clone/pull/push/fetch this many times on transient network errors (overrides settings.retries)")
+	rootCmd.PersistentFlags().BoolVar(&timing, "timing", false, "show how long each repo took, and print timing and gh usage after the command")
+	// --timings was once a separate switch for the per-repo durations
+	rootCmd.PersistentFlags().BoolVar(&timing, "timings", false, "alias of --timing")
+	rootCmd.PersistentFlags().MarkHidden("timings")
+	rootCmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", "", "write run metrics to this file")
+	rootCmd.PersistentFlags().StringVar(&metricsFormat, "metrics-format", metrics.FormatJSON, "metrics file format (json or prometheus)")
+	rootCmd.PersistentFlags().BoolVar(&logJSON, "log-json", false, "write an event per repo, operation, and command to stdout as JSON lines; other output goes to stderr")
+
+	// With --log-json, stdout carries only events, so everything the
+	// commands print is moved to stderr
+	stdout := os.Stdout
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// Catch a bad format before the command runs, not after
+		if metricsFile != "" {
+			if err := metrics.CheckFormat(metricsFormat); err != nil {
+				return err
+			}
+		}
+		if logJSON {
+			jsonlog.Start(stdout, cmd.CommandPath())
+			os.Stdout = os.Stderr
+		}
+		return nil
+	}
+
+	// --timeout 0 disables the configured timeout and --retries 0 the
+	// configured retries, so record whether they were given
+	cobra.OnInitialize(func() {
+		timeoutSet = rootCmd.PersistentFlags().Changed("timeout")
+		retriesSet = rootCmd.PersistentFlags().Changed("retries")
+	})
 
 	rootCmd.AddCommand(
 		initCmd(),
+		addCmd(),
+		removeCmd(),
 		cloneCmd(),
+		recloneCmd(),
 		pullCmd(),
 		pushCmd(),
+		mergeCmd(),
+		rebaseCmd(),
+		cherryPickCmd(),
 		branchCmd(),
 		commitCmd(),
 		statusCmd(),
 		gitCmd(),
+		foreachCmd(),
 		prCmd(),
+		tagCmd(),
+		diffCmd(),
+		replaceCmd(),
+		logCmd(),
+		stashCmd(),
+		migrateDefaultBranchCmd(),
+		validateCmd(),
+		doctorCmd(),
+		syncCmd(),
+		shipCmd(),
+		updateCmd(),
+		versionCmd(),
+		configCmd(),
+		gitConfigCmd(),
+		maintenanceCmd(),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	printParallelLimits(metrics.Get())
+	printCorruptRepos(metrics.Get())
+
+	if logJSON {
+		if !jsonlog.Enabled() {
+			jsonlog.Start(stdout, cmd.CommandPath())
+		}
+		jsonlog.Command(time.Since(start), err)
+	}
+
+	if timing {
+		fmt.Fprintf(os.Stderr, "\nelapsed: %s\n", time.Since(start).Round(time.Millisecond))
+		printOperationTimings(metrics.Get())
+		fmt.Fprintln(os.Stderr, git.GetGHMetrics())
+	}
+
+	// A bad --metrics-format already failed the command before it ran
+	if metricsFile != "" && metrics.CheckFormat(metricsFormat) == nil {
+		gm := git.GetGHMetrics()
+		run := metrics.Run{
+			Command: cmd.CommandPath(),
+			Err:     err,
+			GH:      metrics.GHStats{Calls: gm.Calls, RateLimitRetries: gm.Retries, Waited: gm.Waited},
+		}
+		if werr := metrics.WriteFile(metricsFile, metricsFormat, run); werr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", werr)
+			if err == nil {
+				err = werr
+			}
+		}
+	}
+
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// printParallelLimits notes operations that had to run fewer git processes
+// at once because the system ran out of processes or file descriptors
+func printParallelLimits(snap metrics.Snapshot) {
+	for _, op := range snap.Operations {
+		if op.ParallelLimit > 0 {
+			fmt.Fprintf(os.Stderr, "Note: %s ran out of processes or file descriptors and continued with %d git processes at a time; set --jobs or settings.max_jobs to avoid this\n", op.Name, op.ParallelLimit)
+		}
+	}
+}
+
+// printOperationTimings prints how long each workspace operation took and how
+// many subprocesses were run
+func printOperationTimings(snap metrics.Snapshot) {
+	for _, op := range snap.Operations {
+		ok, failed := op.Outcomes()
+		fmt.Fprintf(os.Stderr, "%s: %s (%d ok, %d failed)\n", op.Name, op.Duration.Round(time.Millisecond), ok, failed)
+	}
+	fmt.Fprintf(os.Stderr, "subprocesses: git=%d gh=%d", snap.Subprocesses["git"], snap.Subprocesses["gh"])
+	if snap.Retries > 0 {
+		fmt.Fprintf(os.Stderr, ", %d network retries", snap.Retries)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
 func getConfigPath() (string, error) {
 	if configPath != "" {
 		return configPath, nil
 	}
 
+	if workspaceName != "" {
+		global, err := config.LoadGlobal()
+		if err != nil {
+			return "", err
+		}
+		dir, ok := global.Workspaces[workspaceName]
+		if !ok {
+			return "", fmt.Errorf("no workspace %q in the global config; add it with `mergeish config set workspaces.%s <dir>`", workspaceName, workspaceName)
+		}
+		if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, rest)
+		}
+		return config.FindConfigFile(dir)
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "", err
@@ -62,20 +232,313 @@ func getConfigPath() (string, error) {
 }
 
 func loadWorkspace() (*workspace.Workspace, error) {
+	return openWorkspace(false)
+}
+
+// openWorkspace loads the workspace. Unless allRepos is set, repos that
+// aren't cloned are left out according to settings.uncloned_policy.
+func openWorkspace(allRepos bool) (*workspace.Workspace, error) {
 	path, err := getConfigPath()
 	if err != nil {
 		return nil, err
 	}
 
-	return workspace.Load(path)
+	ws, err := workspace.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	ws.NoFetch = noFetch
+	if jobs < 0 {
+		return nil, fmt.Errorf("--jobs must be 0 or more, got %d", jobs)
+	}
+	if jobs > 0 {
+		ws.MaxJobs = jobs
+	}
+	if retriesSet {
+		if retries < 0 {
+			return nil, fmt.Errorf("--retries must be 0 or more, got %d", retries)
+		}
+		for _, r := range ws.Repos {
+			r.Settings.Retries = retries
+			r.Retry.Retries = retries
+		}
+	}
+	if len(repoNames) > 0 {
+		if ws, err = ws.FilterByName(repoNames...); err != nil {
+			return nil, err
+		}
+	}
+	if len(includeRepos) > 0 || len(excludeRepos) > 0 {
+		if ws, err = ws.Select(includeRepos, excludeRepos); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyGHToken(); err != nil {
+		return nil, err
+	}
+	if timeoutSet {
+		git.SetTimeout(timeout)
+	}
+
+	if !allRepos {
+		ws.ApplyUnclonedPolicy()
+		if ws.Config.Settings.UnclonedPolicy == config.UnclonedSkip && len(ws.Uncloned) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipping %d repos that aren't cloned (mergeish status --all lists them)\n", len(ws.Uncloned))
+		}
+	}
+
+	return ws, nil
+}
+
+// completeRepoNames completes the names of the repos in the config, after
+// any comma-separated names already given
+func completeRepoNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	path, err := getConfigPath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ws, err := workspace.Load(path)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	names := make([]string, 0, len(ws.Repos))
+	for _, r := range ws.Repos {
+		names = append(names, prefix+r.Name())
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// applyGHToken exports the global config's gh token for gh to use, unless
+// GH_TOKEN is already set
+func applyGHToken() error {
+	if os.Getenv("GH_TOKEN") != "" {
+		return nil
+	}
+	global, err := config.LoadGlobal()
+	if err != nil {
+		return err
+	}
+	if global.GHToken != "" {
+		return os.Setenv("GH_TOKEN", global.GHToken)
+	}
+	return nil
+}
+
+// loadWorkspaceForChange loads the workspace for a command that modifies
+// repos and prints which workspace it is, so the wrong one is easy to spot
+func loadWorkspaceForChange() (*workspace.Workspace, error) {
+	ws, err := loadWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	printIdentity(ws)
+	return ws, nil
+}
+
+// printIdentity prints the workspace name, config path, and repo count
+func printIdentity(ws *workspace.Workspace) {
+	fmt.Printf("Workspace %s (%s, %d repos)\n", colorize(colorBold, ws.Name()), ws.ConfigPath, len(ws.Repos))
+}
+
+// confirmDestructive asks before a high-risk operation. The prompt names the
+// workspace, and with settings.confirm_name the name must be typed out.
+// It returns true without prompting when --yes was given.
+func confirmDestructive(ws *workspace.Workspace, prompt string) bool {
+	if assumeYes {
+		return true
+	}
+
+	name := ws.Name()
+	if !ws.Config.Settings.ConfirmName {
+		return confirm(fmt.Sprintf("[%s] %s", name, prompt))
+	}
+
+	fmt.Printf("[%s] %s\nType the workspace name to continue: ", name, prompt)
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil {
+		return false
+	}
+	return response == name
+}
+
+// confirm prints a prompt and returns true if the user answered yes.
+// It returns true without prompting when --yes was given.
+func confirm(prompt string) bool {
+	if assumeYes {
+		return true
+	}
+
+	fmt.Printf("%s [y/N]: ", prompt)
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil {
+		return false
+	}
+	return response == "y" || response == "Y"
+}
+
+// printResults prints a ✓/✗ line per repo and returns an error with failMsg
+// if any repo failed
+func printResults(results []workspace.Result, failMsg string) error {
+	hasErrors := false
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s%s: %v%s\n", r.Repo.Name(), durationNote(r.Duration), r.Error, attemptsNote(r))
+			hasErrors = true
+		} else {
+			fmt.Printf("  ✓ %s%s%s\n", r.Repo.Name(), durationNote(r.Duration), attemptsNote(r))
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("%s", failMsg)
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
+
+const (
+	colorRed   = "31"
+	colorGreen = "32"
+	colorBold  = "1"
+)
+
+// dryRunPrefix returns the prefix for output lines of a dry run
+func dryRunPrefix(dryRun bool) string {
+	if dryRun {
+		return "[dry-run] "
+	}
+	return ""
+}
+
+// colorize wraps s in an ANSI color code when output is colored
+func colorize(color, s string) string {
+	if !useColor() {
+		return s
+	}
+	return "\033[" + color + "m" + s + "\033[0m"
+}
+
+// useColor reports whether output is colored: when stdout is a terminal and
+// NO_COLOR isn't set
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return stdoutIsTerminal()
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal; tests replace it
+var stdoutIsTerminal = func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// attemptsNote returns a note about retries when an operation needed more
+// than one attempt, so flaky repos stand out
+func attemptsNote(r workspace.Result) string {
+	if r.Attempts > 1 {
+		return fmt.Sprintf(" (%d attempts)", r.Attempts)
+	}
+	return ""
+}
+
+// durationNote returns how long a repo took, for printing after its name
+// when --timing was given
+func durationNote(d time.Duration) string {
+	if !timing {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", formatDuration(d))
+}
+
+// formatDuration rounds d for display: to the millisecond below a second
+// and to a tenth of a second above
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// reportStashLeft lists repos whose autostashed changes could not be
+// restored, so they are not lost silently
+func reportStashLeft(results []workspace.Result) {
+	var left []string
+	for _, r := range results {
+		var stashErr *workspace.StashLeftError
+		if errors.As(r.Error, &stashErr) {
+			left = append(left, r.Repo.Name())
+		}
+	}
+
+	if len(left) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("⚠ Stashed changes were left behind in:")
+	for _, name := range left {
+		fmt.Printf("    %s\n", name)
+	}
+	fmt.Println("  Resolve conflicts and run 'git stash pop' in each to restore them.")
+}
+
+// reportConflicts lists the conflicted files of every repo a pull or merge
+// left mid-merge or mid-rebase, and reports whether there were any
+func reportConflicts(results []workspace.Result) bool {
+	op := ""
+	for _, r := range results {
+		var conflict *git.ConflictError
+		if !errors.As(r.Error, &conflict) {
+			continue
+		}
+		if op == "" {
+			fmt.Println()
+			fmt.Println("Conflicts:")
+			op = conflict.Op
+		}
+		fmt.Printf("  %s:\n", r.Repo.Name())
+		for _, path := range conflict.Paths {
+			fmt.Printf("    %s\n", path)
+		}
+	}
+	if op != "" {
+		fmt.Printf("  Resolve them, 'git add' the files, and run 'git %s --continue' in each.\n", op)
+	}
+	return op != ""
+}
+
+// isTerminal reports whether stdin is attached to a terminal
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func initCmd() *cobra.Command {
-	return &cobra.Command{
+	var format string
+
+	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize a new mergeish workspace",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := config.DefaultConfigFile
+			var path string
+			switch format {
+			case "yaml", "yml":
+				path = config.DefaultConfigFile
+			case "toml":
+				path = config.DefaultTOMLConfigFile
+			default:
+				return fmt.Errorf("unsupported format %q (use yaml or toml)", format)
+			}
 			if configPath != "" {
 				path = configPath
 			}
@@ -95,30 +558,254 @@ func initCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&format, "format", "yaml", "config file format (yaml or toml)")
+	return cmd
+}
+
+func addCmd() *cobra.Command {
+	var url string
+	var path string
+	var branch string
+	var clone bool
+
+	cmd := &cobra.Command{
+		Use:   "add [url]",
+		Short: "Add a repository to the config file",
+		Long: `Add a repository to the config file.
+
+The URL can be given as an argument or with --url. Without --path, the
+repo is placed in a directory named after the repository. --branch sets
+the repo's own default branch.
+
+Examples:
+  mergeish add git@github.com:org/repo.git
+  mergeish add git@github.com:org/repo.git --path services/repo --clone
+  mergeish add --url git@github.com:org/legacy.git --branch master`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if url != "" && url != args[0] {
+					return fmt.Errorf("give the URL either as an argument or with --url, not both")
+				}
+				url = args[0]
+			}
+			if url == "" {
+				return fmt.Errorf("a repository URL is required")
+			}
+			if path == "" {
+				path = config.RepoName(url)
+				if path == "" {
+					return fmt.Errorf("cannot derive a path from %q; use --path", url)
+				}
+			}
+
+			cfgPath, err := getConfigPath()
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load(cfgPath)
+			if err != nil {
+				return err
+			}
+
+			rc := config.RepoConfig{URL: url, Path: filepath.Clean(path)}
+			if branch != "" {
+				rc.Settings = &config.RepoSettings{DefaultBranch: branch}
+			}
+			if err := cfg.AddRepo(rc); err != nil {
+				return err
+			}
+
+			r := repo.New(cfg.Repos[len(cfg.Repos)-1], filepath.Dir(cfgPath))
+			if r.Exists() && !clone {
+				fmt.Printf("Warning: %s already exists but was not in the config\n", r.FullPath)
+			}
+
+			if err := cfg.Save(cfgPath); err != nil {
+				return err
+			}
+			fmt.Printf("Added %s to %s\n", rc.Path, cfgPath)
+
+			if clone {
+				if r.IsCloned() {
+					fmt.Printf("  - %s: already cloned\n", r.Name())
+					return nil
+				}
+				fmt.Printf("Cloning %s...\n", r.Name())
+				if err := r.Clone(false); err != nil {
+					return err
+				}
+				fmt.Println("Done!")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&url, "url", "", "git URL of the repository")
+	cmd.Flags().StringVar(&path, "path", "", "local path relative to the workspace root (default: the repository name)")
+	cmd.Flags().StringVar(&branch, "branch", "", "default branch of the repository, if not the workspace default")
+	cmd.Flags().BoolVar(&clone, "clone", false, "clone the repository after adding it")
+	return cmd
+}
+
+func removeCmd() *cobra.Command {
+	var deleteDir bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "remove <path|name>",
+		Short: "Remove a repository from the config file",
+		Long: `Remove a repository from the config file, given its path or its name
+(the repository name in its URL, or the last element of its path).
+
+With --delete-dir, the local directory is deleted too, after confirmation.
+A repo with uncommitted changes is only deleted with --force.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+			cfg, cfgPath := ws.Config, ws.ConfigPath
+
+			rc, err := cfg.RemoveRepo(args[0])
+			if err != nil {
+				return err
+			}
+
+			r := repo.New(rc, ws.Root)
+			if deleteDir && r.Exists() {
+				if r.IsCloned() {
+					dirty, err := r.HasChanges()
+					if err != nil {
+						return err
+					}
+					if dirty && !force {
+						return fmt.Errorf("%s has uncommitted changes; use --force to delete it anyway", r.Name())
+					}
+				}
+
+				if !confirmDestructive(ws, fmt.Sprintf("Delete %s?", r.FullPath)) {
+					fmt.Println("Aborted")
+					return nil
+				}
+			}
+
+			if err := cfg.Save(cfgPath); err != nil {
+				return err
+			}
+			fmt.Printf("Removed %s from %s\n", rc.Path, cfgPath)
+
+			if deleteDir && r.Exists() {
+				if err := os.RemoveAll(r.FullPath); err != nil {
+					return fmt.Errorf("deleting %s: %w", r.FullPath, err)
+				}
+				fmt.Printf("Deleted %s\n", r.FullPath)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&deleteDir, "delete-dir", false, "also delete the local directory")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "delete the directory even if it has uncommitted changes")
+	return cmd
 }
 
 func cloneCmd() *cobra.Command {
-	return &cobra.Command{
+	var skipForbidden bool
+	var force bool
+	var resume bool
+
+	cmd := &cobra.Command{
 		Use:   "clone",
 		Short: "Clone all configured repositories",
+		Long: `Clone all configured repositories.
+
+With --skip-forbidden (or settings.clone.skip_forbidden), repos that fail
+to clone because of missing permissions are skipped and remembered, so later
+commands report "no access" for them. The command then succeeds as long as
+every other repo cloned.
+
+A repo whose directory exists but isn't a clone, such as one left by an
+interrupted clone, fails with an error saying what is there. With --force,
+the directory is deleted and cloned again. --force also re-clones repos
+that have no refs and no files, as a clone killed partway leaves them.
+
+Progress is saved as each repo finishes. If some repos fail or the clone
+is interrupted, --resume clones only the repos it didn't finish, as long
+as the last one finished less than 24 hours ago.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ws, err := loadWorkspace()
+			ws, err := openWorkspace(true)
 			if err != nil {
 				return err
 			}
+			printIdentity(ws)
+
+			skip := skipForbidden || ws.Config.Settings.Clone.SkipForbidden
+
+			run, err := startCheckpoint(ws, "clone", resume)
+			if err != nil {
+				return err
+			}
+
+			if force {
+				var leftovers []string
+				for _, r := range run.Repos {
+					if r.Leftover() {
+						leftovers = append(leftovers, r.FullPath)
+					}
+				}
+				if len(leftovers) > 0 {
+					fmt.Println("These directories are not complete clones and will be deleted:")
+					for _, path := range leftovers {
+						fmt.Printf("    %s\n", path)
+					}
+					if !confirmDestructive(ws, "Delete them and clone again?") {
+						fmt.Println("Aborted")
+						return nil
+					}
+				}
+			}
 
 			fmt.Println("Cloning repositories...")
-			results := ws.Clone()
+			results := run.Clone(force)
 
 			hasErrors := false
+			succeeded := 0
+			var forbidden []string
 			for _, r := range results {
-				if r.Error != nil {
-					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+				if r.Error != nil && skip && r.Repo.NoAccess {
+					fmt.Printf("  - %s (no access)\n", r.Repo.Name())
+					forbidden = append(forbidden, r.Repo.Name())
+				} else if r.Error != nil {
+					fmt.Printf("  ✗ %s%s: %v%s\n", r.Repo.Name(), durationNote(r.Duration), r.Error, attemptsNote(r))
 					hasErrors = true
 				} else if r.Repo.IsCloned() {
-					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+					fmt.Printf("  ✓ %s%s%s\n", r.Repo.Name(), durationNote(r.Duration), attemptsNote(r))
+				}
+				if r.Error == nil {
+					succeeded++
 				}
 			}
+			finishCheckpoint(ws, run, succeeded, hasErrors)
+
+			if skip {
+				if err := ws.RecordNoAccess(); err != nil {
+					return err
+				}
+			}
+
+			if len(forbidden) > 0 {
+				fmt.Printf("\nSkipped %d repositories you don't have access to:\n", len(forbidden))
+				for _, name := range forbidden {
+					fmt.Printf("    %s\n", name)
+				}
+				fmt.Println()
+			}
 
 			if hasErrors {
 				return fmt.Errorf("some repositories failed to clone")
@@ -128,16 +815,23 @@ func cloneCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&skipForbidden, "skip-forbidden", false, "skip repos you don't have access to instead of failing")
+	cmd.Flags().BoolVar(&force, "force", false, "delete leftover directories that aren't complete clones and clone again")
+	cmd.Flags().BoolVar(&resume, "resume", false, "skip the repos the last unfinished clone completed")
+	return cmd
 }
 
 func pullCmd() *cobra.Command {
 	var rebase bool
+	var autoStash bool
+	var remote string
 
 	cmd := &cobra.Command{
 		Use:   "pull",
 		Short: "Pull changes for all repositories",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ws, err := loadWorkspace()
+			ws, err := loadWorkspaceForChange()
 			if err != nil {
 				return err
 			}
@@ -151,22 +845,35 @@ func pullCmd() *cobra.Command {
 				fmt.Println("Warning: repositories are on different branches")
 			}
 
-			fmt.Printf("Pulling %s...\n", branch)
-			results := ws.Pull(rebase)
+			if autoStash {
+				ws.AutoStash = true
+			}
+
+			if remote != "" {
+				fmt.Printf("Pulling %s from %s...\n", branch, remote)
+			} else {
+				fmt.Printf("Pulling %s...\n", branch)
+			}
+			results, hookErr := ws.Pull(remote, rebase)
 
 			hasErrors := false
 			for _, r := range results {
 				if r.Error != nil {
-					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					fmt.Printf("  ✗ %s%s: %v%s\n", r.Repo.Name(), durationNote(r.Duration), r.Error, attemptsNote(r))
 					hasErrors = true
 				} else {
-					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+					fmt.Printf("  ✓ %s%s%s\n", r.Repo.Name(), durationNote(r.Duration), attemptsNote(r))
 				}
 			}
+			reportConflicts(results)
+			reportStashLeft(results)
 
 			if hasErrors {
 				return fmt.Errorf("some repositories failed to pull")
 			}
+			if hookErr != nil {
+				return hookErr
+			}
 
 			fmt.Println("Done!")
 			return nil
@@ -174,17 +881,46 @@ func pullCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&rebase, "rebase", false, "use rebase instead of merge")
+	cmd.Flags().BoolVar(&autoStash, "autostash", false, "stash local changes before pulling and restore them after")
+	cmd.Flags().StringVar(&remote, "remote", "", "pull the current branch from this remote instead of the upstream")
 	return cmd
 }
 
 func pushCmd() *cobra.Command {
 	var force bool
+	var forceUnsafe bool
+	var noVerify bool
+	var allowProtected bool
+	var dryRun bool
+	var setUpstream bool
+	var refSpecs []string
 
 	cmd := &cobra.Command{
 		Use:   "push",
 		Short: "Push changes for all repositories",
+		Long: `Push the current branch for all repositories.
+
+Before anything is pushed, every repo is checked for uncommitted changes,
+for being behind its upstream (after a fetch; skipped when force pushing),
+and for being on a protected branch (settings.protected_branches, default
+main and master). If any repo fails, nothing is pushed. --no-verify skips
+the first two checks and --allow-protected the last.
+
+A branch without an upstream is pushed with -u, setting the upstream.
+-u sets it even for branches that have one.
+
+--refspec pushes to a differently named remote branch: "name" pushes HEAD
+to it, "src:name" pushes src. Prefix it with a repo to override a single
+repo, as in --refspec api=HEAD:hotfix/x; repeat the flag for each repo.
+The checks then apply to the branch pushed to. A push with a refspec only
+sets the upstream with -u, which pr commands need to find the PR.
+
+--force uses --force-with-lease, which refuses to overwrite a remote branch
+that moved since the last fetch. --force-unsafe overwrites it regardless.
+
+--dry-run runs the checks and git push --dry-run without pushing anything.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ws, err := loadWorkspace()
+			ws, err := loadWorkspaceForChange()
 			if err != nil {
 				return err
 			}
@@ -194,48 +930,141 @@ func pushCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			printBranchScope(ws, branch, consistent)
 			if !consistent {
 				return fmt.Errorf("repositories are on different branches, cannot push")
 			}
 
-			if force {
-				fmt.Print("Force push? This may overwrite remote changes. [y/N]: ")
-				var response string
-				if _, err := fmt.Scanln(&response); err != nil || (response != "y" && response != "Y") {
-					fmt.Println("Aborted")
-					return nil
-				}
+			specs, err := parseRefSpecs(ws, refSpecs)
+			if err != nil {
+				return err
 			}
 
-			fmt.Printf("Pushing %s...\n", branch)
-			results := ws.Push(force)
+			mode := git.NoForce
+			prompt := ""
+			switch {
+			case forceUnsafe:
+				mode = git.ForceUnsafe
+				prompt = "Force push (unsafe)? This overwrites remote changes, including ones you haven't fetched."
+			case force:
+				mode = git.ForceWithLease
+				prompt = "Force push with lease? Remote changes you have already fetched will be overwritten."
+			}
+			if mode != git.NoForce && !dryRun && !confirmDestructive(ws, prompt) {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			prefix := dryRunPrefix(dryRun)
+			fmt.Printf("%sPushing %s...\n", prefix, branch)
+			results, err := ws.Push(workspace.PushOptions{
+				Mode:           mode,
+				NoVerify:       noVerify,
+				AllowProtected: allowProtected,
+				DryRun:         dryRun,
+				SetUpstream:    setUpstream,
+				RefSpecs:       specs,
+			})
+			var preflight *workspace.PreflightError
+			if errors.As(err, &preflight) {
+				for _, c := range preflight.Checks {
+					if c.Error != nil {
+						fmt.Printf("%s  ✗ %s: %v\n", prefix, c.Repo.Name(), c.Error)
+					} else {
+						fmt.Printf("%s  ✗ %s: %s\n", prefix, c.Repo.Name(), strings.Join(c.Problems, ", "))
+					}
+				}
+				return fmt.Errorf("%w; nothing was pushed", err)
+			}
+			if err != nil && results == nil {
+				return err
+			}
 
 			hasErrors := false
 			for _, r := range results {
 				if r.Error != nil {
-					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					fmt.Printf("%s  ✗ %s%s: %v%s\n", prefix, r.Repo.Name(), durationNote(r.Duration), r.Error, attemptsNote(r))
 					hasErrors = true
 				} else {
-					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+					note := ""
+					if r.PushedTo != "" {
+						note = " → " + r.PushedTo
+					}
+					if r.SetUpstream {
+						note += " (set upstream)"
+					}
+					fmt.Printf("%s  ✓ %s%s%s%s\n", prefix, r.Repo.Name(), durationNote(r.Duration), note, attemptsNote(r))
 				}
 			}
 
 			if hasErrors {
 				return fmt.Errorf("some repositories failed to push")
 			}
+			if err != nil {
+				return err
+			}
 
+			if dryRun {
+				fmt.Println(prefix + "Nothing was pushed")
+				return nil
+			}
 			fmt.Println("Done!")
 			return nil
 		},
 	}
 
-	cmd.Flags().BoolVarP(&force, "force", "f", false, "force push")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "force push with lease")
+	cmd.Flags().BoolVar(&forceUnsafe, "force-unsafe", false, "force push without a lease, overwriting any remote changes")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "push despite uncommitted changes or being behind upstream")
+	cmd.Flags().BoolVar(&allowProtected, "allow-protected", false, "allow pushing a protected branch")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show what would be pushed without pushing")
+	cmd.Flags().BoolVarP(&setUpstream, "set-upstream", "u", false, "set the upstream to the pushed branch")
+	cmd.Flags().StringArrayVar(&refSpecs, "refspec", nil, "push to a differently named remote branch: [repo=][src:]branch (repeatable)")
 	return cmd
 }
 
+// parseRefSpecs parses --refspec values into PushOptions.RefSpecs. A value
+// may start with "repo=" to apply to that repo only.
+func parseRefSpecs(ws *workspace.Workspace, values []string) (map[string]git.RefSpec, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	specs := make(map[string]git.RefSpec)
+	for _, v := range values {
+		path := ""
+		if name, rest, ok := strings.Cut(v, "="); ok && name != "" && !strings.Contains(name, ":") {
+			i, err := ws.Config.FindRepo(name)
+			if err != nil {
+				return nil, fmt.Errorf("--refspec %s: %w", v, err)
+			}
+			path, v = ws.Config.Repos[i].Path, rest
+		}
+		if _, dup := specs[path]; dup {
+			if path == "" {
+				return nil, fmt.Errorf("--refspec given more than once without a repo")
+			}
+			return nil, fmt.Errorf("--refspec given more than once for %s", path)
+		}
+		spec, err := git.ParseRefSpec(v)
+		if err != nil {
+			return nil, err
+		}
+		specs[path] = spec
+	}
+	return specs, nil
+}
+
 func branchCmd() *cobra.Command {
 	var deleteBranch bool
 	var checkout bool
+	var from string
+	var autoStash bool
+	var prune bool
+	var remote bool
+	var rename bool
+	var list bool
+	var all bool
 
 	cmd := &cobra.Command{
 		Use:   "branch [name]",
@@ -243,15 +1072,56 @@ func branchCmd() *cobra.Command {
 		Long: `Manage branches across all repositories.
 
 Without arguments, lists current branch for each repo.
+With --list, lists every local branch once, with the repos that have it
+and how each compares with its upstream. --remote lists the
+remote-tracking branches instead, and --all lists both.
 With a name argument, creates a new branch on all repos.
+With --from, the new branch starts at the given ref instead of HEAD.
 With -d flag, deletes the branch from all repos.
-With --checkout flag, switches to the branch on all repos.`,
+With --checkout flag, switches to the branch on all repos.
+With --prune, deletes local branches already merged into each repo's
+default branch, except the current and protected branches, after showing
+them and asking for confirmation. --remote also deletes them from the
+remote.
+With --rename old new, renames the branch in all repos: locally, then on
+the remote by pushing the new name with upstream tracking and deleting the
+old one. Repos without the branch are skipped. An open PR for the old
+branch is closed by the remote when the old branch is deleted.
+
+mergeish branch describe sets a branch's ticket, description, and owner.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ws, err := loadWorkspace()
 			if err != nil {
 				return err
 			}
 
+			if prune {
+				if len(args) > 0 || deleteBranch || checkout || list || all {
+					return fmt.Errorf("--prune takes no branch name and can't be combined with -d, --checkout, --list, or --all")
+				}
+				printIdentity(ws)
+				return pruneBranches(ws, remote)
+			}
+			if list {
+				if len(args) > 0 || deleteBranch || checkout || rename {
+					return fmt.Errorf("--list takes no branch name and can't be combined with -d, --checkout, or --rename")
+				}
+				return listAllBranches(ws, !remote || all, remote || all)
+			}
+			if remote {
+				return fmt.Errorf("--remote needs --prune or --list")
+			}
+			if all {
+				return fmt.Errorf("--all needs --list")
+			}
+			if rename {
+				if len(args) != 2 || deleteBranch || checkout || from != "" {
+					return fmt.Errorf("--rename takes an old and a new branch name and can't be combined with -d, --checkout, or --from")
+				}
+				printIdentity(ws)
+				return renameBranchOp(ws, args[0], args[1])
+			}
+
 			// No args: list branches
 			if len(args) == 0 && !deleteBranch && !checkout {
 				return listBranches(ws)
@@ -261,6 +1131,7 @@ With --checkout flag, switches to the branch on all repos.`,
 				return fmt.Errorf("branch name required")
 			}
 
+			printIdentity(ws)
 			branchName := args[0]
 
 			if deleteBranch {
@@ -268,16 +1139,29 @@ With --checkout flag, switches to the branch on all repos.`,
 			}
 
 			if checkout {
+				if autoStash {
+					ws.AutoStash = true
+				}
 				return checkoutBranch(ws, branchName)
 			}
 
 			// Create new branch
-			return createBranch(ws, branchName)
+			return createBranch(ws, branchName, from)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&deleteBranch, "delete", "d", false, "delete the branch")
 	cmd.Flags().BoolVar(&checkout, "checkout", false, "switch to the branch")
+	cmd.Flags().StringVar(&from, "from", "", "create the branch from this ref instead of HEAD")
+	cmd.Flags().BoolVar(&autoStash, "autostash", false, "with --checkout, stash local changes before switching and restore them after")
+	cmd.Flags().BoolVar(&prune, "prune", false, "delete local branches merged into the default branch")
+	cmd.Flags().BoolVar(&remote, "remote", false, "with --prune, also delete the branches from the remote; with --list, list remote branches")
+	cmd.Flags().BoolVar(&list, "list", false, "list every branch with the repos that have it and their tracking status")
+	cmd.Flags().BoolVar(&all, "all", false, "with --list, list local and remote branches")
+	cmd.Flags().BoolVar(&rename, "rename", false, "rename a branch locally and on the remote: branch --rename <old> <new>")
+
+	cmd.AddCommand(branchDescribeCmd())
+
 	return cmd
 }
 
@@ -285,28 +1169,194 @@ func listBranches(ws *workspace.Workspace) error {
 	results := ws.Status()
 
 	fmt.Println("Current branches:")
+	seen := make(map[string]bool)
+	var branches []string
 	for _, r := range results {
 		if r.Error != nil {
 			fmt.Printf("  %s: error: %v\n", r.Repo.Name(), r.Error)
 		} else {
 			fmt.Printf("  %s: %s\n", r.Repo.Name(), r.Status.Branch)
+			if b := r.Status.Branch; b != "" && !seen[b] {
+				seen[b] = true
+				branches = append(branches, b)
+			}
 		}
 	}
 
+	if lines := branchInfoLines(ws, branches); len(lines) > 0 {
+		fmt.Println()
+		fmt.Println(strings.Join(lines, "\n"))
+	}
+
 	return nil
 }
 
-func createBranch(ws *workspace.Workspace, name string) error {
-	fmt.Printf("Creating branch %s...\n", name)
-	results := ws.CreateBranch(name)
+// listAllBranches prints each branch once, with the repos that have it and,
+// for local branches, how each compares with its upstream
+func listAllBranches(ws *workspace.Workspace, local, remote bool) error {
+	results := ws.ListBranches(local, remote)
 
 	hasErrors := false
 	for _, r := range results {
 		if r.Error != nil {
 			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
 			hasErrors = true
+		}
+	}
+
+	groups := workspace.GroupBranches(results)
+	if len(groups) == 0 {
+		fmt.Println("No branches")
+	}
+
+	heading := ""
+	for _, g := range groups {
+		h := "Local branches:"
+		if g.Remote {
+			h = "Remote branches:"
+		}
+		if h != heading {
+			if heading != "" {
+				fmt.Println()
+			}
+			fmt.Println(h)
+			heading = h
+		}
+		fmt.Printf("  %s (%d of %d repos)\n", g.Name, len(g.Repos), len(ws.Repos))
+
+		if g.Remote {
+			names := make([]string, len(g.Repos))
+			for i, r := range g.Repos {
+				names[i] = r.Name()
+			}
+			fmt.Printf("      %s\n", strings.Join(names, ", "))
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, r := range g.Repos {
+			b := g.Branches[i]
+			marker := " "
+			if b.IsCurrentBranch {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "    %s %s\t%s\n", marker, r.Name(), describeTracking(b))
+		}
+		w.Flush()
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to list branches in some repositories")
+	}
+	return nil
+}
+
+// describeTracking describes a local branch's upstream and how far ahead
+// and behind it the branch is
+func describeTracking(b git.BranchInfo) string {
+	switch {
+	case b.Upstream == "":
+		return "(no upstream)"
+	case b.Gone:
+		return b.Upstream + " (gone)"
+	}
+
+	desc := b.Upstream
+	if b.Ahead > 0 {
+		desc += fmt.Sprintf(" ↑%d", b.Ahead)
+	}
+	if b.Behind > 0 {
+		desc += fmt.Sprintf(" ↓%d", b.Behind)
+	}
+	return desc
+}
+
+// pruneBranches deletes merged branches after listing them and asking
+func pruneBranches(ws *workspace.Workspace, remote bool) error {
+	for _, r := range ws.Refresh() {
+		if r.Error != nil {
+			fmt.Printf("  ⚠ %s: fetch failed, using existing remote refs: %v\n", r.Repo.Name(), r.Error)
+		}
+	}
+
+	plan := ws.MergedBranches()
+
+	fmt.Println("Merged branches:")
+	count, hasErrors := 0, false
+	for _, p := range plan {
+		switch {
+		case p.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", p.Repo.Name(), p.Error)
+			hasErrors = true
+		case len(p.Branches) == 0:
+			fmt.Printf("  - %s: none\n", p.Repo.Name())
+		default:
+			fmt.Printf("  %s (into %s): %s\n", p.Repo.Name(), p.Base, strings.Join(p.Branches, ", "))
+			count += len(p.Branches)
+		}
+	}
+	if count == 0 {
+		if hasErrors {
+			return fmt.Errorf("failed to list merged branches in some repositories")
+		}
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	prompt := fmt.Sprintf("Delete %d merged branches?", count)
+	if remote {
+		prompt = fmt.Sprintf("Delete %d merged branches, locally and on the remote?", count)
+	}
+	if !confirmDestructive(ws, prompt) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	fmt.Println("Deleting merged branches...")
+	for _, r := range ws.PruneBranches(plan, remote) {
+		if len(r.Branches) == 0 {
+			continue
+		}
+		if len(r.Deleted) > 0 {
+			note := ""
+			if len(r.RemoteDeleted) > 0 {
+				note = fmt.Sprintf(" (on the remote: %s)", strings.Join(r.RemoteDeleted, ", "))
+			}
+			fmt.Printf("  ✓ %s: %s%s\n", r.Repo.Name(), strings.Join(r.Deleted, ", "), note)
+		}
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		}
+	}
+
+	// Drop metadata of branches no repo has any more
+	if _, err := ws.PruneBranchInfo(); err != nil {
+		fmt.Printf("⚠ Warning: pruning branch metadata: %v\n", err)
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to prune branches in some repositories")
+	}
+	fmt.Println("Done!")
+	return nil
+}
+
+func createBranch(ws *workspace.Workspace, name, from string) error {
+	if from != "" {
+		fmt.Printf("Creating branch %s from %s...\n", name, from)
+	} else {
+		fmt.Printf("Creating branch %s...\n", name)
+	}
+	results := ws.CreateBranchFrom(name, from)
+
+	hasErrors := false
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s%s: %v\n", r.Repo.Name(), durationNote(r.Duration), r.Error)
+			hasErrors = true
 		} else {
-			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+			fmt.Printf("  ✓ %s%s\n", r.Repo.Name(), durationNote(r.Duration))
 		}
 	}
 
@@ -325,19 +1375,72 @@ func deleteBranchOp(ws *workspace.Workspace, name string) error {
 	hasErrors := false
 	for _, r := range results {
 		if r.Error != nil {
-			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			fmt.Printf("  ✗ %s%s: %v\n", r.Repo.Name(), durationNote(r.Duration), r.Error)
 			hasErrors = true
 		} else {
-			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+			fmt.Printf("  ✓ %s%s\n", r.Repo.Name(), durationNote(r.Duration))
 		}
 	}
 
 	if hasErrors {
 		return fmt.Errorf("failed to delete branch on some repositories")
 	}
-
-	fmt.Println("Done!")
-	return nil
+
+	// Drop the branch's metadata once no repo has it
+	if _, err := ws.PruneBranchInfo(); err != nil {
+		fmt.Printf("⚠ Warning: pruning branch metadata: %v\n", err)
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
+
+func renameBranchOp(ws *workspace.Workspace, oldName, newName string) error {
+	fmt.Printf("Renaming branch %s to %s...\n", oldName, newName)
+	results := ws.RenameBranch(oldName, newName)
+
+	hasErrors := false
+	renamed := 0
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s%s: %v%s\n", r.Repo.Name(), durationNote(r.Duration), r.Error, attemptsNote(r))
+			hasErrors = true
+		case r.Skipped != "":
+			fmt.Printf("  - %s (%s)\n", r.Repo.Name(), r.Skipped)
+		default:
+			renamed++
+			fmt.Printf("  ✓ %s%s%s\n", r.Repo.Name(), durationNote(r.Duration), attemptsNote(r))
+		}
+	}
+
+	// Carry the branch's metadata over to its new name
+	if renamed > 0 {
+		if err := moveBranchInfo(ws, oldName, newName); err != nil {
+			fmt.Printf("⚠ Warning: moving branch metadata: %v\n", err)
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to rename branch on some repositories")
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
+
+// moveBranchInfo copies the metadata of oldName to newName, dropping it
+// from oldName once no repo has that branch any more
+func moveBranchInfo(ws *workspace.Workspace, oldName, newName string) error {
+	info, err := ws.BranchInfo(oldName)
+	if err != nil || info.IsZero() {
+		return err
+	}
+	if err := ws.SetBranchInfo(newName, info); err != nil {
+		return err
+	}
+	_, err = ws.PruneBranchInfo()
+	return err
 }
 
 func checkoutBranch(ws *workspace.Workspace, name string) error {
@@ -347,12 +1450,13 @@ func checkoutBranch(ws *workspace.Workspace, name string) error {
 	hasErrors := false
 	for _, r := range results {
 		if r.Error != nil {
-			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			fmt.Printf("  ✗ %s%s: %v\n", r.Repo.Name(), durationNote(r.Duration), r.Error)
 			hasErrors = true
 		} else {
-			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+			fmt.Printf("  ✓ %s%s\n", r.Repo.Name(), durationNote(r.Duration))
 		}
 	}
+	reportStashLeft(results)
 
 	if hasErrors {
 		return fmt.Errorf("failed to switch branch on some repositories")
@@ -365,53 +1469,94 @@ func checkoutBranch(ws *workspace.Workspace, name string) error {
 func commitCmd() *cobra.Command {
 	var message string
 	var addAll bool
+	var confirmCommit bool
+	var dryRun bool
+	var removeStaleLocks bool
 
 	cmd := &cobra.Command{
 		Use:   "commit",
 		Short: "Commit changes across all repositories",
+		Long: `Commit changes across all repositories with the same message.
+
+While another process, such as an editor refreshing its git view, holds a
+repo's index lock, staging and committing there are retried for up to
+settings.lock_wait (2s by default). A lock file older than 10 minutes with
+no git process running in the repo is treated as left behind by a crash;
+--remove-stale-locks lists those and removes them after confirmation
+before committing.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if message == "" {
 				return fmt.Errorf("commit message required (-m)")
 			}
 
-			ws, err := loadWorkspace()
+			ws, err := loadWorkspaceForChange()
 			if err != nil {
 				return err
 			}
 
 			// Check branch consistency
-			_, consistent, err := ws.CheckBranchConsistency()
+			branch, consistent, err := ws.CheckBranchConsistency()
 			if err != nil {
 				return err
 			}
+			printBranchScope(ws, branch, consistent)
 			if !consistent {
 				return fmt.Errorf("repositories are on different branches, cannot commit")
 			}
 
+			if dryRun {
+				return printPendingCommits(ws.PendingCommits(addAll), message)
+			}
+
+			if removeStaleLocks {
+				if proceed, err := removeLocks(ws); err != nil || !proceed {
+					return err
+				}
+			}
+
+			// Confirm before committing when asked to, or when running interactively
+			if confirmCommit || isTerminal() {
+				pending := countPendingCommits(ws, addAll)
+				if pending == 0 {
+					fmt.Println("No changes to commit")
+					return nil
+				}
+				if !confirm(fmt.Sprintf("Will commit to %d repos with message '%s'. Proceed?", pending, message)) {
+					fmt.Println("Aborted")
+					return nil
+				}
+			}
+
 			fmt.Println("Committing changes...")
-			results := ws.Commit(message, addAll)
+			results, hookErr := ws.Commit(message, addAll)
 
 			committed := 0
 			hasErrors := false
 			for _, r := range results {
 				if r.Error != nil {
-					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					fmt.Printf("  ✗ %s%s: %v\n", r.Repo.Name(), durationNote(r.Duration), r.Error)
 					hasErrors = true
 				} else {
 					// Check if we actually committed something
 					status, _ := r.Repo.Status()
 					if status != nil && !status.HasChanges {
 						committed++
-						fmt.Printf("  ✓ %s (committed)\n", r.Repo.Name())
+						fmt.Printf("  ✓ %s%s (committed)\n", r.Repo.Name(), durationNote(r.Duration))
 					} else {
-						fmt.Printf("  - %s (no changes)\n", r.Repo.Name())
+						fmt.Printf("  - %s%s (no changes)\n", r.Repo.Name(), durationNote(r.Duration))
 					}
 				}
 			}
 
 			if hasErrors {
+				if hasStaleLock(results) {
+					fmt.Println("\nSome repos have stale lock files; run mergeish commit --remove-stale-locks to remove them")
+				}
 				return fmt.Errorf("some repositories failed to commit")
 			}
+			if hookErr != nil {
+				return hookErr
+			}
 
 			if committed == 0 {
 				fmt.Println("No changes to commit")
@@ -425,36 +1570,260 @@ func commitCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&message, "message", "m", "", "commit message")
 	cmd.Flags().BoolVarP(&addAll, "all", "a", false, "stage all changes before committing")
+	cmd.Flags().BoolVar(&confirmCommit, "confirm", false, "show a summary and confirm before committing (default on a terminal)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show which repos would be committed without committing")
+	cmd.Flags().BoolVar(&removeStaleLocks, "remove-stale-locks", false, "remove index lock files left behind by a crash, after confirmation")
 	return cmd
 }
 
+// removeLocks lists the stale index locks in the workspace and removes them
+// once confirmed. It reports whether to go on with the command.
+func removeLocks(ws *workspace.Workspace) (bool, error) {
+	locks := ws.StaleLocks()
+	if len(locks) == 0 {
+		fmt.Println("No stale lock files")
+		return true, nil
+	}
+
+	fmt.Println("Stale lock files:")
+	for _, l := range locks {
+		fmt.Printf("  - %s: %s (created %s ago)\n", l.Repo.Name(), l.Lock.Path, l.Lock.Age.Round(time.Second))
+	}
+	if !confirmDestructive(ws, fmt.Sprintf("Remove %d stale lock files?", len(locks))) {
+		fmt.Println("Aborted")
+		return false, nil
+	}
+
+	hasErrors := false
+	for _, l := range locks {
+		if err := l.Repo.RemoveStaleLock(l.Lock); err != nil {
+			fmt.Printf("  ✗ %s: %v\n", l.Repo.Name(), err)
+			hasErrors = true
+		} else {
+			fmt.Printf("  ✓ %s\n", l.Repo.Name())
+		}
+	}
+	if hasErrors {
+		return false, fmt.Errorf("failed to remove some lock files")
+	}
+	return true, nil
+}
+
+// hasStaleLock reports whether any repo failed on a stale lock file
+func hasStaleLock(results []workspace.Result) bool {
+	for _, r := range results {
+		var lock *git.LockError
+		if errors.As(r.Error, &lock) && lock.Stale {
+			return true
+		}
+	}
+	return false
+}
+
+// printBranchScope says which repos a branch consistency check covered when
+// --repo, --repos, or --exclude left some out, so that a consistent
+// selection isn't taken for a consistent workspace
+func printBranchScope(ws *workspace.Workspace, branch string, consistent bool) {
+	if len(ws.Unselected) == 0 {
+		return
+	}
+
+	scope := fmt.Sprintf("On %s in the %d selected repos", branch, len(ws.Repos))
+	if !consistent {
+		scope = fmt.Sprintf("The %d selected repos are on different branches", len(ws.Repos))
+	}
+
+	counts := ws.UnselectedBranches()
+	branches := make([]string, 0, len(counts))
+	others := 0
+	for b, n := range counts {
+		branches = append(branches, b)
+		others += n
+	}
+	if others == 0 {
+		fmt.Printf("%s; the %d other repos aren't cloned\n", scope, len(ws.Unselected))
+		return
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if counts[branches[i]] != counts[branches[j]] {
+			return counts[branches[i]] > counts[branches[j]]
+		}
+		return branches[i] < branches[j]
+	})
+	parts := make([]string, len(branches))
+	for i, b := range branches {
+		if b == "" {
+			b = "a detached HEAD"
+		}
+		parts[i] = fmt.Sprintf("%d on %s", counts[branches[i]], b)
+	}
+	fmt.Printf("%s; not checked: %d other repos (%s)\n", scope, others, strings.Join(parts, ", "))
+}
+
+// countPendingCommits returns how many repos would get a commit. With addAll,
+// any change counts; otherwise only staged changes do.
+func countPendingCommits(ws *workspace.Workspace, addAll bool) int {
+	pending := 0
+	for _, r := range ws.PendingCommits(addAll) {
+		if r.Error == nil && len(r.Files) > 0 {
+			pending++
+		}
+	}
+	return pending
+}
+
+// printPendingCommits prints what commit --dry-run would do in each repo
+func printPendingCommits(pending []workspace.PendingCommit, message string) error {
+	prefix := dryRunPrefix(true)
+	fmt.Printf("%sCommitting with message '%s'...\n", prefix, message)
+
+	hasErrors := false
+	count := 0
+	for _, p := range pending {
+		switch {
+		case p.Error != nil:
+			fmt.Printf("%s  ✗ %s: %v\n", prefix, p.Repo.Name(), p.Error)
+			hasErrors = true
+		case len(p.Files) == 0:
+			fmt.Printf("%s  - %s (no changes)\n", prefix, p.Repo.Name())
+		default:
+			count++
+			fmt.Printf("%s  ✓ %s: %s\n", prefix, p.Repo.Name(), strings.Join(p.Files, ", "))
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("some repositories could not be checked")
+	}
+	fmt.Printf("%sWould commit to %d repositories\n", prefix, count)
+	return nil
+}
+
 func statusCmd() *cobra.Command {
-	return &cobra.Command{
+	var all bool
+	var fetch bool
+	var jsonOutput bool
+	var sortRepos bool
+	var dirty bool
+	var short bool
+	var noCommitInfo bool
+
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show status of all repositories",
+		Long: `Show the branch, ahead/behind counts, and changes of every repository.
+
+Ahead/behind counts are only as fresh as the last fetch. --fetch fetches
+every repo first, showing how long each took. A repo whose fetch fails
+is still shown, with its last known counts marked "(fetch failed)".
+
+--dirty shows only the repos that need attention: those with changes,
+commits to push or pull, an operation in progress, or a branch other
+than the one most repos are on. When there are none, it prints a single
+line. --short prints one line per repo.
+
+Each repo's last commit is shown too, which takes another git call per
+repo; --no-commit-info leaves it out.
+
+--json prints the same as a JSON array, one object per repo.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ws, err := loadWorkspace()
+			if fetch && noFetch {
+				return fmt.Errorf("--fetch and --no-fetch can't be used together")
+			}
+			if short && jsonOutput {
+				return fmt.Errorf("--short and --json can't be used together")
+			}
+			ws, err := openWorkspace(all)
 			if err != nil {
 				return err
 			}
 
-			results := ws.Status()
+			fetched := make(map[string]workspace.Result)
+			fetchFailed := make(map[string]bool)
+			if fetch {
+				if !jsonOutput {
+					fmt.Println("Fetching...")
+				}
+				for _, r := range ws.Fetch() {
+					fetched[r.Repo.Name()] = r
+					fetchFailed[r.Repo.Name()] = r.Error != nil
+					if jsonOutput {
+						continue
+					}
+					took := formatDuration(r.Duration)
+					if r.Error != nil {
+						fmt.Printf("  ✗ %s (%s): %v\n", r.Repo.Name(), took, r.Error)
+					} else {
+						fmt.Printf("  ✓ %s (%s)\n", r.Repo.Name(), took)
+					}
+				}
+				if !jsonOutput {
+					fmt.Println()
+				}
+			}
+
+			var results []workspace.StatusResult
+			if noCommitInfo || short {
+				results = ws.Status()
+			} else {
+				results = ws.StatusWithLastCommit()
+			}
+			if sortRepos {
+				sortByRepo(results, func(r workspace.StatusResult) string { return r.Repo.Name() })
+			}
+
+			shown := results
+			if dirty {
+				shown = dirtyStatus(results, fetchFailed)
+			}
+			if jsonOutput {
+				return printStatusJSON(shown, fetched)
+			}
+			if dirty && len(shown) == 0 {
+				fmt.Printf("All %d repos clean on %s\n", len(results), commonBranch(results))
+				return nil
+			}
 
 			// Check branch consistency
 			branches := make(map[string]int)
+			detached := false
 			for _, r := range results {
-				if r.Status != nil {
+				if r.Status == nil {
+					continue
+				}
+				if r.Status.Branch == "" {
+					detached = true
+				} else {
 					branches[r.Status.Branch]++
 				}
 			}
 
 			if len(branches) > 1 {
 				fmt.Println("⚠ Warning: repositories are on different branches")
+			}
+			if detached {
+				fmt.Println("⚠ Warning: some repositories are not on a branch (detached HEAD)")
+			}
+			if len(branches) > 1 || detached {
 				fmt.Println()
 			}
 
-			for _, r := range results {
-				fmt.Printf("%s:\n", r.Repo.Name())
+			names := make([]string, 0, len(branches))
+			for b := range branches {
+				names = append(names, b)
+			}
+			if lines := branchInfoLines(ws, names); len(lines) > 0 {
+				fmt.Println(strings.Join(lines, "\n"))
+				fmt.Println()
+			}
+
+			if short {
+				printStatusShort(shown, fetchFailed)
+				return nil
+			}
+
+			for _, r := range shown {
+				fmt.Printf("%s%s:\n", r.Repo.Name(), durationNote(r.Duration))
 
 				if r.Error != nil {
 					fmt.Printf("  error: %v\n", r.Error)
@@ -462,7 +1831,14 @@ func statusCmd() *cobra.Command {
 				}
 
 				s := r.Status
-				fmt.Printf("  branch: %s", s.Branch)
+				switch {
+				case s.Branch == "":
+					fmt.Printf("  branch: (detached HEAD)")
+				case s.Detached:
+					fmt.Printf("  branch: %s (detached HEAD)", s.Branch)
+				default:
+					fmt.Printf("  branch: %s", s.Branch)
+				}
 
 				// Show ahead/behind
 				if s.Ahead > 0 || s.Behind > 0 {
@@ -478,13 +1854,40 @@ func statusCmd() *cobra.Command {
 					}
 					fmt.Printf(")")
 				}
+
+				// Say what ahead/behind is relative to when it isn't the usual
+				switch {
+				case s.Branch == "":
+				case s.Upstream == "":
+					fmt.Printf(" (no upstream)")
+				case s.Upstream != r.Repo.Remote()+"/"+s.Branch:
+					fmt.Printf(" (upstream: %s)", s.Upstream)
+				}
+				if fetchFailed[r.Repo.Name()] {
+					fmt.Printf(" (fetch failed)")
+				}
+				if s.Worktree {
+					fmt.Printf(" (linked worktree)")
+				}
 				fmt.Println()
 
+				if c := s.LastCommit; c != nil {
+					fmt.Printf("  last commit: %s %s (%s, %s)\n", shortHash(c.Hash), c.Subject, c.Author, formatAge(c.Time))
+				}
+
+				if s.State != "" {
+					fmt.Printf("  ⚠ %s in progress (%s)\n", s.State, stateHint(s.State))
+				}
+
 				// Show changes
 				if s.HasChanges {
 					fmt.Printf("  changes: %d file(s)\n", len(s.Files))
 					for _, f := range s.Files {
-						fmt.Printf("    %s %s\n", f.Status, f.Path)
+						if f.OrigPath != "" {
+							fmt.Printf("    %s %s -> %s\n", f.Status, f.OrigPath, f.Path)
+						} else {
+							fmt.Printf("    %s %s\n", f.Status, f.Path)
+						}
 					}
 				} else {
 					fmt.Println("  changes: none")
@@ -496,59 +1899,187 @@ func statusCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "include repos left out by settings.uncloned_policy")
+	cmd.Flags().BoolVar(&fetch, "fetch", false, "fetch every repo before reporting ahead/behind")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+	cmd.Flags().BoolVar(&sortRepos, "sort", false, "list repos by path instead of config order")
+	cmd.Flags().BoolVar(&dirty, "dirty", false, "show only repos with changes, unpushed or unpulled commits, or an odd branch")
+	cmd.Flags().BoolVar(&short, "short", false, "print one line per repo")
+	cmd.Flags().BoolVar(&noCommitInfo, "no-commit-info", false, "don't show each repo's last commit")
+	return cmd
+}
+
+// commonBranch returns the branch most repos are on, preferring the
+// earliest repo's on a tie, or "" if every repo is detached
+func commonBranch(results []workspace.StatusResult) string {
+	counts := make(map[string]int)
+	common := ""
+	for _, r := range results {
+		if r.Status == nil || r.Status.Branch == "" || r.Status.Detached {
+			continue
+		}
+		b := r.Status.Branch
+		counts[b]++
+		if counts[b] > counts[common] {
+			common = b
+		}
+	}
+	return common
+}
+
+// dirtyStatus returns the repos that need attention: unreadable, with a
+// failed fetch, changes, commits to push or pull, an operation in
+// progress, or off the common branch
+func dirtyStatus(results []workspace.StatusResult, fetchFailed map[string]bool) []workspace.StatusResult {
+	// A failed fetch isn't part of the status, so recognize its repos by
+	// their status
+	failed := make(map[*git.Status]bool)
+	for _, r := range results {
+		if fetchFailed[r.Repo.Name()] {
+			failed[r.Status] = true
+		}
+	}
+	needsAttention := workspace.AnyStatus(
+		func(s *git.Status) bool { return failed[s] },
+		workspace.Dirty,
+		workspace.Ahead,
+		workspace.Behind,
+		workspace.InProgress,
+		workspace.Detached,
+		workspace.OffBranch(commonBranch(results)),
+	)
+	return workspace.FilterStatus(results, needsAttention)
+}
+
+// printStatusShort prints a line per repo: branch, ahead/behind, and the
+// number of changed files
+func printStatusShort(results []workspace.StatusResult, fetchFailed map[string]bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		name := r.Repo.Name() + durationNote(r.Duration)
+		if r.Error != nil {
+			fmt.Fprintf(w, "%s\terror: %v\t\t\n", name, r.Error)
+			continue
+		}
+
+		s := r.Status
+		branch := s.Branch
+		switch {
+		case branch == "":
+			branch = "(detached HEAD)"
+		case s.Detached:
+			branch += " (detached HEAD)"
+		}
+
+		var counts []string
+		if s.Ahead > 0 {
+			counts = append(counts, fmt.Sprintf("↑%d", s.Ahead))
+		}
+		if s.Behind > 0 {
+			counts = append(counts, fmt.Sprintf("↓%d", s.Behind))
+		}
+
+		changes := "clean"
+		if s.HasChanges {
+			changes = fmt.Sprintf("%d changed", len(s.Files))
+		}
+		if s.State != "" {
+			changes += fmt.Sprintf(" (%s in progress)", s.State)
+		}
+		if fetchFailed[r.Repo.Name()] {
+			changes += " (fetch failed)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, branch, strings.Join(counts, " "), changes)
+	}
+	w.Flush()
+}
+
+// stateHint tells how to finish or leave an operation a repo is stopped in
+func stateHint(state string) string {
+	if state == git.StateBisect {
+		return "git bisect reset when done"
+	}
+	return fmt.Sprintf("git %s --continue or --abort", state)
 }
 
 func gitCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "git [args...]",
+		Use:   "git [--only-output] [--only-failed] [--only-matching regex] [--json] [--sort] [--timing] [--repos list] [--exclude list] [args...]",
 		Short: "Run a git command across all repositories",
 		Long: `Run an arbitrary git command across all configured repositories.
 
+Options before the git command filter which repos are shown:
+  --only-output            hide repos whose command printed nothing
+  --only-failed            hide repos where the command succeeded
+  --only-matching <regex>  hide repos whose output doesn't match regex
+
+Every repo still runs; the summary counts the repos hidden.
+
+Also before the git command:
+  --json                   print the results as JSON
+  --sort                   list repos by path instead of config order
+  --timing                 show how long each repo took and the total time
+  --repos <list>           only run in repos matching these names or globs
+  --exclude <list>         don't run in repos matching these names or globs
+
 Examples:
   mergeish git status
   mergeish git log --oneline -5
   mergeish git remote -v
-  mergeish git fetch --all`,
+  mergeish git fetch --all
+  mergeish git --only-output log --oneline --since=yesterday
+  mergeish git --exclude 'legacy-*' status --short`,
 		DisableFlagParsing: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, args, err := parseGitFilter(args)
+			if err != nil {
+				return err
+			}
 			if len(args) == 0 {
 				return fmt.Errorf("git command required")
 			}
 
-			ws, err := loadWorkspace()
+			// The identity line would break JSON output
+			load := loadWorkspaceForChange
+			if filter.json {
+				load = loadWorkspace
+			}
+			ws, err := load()
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Running: git %s\n\n", strings.Join(args, " "))
-			results := ws.RunGit(args)
-
-			hasErrors := false
-			for _, r := range results {
-				fmt.Printf("── %s ──\n", r.Repo.Name())
+			if what := destructiveGit(args); what != "" {
+				if !confirmDestructive(ws, fmt.Sprintf("git %s %s in %d repos. Continue?", strings.Join(args, " "), what, len(ws.Repos))) {
+					fmt.Println("Aborted")
+					return nil
+				}
+			}
 
-				if r.Error != nil {
-					hasErrors = true
-					if r.Stderr != "" {
-						fmt.Print(r.Stderr)
-					} else {
-						fmt.Printf("error: %v\n", r.Error)
-					}
-				} else {
-					if r.Stdout != "" {
-						fmt.Print(r.Stdout)
-					}
-					if r.Stderr != "" {
-						fmt.Print(r.Stderr)
-					}
-					if r.Stdout == "" && r.Stderr == "" {
-						fmt.Println("(no output)")
+			if !filter.json {
+				fmt.Printf("Running: git %s\n\n", strings.Join(args, " "))
+			}
+			// Color is for people: JSON output never has it
+			color := !filter.json && (useColor() || askedForColor(args))
+			results := ws.RunGit(args, color)
+			if filter.sort {
+				sortByRepo(results, func(r workspace.GitResult) string { return r.Repo.Name() })
+			}
+			if filter.json {
+				if err := printGitJSON(args, results, filter); err != nil {
+					return err
+				}
+				for _, r := range results {
+					if r.Error != nil {
+						return fmt.Errorf("command failed on some repositories")
 					}
 				}
-				fmt.Println()
+				return nil
 			}
 
-			if hasErrors {
+			if printGitResults(results, filter) {
 				return fmt.Errorf("command failed on some repositories")
 			}
 
@@ -557,6 +2088,213 @@ Examples:
 	}
 }
 
+// destructiveGit describes what a git command given to mergeish git would
+// throw away, or returns "" if it isn't one that does
+func destructiveGit(args []string) string {
+	// Skip git's own options, such as -C dir, to find the subcommand
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		if args[i] == "-C" || args[i] == "-c" {
+			i++
+		}
+		i++
+	}
+	if i >= len(args) {
+		return ""
+	}
+	sub, rest := args[i], args[i+1:]
+
+	has := func(flags ...string) bool {
+		for _, a := range rest {
+			if slices.Contains(flags, a) {
+				return true
+			}
+		}
+		return false
+	}
+	// hasShort reports whether a short option cluster such as -fdx
+	// includes c
+	hasShort := func(c string) bool {
+		for _, a := range rest {
+			if a == "--" {
+				return false
+			}
+			if strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && strings.Contains(a[1:], c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch sub {
+	case "reset":
+		if has("--hard") {
+			return "discards uncommitted changes"
+		}
+	case "clean":
+		if has("--force") || hasShort("f") {
+			return "deletes untracked files"
+		}
+	case "checkout":
+		if has("--") {
+			return "discards uncommitted changes to the paths"
+		}
+	case "branch":
+		if has("-D") || (has("--delete", "-d") && has("--force", "-f")) {
+			return "deletes branches even if unmerged"
+		}
+	}
+	return ""
+}
+
+// printGitResults prints the output of each repo the filter lets through
+// under a header, and reports whether the command failed in any repo
+func printGitResults(results []workspace.GitResult, filter gitFilter) bool {
+	hasErrors := false
+	hidden := 0
+	for _, r := range results {
+		if r.Error != nil {
+			hasErrors = true
+		}
+		if !filter.show(r) {
+			hidden++
+			continue
+		}
+
+		fmt.Printf("── %s%s ──\n", r.Repo.Name(), durationNote(r.Duration))
+
+		if r.Error != nil {
+			fmt.Print(r.Stdout)
+			if r.Stderr != "" {
+				fmt.Print(r.Stderr)
+			} else {
+				fmt.Printf("error: %v\n", r.Error)
+			}
+		} else {
+			if r.Stdout != "" {
+				fmt.Print(r.Stdout)
+			}
+			if r.Stderr != "" {
+				fmt.Print(r.Stderr)
+			}
+			if r.Stdout == "" && r.Stderr == "" {
+				fmt.Println("(no output)")
+			}
+		}
+		fmt.Println()
+	}
+
+	if hidden > 0 {
+		fmt.Printf("%d of %d repos hidden by %s\n", hidden, len(results), filter)
+	}
+	return hasErrors
+}
+
+// askedForColor reports whether git args ask for color whatever the output
+func askedForColor(args []string) bool {
+	for _, arg := range args {
+		if arg == "--color" || arg == "--color=always" {
+			return true
+		}
+	}
+	return false
+}
+
+// gitFilter selects which repos mergeish git shows
+type gitFilter struct {
+	onlyOutput   bool
+	onlyFailed   bool
+	onlyMatching *regexp.Regexp
+	json         bool // print results as JSON
+	sort         bool // list repos by path
+}
+
+// parseGitFilter takes the filter options from the front of args and
+// returns the rest, which belong to git
+func parseGitFilter(args []string) (gitFilter, []string, error) {
+	var f gitFilter
+	for len(args) > 0 {
+		arg := args[0]
+		switch {
+		case arg == "--only-output":
+			f.onlyOutput = true
+		case arg == "--only-failed":
+			f.onlyFailed = true
+		case arg == "--json":
+			f.json = true
+		case arg == "--sort":
+			f.sort = true
+		case arg == "--timing" || arg == "--timings":
+			// git takes its arguments unparsed, so the global flag is
+			// picked up here
+			timing = true
+		case arg == "--repos" || strings.HasPrefix(arg, "--repos=") || arg == "--exclude" || strings.HasPrefix(arg, "--exclude="):
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				if len(args) < 2 {
+					return f, nil, fmt.Errorf("%s requires a list of repos", name)
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			list := &includeRepos
+			if name == "--exclude" {
+				list = &excludeRepos
+			}
+			*list = append(*list, strings.Split(value, ",")...)
+		case arg == "--only-matching" || strings.HasPrefix(arg, "--only-matching="):
+			expr, ok := strings.CutPrefix(arg, "--only-matching=")
+			if !ok {
+				if len(args) < 2 {
+					return f, nil, fmt.Errorf("--only-matching requires a regex")
+				}
+				expr = args[1]
+				args = args[1:]
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return f, nil, fmt.Errorf("invalid --only-matching regex: %w", err)
+			}
+			f.onlyMatching = re
+		case arg == "--":
+			return f, args[1:], nil
+		default:
+			return f, args, nil
+		}
+		args = args[1:]
+	}
+	return f, args, nil
+}
+
+// show reports whether the filter lets r through
+func (f gitFilter) show(r workspace.GitResult) bool {
+	if f.onlyOutput && r.Stdout == "" && r.Stderr == "" {
+		return false
+	}
+	if f.onlyFailed && r.Error == nil {
+		return false
+	}
+	if f.onlyMatching != nil && !f.onlyMatching.MatchString(r.Stdout) {
+		return false
+	}
+	return true
+}
+
+// String lists the filter's options, for the hidden repos summary
+func (f gitFilter) String() string {
+	var opts []string
+	if f.onlyOutput {
+		opts = append(opts, "--only-output")
+	}
+	if f.onlyFailed {
+		opts = append(opts, "--only-failed")
+	}
+	if f.onlyMatching != nil {
+		opts = append(opts, fmt.Sprintf("--only-matching %q", f.onlyMatching))
+	}
+	return strings.Join(opts, " ")
+}
+
 func prCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "pr",
@@ -567,15 +2305,27 @@ Requires the GitHub CLI (gh) to be installed and authenticated.`,
 	}
 
 	cmd.AddCommand(prStatusCmd())
+	cmd.AddCommand(prListCmd())
 	cmd.AddCommand(prCreateCmd())
 	cmd.AddCommand(prCloseCmd())
+	cmd.AddCommand(prMergeCmd())
+	cmd.AddCommand(prDraftCmd())
+	cmd.AddCommand(prReadyCmd())
+	cmd.AddCommand(prUpdateCmd())
+	cmd.AddCommand(prChecksCmd())
+	cmd.AddCommand(prLabelsCmd())
+	cmd.AddCommand(prReviewersCmd())
 	cmd.AddCommand(prOpenCmd())
 
 	return cmd
 }
 
 func prStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	var compact bool
+	var jsonOutput bool
+	var sortRepos bool
+
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show PR status for all repositories",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -584,6 +2334,14 @@ func prStatusCmd() *cobra.Command {
 				return err
 			}
 
+			if jsonOutput {
+				results := ws.GetPRs()
+				if sortRepos {
+					sortByRepo(results, func(r workspace.PRResult) string { return r.Repo.Name() })
+				}
+				return printPRStatusJSON(results)
+			}
+
 			// Check branch consistency
 			branch, consistent, err := ws.CheckBranchConsistency()
 			if err != nil {
@@ -596,20 +2354,38 @@ func prStatusCmd() *cobra.Command {
 				fmt.Printf("Branch: %s\n\n", branch)
 			}
 
+			if consistent {
+				if umbrella, err := ws.FindUmbrella(branch); err != nil {
+					fmt.Printf("Umbrella: error: %v\n\n", err)
+				} else if umbrella != nil {
+					fmt.Printf("Umbrella: %s\n\n", umbrella.URL)
+				}
+			}
+
 			results := ws.GetPRs()
+			if sortRepos {
+				sortByRepo(results, func(r workspace.PRResult) string { return r.Repo.Name() })
+			}
+
+			if compact {
+				printPRMatrix(results)
+				return nil
+			}
 
 			for _, r := range results {
-				fmt.Printf("%s: ", r.Repo.Name())
+				fmt.Printf("%s%s: ", r.Repo.Name(), durationNote(r.Duration))
 
 				if r.Error != nil {
 					fmt.Printf("error: %v\n", r.Error)
 					continue
 				}
 
-				if r.PR == nil {
+				if r.NoPRs {
+					fmt.Println("PRs: n/a")
+				} else if r.PR == nil {
 					fmt.Println("no PR")
 				} else {
-					fmt.Printf("#%d %s (%s)\n", r.PR.Number, r.PR.Title, r.PR.State)
+					fmt.Printf("#%d %s (%s)\n", r.PR.Number, r.PR.Title, prState(r.PR))
 					fmt.Printf("  %s\n", r.PR.URL)
 				}
 			}
@@ -617,23 +2393,111 @@ func prStatusCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&compact, "compact", false, "show a one-row-per-repo table")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+	cmd.Flags().BoolVar(&sortRepos, "sort", false, "list repos by path instead of config order")
+	return cmd
+}
+
+// printPRMatrix prints an aligned table of PR number, state, checks, review,
+// and mergeability, one row per repo
+func printPRMatrix(results []workspace.PRResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tPR\tSTATE\tCHECKS\tREVIEW\tMERGEABLE")
+
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			fmt.Fprintf(w, "%s\terror: %v\t\t\t\t\n", r.Repo.Name(), r.Error)
+		case r.NoPRs:
+			fmt.Fprintf(w, "%s\tn/a\t\t\t\t\n", r.Repo.Name())
+		case r.PR == nil:
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\n", r.Repo.Name())
+		default:
+			fmt.Fprintf(w, "%s\t#%d\t%s\t%s\t%s\t%s\n", r.Repo.Name(), r.PR.Number,
+				prState(r.PR), orDash(r.PR.Checks), orDash(r.PR.Review), orDash(r.PR.Mergeable))
+		}
+	}
+
+	w.Flush()
+}
+
+// prState returns the state of pr, DRAFT for an open draft
+func prState(pr *git.PRInfo) string {
+	if pr.Draft && pr.State == "OPEN" {
+		return "DRAFT"
+	}
+	return pr.State
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ToLower(s)
 }
 
 func prCreateCmd() *cobra.Command {
 	var title string
 	var body string
+	var bodyFile string
 	var base string
 	var infer bool
+	var allowMixedBase bool
+	var umbrella bool
+	var draft bool
+	var reviewers []string
+	var assignees []string
+	var labels []string
+	var link bool
+	var all bool
 
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create pull requests for all repositories",
+		Long: `Create a pull request for the current branch in every repository,
+skipping repos that already have one.
+
+With --umbrella, an issue in settings.pr.umbrella_repo tracks the whole
+change: it lists every PR as a checklist, and each PR links back to it.
+Running it again updates the existing issue instead of creating another.
+
+If the branch has a ticket (see mergeish branch describe), the title goes
+through settings.pr.title_template and the body starts with the ticket
+and description.
+
+Each PR body gets a Related PRs section listing the PRs in the other
+repos. Running it again refreshes the list in every PR, including those
+that already existed. Use --link=false to leave the bodies alone.
+
+A branch that has no upstream yet is pushed first. Repos with no commits
+ahead of the base are skipped, as the change doesn't touch them; use --all
+to open PRs there too.
+
+Without --body or --body-file, each repo's pull request template
+(.github/PULL_REQUEST_TEMPLATE.md and the other places GitHub looks) is the
+body, or settings.pr.template_file for repos without one. --infer puts the
+commit list at a {{commits}} placeholder in the template, or after it.
+
+Reviewers, assignees, and labels in settings.pr are applied to every new
+PR, along with any given by flag. A reviewer or label that doesn't exist
+fails only the repos it is rejected in.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if title == "" {
 				return fmt.Errorf("title required (-t)")
 			}
+			if bodyFile != "" {
+				if body != "" {
+					return fmt.Errorf("--body and --body-file can't be combined")
+				}
+				var err error
+				if body, err = readBodyFile(bodyFile); err != nil {
+					return err
+				}
+			}
 
-			ws, err := loadWorkspace()
+			ws, err := loadWorkspaceForChange()
 			if err != nil {
 				return err
 			}
@@ -643,32 +2507,77 @@ func prCreateCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			printBranchScope(ws, branch, consistent)
 			if !consistent {
 				return fmt.Errorf("repositories are on different branches, cannot create PRs")
 			}
 
-			// Infer body from commits if requested
-			if infer && body == "" {
-				body = inferBodyFromCommits(ws, base)
+			// Fill in each repo's PR template unless given a body, and add
+			// the branch's ticket and description
+			info, err := ws.BranchInfo(branch)
+			if err != nil {
+				return err
+			}
+			prTitle := ws.PRTitle(info, branch, title)
+			bodies, err := prBodies(ws, info, body, base, infer)
+			if err != nil {
+				return err
 			}
 
 			fmt.Printf("Creating PRs for branch %s...\n\n", branch)
-			results := ws.CreatePRs(title, body, base)
+			results, err := ws.CreatePRs(prTitle, bodies, workspace.PRCreateOptions{
+				Base:           base,
+				Draft:          draft,
+				All:            all,
+				AllowMixedBase: allowMixedBase,
+				Meta:           ws.PRMetadata(reviewers, assignees, labels),
+			})
+			var mixed *workspace.MixedBaseError
+			if errors.As(err, &mixed) {
+				fmt.Println("Repositories target different base branches:")
+				printBaseGroups(mixed.Groups)
+				return fmt.Errorf("mixed PR bases, use --base or --allow-mixed-base")
+			}
+			if err != nil {
+				return err
+			}
 
 			hasErrors := false
 			for _, r := range results {
-				if r.Error != nil {
-					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+				name := r.Repo.Name() + durationNote(r.Duration)
+				if r.Error != nil && r.PR != nil {
+					fmt.Printf("  ✗ %s: created %s, but %v\n", name, r.PR.URL, r.Error)
+					hasErrors = true
+				} else if r.Error != nil {
+					fmt.Printf("  ✗ %s: %v\n", name, r.Error)
 					hasErrors = true
+				} else if r.NoPRs {
+					fmt.Printf("  - %s: PRs: n/a\n", name)
+				} else if r.Skipped != "" {
+					fmt.Printf("  - %s: %s\n", name, r.Skipped)
 				} else if r.PR != nil {
 					if r.Existed {
-						fmt.Printf("  - %s: already exists %s\n", r.Repo.Name(), r.PR.URL)
+						fmt.Printf("  - %s: already exists %s\n", name, r.PR.URL)
+					} else if r.Pushed {
+						fmt.Printf("  ✓ %s: pushed + created PR %s\n", name, r.PR.URL)
 					} else {
-						fmt.Printf("  ✓ %s: %s\n", r.Repo.Name(), r.PR.URL)
+						fmt.Printf("  ✓ %s: created PR %s\n", name, r.PR.URL)
 					}
 				}
 			}
 
+			if link {
+				if err := linkRelatedPRs(ws, results); err != nil {
+					return err
+				}
+			}
+
+			if umbrella {
+				if err := linkUmbrella(ws, branch, prTitle, results); err != nil {
+					return err
+				}
+			}
+
 			if hasErrors {
 				return fmt.Errorf("failed to create PRs for some repositories")
 			}
@@ -680,45 +2589,171 @@ func prCreateCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&title, "title", "t", "", "PR title (required)")
 	cmd.Flags().StringVarP(&body, "body", "b", "", "PR body/description")
+	cmd.Flags().StringVarP(&bodyFile, "body-file", "F", "", "read the PR body from this file (- for stdin)")
 	cmd.Flags().StringVar(&base, "base", "", "base branch (default: repo default)")
 	cmd.Flags().BoolVar(&infer, "infer", false, "infer PR body from commit messages")
+	cmd.Flags().BoolVar(&allowMixedBase, "allow-mixed-base", false, "allow repos to target different base branches")
+	cmd.Flags().BoolVar(&umbrella, "umbrella", false, "create or update an umbrella issue listing all PRs (needs settings.pr.umbrella_repo)")
+	cmd.Flags().BoolVar(&draft, "draft", false, "create the PRs as drafts")
+	cmd.Flags().StringSliceVar(&reviewers, "reviewer", nil, "request a review from this user or org/team (repeatable)")
+	cmd.Flags().StringSliceVar(&assignees, "assignee", nil, "assign the PRs to this user (repeatable)")
+	cmd.Flags().StringSliceVar(&labels, "label", nil, "add this label to the PRs (repeatable)")
+	cmd.Flags().BoolVar(&link, "link", true, "list the other repos' PRs in each PR body")
+	cmd.Flags().BoolVar(&all, "all", false, "also open PRs in repos with no commits ahead of the base")
 
 	return cmd
 }
 
-// inferBodyFromCommits generates a PR body from commit messages across all repos
-func inferBodyFromCommits(ws *workspace.Workspace, base string) string {
-	var allCommits []string
-	seen := make(map[string]bool)
+// linkRelatedPRs lists the other repos' PRs in the body of each PR
+func linkRelatedPRs(ws *workspace.Workspace, prs []workspace.PRResult) error {
+	linked := 0
+	for _, r := range prs {
+		if r.PR != nil {
+			linked++
+		}
+	}
+	if linked < 2 {
+		return nil
+	}
+
+	fmt.Println("\nLinking related PRs...")
+	hasErrors := false
+	for _, r := range ws.LinkRelatedPRs(prs) {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		} else {
+			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+		}
+	}
+	if hasErrors {
+		return fmt.Errorf("failed to link some related PRs")
+	}
+	return nil
+}
+
+// linkUmbrella creates or updates the umbrella issue for branch and links
+// every PR to it
+func linkUmbrella(ws *workspace.Workspace, branch, title string, prs []workspace.PRResult) error {
+	issue, created, err := ws.UpdateUmbrella(branch, title, prs, true)
+	if err != nil {
+		return fmt.Errorf("umbrella issue: %w", err)
+	}
+	if created {
+		fmt.Printf("\nCreated umbrella issue %s\n", issue.URL)
+	} else {
+		fmt.Printf("\nUpdated umbrella issue %s\n", issue.URL)
+	}
+
+	fmt.Println("Linking PRs to the umbrella issue...")
+	hasErrors := false
+	for _, r := range ws.LinkUmbrella(issue.URL) {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		} else {
+			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+		}
+	}
+	if hasErrors {
+		return fmt.Errorf("failed to link some PRs to the umbrella issue")
+	}
+	return nil
+}
+
+// printBaseGroups lists repos by PR base, largest group first, so the
+// outliers come last
+func printBaseGroups(groups map[string][]*repo.Repo) {
+	bases := make([]string, 0, len(groups))
+	for b := range groups {
+		bases = append(bases, b)
+	}
+	sort.Slice(bases, func(i, j int) bool {
+		if len(groups[bases[i]]) != len(groups[bases[j]]) {
+			return len(groups[bases[i]]) > len(groups[bases[j]])
+		}
+		return bases[i] < bases[j]
+	})
+
+	for _, b := range bases {
+		names := make([]string, len(groups[b]))
+		for i, r := range groups[b] {
+			names[i] = r.Name()
+		}
+		fmt.Printf("  %s: %s\n", b, strings.Join(names, ", "))
+	}
+}
+
+// readBodyFile reads a PR body from path, or from stdin if path is "-"
+func readBodyFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading --body-file: %w", err)
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// prBodies returns the body of each repo's PR: body if given, or else the
+// repo's PR template, with the commits inferred for --infer at its
+// {{commits}} placeholder. Repos without a template get the commits alone.
+// The branch's ticket and description go first.
+func prBodies(ws *workspace.Workspace, info workspace.BranchInfo, body, base string, infer bool) (func(*repo.Repo) string, error) {
+	commits := ""
+	if infer && body == "" {
+		commits = inferBodyFromCommits(ws, base)
+	}
+
+	bodies := make(map[*repo.Repo]string, len(ws.Repos))
+	for _, r := range ws.Repos {
+		b := body
+		if b == "" {
+			template, err := ws.PRTemplate(r)
+			if err != nil {
+				return nil, fmt.Errorf("PR template of %s: %w", r.Name(), err)
+			}
+			b = commits
+			if template != "" {
+				b = workspace.RenderPRTemplate(template, commits)
+			}
+		}
+		bodies[r] = ws.PRBody(info, b)
+	}
+	return func(r *repo.Repo) string { return bodies[r] }, nil
+}
 
+// inferBodyFromCommits generates a PR body from the branch's commits,
+// grouped by repo, with their authors and, for GitHub repos, a link to
+// compare the branch with its base
+func inferBodyFromCommits(ws *workspace.Workspace, base string) string {
+	var body strings.Builder
 	for _, r := range ws.Repos {
 		if !r.IsCloned() {
 			continue
 		}
 
 		commits, err := r.GetBranchCommits(base)
-		if err != nil {
+		if err != nil || len(commits) == 0 {
 			continue
 		}
 
-		for _, commit := range commits {
-			if commit != "" && !seen[commit] {
-				seen[commit] = true
-				allCommits = append(allCommits, commit)
+		if body.Len() == 0 {
+			body.WriteString("## Changes\n")
+		}
+		fmt.Fprintf(&body, "\n### %s\n\n", r.Name())
+		if branch, err := r.CurrentBranch(); err == nil {
+			if url := git.CompareURL(r.Config.URL, ws.ResolveBase(r, base), branch); url != "" {
+				fmt.Fprintf(&body, "[Compare changes](%s)\n\n", url)
 			}
 		}
-	}
-
-	if len(allCommits) == 0 {
-		return ""
-	}
-
-	var body strings.Builder
-	body.WriteString("## Changes\n\n")
-	for _, commit := range allCommits {
-		body.WriteString("- ")
-		body.WriteString(commit)
-		body.WriteString("\n")
+		for _, c := range commits {
+			fmt.Fprintf(&body, "- %s (%s)\n", c.Subject, c.Author)
+		}
 	}
 
 	return body.String()
@@ -729,7 +2764,7 @@ func prCloseCmd() *cobra.Command {
 		Use:   "close",
 		Short: "Close pull requests for all repositories",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ws, err := loadWorkspace()
+			ws, err := loadWorkspaceForChange()
 			if err != nil {
 				return err
 			}
@@ -748,14 +2783,21 @@ func prCloseCmd() *cobra.Command {
 
 			hasErrors := false
 			for _, r := range results {
+				name := r.Repo.Name() + durationNote(r.Duration)
 				if r.Error != nil {
-					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					fmt.Printf("  ✗ %s: %v\n", name, r.Error)
 					hasErrors = true
+				} else if r.NoPRs {
+					fmt.Printf("  - %s: PRs: n/a\n", name)
 				} else {
-					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+					fmt.Printf("  ✓ %s\n", name)
 				}
 			}
 
+			if consistent {
+				updateUmbrella(ws, branch)
+			}
+
 			if hasErrors {
 				return fmt.Errorf("failed to close PRs for some repositories")
 			}
@@ -766,6 +2808,302 @@ func prCloseCmd() *cobra.Command {
 	}
 }
 
+func prMergeCmd() *cobra.Command {
+	var squash, rebase, merge bool
+	var deleteBranch bool
+	var auto bool
+	var admin bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Merge the pull requests of all repositories",
+		Long: `Merge the pull request for the current branch of every repository with
+gh pr merge, using a merge commit unless --squash or --rebase is given.
+
+Before merging anything, every PR is checked: each must be open and not a
+draft, with no failing or pending checks and no merge conflicts. If any is
+not, the problems are listed and nothing is merged, unless --force is
+given. Repos without a PR, and repos whose PR is already merged, are
+skipped.
+
+With --auto, GitHub's auto-merge is enabled instead, so each PR merges once
+its requirements are met; pending checks don't count against it.
+
+With --admin, the PRs are merged even if required checks or reviews are
+missing, as gh pr merge --admin does; failing and pending checks don't
+count against them. It needs admin rights on each repository.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			strategy := git.MergeStrategyMerge
+			chosen := 0
+			for _, f := range []struct {
+				set      bool
+				strategy string
+			}{{merge, git.MergeStrategyMerge}, {squash, git.MergeStrategySquash}, {rebase, git.MergeStrategyRebase}} {
+				if f.set {
+					strategy = f.strategy
+					chosen++
+				}
+			}
+			if chosen > 1 {
+				return fmt.Errorf("only one of --merge, --squash, and --rebase can be given")
+			}
+			if auto && admin {
+				return fmt.Errorf("--auto and --admin can't be combined")
+			}
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("⚠ Warning: repositories are on different branches")
+			}
+
+			opts := workspace.MergePROptions{
+				MergeOptions: git.MergeOptions{Strategy: strategy, DeleteBranch: deleteBranch, Auto: auto, Admin: admin},
+				Force:        force,
+			}
+
+			fmt.Printf("Checking PRs for branch %s...\n\n", branch)
+			checks := ws.CheckMerge(opts.MergeOptions)
+			open := 0
+			for _, c := range checks {
+				name := c.Repo.Name()
+				switch {
+				case c.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", name, c.Error)
+				case c.NoPRs:
+					fmt.Printf("  - %s: PRs: n/a\n", name)
+				case c.PR == nil:
+					fmt.Printf("  - %s: no PR\n", name)
+				case c.PR.State == "MERGED":
+					fmt.Printf("  - %s: #%d already merged\n", name, c.PR.Number)
+				case c.Reason != "":
+					fmt.Printf("  ✗ %s: #%d %s\n", name, c.PR.Number, c.Reason)
+				default:
+					fmt.Printf("  ✓ %s: #%d ready\n", name, c.PR.Number)
+				}
+				if c.Open() {
+					open++
+				}
+			}
+			if err := workspace.VerifyMerge(checks, opts); err != nil {
+				return fmt.Errorf("some PRs are not ready to merge; fix them or use --force to merge the open ones anyway")
+			}
+
+			if open == 0 {
+				fmt.Println("\nNo open PRs to merge")
+				return nil
+			}
+			verb := "Merge"
+			if auto {
+				verb = "Enable auto-merge for"
+			} else if admin {
+				verb = "Merge as admin"
+			}
+			if !confirmDestructive(ws, fmt.Sprintf("%s %d PRs for branch %s (%s)?", verb, open, branch, strategy)) {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			if auto {
+				fmt.Printf("\nEnabling auto-merge for branch %s...\n\n", branch)
+			} else {
+				fmt.Printf("\nMerging PRs for branch %s...\n\n", branch)
+			}
+			results, err := ws.MergePRs(checks, opts)
+			if err != nil {
+				return err
+			}
+			merged, failed := 0, 0
+			for _, r := range results {
+				name := r.Repo.Name() + durationNote(r.Duration)
+				if r.Error != nil {
+					fmt.Printf("  ✗ %s: %v\n", name, r.Error)
+					failed++
+				} else {
+					fmt.Printf("  ✓ %s\n", name)
+					merged++
+				}
+			}
+
+			if consistent {
+				updateUmbrella(ws, branch)
+			}
+
+			verb = "Merged"
+			if auto {
+				verb = "Auto-merge enabled for"
+			}
+			fmt.Printf("\n%s %d, skipped %d, failed %d\n", verb, merged, len(checks)-len(results), failed)
+
+			if failed > 0 {
+				return fmt.Errorf("failed to merge PRs for some repositories")
+			}
+
+			fmt.Println("\nDone!")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&merge, "merge", false, "merge with a merge commit (the default)")
+	cmd.Flags().BoolVar(&squash, "squash", false, "squash the commits into one")
+	cmd.Flags().BoolVar(&rebase, "rebase", false, "rebase the commits onto the base branch")
+	cmd.Flags().BoolVar(&deleteBranch, "delete-branch", false, "delete the branch after merging")
+	cmd.Flags().BoolVar(&auto, "auto", false, "enable auto-merge, merging each PR once its requirements are met")
+	cmd.Flags().BoolVar(&admin, "admin", false, "merge even if required checks or reviews are missing (needs admin rights)")
+	cmd.Flags().BoolVar(&force, "force", false, "merge the open PRs even if some are not ready")
+	return cmd
+}
+
+func prDraftCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "draft",
+		Short: "Convert the open pull requests of all repositories to drafts",
+		Long: `Convert the open pull request for the current branch of every repository
+to a draft, to signal it is still a work in progress. Repos without an open
+PR, or whose PR already is a draft, are skipped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Converting PRs to drafts...")
+			return printPRResults(ws.MarkPRsDraft(), "convert PRs to drafts for")
+		},
+	}
+}
+
+func prReadyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ready",
+		Short: "Mark the draft pull requests of all repositories ready for review",
+		Long: `Mark the draft pull request for the current branch of every repository
+as ready for review. Repos without an open draft PR are skipped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Marking PRs ready for review...")
+			return printPRResults(ws.MarkPRsReady(), "mark PRs ready for")
+		},
+	}
+}
+
+func prUpdateCmd() *cobra.Command {
+	var title string
+	var body string
+	var appendBody bool
+	var infer bool
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update the title or body of the open pull requests",
+		Long: `Update the title, the body, or both of the open pull request for the
+current branch of every repository. Repos without an open PR are skipped.
+
+A new title goes through settings.pr.title_template, and a new body starts
+with the branch's ticket and description, as with pr create. With --append,
+the body is added to the end of the existing one instead. With --infer and
+no --body, the body is generated again from the branch's commits.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if title == "" && body == "" && !infer {
+				return fmt.Errorf("nothing to update; give --title, --body, or --infer")
+			}
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				return fmt.Errorf("repositories are on different branches, cannot update PRs")
+			}
+
+			if infer && body == "" {
+				if body = inferBodyFromCommits(ws, ""); body == "" {
+					return fmt.Errorf("no commits to infer the PR body from")
+				}
+			}
+
+			info, err := ws.BranchInfo(branch)
+			if err != nil {
+				return err
+			}
+			if title != "" {
+				title = ws.PRTitle(info, branch, title)
+			}
+			if body != "" && !appendBody {
+				body = ws.PRBody(info, body)
+			}
+
+			fmt.Printf("Updating PRs for branch %s...\n", branch)
+			return printPRResults(ws.UpdatePRs(title, body, appendBody), "update PRs for")
+		},
+	}
+
+	cmd.Flags().StringVarP(&title, "title", "t", "", "new PR title")
+	cmd.Flags().StringVarP(&body, "body", "b", "", "new PR body/description")
+	cmd.Flags().BoolVar(&appendBody, "append", false, "add the body to the end of the existing one")
+	cmd.Flags().BoolVar(&infer, "infer", false, "infer PR body from commit messages")
+	return cmd
+}
+
+// printPRResults prints the results of a command acting on each open PR,
+// such as pr draft. what completes "failed to ... some repositories".
+func printPRResults(results []workspace.PRResult, what string) error {
+	hasErrors := false
+	for _, r := range results {
+		name := r.Repo.Name() + durationNote(r.Duration)
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", name, r.Error)
+			hasErrors = true
+		case r.NoPRs:
+			fmt.Printf("  - %s: PRs: n/a\n", name)
+		case r.Skipped != "":
+			fmt.Printf("  - %s (%s)\n", name, r.Skipped)
+		default:
+			fmt.Printf("  ✓ %s: %s\n", name, r.PR.URL)
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to %s some repositories", what)
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
+
+// updateUmbrella refreshes the checklist of the umbrella issue for branch,
+// if there is one. Failures are reported but not fatal.
+func updateUmbrella(ws *workspace.Workspace, branch string) {
+	if ws.Config.Settings.PR.UmbrellaRepo == "" {
+		return
+	}
+	issue, _, err := ws.UpdateUmbrella(branch, "", ws.GetPRs(), false)
+	if err != nil {
+		fmt.Printf("⚠ Warning: could not update the umbrella issue: %v\n", err)
+	} else if issue != nil {
+		fmt.Printf("Updated umbrella issue %s\n", issue.URL)
+	}
+}
+
 func prOpenCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "open",
@@ -785,6 +3123,10 @@ func prOpenCmd() *cobra.Command {
 					continue
 				}
 
+				if r.NoPRs {
+					fmt.Printf("  - %s: PRs: n/a\n", r.Repo.Name())
+					continue
+				}
 				if r.PR == nil {
 					fmt.Printf("  - %s: no PR\n", r.Repo.Name())
 					continue
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func maintenanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Keep the repositories' git directories compact and fast",
+	}
+
+	cmd.AddCommand(maintenanceRunCmd())
+	cmd.AddCommand(maintenanceRegisterCmd())
+	cmd.AddCommand(maintenanceUnregisterCmd())
+
+	return cmd
+}
+
+func maintenanceRunCmd() *cobra.Command {
+	var ifNeeded bool
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run git maintenance in all repositories",
+		Long: `Run git maintenance (gc, commit-graph, and prefetch) in every repository,
+showing the size of each .git directory before and after and how long it
+took. On a git without git maintenance, git gc --auto runs instead.
+
+gc is heavy on disk, so only 2 repositories run at once regardless of
+settings.max_jobs; use --jobs to change that.
+
+With --if-needed, repositories with few loose objects and packs are
+skipped, so the command is cheap enough to run routinely.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+			if jobs == 0 {
+				ws.MaxJobs = workspace.MaintenanceJobs
+			}
+
+			fmt.Println("Running maintenance...")
+			hasErrors := false
+			var before, after int64
+			for _, r := range ws.RunMaintenance(ifNeeded) {
+				switch {
+				case r.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+				case r.Skipped != "":
+					fmt.Printf("  - %s: %s\n", r.Repo.Name(), r.Skipped)
+				default:
+					before += r.Before
+					after += r.After
+					line := fmt.Sprintf("  ✓ %s: %s → %s in %s", r.Repo.Name(), formatSize(r.Before), formatSize(r.After), formatDuration(r.Duration))
+					if r.FellBack {
+						line += " (git gc --auto)"
+					}
+					fmt.Println(line)
+				}
+			}
+
+			if hasErrors {
+				return fmt.Errorf("maintenance failed in some repositories")
+			}
+			if before > 0 {
+				fmt.Printf("\nTotal: %s → %s\n", formatSize(before), formatSize(after))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&ifNeeded, "if-needed", false, "skip repos with few loose objects and packs")
+	return cmd
+}
+
+func maintenanceRegisterCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "register",
+		Short: "Enroll all repositories in git's background maintenance",
+		Long: `Add every repository to git's background maintenance, which git then runs
+on a schedule once git maintenance start has been run on this machine.
+Needs git 2.30 or newer.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Registering for background maintenance...")
+			hasErrors := false
+			for _, r := range ws.RegisterMaintenance() {
+				switch {
+				case errors.Is(r.Error, git.ErrNoMaintenance):
+					fmt.Printf("  - %s: not supported by this git\n", r.Repo.Name())
+				case r.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+				default:
+					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+				}
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to register some repositories")
+			}
+			fmt.Println("\nRun git maintenance start once if background maintenance isn't scheduled yet.")
+			return nil
+		},
+	}
+}
+
+func maintenanceUnregisterCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unregister",
+		Short: "Remove all repositories from git's background maintenance",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Unregistering from background maintenance...")
+			hasErrors := false
+			for _, r := range ws.UnregisterMaintenance() {
+				switch {
+				case errors.Is(r.Error, git.ErrNoMaintenance):
+					fmt.Printf("  - %s: not supported by this git\n", r.Repo.Name())
+				case r.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+				case r.Skipped != "":
+					fmt.Printf("  - %s: %s\n", r.Repo.Name(), r.Skipped)
+				default:
+					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+				}
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to unregister some repositories")
+			}
+			return nil
+		},
+	}
+}
+
+// formatSize formats a size in bytes for display, e.g. "1.5 MB"
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func mergeCmd() *cobra.Command {
+	var noFF bool
+	var abort bool
+
+	cmd := &cobra.Command{
+		Use:   "merge <branch>",
+		Short: "Merge a branch into the current branch of all repositories",
+		Long: `Merge a branch, or any ref such as origin/main, into the current branch
+of every repository.
+
+A repo where the merge hits conflicts is reported as a conflict and left
+mid-merge, with the conflicted files listed. Resolve them and run
+'git merge --continue' in that repo, or run mergeish merge --abort to back
+out of the merge in every repo that is still mid-merge.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if abort {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			if abort {
+				if noFF {
+					return fmt.Errorf("--no-ff can't be used with --abort")
+				}
+				return mergeAbort(ws)
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("Warning: repositories are on different branches")
+			}
+
+			fmt.Printf("Merging %s into %s...\n", args[0], branch)
+			results := ws.Merge(args[0], noFF)
+
+			hasErrors := false
+			for _, r := range results {
+				if r.Error != nil {
+					fmt.Printf("  ✗ %s%s: %v\n", r.Repo.Name(), durationNote(r.Duration), r.Error)
+					hasErrors = true
+				} else {
+					fmt.Printf("  ✓ %s%s\n", r.Repo.Name(), durationNote(r.Duration))
+				}
+			}
+			if reportConflicts(results) {
+				fmt.Println("  Or run 'mergeish merge --abort' to back out of the merge everywhere.")
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to merge some repositories")
+			}
+
+			fmt.Println("Done!")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&noFF, "no-ff", false, "always create a merge commit")
+	cmd.Flags().BoolVar(&abort, "abort", false, "abort the merge in progress in every repo")
+	return cmd
+}
+
+// mergeAbort runs git merge --abort in every repo that is mid-merge
+func mergeAbort(ws *workspace.Workspace) error {
+	fmt.Println("Aborting merges...")
+	results := ws.MergeAbort()
+
+	hasErrors := false
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		case r.Skipped != "":
+			fmt.Printf("  - %s (%s)\n", r.Repo.Name(), r.Skipped)
+		default:
+			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to abort the merge in some repositories")
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
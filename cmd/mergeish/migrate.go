@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/repo"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func migrateDefaultBranchCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate-default-branch",
+		Short: "Follow remote default branch renames (e.g. master → main)",
+		Long: `Detect repos whose remote default branch was renamed and update the
+local clones to match.
+
+For each repo, the remote's current HEAD branch is looked up and:
+  - <remote>/HEAD is updated to point at it
+  - a local branch still using the old name is renamed and its upstream retargeted
+  - the stale remote-tracking branch is pruned
+
+If settings.default_branch uses the old name and every repo now agrees on
+the new one, the config is updated too. So is a repo's own
+settings.default_branch, or one it inherits, when it names the repo's old
+branch.
+
+The plan is shown first and applied only after confirmation.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Checking remote default branches...")
+			plans := ws.PlanDefaultBranchMigration()
+
+			pending := 0
+			for _, p := range plans {
+				if p.Error != nil {
+					fmt.Printf("  ✗ %s: %v\n", p.Repo.Name(), p.Error)
+					continue
+				}
+				if !p.NeedsChanges() && !p.Override {
+					fmt.Printf("  - %s: up to date (%s)\n", p.Repo.Name(), p.NewBranch)
+					continue
+				}
+				fmt.Printf("  • %s: %s\n", p.Repo.Name(), describePlan(p))
+				pending++
+			}
+
+			newDefault := migratedDefaultBranch(ws, plans)
+
+			if pending == 0 && newDefault == "" {
+				fmt.Println("Nothing to migrate")
+				return nil
+			}
+			if newDefault != "" {
+				fmt.Printf("  • config: default_branch %s → %s\n", ws.Config.Settings.DefaultBranch, newDefault)
+			}
+
+			if !confirm("Apply these changes?") {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			fmt.Println("Migrating...")
+			results := ws.MigrateDefaultBranch(plans)
+
+			hasErrors := false
+			failed := make(map[*repo.Repo]bool)
+			for _, r := range results {
+				if r.Error != nil {
+					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+					failed[r.Repo] = true
+				} else {
+					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+				}
+			}
+
+			// Overrides of the repos that migrated follow them even if
+			// others failed
+			var overridden []workspace.DefaultBranchPlan
+			for _, p := range plans {
+				if p.Error != nil || !p.Override || failed[p.Repo] {
+					continue
+				}
+				if err := ws.Config.SetRepoDefaultBranch(p.Repo.Config.Path, p.NewBranch); err != nil {
+					fmt.Printf("  ✗ %s: %v\n", p.Repo.Name(), err)
+					hasErrors = true
+					continue
+				}
+				overridden = append(overridden, p)
+			}
+			if newDefault != "" && !hasErrors {
+				ws.Config.Settings.DefaultBranch = newDefault
+			} else {
+				newDefault = ""
+			}
+
+			if newDefault != "" || len(overridden) > 0 {
+				if err := ws.Config.Save(ws.ConfigPath); err != nil {
+					return err
+				}
+				if newDefault != "" {
+					fmt.Printf("  ✓ %s: default_branch = %s\n", ws.ConfigPath, newDefault)
+				}
+				for _, p := range overridden {
+					fmt.Printf("  ✓ %s: %s default_branch = %s\n", ws.ConfigPath, p.Repo.Name(), p.NewBranch)
+				}
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to migrate some repositories")
+			}
+
+			fmt.Println("Done!")
+			return nil
+		},
+	}
+}
+
+func describePlan(p workspace.DefaultBranchPlan) string {
+	var steps []string
+	if p.SetHead {
+		steps = append(steps, fmt.Sprintf("set %s/HEAD → %s", p.Repo.Remote(), p.NewBranch))
+	}
+	if p.RenameLocal {
+		steps = append(steps, fmt.Sprintf("rename %s → %s", p.OldBranch, p.NewBranch))
+	}
+	if p.PruneStale {
+		steps = append(steps, fmt.Sprintf("prune %s/%s", p.Repo.Remote(), p.OldBranch))
+	}
+	if p.Override {
+		steps = append(steps, fmt.Sprintf("set its default_branch %s → %s", p.OldBranch, p.NewBranch))
+	}
+	return strings.Join(steps, ", ")
+}
+
+// migratedDefaultBranch returns the new default branch name for the config
+// if the configured name is an old name and all repos agree on a new one
+func migratedDefaultBranch(ws *workspace.Workspace, plans []workspace.DefaultBranchPlan) string {
+	current := ws.Config.Settings.DefaultBranch
+	newBranch := ""
+	renamed := false
+
+	for _, p := range plans {
+		if p.Error != nil {
+			return ""
+		}
+		if newBranch == "" {
+			newBranch = p.NewBranch
+		} else if p.NewBranch != newBranch {
+			return ""
+		}
+		if p.OldBranch == current {
+			renamed = true
+		}
+	}
+
+	if !renamed || newBranch == current {
+		return ""
+	}
+	return newBranch
+}
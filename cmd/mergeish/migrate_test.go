@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/config"
+)
+
+func TestMigrateDefaultBranchOverrides(t *testing.T) {
+	root := scopeWorkspace(t)
+	urls := make(map[string]string)
+	for _, name := range []string{"api", "web", "legacy"} {
+		urls[name] = gitIn(t, filepath.Join(root, name), "remote", "get-url", "origin")
+	}
+	// api and web rename main to trunk; legacy keeps main
+	for _, name := range []string{"api", "web"} {
+		gitIn(t, root, "-C", urls[name], "branch", "-m", "main", "trunk")
+	}
+	yml := "repos:\n" +
+		"  - url: " + urls["api"] + "\n    path: api\n    provider: github\n    settings:\n      default_branch: main\n" +
+		"  - url: " + urls["web"] + "\n    path: web\n    provider: github\n    preset: old\n" +
+		"  - url: " + urls["legacy"] + "\n    path: legacy\n    provider: github\n    preset: old\n" +
+		"presets:\n  old:\n    settings:\n      default_branch: main\n"
+	if err := os.WriteFile(configPath, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCommand(t, migrateDefaultBranchCmd)
+	if err != nil {
+		t.Fatalf("migrate-default-branch: %v\n%s", err, out)
+	}
+	for _, want := range []string{"set its default_branch main → trunk", "api default_branch = trunk", "web default_branch = trunk"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "legacy default_branch") {
+		t.Errorf("legacy's default_branch changed though its remote kept main:\n%s", out)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rc := range cfg.Repos {
+		want := "trunk"
+		if rc.Path == "legacy" {
+			want = "main"
+		}
+		if got := rc.EffectiveSettings(cfg.Settings).DefaultBranch; got != want {
+			t.Errorf("%s default_branch = %q, want %q", rc.Path, got, want)
+		}
+	}
+	if got := cfg.Presets["old"].Settings.DefaultBranch; got != "main" {
+		t.Errorf("preset default_branch = %q, want it left at main for legacy", got)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/config"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+// bodyWorkspace creates a workspace of plain directories api and web, with
+// the given files relative to the root
+func bodyWorkspace(t *testing.T, templateFile string, files map[string]string) *workspace.Workspace {
+	t.Helper()
+	root := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Settings.PR.TemplateFile = templateFile
+	for _, p := range []string{"api", "web"} {
+		if err := os.MkdirAll(filepath.Join(root, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		cfg.Repos = append(cfg.Repos, config.RepoConfig{URL: "git@github.com:org/" + p + ".git", Path: p})
+	}
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return workspace.New(cfg, root)
+}
+
+func TestPRBodies(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		files        map[string]string
+		templateFile string
+		body         string
+		info         workspace.BranchInfo
+		want         map[string]string // by repo name
+		wantErr      bool
+	}{
+		{
+			name: "no templates",
+			want: map[string]string{"api": "", "web": ""},
+		},
+		{
+			name:  "each repo its own template",
+			files: map[string]string{"api/.github/PULL_REQUEST_TEMPLATE.md": "api template\r\n"},
+			want:  map[string]string{"api": "api template\n", "web": ""},
+		},
+		{
+			name:         "workspace template for repos without one",
+			files:        map[string]string{"api/.github/PULL_REQUEST_TEMPLATE.md": "api\n", "pr.md": "shared\r\n\r\n{{commits}}\r\n"},
+			templateFile: "pr.md",
+			want:         map[string]string{"api": "api\n", "web": "shared\n\n\n"},
+		},
+		{
+			name:  "body wins over templates",
+			files: map[string]string{"api/.github/PULL_REQUEST_TEMPLATE.md": "api\n"},
+			body:  "given",
+			want:  map[string]string{"api": "given", "web": "given"},
+		},
+		{
+			name:  "branch ticket before the template",
+			files: map[string]string{"web/PULL_REQUEST_TEMPLATE.md": "web\n"},
+			info:  workspace.BranchInfo{Ticket: "ENG-1"},
+			want:  map[string]string{"api": "Ticket: ENG-1", "web": "Ticket: ENG-1\n\nweb\n"},
+		},
+		{
+			name:         "missing template_file",
+			templateFile: "missing.md",
+			wantErr:      true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := bodyWorkspace(t, tt.templateFile, tt.files)
+			bodies, err := prBodies(ws, tt.info, tt.body, "", false)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("prBodies succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range ws.Repos {
+				if got := bodies(r); got != tt.want[r.Name()] {
+					t.Errorf("body of %s = %q, want %q", r.Name(), got, tt.want[r.Name()])
+				}
+			}
+		})
+	}
+}
+
+func TestReadBodyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.md")
+	if err := os.WriteFile(path, []byte("## Why\r\n\r\nBecause\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBodyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "## Why\n\nBecause\n"; got != want {
+		t.Errorf("readBodyFile = %q, want %q", got, want)
+	}
+
+	if _, err := readBodyFile(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("readBodyFile succeeded on a missing file")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func prChecksCmd() *cobra.Command {
+	var watch bool
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "checks",
+		Short: "Show CI check results for the pull requests of all repositories",
+		Long: `Show how many CI checks passed, failed, or are still pending on the pull
+request for the current branch of every repository, with the failed ones
+listed, and an overall verdict.
+
+The command fails if a required check failed. In a repo without required
+checks, any failed check counts. With --watch, the checks are fetched again
+every --interval until none are pending.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("⚠ Warning: repositories are on different branches")
+			}
+
+			for {
+				fmt.Printf("Checks for branch %s:\n\n", branch)
+				results := ws.GetPRChecks()
+				failed, pending, hasErrors := printPRChecks(results)
+
+				if !watch || pending == 0 || hasErrors {
+					fmt.Println()
+					switch {
+					case hasErrors:
+						return fmt.Errorf("failed to get checks for some repositories")
+					case failed > 0:
+						return fmt.Errorf("checks failed in %d repositories", failed)
+					case pending > 0:
+						fmt.Printf("Checks pending in %d repositories\n", pending)
+					default:
+						fmt.Println("All checks passed")
+					}
+					return nil
+				}
+
+				fmt.Printf("\nChecks pending in %d repositories; checking again in %s...\n\n", pending, interval)
+				time.Sleep(interval)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&watch, "watch", false, "keep checking until no checks are pending")
+	cmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "how often --watch checks again")
+	return cmd
+}
+
+// printPRChecks prints the check counts of each repo's PR and its failed
+// checks. It returns how many repos have failed and pending checks, and
+// whether any repo's checks couldn't be fetched.
+func printPRChecks(results []workspace.PRResult) (failed, pending int, hasErrors bool) {
+	for _, r := range results {
+		name := r.Repo.Name() + durationNote(r.Duration)
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", name, r.Error)
+			hasErrors = true
+			continue
+		case r.NoPRs:
+			fmt.Printf("  - %s: PRs: n/a\n", name)
+			continue
+		case r.PR == nil:
+			fmt.Printf("  - %s: no PR\n", name)
+			continue
+		case len(r.Checks) == 0:
+			fmt.Printf("  - %s: #%d no checks\n", name, r.PR.Number)
+			continue
+		}
+
+		counts := make(map[string]int)
+		for _, c := range r.Checks {
+			counts[c.Status]++
+		}
+		summary := fmt.Sprintf("#%d %d passed, %d failed, %d pending", r.PR.Number,
+			counts[git.CheckPass], counts[git.CheckFail]+counts[git.CheckCancel], counts[git.CheckPending])
+		if counts[git.CheckSkipping] > 0 {
+			summary += fmt.Sprintf(", %d skipped", counts[git.CheckSkipping])
+		}
+
+		failing := git.FailedChecks(r.Checks)
+		switch {
+		case len(failing) > 0:
+			fmt.Printf("  ✗ %s: %s\n", name, summary)
+			failed++
+		case counts[git.CheckPending] > 0:
+			fmt.Printf("  - %s: %s\n", name, summary)
+			pending++
+		default:
+			fmt.Printf("  ✓ %s: %s\n", name, summary)
+		}
+		for _, c := range failing {
+			fmt.Printf("      ✗ %s\n", checkLabel(c))
+		}
+	}
+	return failed, pending, hasErrors
+}
+
+// checkLabel describes a failed check: its name, whether it is required,
+// and where to see it
+func checkLabel(c git.PRCheck) string {
+	parts := []string{c.Name}
+	if c.Workflow != "" && c.Workflow != c.Name {
+		parts[0] = c.Workflow + " / " + c.Name
+	}
+	if c.Required {
+		parts = append(parts, "(required)")
+	}
+	if c.Link != "" {
+		parts = append(parts, c.Link)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func prLabelsCmd() *cobra.Command {
+	var add []string
+	var remove []string
+
+	cmd := &cobra.Command{
+		Use:   "labels",
+		Short: "Add or remove labels on the pull requests of all repositories",
+		Long: `Add and remove labels on the open pull request for the current branch of
+every repository. Repos without an open PR are skipped, and a PR is only
+edited if it lacks a label to add or has a label to remove.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(add) == 0 && len(remove) == 0 {
+				return fmt.Errorf("nothing to do, use --add or --remove")
+			}
+			for _, l := range add {
+				if slices.Contains(remove, l) {
+					return fmt.Errorf("label %q is both added and removed", l)
+				}
+			}
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("⚠ Warning: repositories are on different branches")
+			}
+
+			fmt.Printf("Editing PR labels for branch %s...\n\n", branch)
+			hasErrors := false
+			for _, r := range ws.EditPRLabels(add, remove) {
+				name := r.Repo.Name() + durationNote(r.Duration)
+				switch {
+				case r.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", name, r.Error)
+					hasErrors = true
+				case r.NoPRs:
+					fmt.Printf("  - %s: PRs: n/a\n", name)
+				case r.Skipped != "":
+					fmt.Printf("  - %s: %s\n", name, r.Skipped)
+				case len(r.Added) == 0 && len(r.Removed) == 0:
+					fmt.Printf("  - %s: #%d %s\n", name, r.PR.Number, unchangedLabels(r, add, remove))
+				default:
+					fmt.Printf("  ✓ %s: #%d %s\n", name, r.PR.Number, changedLabels(r, add, remove))
+				}
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to edit labels for some repositories")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&add, "add", nil, "add this label to the PRs (repeatable)")
+	cmd.Flags().StringSliceVar(&remove, "remove", nil, "remove this label from the PRs (repeatable)")
+	return cmd
+}
+
+// changedLabels describes the labels r's PR gained and lost, followed by
+// those left alone
+func changedLabels(r workspace.PRLabelResult, add, remove []string) string {
+	var parts []string
+	if len(r.Added) > 0 {
+		parts = append(parts, "added "+strings.Join(r.Added, ", "))
+	}
+	if len(r.Removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(r.Removed, ", "))
+	}
+	if unchanged := unchangedLabels(r, add, remove); unchanged != "" {
+		parts = append(parts, unchanged)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// unchangedLabels describes the labels r's PR already had or already
+// lacked
+func unchangedLabels(r workspace.PRLabelResult, add, remove []string) string {
+	var had, lacked []string
+	for _, l := range add {
+		if !slices.Contains(r.Added, l) {
+			had = append(had, l)
+		}
+	}
+	for _, l := range remove {
+		if !slices.Contains(r.Removed, l) {
+			lacked = append(lacked, l)
+		}
+	}
+
+	var parts []string
+	if len(had) > 0 {
+		parts = append(parts, "already has "+strings.Join(had, ", "))
+	}
+	if len(lacked) > 0 {
+		parts = append(parts, "doesn't have "+strings.Join(lacked, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func prListCmd() *cobra.Command {
+	var state string
+	var jsonOutput bool
+	var sortRepos bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the pull requests of all repositories",
+		Long: `List the pull requests of every repository, whatever branch they are
+for, in one table. Only open PRs are listed unless --state is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch state {
+			case "open", "closed", "merged", "all":
+			default:
+				return fmt.Errorf("--state must be open, closed, merged, or all, got %q", state)
+			}
+
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			results := ws.ListPRs(state)
+			if sortRepos {
+				sortByRepo(results, func(r workspace.PRListResult) string { return r.Repo.Name() })
+			}
+			if jsonOutput {
+				return printPRListJSON(results)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "REPO\tPR\tTITLE\tSTATE\tBRANCH\tAUTHOR")
+			hasErrors := false
+			for _, r := range results {
+				switch {
+				case r.Error != nil:
+					fmt.Fprintf(w, "%s\terror: %v\t\t\t\t\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+				case r.NoPRs:
+					fmt.Fprintf(w, "%s\tn/a\t\t\t\t\n", r.Repo.Name())
+				}
+				for _, pr := range r.PRs {
+					author := pr.Author
+					if author == "" {
+						author = "-"
+					}
+					fmt.Fprintf(w, "%s\t#%d\t%s\t%s\t%s\t%s\n", r.Repo.Name(), pr.Number,
+						pr.Title, prState(&pr), pr.Branch, author)
+				}
+			}
+			w.Flush()
+
+			if hasErrors {
+				return fmt.Errorf("failed to list PRs for some repositories")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&state, "state", "open", "which PRs to list: open, closed, merged, or all")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+	cmd.Flags().BoolVar(&sortRepos, "sort", false, "list repos by path instead of config order")
+	return cmd
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func prReviewersCmd() *cobra.Command {
+	var users []string
+	var teams []string
+	var status bool
+
+	cmd := &cobra.Command{
+		Use:   "reviewers",
+		Short: "Request reviews on the pull requests of all repositories",
+		Long: `Request reviews from users (--add) and teams (--add-team org/team) on the
+open pull request for the current branch of every repository. Reviewers
+already requested aren't asked again.
+
+With --status, show instead whether each PR is approved, has changes
+requested, or is still waiting for review, with each reviewer's latest
+review and the reviews still requested.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case status && (len(users) > 0 || len(teams) > 0):
+				return fmt.Errorf("--status can't be combined with --add or --add-team")
+			case !status && len(users) == 0 && len(teams) == 0:
+				return fmt.Errorf("nothing to do, use --add, --add-team, or --status")
+			}
+			for _, t := range teams {
+				if owner, name, ok := strings.Cut(t, "/"); !ok || owner == "" || name == "" {
+					return fmt.Errorf("team %q must be org/team", t)
+				}
+			}
+
+			var ws *workspace.Workspace
+			var err error
+			if status {
+				ws, err = loadWorkspace()
+			} else {
+				ws, err = loadWorkspaceForChange()
+			}
+			if err != nil {
+				return err
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("⚠ Warning: repositories are on different branches")
+			}
+
+			if status {
+				fmt.Printf("Reviews for branch %s:\n\n", branch)
+				return printPRReviews(ws.GetPRReviews())
+			}
+
+			fmt.Printf("Requesting reviews for branch %s...\n\n", branch)
+			hasErrors := false
+			for _, r := range ws.RequestReviewers(users, teams) {
+				name := r.Repo.Name() + durationNote(r.Duration)
+				switch {
+				case r.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", name, r.Error)
+					hasErrors = true
+				case r.NoPRs:
+					fmt.Printf("  - %s: PRs: n/a\n", name)
+				case r.Skipped != "":
+					fmt.Printf("  - %s: %s\n", name, r.Skipped)
+				case len(r.Requested) == 0:
+					fmt.Printf("  - %s: #%d already requested\n", name, r.PR.Number)
+				default:
+					fmt.Printf("  ✓ %s: #%d requested %s\n", name, r.PR.Number, strings.Join(r.Requested, ", "))
+				}
+			}
+
+			if hasErrors {
+				return fmt.Errorf("failed to request reviews for some repositories")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&users, "add", nil, "request a review from this user (repeatable)")
+	cmd.Flags().StringSliceVar(&teams, "add-team", nil, "request a review from this team, as org/team (repeatable)")
+	cmd.Flags().BoolVar(&status, "status", false, "show the review status of each PR instead")
+	return cmd
+}
+
+// printPRReviews prints the review status of each repo's PR and a count of
+// the approved ones
+func printPRReviews(results []workspace.PRReviewResult) error {
+	hasErrors := false
+	approved, total := 0, 0
+	for _, r := range results {
+		name := r.Repo.Name() + durationNote(r.Duration)
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", name, r.Error)
+			hasErrors = true
+			continue
+		case r.NoPRs:
+			fmt.Printf("  - %s: PRs: n/a\n", name)
+			continue
+		case r.Skipped != "":
+			fmt.Printf("  - %s: %s\n", name, r.Skipped)
+			continue
+		}
+
+		total++
+		mark, verdict := "-", "review required"
+		switch r.Reviews.Decision {
+		case "APPROVED":
+			mark, verdict = "✓", "approved"
+			approved++
+		case "CHANGES_REQUESTED":
+			mark, verdict = "✗", "changes requested"
+		case "":
+			verdict = "no review required"
+			if len(r.Reviews.Reviews) > 0 {
+				verdict = "reviewed"
+			}
+		}
+		line := fmt.Sprintf("  %s %s: #%d %s", mark, name, r.PR.Number, verdict)
+		if details := reviewDetails(r.Reviews); details != "" {
+			line += " (" + details + ")"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println()
+	if hasErrors {
+		return fmt.Errorf("failed to get reviews for some repositories")
+	}
+	fmt.Printf("Approved in %d of %d repositories\n", approved, total)
+	return nil
+}
+
+// reviewDetails lists each reviewer's latest review and the reviews still
+// requested
+func reviewDetails(reviews *git.PRReviews) string {
+	var parts []string
+	for _, r := range reviews.Reviews {
+		state := strings.ToLower(strings.ReplaceAll(r.State, "_", " "))
+		parts = append(parts, r.Author+" "+state)
+	}
+	if len(reviews.Requested) > 0 {
+		parts = append(parts, "waiting on "+strings.Join(reviews.Requested, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
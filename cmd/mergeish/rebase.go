@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func rebaseCmd() *cobra.Command {
+	var abort bool
+	var cont bool
+
+	cmd := &cobra.Command{
+		Use:   "rebase <onto>",
+		Short: "Rebase the current branch of all repositories",
+		Long: `Rebase the current branch of every repository onto a branch, or any ref
+such as origin/main.
+
+Each repo is reported as rebased, already up to date, or stopped on a
+conflict. A repo that stops is left mid-rebase, with the conflicted files
+listed. Resolve them and 'git add' the files, then run mergeish rebase
+--continue to continue every repo that is still mid-rebase, or
+mergeish rebase --abort to back out of the rebase in all of them.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if abort || cont {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if abort && cont {
+				return fmt.Errorf("--abort and --continue can't be used together")
+			}
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			if abort {
+				fmt.Println("Aborting rebases...")
+				return printStoppableResults(ws.RebaseAbort(), "rebase", "abort the rebase in")
+			}
+			if cont {
+				fmt.Println("Continuing rebases...")
+				return printStoppableResults(ws.RebaseContinue(), "rebase", "continue the rebase in")
+			}
+
+			branch, consistent, err := ws.CheckBranchConsistency()
+			if err != nil {
+				return err
+			}
+			if !consistent {
+				fmt.Println("Warning: repositories are on different branches")
+			}
+
+			fmt.Printf("Rebasing %s onto %s...\n", branch, args[0])
+			return printStoppableResults(ws.Rebase(args[0]), "rebase", "rebase")
+		},
+	}
+
+	cmd.Flags().BoolVar(&abort, "abort", false, "abort the rebase in progress in every repo")
+	cmd.Flags().BoolVar(&cont, "continue", false, "continue the rebase in progress in every repo after resolving conflicts")
+	return cmd
+}
+
+// printStoppableResults prints the results of a command that can stop on
+// conflicts, such as rebase, or of its --continue or --abort, with the
+// conflicts left to resolve. what completes "failed to ... some
+// repositories".
+func printStoppableResults(results []workspace.Result, command, what string) error {
+	hasErrors := false
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s%s: %v\n", r.Repo.Name(), durationNote(r.Duration), r.Error)
+			hasErrors = true
+		case r.Skipped != "":
+			fmt.Printf("  - %s%s (%s)\n", r.Repo.Name(), durationNote(r.Duration), r.Skipped)
+		default:
+			fmt.Printf("  ✓ %s%s\n", r.Repo.Name(), durationNote(r.Duration))
+		}
+	}
+	if reportConflicts(results) {
+		fmt.Printf("  Or, once they are added, run 'mergeish %s --continue' to continue every repo at once.\n", command)
+		fmt.Printf("  Run 'mergeish %s --abort' to back out of the %s everywhere.\n", command, command)
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to %s some repositories", what)
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
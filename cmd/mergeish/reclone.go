@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/metrics"
+)
+
+func recloneCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reclone <repo>",
+		Short: "Move a damaged clone aside and clone it fresh",
+		Long: `Move a repository's clone aside and clone the repository again, for a
+clone that git reports as damaged (corrupt objects or index, or a .git that
+git doesn't recognize).
+
+The old clone is kept next to the new one as <path>.broken-<timestamp>, so
+uncommitted changes or unpushed commits can be copied out of it. Delete it
+once you're done with it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := openWorkspace(true)
+			if err != nil {
+				return err
+			}
+			if ws, err = ws.FilterByName(args[0]); err != nil {
+				return err
+			}
+			if len(ws.Repos) == 0 {
+				return fmt.Errorf("%s is not among the selected repos", args[0])
+			}
+			r := ws.Repos[0]
+
+			if !r.Exists() {
+				return fmt.Errorf("%s isn't cloned; run mergeish clone", r.Name())
+			}
+			aside := fmt.Sprintf("%s.broken-%s", r.FullPath, time.Now().Format("20060102-150405"))
+			if !confirmDestructive(ws, fmt.Sprintf("Move %s to %s and clone it again?", r.FullPath, aside)) {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			if err := r.Reclone(aside); err != nil {
+				if _, statErr := os.Stat(aside); statErr == nil {
+					fmt.Printf("The old clone is in %s\n", aside)
+				}
+				return err
+			}
+			fmt.Printf("  ✓ %s\n", r.Name())
+			fmt.Printf("The old clone is in %s; delete it once nothing in it is needed\n", aside)
+			return nil
+		},
+	}
+}
+
+// printCorruptRepos warns once about each repo whose clone turned out to be
+// damaged, with how to repair it
+func printCorruptRepos(snap metrics.Snapshot) {
+	seen := make(map[string]bool)
+	for _, op := range snap.Operations {
+		for _, t := range op.Repos {
+			var corrupt *git.CorruptError
+			if !errors.As(t.Err, &corrupt) || seen[t.Repo] {
+				continue
+			}
+			seen[t.Repo] = true
+
+			fmt.Fprintf(os.Stderr, "⚠ Warning: %s is a damaged clone (%s); mergeish stopped running git in it\n", t.Repo, corrupt.Problem)
+			if corrupt.Fix != "" {
+				fmt.Fprintf(os.Stderr, "    To repair it, %s\n", corrupt.Fix)
+			}
+			fmt.Fprintf(os.Stderr, "    Or move it aside and clone it fresh: mergeish reclone %s\n", t.Repo)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/replace"
+	"github.com/willnewby/mergeish/internal/repo"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func replaceCmd() *cobra.Command {
+	var pattern string
+	var replacement string
+	var regex bool
+	var paths []string
+	var maxSize int64
+	var branch string
+	var message string
+	var createPR bool
+
+	cmd := &cobra.Command{
+		Use:   "replace",
+		Short: "Search and replace across all repositories",
+		Long: `Search and replace text in tracked files across all repositories.
+
+A preview of every edit is shown, grouped by repo, and changes are only
+written after confirmation (or --yes). Edited files are staged.
+
+Binary files and files larger than --max-size are skipped.
+
+With --branch, the branch is checked out in each repo with matches before
+editing. If the edits found on the branch differ from the preview, they
+are shown and confirmed again. With --commit, the edits are committed, and
+with --pr they are pushed and a PR is opened using the commit message as
+the title.
+
+Examples:
+  mergeish replace --pattern OldClientV1 --replacement ClientV2
+  mergeish replace --pattern 'v(\d+)\.Client' --replacement 'v${1}.NewClient' --regex --path '**/*.go'
+  mergeish replace --pattern Old --replacement New --branch rename-old --commit "Rename Old to New" --pr`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pattern == "" {
+				return fmt.Errorf("pattern required (--pattern)")
+			}
+			if createPR && (message == "" || branch == "") {
+				return fmt.Errorf("--pr requires --branch and --commit")
+			}
+
+			rep, err := replace.New(pattern, replacement, regex)
+			if err != nil {
+				return err
+			}
+			rep.MaxFileSize = maxSize
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			results := ws.FindReplacements(rep, paths...)
+			matched, files, lines, err := printReplacements(results)
+			if err != nil {
+				return err
+			}
+
+			if len(matched) == 0 {
+				fmt.Println("No matches")
+				return nil
+			}
+
+			prompt := fmt.Sprintf("Apply %d change(s) in %d file(s) across %d repos?", lines, files, len(matched))
+			if !confirm(prompt) {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			sub := ws.Filter(func(r *repo.Repo) bool { return matched[r] })
+			return rolloutReplacements(sub, results, rep, paths, branch, message, createPR)
+		},
+	}
+
+	cmd.Flags().StringVar(&pattern, "pattern", "", "text or regular expression to search for")
+	cmd.Flags().StringVar(&replacement, "replacement", "", "replacement text (may reference regex groups as $1)")
+	cmd.Flags().BoolVar(&regex, "regex", false, "treat the pattern as a Go regular expression")
+	cmd.Flags().StringSliceVar(&paths, "path", nil, "only edit files matching this glob (repeatable)")
+	cmd.Flags().Int64Var(&maxSize, "max-size", replace.DefaultMaxFileSize, "skip files larger than this many bytes")
+	cmd.Flags().StringVar(&branch, "branch", "", "check out this branch before editing")
+	cmd.Flags().StringVar(&message, "commit", "", "commit the edits with this message")
+	cmd.Flags().BoolVar(&createPR, "pr", false, "push and open a PR (requires --branch and --commit)")
+
+	return cmd
+}
+
+// rolloutReplacements applies the edits in the repos of ws, optionally on a
+// new branch, then commits, pushes, and opens PRs as requested. With a
+// branch, the edits are found again once it is checked out, since results
+// were computed against the files of the branch each repo was on; if they
+// differ from the confirmed ones, they are shown and confirmed again.
+func rolloutReplacements(ws *workspace.Workspace, results []workspace.ReplaceResult, rep *replace.Replacer, paths []string, branch, message string, createPR bool) error {
+	if branch != "" {
+		fmt.Printf("Switching to branch %s...\n", branch)
+		if err := printResults(ws.Checkout(branch), "failed to switch branch on some repositories"); err != nil {
+			return err
+		}
+
+		found := ws.FindReplacements(rep, paths...)
+		if !sameEdits(results, found) {
+			fmt.Printf("The edits on %s differ from the ones confirmed:\n\n", branch)
+			matched, files, lines, err := printReplacements(found)
+			if err != nil {
+				return err
+			}
+			if len(matched) == 0 {
+				fmt.Printf("No matches on %s\n", branch)
+				return nil
+			}
+			prompt := fmt.Sprintf("Apply %d change(s) in %d file(s) across %d repos?", lines, files, len(matched))
+			if !confirm(prompt) {
+				fmt.Printf("Aborted, repositories are left on %s\n", branch)
+				return nil
+			}
+		}
+		results = found
+	}
+
+	fmt.Println("Applying changes...")
+	if err := printResults(workspace.ApplyReplacements(results), "failed to apply changes to some repositories"); err != nil {
+		return err
+	}
+
+	if message == "" {
+		return nil
+	}
+
+	fmt.Println("Committing changes...")
+	committed, hookErr := ws.Commit(message, false)
+	if err := printResults(committed, "some repositories failed to commit"); err != nil {
+		return err
+	}
+	if hookErr != nil {
+		return hookErr
+	}
+
+	if !createPR {
+		return nil
+	}
+
+	fmt.Printf("Pushing %s...\n", branch)
+	if err := printResults(ws.PushSetUpstream(), "some repositories failed to push"); err != nil {
+		return err
+	}
+
+	fmt.Println("Creating PRs...")
+	hasErrors := false
+	prs, err := ws.CreatePRs(message, func(*repo.Repo) string { return "" }, workspace.PRCreateOptions{Meta: ws.PRMetadata(nil, nil, nil)})
+	var mixed *workspace.MixedBaseError
+	if errors.As(err, &mixed) {
+		fmt.Println("Repositories target different base branches:")
+		printBaseGroups(mixed.Groups)
+		return fmt.Errorf("mixed PR bases, can't open PRs")
+	}
+	if err != nil {
+		return err
+	}
+	for _, r := range prs {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		} else if r.Skipped != "" {
+			fmt.Printf("  - %s: %s\n", r.Repo.Name(), r.Skipped)
+		} else if r.PR != nil {
+			fmt.Printf("  ✓ %s: %s\n", r.Repo.Name(), r.PR.URL)
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to create PRs for some repositories")
+	}
+
+	return nil
+}
+
+// printReplacements prints the edits of results grouped by repo and returns
+// the repos with edits and the number of files and lines changed. Repos the
+// search failed in are printed, and make it return an error.
+func printReplacements(results []workspace.ReplaceResult) (map[*repo.Repo]bool, int, int, error) {
+	hasErrors := false
+	files, lines := 0, 0
+	matched := make(map[*repo.Repo]bool)
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+			continue
+		}
+		if len(r.Edits) == 0 {
+			continue
+		}
+
+		matched[r.Repo] = true
+		fmt.Printf("── %s ──\n", r.Repo.Name())
+		for _, e := range r.Edits {
+			fmt.Println(colorize(colorBold, e.Path))
+			for _, c := range e.Changes {
+				fmt.Printf("  %d: %s\n", c.Line, colorize(colorRed, "- "+c.Old))
+				fmt.Printf("  %d: %s\n", c.Line, colorize(colorGreen, "+ "+c.New))
+				lines++
+			}
+			files++
+		}
+		fmt.Println()
+	}
+
+	if hasErrors {
+		return nil, 0, 0, fmt.Errorf("search failed on some repositories")
+	}
+	return matched, files, lines, nil
+}
+
+// sameEdits reports whether found changes the same lines of the same files
+// in each repo as confirmed. Repos found has no edits for may be missing
+// from confirmed.
+func sameEdits(confirmed, found []workspace.ReplaceResult) bool {
+	edits := make(map[*repo.Repo][]*replace.FileEdit)
+	for _, r := range confirmed {
+		edits[r.Repo] = r.Edits
+	}
+	for _, r := range found {
+		if r.Error != nil || len(edits[r.Repo]) != len(r.Edits) {
+			return false
+		}
+		for i, e := range r.Edits {
+			want := edits[r.Repo][i]
+			if want.Path != e.Path || !slices.Equal(want.Changes, e.Changes) {
+				return false
+			}
+		}
+	}
+	return true
+}
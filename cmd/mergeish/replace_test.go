@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// replaceBranchWorkspace commits a.txt with content to api in a scope
+// workspace, and creates branch rename with branchContent for it
+func replaceBranchWorkspace(t *testing.T, content, branchContent string) string {
+	t.Helper()
+	root := scopeWorkspace(t)
+	api := filepath.Join(root, "api")
+	write := func(s string) {
+		if err := os.WriteFile(filepath.Join(api, "a.txt"), []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(content)
+	gitIn(t, api, "add", "a.txt")
+	gitIn(t, api, "commit", "-q", "-m", "add a")
+	gitIn(t, api, "checkout", "-q", "-b", "rename")
+	write(branchContent)
+	gitIn(t, api, "commit", "-q", "--allow-empty", "-am", "change a")
+	gitIn(t, api, "checkout", "-q", "feat")
+	return api
+}
+
+// answer feeds lines to the prompts of the test, with --yes off
+func answer(t *testing.T, lines ...string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin, assumeYes = r, false
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+func TestReplaceBranchConfirmsChangedEdits(t *testing.T) {
+	api := replaceBranchWorkspace(t, "old\n", "old\nold\n")
+	answer(t, "y", "n")
+
+	out, err := runCommand(t, replaceCmd, "--pattern", "old", "--replacement", "new", "--branch", "rename")
+	if err != nil {
+		t.Fatalf("replace: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "differ from the ones confirmed") || !strings.Contains(out, "2: - old") {
+		t.Errorf("recomputed edits not shown:\n%s", out)
+	}
+	if !strings.Contains(out, "Aborted, repositories are left on rename") {
+		t.Errorf("declining didn't abort:\n%s", out)
+	}
+	if branch := gitIn(t, api, "branch", "--show-current"); branch != "rename" {
+		t.Errorf("branch = %q, want rename", branch)
+	}
+	data, err := os.ReadFile(filepath.Join(api, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old\nold\n" {
+		t.Errorf("a.txt = %q, edits applied without confirmation", data)
+	}
+}
+
+func TestReplaceBranchSameEdits(t *testing.T) {
+	api := replaceBranchWorkspace(t, "old\n", "old\n")
+	// A second prompt would read EOF and abort
+	answer(t, "y")
+
+	out, err := runCommand(t, replaceCmd, "--pattern", "old", "--replacement", "new", "--branch", "rename")
+	if err != nil {
+		t.Fatalf("replace: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "differ") || strings.Contains(out, "Aborted") {
+		t.Errorf("unchanged edits confirmed again:\n%s", out)
+	}
+	data, err := os.ReadFile(filepath.Join(api, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new\n" {
+		t.Errorf("a.txt = %q, want %q", data, "new\n")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+// startCheckpoint starts recording the progress of command and returns the
+// workspace of repos to run it in. With resume, the repos finished by its
+// last unfinished run are left out, unless that run is stale.
+func startCheckpoint(ws *workspace.Workspace, command string, resume bool) (*workspace.Workspace, error) {
+	var prev *workspace.Checkpoint
+	if resume {
+		cp, err := ws.LoadCheckpoint(command)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case cp == nil:
+			fmt.Printf("No unfinished %s to resume; running on every repository\n", command)
+		case cp.Stale():
+			fmt.Printf("The unfinished %s made no progress in the last 24 hours; running on every repository\n", command)
+		default:
+			prev = cp
+		}
+	}
+
+	run, err := ws.StartCheckpoint(command, prev)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		fmt.Printf("Resuming the %s started %s: skipping %d repositories already done\n",
+			command, prev.Started.Format("2006-01-02 15:04"), len(ws.Repos)-len(run.Repos))
+	}
+	return run, nil
+}
+
+// finishCheckpoint ends the run started by startCheckpoint. After a resumed
+// run it prints the totals of every run since the first, given how many
+// repos succeeded in this one.
+func finishCheckpoint(ws, run *workspace.Workspace, succeeded int, failed bool) {
+	if err := run.FinishCheckpoint(failed); err != nil {
+		fmt.Printf("⚠ Warning: saving progress for --resume: %v\n", err)
+	}
+
+	skipped := len(ws.Repos) - len(run.Repos)
+	if skipped > 0 {
+		fmt.Printf("%d of %d repositories done: %d in this run, %d in earlier runs\n",
+			skipped+succeeded, len(ws.Repos), succeeded, skipped)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// scopeWorkspace creates a workspace config with repos api and web on
+// branch feat with a file to commit, and legacy on main, each cloned from
+// its own bare remote. It points the command flags at it and resets them
+// when the test ends.
+func scopeWorkspace(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	gitconfig := filepath.Join(home, ".gitconfig")
+	if err := os.WriteFile(gitconfig, []byte("[user]\n\tname = Test\n\temail = test@example.com\n[commit]\n\tgpgsign = false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gitconfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	root := t.TempDir()
+	remotes := t.TempDir()
+	var yml strings.Builder
+	yml.WriteString("repos:\n")
+	for _, name := range []string{"api", "web", "legacy"} {
+		remote := filepath.Join(remotes, name+".git")
+		dir := filepath.Join(root, name)
+		gitIn(t, root, "init", "-q", "--bare", "-b", "main", remote)
+		gitIn(t, root, "clone", "-q", remote, dir)
+		gitIn(t, dir, "checkout", "-q", "-b", "main")
+		gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "initial")
+		gitIn(t, dir, "push", "-q", "-u", "origin", "main")
+		gitIn(t, dir, "remote", "set-head", "origin", "main")
+		if name != "legacy" {
+			gitIn(t, dir, "checkout", "-q", "-b", "feat")
+			if err := os.WriteFile(filepath.Join(dir, "change.txt"), []byte(name+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fmt.Fprintf(&yml, "  - url: %s\n    path: %s\n    provider: github\n", remote, name)
+	}
+	path := filepath.Join(root, "mergeish.yml")
+	if err := os.WriteFile(path, []byte(yml.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath, assumeYes, noFetch = path, true, true
+	t.Cleanup(func() {
+		configPath, assumeYes, noFetch = "", false, false
+		repoNames, includeRepos, excludeRepos = nil, nil, nil
+	})
+	return root
+}
+
+// gitIn runs git in dir, failing the test on error, and returns its
+// trimmed output
+func gitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// runCommand runs a mergeish subcommand with args and returns its output
+// and error
+func runCommand(t *testing.T, newCmd func() *cobra.Command, args ...string) (string, error) {
+	t.Helper()
+	cmd := newCmd()
+	cmd.SetArgs(args)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	var err error
+	out := captureStdout(t, func() error {
+		err = cmd.Execute()
+		return nil
+	})
+	return string(out), err
+}
+
+const scopeLine = "On feat in the 2 selected repos; not checked: 1 other repos (1 on main)"
+
+func TestCommitScope(t *testing.T) {
+	root := scopeWorkspace(t)
+
+	out, err := runCommand(t, commitCmd, "-a", "-m", "change")
+	if err == nil || !strings.Contains(err.Error(), "different branches") {
+		t.Fatalf("commit across all repos = %v, want a different branches error\n%s", err, out)
+	}
+
+	excludeRepos = []string{"legacy"}
+	out, err = runCommand(t, commitCmd, "-a", "-m", "change")
+	if err != nil {
+		t.Fatalf("commit = %v\n%s", err, out)
+	}
+	if !strings.Contains(out, scopeLine) {
+		t.Errorf("commit output lacks %q:\n%s", scopeLine, out)
+	}
+	for _, name := range []string{"api", "web"} {
+		if got := gitIn(t, filepath.Join(root, name), "log", "-1", "--format=%s"); got != "change" {
+			t.Errorf("%s: last commit %q, want change", name, got)
+		}
+	}
+	if got := gitIn(t, filepath.Join(root, "legacy"), "log", "-1", "--format=%s"); got != "initial" {
+		t.Errorf("legacy got a commit: %q", got)
+	}
+}
+
+func TestCommitScopeOnlyWhenSelecting(t *testing.T) {
+	root := scopeWorkspace(t)
+	gitIn(t, filepath.Join(root, "legacy"), "checkout", "-q", "-b", "feat")
+
+	out, err := runCommand(t, commitCmd, "-a", "-m", "change")
+	if err != nil {
+		t.Fatalf("commit = %v\n%s", err, out)
+	}
+	if strings.Contains(out, "selected repos") {
+		t.Errorf("commit without a selection reported a scope:\n%s", out)
+	}
+}
+
+func TestPushScope(t *testing.T) {
+	root := scopeWorkspace(t)
+	for _, name := range []string{"api", "web"} {
+		gitIn(t, filepath.Join(root, name), "commit", "-q", "--allow-empty", "-m", "change")
+	}
+	gitIn(t, filepath.Join(root, "legacy"), "commit", "-q", "--allow-empty", "-m", "not selected")
+	for _, name := range []string{"api", "web"} {
+		os.Remove(filepath.Join(root, name, "change.txt"))
+	}
+
+	excludeRepos = []string{"legacy"}
+	out, err := runCommand(t, pushCmd)
+	if err != nil {
+		t.Fatalf("push = %v\n%s", err, out)
+	}
+	if !strings.Contains(out, scopeLine) {
+		t.Errorf("push output lacks %q:\n%s", scopeLine, out)
+	}
+	for _, name := range []string{"api", "web"} {
+		gitIn(t, filepath.Join(root, name), "rev-parse", "--verify", "origin/feat")
+	}
+	legacy := filepath.Join(root, "legacy")
+	if gitIn(t, legacy, "rev-parse", "HEAD") == gitIn(t, legacy, "rev-parse", "origin/main") {
+		t.Error("legacy was pushed")
+	}
+}
+
+func TestPRCreateScope(t *testing.T) {
+	root := scopeWorkspace(t)
+	for _, name := range []string{"api", "web"} {
+		gitIn(t, filepath.Join(root, name), "commit", "-q", "--allow-empty", "-m", "change")
+	}
+
+	// A gh that records the repos it creates PRs in
+	bin := t.TempDir()
+	log := filepath.Join(bin, "log")
+	script := `#!/bin/sh
+case "$1 $2" in
+"pr list") echo '[]';;
+"pr create") basename "$PWD" >> ` + log + `; echo https://example.com/1;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	includeRepos = []string{"api", "web"}
+	out, _ := runCommand(t, prCreateCmd, "-t", "Change", "--link=false")
+	if !strings.Contains(out, scopeLine) {
+		t.Errorf("pr create output lacks %q:\n%s", scopeLine, out)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("gh pr create never ran: %v\n%s", err, out)
+	}
+	if got := strings.Fields(string(data)); strings.Join(got, ",") != "api,web" && strings.Join(got, ",") != "web,api" {
+		t.Errorf("PRs created in %v, want api and web", got)
+	}
+}
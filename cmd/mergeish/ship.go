@@ -0,0 +1,379 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/repo"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+// shipStages are the stages of mergeish ship, in order. Each saves its
+// progress under its own checkpoint, "ship <stage>".
+var shipStages = []string{"branch", "commit", "push", "pr"}
+
+// shipRepo is what ship did in a single repo, for the summary
+type shipRepo struct {
+	files  int // changed files to commit
+	pushed bool
+	pr     workspace.PRResult
+}
+
+func shipCmd() *cobra.Command {
+	var branch string
+	var message string
+	var title string
+	var body string
+	var base string
+	var infer bool
+	var draft bool
+	var resume bool
+
+	cmd := &cobra.Command{
+		Use:   "ship",
+		Short: "Branch, commit, push, and open PRs in one go",
+		Long: `Create a branch, commit every change, push it, and open pull requests,
+in every repository with changes.
+
+A plan is shown first and confirmed (or --yes). Each stage finishes in
+every repo before the next starts, so a failure stops the run with
+nothing half done in the later stages. Repos without changes that aren't
+already on the branch are left out.
+
+Progress is saved as each repo finishes a stage. If a stage fails or the
+run is interrupted, fix the problem and run it again with --resume and
+the same flags: finished stages are skipped, and the failed stage runs
+only in the repos that didn't finish it.
+
+Example:
+  mergeish ship --branch feature-x -m "Add feature X" -t "Feature X" --infer`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case branch == "":
+				return fmt.Errorf("branch required (--branch)")
+			case message == "":
+				return fmt.Errorf("commit message required (-m)")
+			case title == "":
+				return fmt.Errorf("PR title required (-t)")
+			}
+
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+			for _, b := range ws.Config.Settings.ProtectedBranches {
+				if b == branch {
+					return fmt.Errorf("%s is a protected branch", branch)
+				}
+			}
+
+			start, prev, err := shipResumePoint(ws, resume)
+			if err != nil {
+				return err
+			}
+
+			// Plan: which repos take part, and what each has to commit
+			plan := make(map[*repo.Repo]*shipRepo)
+			fmt.Printf("Ship plan for branch %s:\n", branch)
+			hasErrors := false
+			for _, s := range ws.Status() {
+				name := s.Repo.Name()
+				switch {
+				case s.Error != nil:
+					fmt.Printf("  ✗ %s: %v\n", name, s.Error)
+					hasErrors = true
+				case s.Status.State != "":
+					fmt.Printf("  ✗ %s: in the middle of a %s\n", name, s.Status.State)
+					hasErrors = true
+				case len(s.Status.Files) == 0 && s.Status.Branch != branch:
+					fmt.Printf("  - %s: no changes, left out\n", name)
+				default:
+					plan[s.Repo] = &shipRepo{files: len(s.Status.Files)}
+					fmt.Printf("  %s: %s\n", name, describeShipPlan(ws, s, branch, base))
+				}
+			}
+			if hasErrors {
+				return fmt.Errorf("some repositories can't be shipped")
+			}
+			if len(plan) == 0 {
+				fmt.Println("Nothing to ship")
+				return nil
+			}
+			sel := ws.Filter(func(r *repo.Repo) bool { return plan[r] != nil })
+
+			if groups, consistent := sel.CheckBaseConsistency(base); !consistent {
+				fmt.Println("\nRepositories target different base branches:")
+				printBaseGroups(groups)
+				return fmt.Errorf("mixed PR bases, use --base")
+			}
+
+			fmt.Printf("\nCommit message: %s\nPR title: %s\n", message, title)
+			if start > 0 {
+				fmt.Printf("Resuming at the %s stage\n", shipStages[start])
+			}
+			if !confirm(fmt.Sprintf("Ship %d repos?", len(sel.Repos))) {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			// Start a checkpoint for every stage left, so a later --resume
+			// knows which ones finished
+			runs := make([]*workspace.Workspace, len(shipStages))
+			for i := start; i < len(shipStages); i++ {
+				if runs[i], err = sel.StartCheckpoint("ship "+shipStages[i], prev[shipStages[i]]); err != nil {
+					return err
+				}
+			}
+
+			for i := start; i < len(shipStages); i++ {
+				stage, run := shipStages[i], runs[i]
+				fmt.Printf("\n[%d/%d] %s\n", i+1, len(shipStages), shipStageTitle(stage, branch))
+
+				var failed []workspace.Result
+				var stageErr error
+				switch stage {
+				case "branch":
+					failed = failedResults(run.Checkout(branch))
+				case "commit":
+					var results []workspace.Result
+					results, stageErr = run.Commit(message, true)
+					failed = failedResults(results)
+				case "push":
+					failed, stageErr = shipPush(sel, run, plan)
+				case "pr":
+					failed, stageErr = shipPRs(sel, run, plan, branch, title, body, base, infer, draft)
+				}
+				if stageErr != nil {
+					return stageErr
+				}
+
+				for _, r := range failed {
+					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+				}
+				if err := run.FinishCheckpoint(len(failed) > 0); err != nil {
+					fmt.Printf("⚠ Warning: saving progress for --resume: %v\n", err)
+				}
+				if len(failed) > 0 {
+					return fmt.Errorf("ship stopped at the %s stage; fix the problems and run it again with --resume", stage)
+				}
+
+				done := fmt.Sprintf("  ✓ %d repos", len(run.Repos))
+				if earlier := len(sel.Repos) - len(run.Repos); earlier > 0 {
+					done += fmt.Sprintf(" (%d done in an earlier run)", earlier)
+				}
+				fmt.Println(done)
+			}
+
+			if err := linkShipPRs(sel, plan); err != nil {
+				return err
+			}
+
+			fmt.Printf("\nShipped %s:\n", branch)
+			printShipSummary(sel, plan, start)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&branch, "branch", "", "branch to create or switch to (required)")
+	cmd.Flags().StringVarP(&message, "message", "m", "", "commit message (required)")
+	cmd.Flags().StringVarP(&title, "title", "t", "", "PR title (required)")
+	cmd.Flags().StringVarP(&body, "body", "b", "", "PR body/description")
+	cmd.Flags().StringVar(&base, "base", "", "base branch (default: repo default)")
+	cmd.Flags().BoolVar(&infer, "infer", false, "infer PR body from commit messages")
+	cmd.Flags().BoolVar(&draft, "draft", false, "create the PRs as drafts")
+	cmd.Flags().BoolVar(&resume, "resume", false, "skip the stages and repos the last unfinished ship completed")
+	return cmd
+}
+
+// shipResumePoint returns the index of the first stage to run and the
+// checkpoints to resume it and the later stages from. Without resume, or
+// without an unfinished ship to resume, every stage runs.
+func shipResumePoint(ws *workspace.Workspace, resume bool) (int, map[string]*workspace.Checkpoint, error) {
+	prev := make(map[string]*workspace.Checkpoint)
+	if !resume {
+		return 0, prev, nil
+	}
+
+	start := -1
+	for i, stage := range shipStages {
+		cp, err := ws.LoadCheckpoint("ship " + stage)
+		if err != nil {
+			return 0, nil, err
+		}
+		if cp == nil {
+			continue
+		}
+		if start < 0 {
+			// Only the stage that stopped made progress lately
+			if cp.Stale() {
+				fmt.Println("The unfinished ship made no progress in the last 24 hours; running every stage")
+				return 0, make(map[string]*workspace.Checkpoint), nil
+			}
+			start = i
+		}
+		prev[stage] = cp
+	}
+	if start < 0 {
+		fmt.Println("No unfinished ship to resume; running every stage")
+		return 0, prev, nil
+	}
+	return start, prev, nil
+}
+
+// describeShipPlan says what ship will do in the repo of s
+func describeShipPlan(ws *workspace.Workspace, s workspace.StatusResult, branch, base string) string {
+	var parts []string
+	switch {
+	case s.Status.Branch == branch:
+		parts = append(parts, "on "+branch)
+	case s.Repo.BranchExists(branch):
+		parts = append(parts, "switch to "+branch)
+	default:
+		parts = append(parts, "new branch from "+s.Status.Branch)
+	}
+	if n := len(s.Status.Files); n > 0 {
+		parts = append(parts, fmt.Sprintf("commit %d file(s)", n))
+	} else {
+		parts = append(parts, "nothing to commit")
+	}
+	if !s.Repo.Config.HasPRs() {
+		parts = append(parts, "push only (PRs: n/a)")
+	} else if prBase := ws.ResolveBase(s.Repo, base); prBase != "" {
+		parts = append(parts, "PR into "+prBase)
+	} else {
+		parts = append(parts, "PR into the default branch")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// shipStageTitle describes a stage of ship
+func shipStageTitle(stage, branch string) string {
+	switch stage {
+	case "branch":
+		return "Switching to branch " + branch
+	case "commit":
+		return "Committing all changes"
+	case "push":
+		return "Pushing " + branch
+	default:
+		return "Creating PRs"
+	}
+}
+
+// failedResults returns the results with an error
+func failedResults(results []workspace.Result) []workspace.Result {
+	var failed []workspace.Result
+	for _, r := range results {
+		if r.Error != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// shipPush pushes the repos of run. The pre-flight checks run on the repos
+// of sel instead, so that they don't count towards run's checkpoint.
+func shipPush(sel, run *workspace.Workspace, plan map[*repo.Repo]*shipRepo) ([]workspace.Result, error) {
+	inRun := make(map[*repo.Repo]bool)
+	for _, r := range run.Repos {
+		inRun[r] = true
+	}
+	check := sel.Filter(func(r *repo.Repo) bool { return inRun[r] })
+	var failed []workspace.Result
+	for _, c := range check.CheckPush(workspace.PushOptions{}) {
+		err := c.Error
+		if err == nil {
+			err = errors.New(strings.Join(c.Problems, ", "))
+		}
+		failed = append(failed, workspace.Result{Repo: c.Repo, Error: err})
+	}
+	if len(failed) > 0 {
+		return failed, nil
+	}
+
+	results, err := run.Push(workspace.PushOptions{NoVerify: true, AllowProtected: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.Error == nil {
+			plan[r.Repo].pushed = true
+		}
+	}
+	return failedResults(results), nil
+}
+
+// shipPRs creates the PRs of the repos of run and links them to each other
+func shipPRs(sel, run *workspace.Workspace, plan map[*repo.Repo]*shipRepo, branch, title, body, base string, infer, draft bool) ([]workspace.Result, error) {
+	info, err := sel.BranchInfo(branch)
+	if err != nil {
+		return nil, err
+	}
+	prTitle := sel.PRTitle(info, branch, title)
+	bodies, err := prBodies(sel, info, body, base, infer)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := run.CreatePRs(prTitle, bodies, workspace.PRCreateOptions{
+		Base:  base,
+		Draft: draft,
+		Meta:  sel.PRMetadata(nil, nil, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var failed []workspace.Result
+	for _, r := range results {
+		plan[r.Repo].pr = r
+		if r.Error != nil {
+			failed = append(failed, workspace.Result{Repo: r.Repo, Error: r.Error})
+		}
+	}
+	return failed, nil
+}
+
+// linkShipPRs links the PRs of every repo in sel to each other, including
+// those created by an earlier run, and records them for the summary
+func linkShipPRs(sel *workspace.Workspace, plan map[*repo.Repo]*shipRepo) error {
+	prs := sel.GetPRs()
+	for _, r := range prs {
+		if p := plan[r.Repo]; p.pr.PR == nil && !p.pr.NoPRs && p.pr.Skipped == "" {
+			p.pr = r
+		}
+	}
+	return linkRelatedPRs(sel, prs)
+}
+
+// printShipSummary prints a line per repo with what ship did there. Stages
+// before start ran in an earlier run and aren't described.
+func printShipSummary(sel *workspace.Workspace, plan map[*repo.Repo]*shipRepo, start int) {
+	for _, r := range sel.Repos {
+		p := plan[r]
+		var parts []string
+		if start <= 1 {
+			if p.files > 0 {
+				parts = append(parts, fmt.Sprintf("committed %d file(s)", p.files))
+			} else {
+				parts = append(parts, "nothing to commit")
+			}
+		}
+		if p.pushed {
+			parts = append(parts, "pushed")
+		}
+		switch {
+		case p.pr.NoPRs:
+			parts = append(parts, "PRs: n/a")
+		case p.pr.Skipped != "":
+			parts = append(parts, p.pr.Skipped)
+		case p.pr.PR != nil && p.pr.Existed:
+			parts = append(parts, "PR already exists "+p.pr.PR.URL)
+		case p.pr.PR != nil:
+			parts = append(parts, p.pr.PR.URL)
+		}
+		fmt.Printf("  ✓ %s: %s\n", r.Name(), strings.Join(parts, ", "))
+	}
+}
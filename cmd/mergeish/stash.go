@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func stashCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stash",
+		Short: "Stash changes across all repositories",
+		Long: `Stash and restore uncommitted changes across all repositories.
+
+Useful before switching branches when some repos have local changes.`,
+	}
+
+	cmd.AddCommand(stashPushCmd())
+	cmd.AddCommand(stashPopCmd())
+	cmd.AddCommand(stashListCmd())
+
+	return cmd
+}
+
+func stashPushCmd() *cobra.Command {
+	var message string
+
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Stash changes in all repositories",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Stashing changes...")
+			return printStashResults(ws.Stash(message), "no changes", "failed to stash some repositories")
+		},
+	}
+
+	cmd.Flags().StringVarP(&message, "message", "m", "", "stash message")
+	return cmd
+}
+
+func stashPopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pop",
+		Short: "Restore the most recent mergeish stash in all repositories",
+		Long: `Restore the most recent stash entry made by mergeish stash push (or left
+behind by --autostash) in all repositories. Stash entries you made
+yourself are left alone.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Popping stash...")
+			return printStashResults(ws.StashPop(), "no mergeish stash", "failed to pop stash in some repositories")
+		},
+	}
+}
+
+func stashListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Show the most recent stash entry for each repository",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			for _, r := range ws.StashList() {
+				switch {
+				case r.Error != nil:
+					fmt.Printf("  %s: error: %v\n", r.Repo.Name(), r.Error)
+				case len(r.Entries) == 0:
+					fmt.Printf("  %s: no stash\n", r.Repo.Name())
+				default:
+					top := r.Entries[0]
+					fmt.Printf("  %s: %s %s (%s", r.Repo.Name(), top.Ref, top.Message, formatAge(top.Time))
+					if len(r.Entries) > 1 {
+						fmt.Printf(", %d more", len(r.Entries)-1)
+					}
+					fmt.Println(")")
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func printStashResults(results []workspace.StashResult, skipped, failMsg string) error {
+	hasErrors := false
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		case !r.Changed:
+			fmt.Printf("  - %s (%s)\n", r.Repo.Name(), skipped)
+		default:
+			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+		}
+	}
+
+	if hasErrors {
+		return fmt.Errorf("%s", failMsg)
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
+
+// formatAge formats the time since t in a short human-readable form
+func formatAge(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func TestDirtyStatus(t *testing.T) {
+	results := []workspace.StatusResult{
+		{Repo: testRepo("clean"), Status: &git.Status{Branch: "feat"}},
+		{Repo: testRepo("changes"), Status: &git.Status{Branch: "feat", HasChanges: true}},
+		{Repo: testRepo("ahead"), Status: &git.Status{Branch: "feat", Ahead: 1}},
+		{Repo: testRepo("behind"), Status: &git.Status{Branch: "feat", Behind: 1}},
+		{Repo: testRepo("rebasing"), Status: &git.Status{Branch: "feat", Detached: true, State: git.StateRebase}},
+		{Repo: testRepo("other"), Status: &git.Status{Branch: "main"}},
+		{Repo: testRepo("unreadable"), Error: errors.New("broken")},
+		{Repo: testRepo("unfetched"), Status: &git.Status{Branch: "feat"}},
+		{Repo: testRepo("clean2"), Status: &git.Status{Branch: "feat"}},
+	}
+	got := dirtyStatus(results, map[string]bool{"unfetched": true})
+
+	want := []string{"changes", "ahead", "behind", "rebasing", "other", "unreadable", "unfetched"}
+	if len(got) != len(want) {
+		t.Fatalf("dirtyStatus returned %d repos, want %v", len(got), want)
+	}
+	for i, r := range got {
+		if r.Repo.Name() != want[i] {
+			t.Errorf("dirtyStatus[%d] = %s, want %s", i, r.Repo.Name(), want[i])
+		}
+	}
+}
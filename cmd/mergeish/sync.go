@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func syncCmd() *cobra.Command {
+	var force bool
+	var autoStash bool
+	var resume bool
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Return all repos to their default branch after a merge",
+		Long: `Bring every repository back to its default branch after a merge.
+
+For each repo this fetches with --prune, switches to the default branch,
+pulls, and deletes the branch that was checked out before. The default
+branch is the repo's default_branch setting, the remote's HEAD, or
+settings.default_branch, in that order.
+
+A branch that is not fully merged into the default branch is kept unless
+--force is given.
+
+Progress is saved as each repo finishes. If some repos fail or the sync
+is interrupted, --resume syncs only the repos it didn't finish, as long
+as the last one finished less than 24 hours ago.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+			if autoStash {
+				ws.AutoStash = true
+			}
+
+			run, err := startCheckpoint(ws, "sync", resume)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Syncing...")
+			results := run.Sync(workspace.SyncOptions{Force: force})
+
+			hasErrors := false
+			succeeded := 0
+			for _, r := range results {
+				if r.Error != nil {
+					fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+					hasErrors = true
+					continue
+				}
+				succeeded++
+				fmt.Printf("  ✓ %s: %s\n", r.Repo.Name(), describeSync(r))
+			}
+			finishCheckpoint(ws, run, succeeded, hasErrors)
+
+			if hasErrors {
+				return fmt.Errorf("failed to sync some repositories")
+			}
+
+			fmt.Println("Done!")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "delete the previous branch even if it is not fully merged")
+	cmd.Flags().BoolVar(&autoStash, "autostash", false, "stash local changes before switching and restore them after")
+	cmd.Flags().BoolVar(&resume, "resume", false, "skip the repos the last unfinished sync completed")
+	return cmd
+}
+
+func describeSync(r workspace.SyncResult) string {
+	parts := []string{r.Branch + " up to date"}
+	if r.Updated {
+		parts[0] = r.Branch + " updated"
+	}
+	if r.Deleted != "" {
+		parts = append(parts, "deleted "+r.Deleted)
+	}
+	if r.Kept != "" {
+		parts = append(parts, fmt.Sprintf("kept %s (not fully merged; use --force)", r.Kept))
+	}
+	if len(r.Pruned) > 0 {
+		parts = append(parts, "pruned "+strings.Join(r.Pruned, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func tagCmd() *cobra.Command {
+	var (
+		message string
+		del     bool
+		push    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tag [--] [name]",
+		Short: "Manage tags across all repositories",
+		Long: `Manage git tags across all configured repositories.
+
+With a name, creates the tag at HEAD on every repo (annotated with -m).
+Repos that already have the tag fail; the others are still tagged.
+With --delete, removes the tag instead. --push also pushes the new tag,
+or removes it from the remote when combined with --delete.
+
+Without arguments, shows the latest tag in each repo so version drift
+is easy to spot. --push without a name pushes all tags.
+
+A name that is, or looks like, one of the subcommands (create, delete,
+list, push) must follow --, e.g. mergeish tag -- list, or be given to
+tag create.`,
+		Args:                       cobra.MaximumNArgs(1),
+		SuggestionsMinimumDistance: 2,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				if del {
+					return fmt.Errorf("--delete requires a tag name")
+				}
+				if push {
+					printIdentity(ws)
+					fmt.Println("Pushing tags...")
+					return printResults(ws.PushTags(), "failed to push tags for some repositories")
+				}
+				return printLatestTags(ws.LatestTags())
+			}
+
+			name := args[0]
+			// Without --, a mistyped subcommand would become a tag
+			if cmd.ArgsLenAtDash() < 0 {
+				if s := cmd.SuggestionsFor(name); len(s) > 0 {
+					return fmt.Errorf("%q looks like the %s subcommand; to use it as a tag name, put -- before it: mergeish tag -- %s", name, s[0], name)
+				}
+			}
+			printIdentity(ws)
+
+			if del {
+				fmt.Printf("Deleting tag %s...\n", name)
+				if err := printResults(ws.DeleteTag(name), "failed to delete tag on some repositories"); err != nil {
+					return err
+				}
+				if push {
+					fmt.Printf("Deleting tag %s from remotes...\n", name)
+					return printResults(ws.DeleteRemoteTag(name), "failed to delete remote tag on some repositories")
+				}
+				return nil
+			}
+
+			fmt.Printf("Creating tag %s...\n", name)
+			if err := printResults(ws.CreateTag(name, message), "failed to create tag on some repositories"); err != nil {
+				return err
+			}
+			if push {
+				fmt.Printf("Pushing tag %s...\n", name)
+				return printResults(ws.PushTag(name), "failed to push tag for some repositories")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&message, "message", "m", "", "tag message (creates an annotated tag)")
+	cmd.Flags().BoolVarP(&del, "delete", "d", false, "delete the tag instead of creating it")
+	cmd.Flags().BoolVar(&push, "push", false, "push the tag to the remote (or delete it there with --delete)")
+
+	cmd.AddCommand(tagCreateCmd())
+	cmd.AddCommand(tagDeleteCmd())
+	cmd.AddCommand(tagListCmd())
+	cmd.AddCommand(tagPushCmd())
+
+	return cmd
+}
+
+// printLatestTags shows the latest tag per repo and warns when they differ
+func printLatestTags(results []workspace.LatestTagResult) error {
+	tags := make(map[string]bool)
+	hasErrors := false
+
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			hasErrors = true
+		case r.Tag == "":
+			fmt.Printf("  %s: (no tags)\n", r.Repo.Name())
+			tags[""] = true
+		default:
+			fmt.Printf("  %s: %s\n", r.Repo.Name(), r.Tag)
+			tags[r.Tag] = true
+		}
+	}
+
+	if len(tags) > 1 {
+		fmt.Println(colorize(colorRed, "Latest tags differ between repositories"))
+	}
+
+	if hasErrors {
+		return fmt.Errorf("failed to read tags for some repositories")
+	}
+	return nil
+}
+
+func tagCreateCmd() *cobra.Command {
+	var message string
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a tag on all repositories",
+		Long: `Create a tag at HEAD on all repositories.
+
+With -m, an annotated tag is created. Otherwise a lightweight tag is created.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Creating tag %s...\n", args[0])
+			return printResults(ws.CreateTag(args[0], message), "failed to create tag on some repositories")
+		},
+	}
+
+	cmd.Flags().StringVarP(&message, "message", "m", "", "tag message (creates an annotated tag)")
+	return cmd
+}
+
+func tagDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a local tag from all repositories",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Deleting tag %s...\n", args[0])
+			return printResults(ws.DeleteTag(args[0]), "failed to delete tag on some repositories")
+		},
+	}
+}
+
+func tagPushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Push tags for all repositories",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspaceForChange()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Pushing tags...")
+			return printResults(ws.PushTags(), "failed to push tags for some repositories")
+		},
+	}
+}
+
+func tagListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [name]",
+		Short: "List tags across all repositories",
+		Long: `List tags across all repositories.
+
+Without arguments, shows every tag and the repos missing it.
+With a name argument, shows which repos have that tag.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				return err
+			}
+
+			results := ws.ListTags()
+
+			if len(args) == 1 {
+				return listTag(results, args[0])
+			}
+			return listAllTags(results)
+		},
+	}
+}
+
+// listTag shows which repos have the given tag
+func listTag(results []workspace.TagResult, name string) error {
+	fmt.Printf("Tag %s:\n", name)
+
+	missing := 0
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			continue
+		}
+		if containsString(r.Tags, name) {
+			fmt.Printf("  ✓ %s\n", r.Repo.Name())
+		} else {
+			fmt.Printf("  - %s (missing)\n", r.Repo.Name())
+			missing++
+		}
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("tag %s is missing from %d repositories", name, missing)
+	}
+	return nil
+}
+
+// listAllTags shows every tag found in any repo, with the repos missing it
+func listAllTags(results []workspace.TagResult) error {
+	present := make(map[string]map[string]bool)
+	var repos []string
+
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("  ✗ %s: %v\n", r.Repo.Name(), r.Error)
+			continue
+		}
+		repos = append(repos, r.Repo.Name())
+		for _, tag := range r.Tags {
+			if present[tag] == nil {
+				present[tag] = make(map[string]bool)
+			}
+			present[tag][r.Repo.Name()] = true
+		}
+	}
+
+	if len(present) == 0 {
+		fmt.Println("No tags")
+		return nil
+	}
+
+	tags := make([]string, 0, len(present))
+	for tag := range present {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		var missing []string
+		for _, name := range repos {
+			if !present[tag][name] {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) == 0 {
+			fmt.Printf("  %s\n", tag)
+		} else {
+			fmt.Printf("  %s (missing: %s)\n", tag, strings.Join(missing, ", "))
+		}
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTagNameLikeSubcommand(t *testing.T) {
+	root := scopeWorkspace(t)
+	api := filepath.Join(root, "api")
+
+	// A typo of a subcommand isn't taken as a tag name
+	out, err := runCommand(t, tagCmd, "lsit")
+	if err == nil || !strings.Contains(err.Error(), "list subcommand") {
+		t.Fatalf("tag lsit = %v, want an error naming the list subcommand\n%s", err, out)
+	}
+	if tags := gitIn(t, api, "tag"); tags != "" {
+		t.Errorf("tag lsit created tags: %q", tags)
+	}
+
+	// After --, even a subcommand's name is a tag name
+	for _, name := range []string{"list", "lsit"} {
+		if out, err := runCommand(t, tagCmd, "--", name); err != nil {
+			t.Fatalf("tag -- %s = %v\n%s", name, err, out)
+		}
+	}
+	if tags := gitIn(t, api, "tag"); tags != "list\nlsit" {
+		t.Errorf("tags after tag -- list and tag -- lsit = %q", tags)
+	}
+
+	// Names unlike any subcommand need no --
+	if out, err := runCommand(t, tagCmd, "v1.2.0"); err != nil {
+		t.Fatalf("tag v1.2.0 = %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, tagCmd, "create", "push"); err != nil {
+		t.Fatalf("tag create push = %v\n%s", err, out)
+	}
+	if tags := gitIn(t, api, "tag"); tags != "list\nlsit\npush\nv1.2.0" {
+		t.Errorf("tags = %q, want list, lsit, push, v1.2.0", tags)
+	}
+
+	// The subcommand still runs without --
+	out, err = runCommand(t, tagCmd, "list", "v1.2.0")
+	if err != nil || !strings.Contains(out, "Tag v1.2.0:") {
+		t.Errorf("tag list v1.2.0 = %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/workspace"
+)
+
+func updateCmd() *cobra.Command {
+	var rebase bool
+	var push bool
+	var allowProtected bool
+	var autoStash bool
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Fetch, pull, and optionally push all repositories",
+		Long: `Catch every repository up with its remote on the current branch.
+
+For each repo this fetches, pulls (with --rebase if given), and with
+--push pushes any local commits. A repo whose fetch or pull fails is not
+pushed. Repos on a protected branch (settings.protected_branches) are not
+pushed unless --allow-protected is given.
+
+To return to the default branch after a merge, use mergeish sync.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			load := loadWorkspace
+			if push {
+				load = loadWorkspaceForChange
+			}
+			ws, err := load()
+			if err != nil {
+				return err
+			}
+			if autoStash {
+				ws.AutoStash = true
+			}
+
+			results := ws.Update(workspace.UpdateOptions{Rebase: rebase, Push: push, AllowProtected: allowProtected})
+
+			fmt.Println("Fetching...")
+			for _, r := range results {
+				printStage(r.Repo.Name(), r.FetchError, "")
+			}
+
+			if rebase {
+				fmt.Println("Pulling with rebase...")
+			} else {
+				fmt.Println("Pulling...")
+			}
+			for _, r := range results {
+				if r.FetchError != nil {
+					fmt.Printf("  - %s (fetch failed)\n", r.Repo.Name())
+					continue
+				}
+				printStage(r.Repo.Name(), r.PullError, "")
+			}
+
+			if push {
+				fmt.Println("Pushing...")
+				for _, r := range results {
+					if r.PushSkip != "" {
+						fmt.Printf("  - %s (%s)\n", r.Repo.Name(), r.PushSkip)
+						continue
+					}
+					printStage(r.Repo.Name(), r.PushError, fmt.Sprintf(" (%d commits)", r.Pushed))
+				}
+			}
+
+			for _, r := range results {
+				if r.Err() != nil {
+					return fmt.Errorf("failed to update some repositories")
+				}
+			}
+
+			fmt.Println("Done!")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&rebase, "rebase", false, "use rebase instead of merge when pulling")
+	cmd.Flags().BoolVar(&push, "push", false, "push local commits after pulling")
+	cmd.Flags().BoolVar(&allowProtected, "allow-protected", false, "allow pushing a protected branch")
+	cmd.Flags().BoolVar(&autoStash, "autostash", false, "stash local changes before pulling and restore them after")
+	return cmd
+}
+
+// printStage prints one repo's result for a stage of a multi-stage command
+func printStage(name string, err error, note string) {
+	if err != nil {
+		fmt.Printf("  ✗ %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("  ✓ %s%s\n", name, note)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+func validateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config and workspace for drift",
+		Long: `Check that the config is valid and that every repo matches it.
+
+For each repo this checks that the directory exists and is a git repo,
+that its primary remote (origin unless "remote" is set) points at the
+configured URL and is reachable
+with no remote-tracking refs left over from deleted branches.
+
+Exits non-zero if any check fails, which makes it useful in CI.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace()
+			if err != nil {
+				fmt.Printf("  ✗ config: %v\n", err)
+				return fmt.Errorf("validation failed")
+			}
+			fmt.Printf("  ✓ config: %s\n", ws.ConfigPath)
+
+			failed := 0
+			for _, r := range ws.Validate() {
+				if len(r.Issues) == 0 {
+					fmt.Printf("  ✓ %s\n", r.Repo.Name())
+					continue
+				}
+
+				failed++
+				fmt.Printf("  ✗ %s\n", r.Repo.Name())
+				for _, issue := range r.Issues {
+					label := colorize(colorRed, issue.Severity)
+					if issue.Severity == repo.SeverityWarning {
+						label = issue.Severity
+					}
+					fmt.Printf("      %s: %s\n", label, issue.Message)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d repositories failed validation", failed, len(ws.Repos))
+			}
+
+			fmt.Println("All checks passed")
+			return nil
+		},
+	}
+}
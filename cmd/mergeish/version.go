@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/willnewby/mergeish/internal/git"
+)
+
+const (
+	// latestReleaseURL is the GitHub API endpoint for the newest release
+	latestReleaseURL = "https://api.github.com/repos/willnewby/mergeish/releases/latest"
+	// releasesURL is where a newer release can be downloaded
+	releasesURL = "https://github.com/willnewby/mergeish/releases/latest"
+	// versionCheckTimeout bounds the update check and each git or gh call
+	versionCheckTimeout = 2 * time.Second
+)
+
+// versionJSON is the --json output of mergeish version
+type versionJSON struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	Built           string `json:"built"`
+	Git             string `json:"git"` // "" if git can't be run
+	GH              string `json:"gh"`  // "" if gh can't be run
+	GHAuthenticated bool   `json:"gh_authenticated"`
+	Latest          string `json:"latest"` // "" with --offline or if the check failed
+	UpdateAvailable bool   `json:"update_available"`
+	Config          string `json:"config"` // "" outside a workspace
+}
+
+func versionCmd() *cobra.Command {
+	var offline bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version and environment information",
+		Long: `Show the mergeish version and build, the git and gh versions, whether gh
+is authenticated, whether a newer mergeish release exists, and the config
+file of the current workspace. Include this output when filing an issue.
+
+The release check asks GitHub and gives up after 2 seconds; it never fails
+the command. Use --offline to skip it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !timeoutSet {
+				git.SetTimeout(versionCheckTimeout)
+			}
+
+			type release struct {
+				tag string
+				err error
+			}
+			latest := make(chan release, 1)
+			if !offline {
+				go func() {
+					tag, err := latestRelease()
+					latest <- release{tag, err}
+				}()
+			}
+
+			out := versionJSON{Version: version, Commit: commit, Built: date}
+			gitVersion, gitErr := git.Version()
+			out.Git = gitVersion
+			ghVersion, ghErr := git.GHVersion()
+			out.GH = ghVersion
+			var authErr error
+			if ghErr == nil {
+				authErr = git.GHAuthStatus()
+				out.GHAuthenticated = authErr == nil
+			}
+			if path, err := getConfigPath(); err == nil {
+				out.Config = path
+			}
+			var rel release
+			if !offline {
+				rel = <-latest
+				out.Latest = rel.tag
+				out.UpdateAvailable = rel.err == nil && newerVersion(rel.tag, version)
+			}
+
+			if jsonOutput {
+				return printJSON(out)
+			}
+
+			fmt.Printf("mergeish %s (commit: %s, built: %s)\n", version, commit, date)
+			if gitErr != nil {
+				fmt.Printf("  ✗ git: %v\n", gitErr)
+			} else {
+				fmt.Printf("  ✓ git %s\n", gitVersion)
+			}
+			switch {
+			case ghErr != nil:
+				fmt.Printf("  ! gh: %v (needed for mergeish pr)\n", ghErr)
+			case authErr != nil:
+				fmt.Printf("  ! gh %s: not authenticated, run `gh auth login` (needed for mergeish pr)\n", ghVersion)
+			default:
+				fmt.Printf("  ✓ gh %s, authenticated\n", ghVersion)
+			}
+			switch {
+			case offline:
+				fmt.Println("  - update check: skipped (--offline)")
+			case rel.err != nil:
+				fmt.Printf("  - update check: %v\n", rel.err)
+			case out.UpdateAvailable:
+				fmt.Printf("  ! mergeish %s is available: %s\n", rel.tag, releasesURL)
+			default:
+				fmt.Printf("  ✓ latest release: %s\n", rel.tag)
+			}
+			if out.Config != "" {
+				fmt.Printf("  config: %s\n", out.Config)
+			} else {
+				fmt.Println("  config: none (not in a workspace)")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&offline, "offline", false, "don't check for a newer release")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+	return cmd
+}
+
+// latestRelease returns the tag of the newest mergeish release on GitHub,
+// giving up after versionCheckTimeout
+func latestRelease() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("no answer from GitHub within %s", versionCheckTimeout)
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", fmt.Errorf("can't reach GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
+	var result struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("parsing GitHub response: %w", err)
+	}
+	return result.TagName, nil
+}
+
+// newerVersion reports whether release is a later version than current.
+// A current version that isn't a release, such as "dev", is never older.
+func newerVersion(release, current string) bool {
+	r, ok := parseVersion(release)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range r {
+		if r[i] != c[i] {
+			return r[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion splits a version such as "v1.4.2" into its numbers
+func parseVersion(v string) ([3]int, bool) {
+	var nums [3]int
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return nums, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nums, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
@@ -1,31 +1,243 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
 const DefaultConfigFile = "mergeish.yml"
 
+// DefaultTOMLConfigFile is the config file name used for TOML configs
+const DefaultTOMLConfigFile = "mergeish.toml"
+
 // RepoConfig represents a single repository configuration
 type RepoConfig struct {
-	URL  string `yaml:"url"`
-	Path string `yaml:"path"`
+	URL  string `yaml:"url" toml:"url"`
+	Path string `yaml:"path" toml:"path"`
+
+	// Preset names an entry of the config's presets to inherit from
+	Preset string `yaml:"preset,omitempty" toml:"preset,omitempty"`
+
+	RepoDefaults `yaml:",inline"`
+
+	rawURL       string      // URL before variable expansion, written back on Save
+	includedFrom string      // path of the included file that lists the repo, "" for the main file
+	declared     *RepoConfig // the repo as written, before repo_defaults and its preset applied; written back on Save
+}
+
+// RepoDefaults are the repo settings that can be shared through
+// repo_defaults and presets: everything but the url and path. A repo
+// inherits repo_defaults, then its preset, then its own values, each
+// overriding the fields the one before sets.
+type RepoDefaults struct {
+	PRBase string `yaml:"pr_base,omitempty" toml:"pr_base,omitempty"` // base branch for PRs, overrides the remote default
+
+	// Remote names the remote for URL, "origin" if empty
+	Remote string `yaml:"remote,omitempty" toml:"remote,omitempty"`
+
+	// Remotes are extra remotes added after cloning, keyed by name
+	Remotes map[string]string `yaml:"remotes,omitempty" toml:"remotes,omitempty"`
+
+	// Provider hosts the repo's pull requests: github, or none for repos
+	// without one. Empty means github unless the URL is a local path.
+	Provider string `yaml:"provider,omitempty" toml:"provider,omitempty"`
+
+	// Settings overrides the top-level settings for this repo
+	Settings *RepoSettings `yaml:"settings,omitempty" toml:"settings,omitempty"`
+}
+
+// merge returns d with the fields over sets replacing its own. Remotes and
+// settings are merged one by one.
+func (d RepoDefaults) merge(over RepoDefaults) RepoDefaults {
+	if over.PRBase != "" {
+		d.PRBase = over.PRBase
+	}
+	if over.Remote != "" {
+		d.Remote = over.Remote
+	}
+	if over.Provider != "" {
+		d.Provider = over.Provider
+	}
+	if len(over.Remotes) > 0 {
+		remotes := make(map[string]string, len(d.Remotes)+len(over.Remotes))
+		for name, url := range d.Remotes {
+			remotes[name] = url
+		}
+		for name, url := range over.Remotes {
+			remotes[name] = url
+		}
+		d.Remotes = remotes
+	}
+	if over.Settings != nil {
+		var s RepoSettings
+		if d.Settings != nil {
+			s = *d.Settings
+		}
+		s = s.merge(*over.Settings)
+		d.Settings = &s
+	}
+	return d
+}
+
+// CloneSettings represents settings for the clone command
+type CloneSettings struct {
+	SkipForbidden bool `yaml:"skip_forbidden" toml:"skip_forbidden"` // skip repos the user cannot access
+}
+
+// PRSettings configures pull request commands
+type PRSettings struct {
+	UmbrellaRepo  string   `yaml:"umbrella_repo,omitempty" toml:"umbrella_repo,omitempty"`   // owner/name of the repo for umbrella issues
+	TitleTemplate string   `yaml:"title_template,omitempty" toml:"title_template,omitempty"` // PR title for branches with a ticket, e.g. "{ticket}: {title}"
+	TicketURL     string   `yaml:"ticket_url,omitempty" toml:"ticket_url,omitempty"`         // link for tickets, e.g. "https://jira.example.com/browse/{ticket}"
+	Reviewers     []string `yaml:"reviewers,omitempty" toml:"reviewers,omitempty"`           // requested on every PR pr create opens
+	Assignees     []string `yaml:"assignees,omitempty" toml:"assignees,omitempty"`           // assigned every PR pr create opens
+	Labels        []string `yaml:"labels,omitempty" toml:"labels,omitempty"`                 // applied to every PR pr create opens
+	TemplateFile  string   `yaml:"template_file,omitempty" toml:"template_file,omitempty"`   // PR body for repos without a PR template of their own, relative to the workspace root
 }
 
 // Settings represents optional configuration settings
 type Settings struct {
-	DefaultBranch string `yaml:"default_branch"`
-	Parallel      bool   `yaml:"parallel"`
+	Name              string        `yaml:"name,omitempty" toml:"name,omitempty"`                 // shown before changes so the workspace is recognizable
+	ConfirmName       bool          `yaml:"confirm_name,omitempty" toml:"confirm_name,omitempty"` // require typing the name for destructive operations
+	DefaultBranch     string        `yaml:"default_branch" toml:"default_branch"`
+	Parallel          bool          `yaml:"parallel" toml:"parallel"`
+	MaxJobs           int           `yaml:"max_jobs" toml:"max_jobs"`           // repos run at once, 0 to derive from CPUs and open file limit
+	GHRateLimit       float64       `yaml:"gh_rate_limit" toml:"gh_rate_limit"` // max gh invocations per second, 0 for unlimited
+	AutoStash         bool          `yaml:"autostash" toml:"autostash"`         // stash local changes around pull and checkout
+	Clone             CloneSettings `yaml:"clone" toml:"clone"`
+	PR                PRSettings    `yaml:"pr,omitempty" toml:"pr,omitempty"`
+	Retries           int           `yaml:"retries" toml:"retries"`                                         // retries for transient network failures
+	RetryBackoff      time.Duration `yaml:"retry_backoff" toml:"retry_backoff"`                             // delay before the first retry, doubled each time
+	Timeout           time.Duration `yaml:"timeout" toml:"timeout"`                                         // kill a git or gh process after this long, 0 for no limit
+	LockWait          time.Duration `yaml:"lock_wait" toml:"lock_wait"`                                     // keep retrying add and commit this long while the index is locked
+	UnclonedPolicy    string        `yaml:"uncloned_policy" toml:"uncloned_policy"`                         // error, skip, or hide repos that are not cloned
+	ProtectedBranches []string      `yaml:"protected_branches" toml:"protected_branches"`                   // branches push refuses without --allow-protected
+	ConfigAuditKeys   []string      `yaml:"config_audit_keys,omitempty" toml:"config_audit_keys,omitempty"` // git config keys audited in addition to the defaults
+}
+
+// Values for Settings.UnclonedPolicy
+const (
+	UnclonedError = "error" // report uncloned repos as errors
+	UnclonedSkip  = "skip"  // leave them out and summarize them once
+	UnclonedHide  = "hide"  // leave them out silently
+)
+
+// Values for RepoConfig.Provider
+const (
+	ProviderGitHub = "github" // pull requests through gh
+	ProviderNone   = "none"   // no pull requests, e.g. a repo on a file server
+)
+
+// HasPRs reports whether the repo's provider supports pull requests
+func (rc RepoConfig) HasPRs() bool {
+	switch rc.Provider {
+	case ProviderGitHub:
+		return true
+	case ProviderNone:
+		return false
+	}
+	return !isLocalURL(rc.URL)
+}
+
+// isLocalURL reports whether a git URL is a local path or file:// URL
+func isLocalURL(url string) bool {
+	if strings.HasPrefix(url, "file://") || filepath.IsAbs(url) || strings.HasPrefix(url, ".") {
+		return true
+	}
+	// scp-like URLs (host:path) and URLs with a scheme contain a colon
+	return !strings.Contains(url, ":")
+}
+
+// RemoteName returns the name of the repo's primary remote
+func (rc RepoConfig) RemoteName() string {
+	if rc.Remote != "" {
+		return rc.Remote
+	}
+	return "origin"
+}
+
+// RepoSettings are the settings a repo can override. Unset fields inherit
+// the top-level value.
+type RepoSettings struct {
+	DefaultBranch string         `yaml:"default_branch,omitempty" toml:"default_branch,omitempty"`
+	Parallel      *bool          `yaml:"parallel,omitempty" toml:"parallel,omitempty"`
+	AutoStash     *bool          `yaml:"autostash,omitempty" toml:"autostash,omitempty"`
+	Retries       *int           `yaml:"retries,omitempty" toml:"retries,omitempty"`
+	RetryBackoff  *time.Duration `yaml:"retry_backoff,omitempty" toml:"retry_backoff,omitempty"`
+}
+
+// merge returns s with the fields over sets replacing its own
+func (s RepoSettings) merge(over RepoSettings) RepoSettings {
+	if over.DefaultBranch != "" {
+		s.DefaultBranch = over.DefaultBranch
+	}
+	if over.Parallel != nil {
+		s.Parallel = over.Parallel
+	}
+	if over.AutoStash != nil {
+		s.AutoStash = over.AutoStash
+	}
+	if over.Retries != nil {
+		s.Retries = over.Retries
+	}
+	if over.RetryBackoff != nil {
+		s.RetryBackoff = over.RetryBackoff
+	}
+	return s
+}
+
+// EffectiveSettings returns global with this repo's overrides applied
+func (rc RepoConfig) EffectiveSettings(global Settings) Settings {
+	s := global
+	o := rc.Settings
+	if o == nil {
+		return s
+	}
+
+	if o.DefaultBranch != "" {
+		s.DefaultBranch = o.DefaultBranch
+	}
+	if o.Parallel != nil {
+		s.Parallel = *o.Parallel
+	}
+	if o.AutoStash != nil {
+		s.AutoStash = *o.AutoStash
+	}
+	if o.Retries != nil {
+		s.Retries = *o.Retries
+	}
+	if o.RetryBackoff != nil {
+		s.RetryBackoff = *o.RetryBackoff
+	}
+	return s
 }
 
 // Config represents the mergeish.yml configuration file
 type Config struct {
-	Repos    []RepoConfig `yaml:"repos"`
-	Settings Settings     `yaml:"settings"`
+	Include      []string                `yaml:"include,omitempty" toml:"include,omitempty"` // config files whose repos, vars, and settings this one extends
+	Vars         map[string]string       `yaml:"vars,omitempty" toml:"vars,omitempty"`
+	RepoDefaults *RepoDefaults           `yaml:"repo_defaults,omitempty" toml:"repo_defaults,omitempty"` // inherited by every repo
+	Presets      map[string]RepoDefaults `yaml:"presets,omitempty" toml:"presets,omitempty"`             // inherited by the repos naming them
+	Repos        []RepoConfig            `yaml:"repos" toml:"repos,omitempty"`
+	Settings     Settings                `yaml:"settings" toml:"settings"`
+	Hooks        map[string]Hook         `yaml:"hooks,omitempty" toml:"hooks,omitempty"` // shell commands run before and after pull, push, and commit
+
+	fileVars         map[string]string       // vars as written in the file, without included ones
+	fileRepoDefaults *RepoDefaults           // repo_defaults as written in the file, without included ones
+	filePresets      map[string]RepoDefaults // presets as written in the file, without included ones
+	fileHooks        map[string]Hook         // hooks as written in the file, without included ones
+	fileSettings     *Settings               // settings as written in the file, without global defaults
+	fileSettingKeys  map[string]any          // settings the file sets, nested like the file
+	loadedSettings   *Settings               // Settings as loaded, to detect changes on Save
+	doc              *yaml.Node              // YAML file as loaded, so Save keeps comments
 }
 
 // DefaultConfig returns a config with default settings
@@ -33,20 +245,170 @@ func DefaultConfig() *Config {
 	return &Config{
 		Repos: []RepoConfig{},
 		Settings: Settings{
-			DefaultBranch: "main",
-			Parallel:      true,
+			DefaultBranch:     "main",
+			Parallel:          true,
+			GHRateLimit:       5,
+			RetryBackoff:      2 * time.Second,
+			LockWait:          2 * time.Second,
+			UnclonedPolicy:    UnclonedError,
+			ProtectedBranches: []string{"main", "master"},
 		},
 	}
 }
 
-// Load reads and parses a config file from the given path
+// Load reads and parses a config file from the given path. Included files
+// come first: their repos are listed before the file's own, and the file's
+// vars and settings override theirs. Settings given in none of them come
+// from the global config.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
 
-	return Parse(data)
+	global, err := LoadGlobal()
+	if err != nil {
+		return nil, err
+	}
+
+	file := DefaultConfig()
+	if err := unmarshalFile(path, data, file); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := loadIncludes(path, file.Include, []string{abs}, map[string]bool{abs: true})
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, source{path: path, data: data})
+
+	cfg := DefaultConfig()
+	cfg.Settings = global.Settings
+	for _, src := range sources {
+		part := &Config{Settings: cfg.Settings}
+		if err := unmarshalFile(src.path, src.data, part); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", src.path, err)
+		}
+		cfg.Settings = part.Settings
+		for k, v := range part.Vars {
+			if cfg.Vars == nil {
+				cfg.Vars = make(map[string]string)
+			}
+			cfg.Vars[k] = v
+		}
+		if part.RepoDefaults != nil {
+			var d RepoDefaults
+			if cfg.RepoDefaults != nil {
+				d = *cfg.RepoDefaults
+			}
+			d = d.merge(*part.RepoDefaults)
+			cfg.RepoDefaults = &d
+		}
+		for name, preset := range part.Presets {
+			if cfg.Presets == nil {
+				cfg.Presets = make(map[string]RepoDefaults)
+			}
+			cfg.Presets[name] = preset
+		}
+		for name, hook := range part.Hooks {
+			if cfg.Hooks == nil {
+				cfg.Hooks = make(map[string]Hook)
+			}
+			cfg.Hooks[name] = hook
+		}
+		for _, rc := range part.Repos {
+			if src.path != path {
+				rc.includedFrom = src.path
+			}
+			cfg.Repos = append(cfg.Repos, rc)
+		}
+	}
+	cfg.Include = file.Include
+
+	loaded := cfg.Settings
+	cfg.fileVars = file.Vars
+	cfg.fileRepoDefaults = file.RepoDefaults
+	cfg.filePresets = file.Presets
+	cfg.fileHooks = file.Hooks
+	cfg.fileSettings = &file.Settings
+	cfg.loadedSettings = &loaded
+	var keys struct {
+		Settings map[string]any `yaml:"settings" toml:"settings"`
+	}
+	if err := unmarshalFile(path, data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	cfg.fileSettingKeys = keys.Settings
+	if !isTOML(path) {
+		cfg.doc = parseDocument(data)
+	}
+
+	return cfg.resolve()
+}
+
+// source is a config file's contents
+type source struct {
+	path string
+	data []byte
+}
+
+// loadIncludes reads the files included by the config at path, resolved
+// relative to it, each preceded by the files it includes in turn. stack
+// holds the absolute paths of the including files, to detect cycles; a
+// file included twice elsewhere is only read once.
+func loadIncludes(path string, includes, stack []string, seen map[string]bool) ([]source, error) {
+	var sources []source
+	for _, inc := range includes {
+		p := inc
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(path), p)
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(stack, abs) {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading included config: %w", err)
+		}
+		var part Config
+		if err := unmarshalFile(p, data, &part); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", p, err)
+		}
+
+		nested, err := loadIncludes(p, part.Include, append(stack, abs), seen)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, nested...)
+		sources = append(sources, source{path: p, data: data})
+	}
+	return sources, nil
+}
+
+// unmarshalFile parses data into v as TOML or YAML, depending on path
+func unmarshalFile(path string, data []byte, v any) error {
+	if isTOML(path) {
+		return toml.Unmarshal(data, v)
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// isTOML reports whether path names a TOML config file
+func isTOML(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".toml")
 }
 
 // Parse parses config from YAML bytes
@@ -56,16 +418,189 @@ func Parse(data []byte) (*Config, error) {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
 
-	if err := cfg.Validate(); err != nil {
+	return cfg.resolve()
+}
+
+// ParseTOML parses config from TOML bytes
+func ParseTOML(data []byte) (*Config, error) {
+	cfg := DefaultConfig()
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	return cfg.resolve()
+}
+
+// resolve applies repo defaults and presets, expands variables, and
+// validates a freshly parsed config
+func (c *Config) resolve() (*Config, error) {
+	for i := range c.Repos {
+		c.inherit(&c.Repos[i])
+	}
+
+	// Expand variables in repo URLs before validating
+	for i := range c.Repos {
+		raw := c.Repos[i].URL
+		c.Repos[i].rawURL = raw
+		c.Repos[i].URL = c.expandVars(raw)
+		if raw != "" && c.Repos[i].URL == "" {
+			return nil, fmt.Errorf("repo %d: url %q is empty after variable expansion", i, raw)
+		}
+	}
+
+	if err := c.Validate(); err != nil {
 		return nil, err
 	}
 
-	return cfg, nil
+	return c, nil
+}
+
+// inherit applies repo_defaults and the repo's preset to rc, keeping rc as
+// written to save. An unknown preset is left for Validate to report.
+func (c *Config) inherit(rc *RepoConfig) {
+	declared := *rc
+	var d RepoDefaults
+	if c.RepoDefaults != nil {
+		d = *c.RepoDefaults
+	}
+	if preset, ok := c.Presets[rc.Preset]; ok && rc.Preset != "" {
+		d = d.merge(preset)
+	}
+	rc.RepoDefaults = RepoDefaults{}.merge(d).merge(declared.RepoDefaults)
+	rc.declared = &declared
+}
+
+// RemoveRepo removes a repo from the config, matched by its path or, if no
+// path matches, by name (see RepoName or the last element of its path)
+func (c *Config) RemoveRepo(pathOrName string) (RepoConfig, error) {
+	i, err := c.FindRepo(pathOrName)
+	if err != nil {
+		return RepoConfig{}, err
+	}
+	rc := c.Repos[i]
+	if rc.includedFrom != "" {
+		return RepoConfig{}, fmt.Errorf("%s is listed in included config %s; remove it there", rc.Path, rc.includedFrom)
+	}
+	c.Repos = append(c.Repos[:i], c.Repos[i+1:]...)
+	return rc, nil
+}
+
+// SetRepoDefaultBranch sets the settings.default_branch override of the repo
+// at path. It is written to the repo's own entry, even where the old value
+// came from repo_defaults or a preset, so other repos keep theirs.
+func (c *Config) SetRepoDefaultBranch(path, branch string) error {
+	i, err := c.FindRepo(path)
+	if err != nil {
+		return err
+	}
+	rc := &c.Repos[i]
+	if rc.includedFrom != "" {
+		return fmt.Errorf("repo %s is listed in %s; set its default_branch there", rc.Path, rc.includedFrom)
+	}
+
+	set := func(d *RepoDefaults) {
+		var s RepoSettings
+		if d.Settings != nil {
+			s = *d.Settings
+		}
+		s.DefaultBranch = branch
+		d.Settings = &s
+	}
+	set(&rc.RepoDefaults)
+	if rc.declared != nil {
+		set(&rc.declared.RepoDefaults)
+	}
+	return nil
+}
+
+// FindRepo returns the index of the repo with the given path, or else the
+// only repo with the given name
+func (c *Config) FindRepo(pathOrName string) (int, error) {
+	for i, rc := range c.Repos {
+		if rc.Path == filepath.Clean(pathOrName) {
+			return i, nil
+		}
+	}
+
+	var matches []int
+	for i, rc := range c.Repos {
+		if filepath.Base(rc.Path) == pathOrName || RepoName(rc.URL) == pathOrName {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("repo %q not found in config", pathOrName)
+	case 1:
+		return matches[0], nil
+	}
+
+	paths := make([]string, len(matches))
+	for j, i := range matches {
+		paths[j] = c.Repos[i].Path
+	}
+	return -1, fmt.Errorf("%q matches several repos (%s); use the path", pathOrName, strings.Join(paths, ", "))
+}
+
+// RepoName returns the repository name in a git URL, e.g. "repo" for
+// git@github.com:org/repo.git, or "" if there is none
+func RepoName(url string) string {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	if url == "." || url == ".." {
+		return ""
+	}
+	return url
+}
+
+// expandVars replaces $VAR and ${VAR} in s. Variables defined in the
+// config's vars section take precedence over environment variables.
+func (c *Config) expandVars(s string) string {
+	return os.Expand(s, func(name string) string {
+		if v, ok := c.Vars[name]; ok {
+			return v
+		}
+		return os.Getenv(name)
+	})
 }
 
 // Validate checks the config for errors
 func (c *Config) Validate() error {
-	seen := make(map[string]bool)
+	switch c.Settings.UnclonedPolicy {
+	case UnclonedError, UnclonedSkip, UnclonedHide:
+	default:
+		return fmt.Errorf("settings.uncloned_policy must be %s, %s, or %s, got %q",
+			UnclonedError, UnclonedSkip, UnclonedHide, c.Settings.UnclonedPolicy)
+	}
+
+	if c.Settings.MaxJobs < 0 {
+		return fmt.Errorf("settings.max_jobs must be 0 or more, got %d", c.Settings.MaxJobs)
+	}
+
+	if r := c.Settings.PR.UmbrellaRepo; r != "" {
+		if owner, name, ok := strings.Cut(r, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("settings.pr.umbrella_repo must be owner/name, got %q", r)
+		}
+	}
+
+	for name, hook := range c.Hooks {
+		if !slices.Contains(HookNames, name) {
+			return fmt.Errorf("hooks: unknown hook %q, must be one of %s", name, strings.Join(HookNames, ", "))
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			return fmt.Errorf("hooks.%s: command is required", name)
+		}
+	}
+
+	for _, key := range c.Settings.ConfigAuditKeys {
+		if !strings.Contains(key, ".") {
+			return fmt.Errorf("settings.config_audit_keys: %q is not a git config key (section.name)", key)
+		}
+	}
+
+	seen := make(map[string]string) // path -> file that lists it
 	for i, repo := range c.Repos {
 		if repo.URL == "" {
 			return fmt.Errorf("repo %d: url is required", i)
@@ -73,36 +608,174 @@ func (c *Config) Validate() error {
 		if repo.Path == "" {
 			return fmt.Errorf("repo %d: path is required", i)
 		}
-		if seen[repo.Path] {
-			return fmt.Errorf("repo %d: duplicate path %q", i, repo.Path)
+		if from, ok := seen[repo.Path]; ok {
+			return fmt.Errorf("repo %d: duplicate path %q%s", i, repo.Path, duplicateSource(from, repo.includedFrom))
+		}
+		if _, ok := c.Presets[repo.Preset]; repo.Preset != "" && !ok {
+			return fmt.Errorf("repo %d: unknown preset %q", i, repo.Preset)
+		}
+		switch repo.Provider {
+		case "", ProviderGitHub, ProviderNone:
+		default:
+			return fmt.Errorf("repo %d: provider must be %s or %s, got %q", i, ProviderGitHub, ProviderNone, repo.Provider)
+		}
+		for name, url := range repo.Remotes {
+			if name == repo.RemoteName() {
+				return fmt.Errorf("repo %d: remote %q collides with the remote for url", i, name)
+			}
+			if name == "" || url == "" {
+				return fmt.Errorf("repo %d: remotes need a name and a url", i)
+			}
+		}
+		seen[repo.Path] = repo.includedFrom
+	}
+	return nil
+}
+
+// duplicateSource describes where the two copies of a duplicate repo come
+// from, given the included files that list them ("" for the main file)
+func duplicateSource(first, second string) string {
+	if first == "" && second == "" {
+		return ""
+	}
+	name := func(from string) string {
+		if from == "" {
+			return "this config"
+		}
+		return from
+	}
+	return fmt.Sprintf(" (listed in %s and %s)", name(first), name(second))
+}
+
+// AddRepo appends a repo to the config. The URL may reference variables,
+// which are expanded for validation but saved as written. A config written
+// by hand may clone a URL twice, but AddRepo refuses to.
+func (c *Config) AddRepo(rc RepoConfig) error {
+	c.inherit(&rc)
+	rc.rawURL = rc.URL
+	rc.URL = c.expandVars(rc.URL)
+	if rc.rawURL != "" && rc.URL == "" {
+		return fmt.Errorf("url %q is empty after variable expansion", rc.rawURL)
+	}
+	for _, other := range c.Repos {
+		if other.URL == rc.URL {
+			return fmt.Errorf("url %q is already in the config at path %q", rc.URL, other.Path)
 		}
-		seen[repo.Path] = true
+	}
+
+	c.Repos = append(c.Repos, rc)
+	if err := c.Validate(); err != nil {
+		c.Repos = c.Repos[:len(c.Repos)-1]
+		return err
 	}
 	return nil
 }
 
-// Save writes the config to the given path
+// Save writes the config to the given path, as TOML if the path has a
+// .toml extension and as YAML otherwise. A YAML config read by Load keeps
+// its comments, key order, and formatting wherever values are unchanged.
 func (c *Config) Save(path string) error {
-	data, err := yaml.Marshal(c)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	// Write URLs as they were written, with variables unexpanded. Repos and
+	// vars from included files stay in those files.
+	settings, err := c.settingsToSave(path)
 	if err != nil {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
+	out := savedConfig{
+		Include:      c.Include,
+		Vars:         c.Vars,
+		RepoDefaults: c.RepoDefaults,
+		Presets:      c.Presets,
+		Settings:     settings,
+		Hooks:        c.Hooks,
+	}
+	if c.fileSettings != nil {
+		out.Vars = c.fileVars
+		out.RepoDefaults = c.fileRepoDefaults
+		out.Presets = c.filePresets
+		out.Hooks = c.fileHooks
+	}
+	out.Repos = make([]RepoConfig, 0, len(c.Repos))
+	for _, rc := range c.Repos {
+		if rc.includedFrom != "" {
+			continue
+		}
+		if rc.declared != nil {
+			rc = *rc.declared
+		}
+		if rc.rawURL != "" {
+			rc.URL = rc.rawURL
+		}
+		out.Repos = append(out.Repos, rc)
+	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	var buf bytes.Buffer
+	if isTOML(path) {
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = ""
+		err = enc.Encode(&out)
+	} else {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if c.doc != nil {
+			err = c.mergeInto(&out)
+			if err == nil {
+				err = enc.Encode(c.doc)
+			}
+		} else {
+			err = enc.Encode(&out)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
 		return fmt.Errorf("writing config file: %w", err)
 	}
 
 	return nil
 }
 
-// FindConfigFile searches for mergeish.yml starting from the given directory
-// and walking up to parent directories
+// savedConfig is a Config as Save writes it, with only the settings to keep
+type savedConfig struct {
+	Include      []string                `yaml:"include,omitempty" toml:"include,omitempty"`
+	Vars         map[string]string       `yaml:"vars,omitempty" toml:"vars,omitempty"`
+	RepoDefaults *RepoDefaults           `yaml:"repo_defaults,omitempty" toml:"repo_defaults,omitempty"`
+	Presets      map[string]RepoDefaults `yaml:"presets,omitempty" toml:"presets,omitempty"`
+	Repos        []RepoConfig            `yaml:"repos" toml:"repos,omitempty"`
+	Settings     any                     `yaml:"settings,omitempty" toml:"settings,omitempty"`
+	Hooks        map[string]Hook         `yaml:"hooks,omitempty" toml:"hooks,omitempty"`
+}
+
+// mergeInto updates the loaded YAML document to hold out
+func (c *Config) mergeInto(out *savedConfig) error {
+	var fresh, base yaml.Node
+	if err := fresh.Encode(out); err != nil {
+		return err
+	}
+	if err := base.Encode(&Config{Settings: *c.fileSettings}); err != nil {
+		return err
+	}
+	mergeNode(c.doc.Content[0], &fresh, &base)
+	return nil
+}
+
+// FindConfigFile searches for mergeish.yml or mergeish.toml starting from the
+// given directory and walking up to parent directories. If both exist in the
+// same directory, mergeish.yml is preferred.
 func FindConfigFile(startDir string) (string, error) {
 	dir := startDir
 	for {
-		path := filepath.Join(dir, DefaultConfigFile)
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
+		for _, name := range []string{DefaultConfigFile, DefaultTOMLConfigFile} {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
 		}
 
 		parent := filepath.Dir(dir)
@@ -112,5 +785,5 @@ func FindConfigFile(startDir string) (string, error) {
 		dir = parent
 	}
 
-	return "", fmt.Errorf("config file %s not found", DefaultConfigFile)
+	return "", fmt.Errorf("config file %s or %s not found", DefaultConfigFile, DefaultTOMLConfigFile)
 }
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAllowsSameURLTwice(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Repos = []RepoConfig{
+		{URL: "git@github.com:org/api.git", Path: "api"},
+		{URL: "git@github.com:org/api.git", Path: "api-release"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate = %v, want two checkouts of one url to be allowed", err)
+	}
+}
+
+func TestValidateRejectsSamePathTwice(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Repos = []RepoConfig{
+		{URL: "git@github.com:org/api.git", Path: "api"},
+		{URL: "git@github.com:org/web.git", Path: "api"},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "duplicate path") {
+		t.Errorf("Validate = %v, want a duplicate path error", err)
+	}
+}
+
+func TestAddRepoRejectsSameURL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Vars = map[string]string{"org": "org"}
+	if err := cfg.AddRepo(RepoConfig{URL: "git@github.com:org/api.git", Path: "api"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same url, spelled with a variable
+	err := cfg.AddRepo(RepoConfig{URL: "git@github.com:${org}/api.git", Path: "api2"})
+	if err == nil || !strings.Contains(err.Error(), "already in the config") {
+		t.Errorf("AddRepo = %v, want an already in the config error", err)
+	}
+	if len(cfg.Repos) != 1 {
+		t.Errorf("config has %d repos after the rejected add, want 1", len(cfg.Repos))
+	}
+}
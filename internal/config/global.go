@@ -0,0 +1,262 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// GlobalConfig holds user-level defaults shared by every workspace. It
+// cannot list repos; those belong to a workspace.
+type GlobalConfig struct {
+	Settings   Settings          `yaml:"settings"`
+	GHToken    string            `yaml:"gh_token,omitempty"`   // used for gh when GH_TOKEN is not set
+	Workspaces map[string]string `yaml:"workspaces,omitempty"` // shortcut name → workspace directory
+}
+
+// GlobalConfigPath returns $XDG_CONFIG_HOME/mergeish/config.yml, falling
+// back to ~/.config/mergeish/config.yml
+func GlobalConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("finding home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "mergeish", "config.yml"), nil
+}
+
+// LoadGlobal reads the global config. A missing file, or no home directory
+// to look for one in, yields the built-in defaults.
+func LoadGlobal() (*GlobalConfig, error) {
+	path, err := GlobalConfigPath()
+	if err != nil {
+		return &GlobalConfig{Settings: DefaultConfig().Settings}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &GlobalConfig{Settings: DefaultConfig().Settings}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading global config: %w", err)
+	}
+
+	return parseGlobal(data)
+}
+
+// parseGlobal parses global config YAML on top of the built-in defaults,
+// rejecting unknown keys and repos
+func parseGlobal(data []byte) (*GlobalConfig, error) {
+	g := &GlobalConfig{Settings: DefaultConfig().Settings}
+
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing global config: %w", err)
+	}
+	if _, ok := raw["repos"]; ok {
+		return nil, fmt.Errorf("global config cannot list repos; add them to a workspace's %s", DefaultConfigFile)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(g); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing global config: %w", err)
+	}
+	return g, nil
+}
+
+// GetGlobal returns the effective value of a dotted key such as
+// "settings.parallel" from the global config
+func GetGlobal(key string) (string, error) {
+	g, err := LoadGlobal()
+	if err != nil {
+		return "", err
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(g); err != nil {
+		return "", err
+	}
+
+	node := lookupKey(&doc, strings.Split(key, "."), false)
+	if node == nil {
+		return "", fmt.Errorf("unknown key %q", key)
+	}
+	if node.Kind != yaml.ScalarNode {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+	return node.Value, nil
+}
+
+// SetGlobal sets a dotted key such as "settings.parallel" in the global
+// config file, creating it if needed. Only keys written by SetGlobal or by
+// hand are stored; everything else keeps its default. The file can hold
+// gh_token, so only the user may read it.
+func SetGlobal(key, value string) error {
+	path, err := GlobalConfigPath()
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("reading global config: %w", err)
+	default:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parsing global config: %w", err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	node := lookupKey(&doc, strings.Split(key, "."), true)
+	if node == nil {
+		return fmt.Errorf("cannot set %q", key)
+	}
+	*node = yaml.Node{Kind: yaml.ScalarNode, Value: value}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("marshaling global config: %w", err)
+	}
+
+	// Reject unknown keys and values of the wrong type before writing
+	if _, err := parseGlobal(buf.Bytes()); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing global config: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("writing global config: %w", err)
+	}
+	return nil
+}
+
+// lookupKey walks a YAML document along path. With create, missing mapping
+// entries are added.
+func lookupKey(doc *yaml.Node, path []string, create bool) *yaml.Node {
+	node := doc
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			if !create {
+				return nil
+			}
+			next = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, next)
+		}
+		node = next
+	}
+	return node
+}
+
+// settingsToSave returns the settings to write back to a workspace file
+// at path: those set in the file, plus any changed since loading. Values
+// from included files, the global config, and defaults are not written.
+// A config that wasn't loaded from a file keeps all its settings.
+func (c *Config) settingsToSave(path string) (any, error) {
+	if c.fileSettings == nil {
+		return c.Settings, nil
+	}
+
+	out := *c.fileSettings
+	keep := make(map[string]bool)
+	cur := reflect.ValueOf(c.Settings)
+	loaded := reflect.ValueOf(*c.loadedSettings)
+	dst := reflect.ValueOf(&out).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		if !reflect.DeepEqual(cur.Field(i).Interface(), loaded.Field(i).Interface()) {
+			dst.Field(i).Set(cur.Field(i))
+			key, _, _ := strings.Cut(cur.Type().Field(i).Tag.Get("yaml"), ",")
+			keep[key] = true
+		}
+	}
+
+	// Encode the settings as the file would have them, to drop the rest
+	var encoded map[string]any
+	var buf bytes.Buffer
+	if isTOML(path) {
+		if err := toml.NewEncoder(&buf).Encode(out); err != nil {
+			return nil, err
+		}
+		if err := toml.Unmarshal(buf.Bytes(), &encoded); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := yaml.NewEncoder(&buf).Encode(out); err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(buf.Bytes(), &encoded); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range encoded {
+		if !keep[key] {
+			keepSet(encoded, key, value, c.fileSettingKeys)
+		}
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	return encoded, nil
+}
+
+// keepSet removes key from encoded unless set has it. Of a nested
+// table, only the keys set has are kept.
+func keepSet(encoded map[string]any, key string, value any, set map[string]any) {
+	setValue, ok := set[key]
+	if !ok {
+		delete(encoded, key)
+		return
+	}
+	table, isTable := value.(map[string]any)
+	setTable, setIsTable := setValue.(map[string]any)
+	if isTable && setIsTable {
+		for k, v := range table {
+			keepSet(table, k, v, setTable)
+		}
+	}
+}
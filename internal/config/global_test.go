@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetGlobalPermissions(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	if err := SetGlobal("gh_token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(xdg, "mergeish")
+	assertMode(t, dir, 0700)
+	assertMode(t, filepath.Join(dir, "config.yml"), 0600)
+
+	token, err := GetGlobal("gh_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret" {
+		t.Errorf("gh_token = %q, want secret", token)
+	}
+}
+
+func TestSetGlobalTightensExistingFile(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	path := filepath.Join(xdg, "mergeish", "config.yml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("settings:\n  parallel: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetGlobal("gh_token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	assertMode(t, path, 0600)
+}
+
+// assertMode fails the test unless path has the permission bits want
+func assertMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != want {
+		t.Errorf("%s has mode %v, want %v", path, got, want)
+	}
+}
+
+func TestLoadGlobalWithoutHome(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+	if _, err := GlobalConfigPath(); err == nil {
+		t.Skip("home directory found without HOME")
+	}
+
+	g, err := LoadGlobal()
+	if err != nil {
+		t.Fatalf("LoadGlobal without a home directory: %v", err)
+	}
+	if !reflect.DeepEqual(g.Settings, DefaultConfig().Settings) {
+		t.Errorf("Settings = %+v, want the defaults", g.Settings)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultConfigFile)
+	if err := os.WriteFile(path, []byte("repos:\n  - url: git@github.com:org/api.git\n    path: api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Errorf("Load without a home directory: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HookNames are the hooks a config can define, run before and after the
+// workspace operation they are named for
+var HookNames = []string{"pre-pull", "post-pull", "pre-push", "post-push", "pre-commit", "post-commit"}
+
+// Hook is a shell command run in the workspace root before or after an
+// operation. In the config it is either the command as a string, or a
+// mapping with command and continue_on_error.
+type Hook struct {
+	Command         string `yaml:"command" toml:"command"`
+	ContinueOnError bool   `yaml:"continue_on_error,omitempty" toml:"continue_on_error,omitempty"` // warn instead of aborting the operation when it fails
+}
+
+// hookFields is Hook without its (un)marshaling methods
+type hookFields Hook
+
+// UnmarshalYAML reads a hook given as a string or a mapping
+func (h *Hook) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*h = Hook{}
+		return value.Decode(&h.Command)
+	}
+	return value.Decode((*hookFields)(h))
+}
+
+// MarshalYAML writes a hook as a string unless it needs the mapping
+func (h Hook) MarshalYAML() (any, error) {
+	if !h.ContinueOnError {
+		return h.Command, nil
+	}
+	return hookFields(h), nil
+}
+
+// UnmarshalTOML reads a hook given as a string or a table
+func (h *Hook) UnmarshalTOML(data any) error {
+	*h = Hook{}
+	switch v := data.(type) {
+	case string:
+		h.Command = v
+	case map[string]any:
+		for key, value := range v {
+			var ok bool
+			switch key {
+			case "command":
+				h.Command, ok = value.(string)
+			case "continue_on_error":
+				h.ContinueOnError, ok = value.(bool)
+			default:
+				return fmt.Errorf("unknown hook key %q", key)
+			}
+			if !ok {
+				return fmt.Errorf("hook %s has the wrong type", key)
+			}
+		}
+	default:
+		return fmt.Errorf("hook must be a string or a table, got %T", data)
+	}
+	return nil
+}
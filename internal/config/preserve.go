@@ -0,0 +1,144 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// parseDocument parses YAML into a document node to keep for Save, or
+// returns nil if data isn't a mapping
+func parseDocument(data []byte) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return &doc
+}
+
+// mergeNode updates dst in place to hold the values of src while keeping
+// the comments, key order, and styles of dst wherever a value is unchanged.
+// Mapping keys missing from src are removed and new ones appended, unless
+// src has the same value as base, the values dst was loaded as. That keeps
+// defaults the file never spelled out from being written. base may be nil.
+func mergeNode(dst, src, base *yaml.Node) {
+	if dst.Kind != src.Kind {
+		replaceNode(dst, src)
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.DocumentNode:
+		mergeNode(dst.Content[0], src.Content[0], base)
+
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			key := dst.Content[i].Value
+			if v := mappingValue(src, key); v != nil {
+				mergeNode(dst.Content[i+1], v, mappingValue(base, key))
+				content = append(content, dst.Content[i], dst.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, v := src.Content[i].Value, src.Content[i+1]
+			if mappingValue(dst, key) == nil && !equalNode(v, mappingValue(base, key)) {
+				content = append(content, src.Content[i], v)
+			}
+		}
+		dst.Content = content
+
+	case yaml.SequenceNode:
+		// Match items by identity rather than position, so removing an
+		// item doesn't shift the comments of those after it
+		used := make([]bool, len(dst.Content))
+		content := make([]*yaml.Node, 0, len(src.Content))
+		for i, item := range src.Content {
+			j := matchItem(dst.Content, used, item, i)
+			if j < 0 {
+				content = append(content, item)
+				continue
+			}
+			used[j] = true
+			mergeNode(dst.Content[j], item, nil)
+			content = append(content, dst.Content[j])
+		}
+		dst.Content = content
+
+	case yaml.ScalarNode:
+		if dst.Value != src.Value {
+			replaceNode(dst, src)
+		}
+
+	default:
+		replaceNode(dst, src)
+	}
+}
+
+// replaceNode overwrites dst with src, keeping the comments of dst
+func replaceNode(dst, src *yaml.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+}
+
+// equalNode reports whether a and b hold the same value
+func equalNode(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !equalNode(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// matchItem finds the unused item of items that item updates: the one with
+// the same identity, or failing that the one at the same index if it has
+// no identity. Returns -1 for a new item.
+func matchItem(items []*yaml.Node, used []bool, item *yaml.Node, index int) int {
+	if id := itemIdentity(item); id != "" {
+		for j, old := range items {
+			if !used[j] && old.Kind == item.Kind && itemIdentity(old) == id {
+				return j
+			}
+		}
+	}
+	if index < len(items) && !used[index] && itemIdentity(items[index]) == "" {
+		return index
+	}
+	return -1
+}
+
+// itemIdentity identifies a sequence item across saves: a scalar by its
+// value and a repo by its path
+func itemIdentity(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value
+	case yaml.MappingNode:
+		if v := mappingValue(node, "path"); v != nil {
+			return v.Value
+		}
+	}
+	return ""
+}
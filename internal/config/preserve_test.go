@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const commentedConfig = `# Platform services
+repos:
+  # The API, owned by the backend team
+  - url: git@github.com:org/api.git
+    path: api # inline comment
+
+  - url: git@github.com:org/web.git
+    path: web
+
+settings:
+  # Keep it quiet
+  parallel: false
+`
+
+func TestSavePreservesComments(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := os.WriteFile(path, []byte(commentedConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddRepo(RepoConfig{URL: "git@github.com:org/docs.git", Path: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, comment := range []string{
+		"# Platform services",
+		"# The API, owned by the backend team",
+		"# inline comment",
+		"# Keep it quiet",
+	} {
+		if !strings.Contains(saved, comment) {
+			t.Errorf("saved config lost %q:\n%s", comment, saved)
+		}
+	}
+	if !strings.Contains(saved, "path: docs") {
+		t.Errorf("saved config is missing the added repo:\n%s", saved)
+	}
+	if api, web := strings.Index(saved, "path: api"), strings.Index(saved, "path: web"); api < 0 || web < api {
+		t.Errorf("saved config changed the repo order:\n%s", saved)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Repos) != 3 || reloaded.Repos[2].Path != "docs" {
+		t.Errorf("reloaded repos = %+v, want api, web, docs", reloaded.Repos)
+	}
+	if reloaded.Settings.Parallel {
+		t.Error("reloaded settings.parallel = true, want the file's false")
+	}
+}
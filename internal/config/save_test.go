@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFiles writes files into a new directory and returns it
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// addAndReload adds a repo to the config at path, saves it, and loads it
+// again, returning the reloaded config and the saved file
+func addAndReload(t *testing.T, path string, change func(*Config)) (*Config, string) {
+	t.Helper()
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddRepo(RepoConfig{URL: "git@github.com:org/docs.git", Path: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+	if change != nil {
+		change(cfg)
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reloaded, string(data)
+}
+
+func TestSaveKeepsIncludedSettings(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		main  string
+	}{
+		{
+			name: "yaml",
+			files: map[string]string{
+				"base.yml":     "repos:\n  - url: git@github.com:org/api.git\n    path: api\nsettings:\n  parallel: false\n  default_branch: develop\n  clone:\n    skip_forbidden: true\n",
+				"mergeish.yml": "include: [base.yml]\nrepos:\n  - url: git@github.com:org/web.git\n    path: web\n",
+			},
+			main: "mergeish.yml",
+		},
+		{
+			name: "toml",
+			files: map[string]string{
+				"base.toml":     "[[repos]]\nurl = \"git@github.com:org/api.git\"\npath = \"api\"\n\n[settings]\nparallel = false\ndefault_branch = \"develop\"\n\n[settings.clone]\nskip_forbidden = true\n",
+				"mergeish.toml": "include = [\"base.toml\"]\n\n[[repos]]\nurl = \"git@github.com:org/web.git\"\npath = \"web\"\n",
+			},
+			main: "mergeish.toml",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			dir := writeConfigFiles(t, tt.files)
+			reloaded, saved := addAndReload(t, filepath.Join(dir, tt.main), nil)
+
+			if strings.Contains(saved, "settings") {
+				t.Errorf("saved config has settings the file didn't set:\n%s", saved)
+			}
+			s := reloaded.Settings
+			if s.Parallel || s.DefaultBranch != "develop" || !s.Clone.SkipForbidden {
+				t.Errorf("reloaded parallel = %v, default_branch = %q, clone.skip_forbidden = %v; want the included false, develop, true\n%s",
+					s.Parallel, s.DefaultBranch, s.Clone.SkipForbidden, saved)
+			}
+		})
+	}
+}
+
+func TestSaveKeepsGlobalSettings(t *testing.T) {
+	for _, name := range []string{"mergeish.yml", "mergeish.toml"} {
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", home)
+			if err := os.MkdirAll(filepath.Join(home, "mergeish"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			global := "settings:\n  gh_rate_limit: 2\n  retries: 3\n"
+			if err := os.WriteFile(filepath.Join(home, "mergeish", "config.yml"), []byte(global), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			main := "repos:\n  - url: git@github.com:org/api.git\n    path: api\nsettings:\n  retries: 1\n"
+			if name == "mergeish.toml" {
+				main = "[[repos]]\nurl = \"git@github.com:org/api.git\"\npath = \"api\"\n\n[settings]\nretries = 1\n"
+			}
+			dir := writeConfigFiles(t, map[string]string{name: main})
+			reloaded, saved := addAndReload(t, filepath.Join(dir, name), nil)
+
+			if strings.Contains(saved, "gh_rate_limit") {
+				t.Errorf("saved config has gh_rate_limit, which only the global config sets:\n%s", saved)
+			}
+			if reloaded.Settings.GHRateLimit != 2 {
+				t.Errorf("reloaded gh_rate_limit = %v, want the global 2", reloaded.Settings.GHRateLimit)
+			}
+			if reloaded.Settings.Retries != 1 {
+				t.Errorf("reloaded retries = %d, want the file's 1", reloaded.Settings.Retries)
+			}
+		})
+	}
+}
+
+func TestSaveWritesChangedSettings(t *testing.T) {
+	for _, name := range []string{"mergeish.yml", "mergeish.toml"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			main := "repos:\n  - url: git@github.com:org/api.git\n    path: api\n"
+			if name == "mergeish.toml" {
+				main = "[[repos]]\nurl = \"git@github.com:org/api.git\"\npath = \"api\"\n"
+			}
+			dir := writeConfigFiles(t, map[string]string{name: main})
+			reloaded, saved := addAndReload(t, filepath.Join(dir, name), func(cfg *Config) {
+				cfg.Settings.DefaultBranch = "trunk"
+			})
+
+			if !strings.Contains(saved, "trunk") {
+				t.Errorf("saved config is missing the changed default_branch:\n%s", saved)
+			}
+			for _, key := range []string{"parallel", "gh_rate_limit", "uncloned_policy", "protected_branches"} {
+				if strings.Contains(saved, key) {
+					t.Errorf("saved config has the default %s:\n%s", key, saved)
+				}
+			}
+			if reloaded.Settings.DefaultBranch != "trunk" {
+				t.Errorf("reloaded default_branch = %q, want trunk", reloaded.Settings.DefaultBranch)
+			}
+		})
+	}
+}
+
+func TestSaveNewConfigWritesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := DefaultConfig().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "default_branch: main") {
+		t.Errorf("new config is missing the default settings:\n%s", data)
+	}
+}
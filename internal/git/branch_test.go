@@ -0,0 +1,22 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergedBranchesDetachedHead(t *testing.T) {
+	g := testRepo(t)
+	runGit(t, g.dir, "branch", "done")
+	runGit(t, g.dir, "checkout", "-b", "wip")
+	runGit(t, g.dir, "commit", "--allow-empty", "-m", "wip")
+	runGit(t, g.dir, "checkout", "--detach", "main")
+
+	merged, err := g.MergedBranches("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"done", "main"}; !slices.Equal(merged, want) {
+		t.Errorf("MergedBranches on a detached HEAD = %q, want %q", merged, want)
+	}
+}
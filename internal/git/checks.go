@@ -0,0 +1,101 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Values for PRCheck.Status, as gh groups check states
+const (
+	CheckPass     = "pass"
+	CheckFail     = "fail"
+	CheckPending  = "pending"
+	CheckSkipping = "skipping"
+	CheckCancel   = "cancel"
+)
+
+// PRCheck is one CI check on a pull request
+type PRCheck struct {
+	Name       string
+	Workflow   string // the GitHub Actions workflow, if any
+	Status     string // one of the Check constants
+	Conclusion string // the detailed state, e.g. "SUCCESS", "TIMED_OUT", or "IN_PROGRESS"
+	Link       string
+	Required   bool // branch protection requires it to pass
+}
+
+// prCheckJSON is a check as reported by gh pr checks --json
+type prCheckJSON struct {
+	Name     string `json:"name"`
+	Workflow string `json:"workflow"`
+	State    string `json:"state"`
+	Bucket   string `json:"bucket"`
+	Link     string `json:"link"`
+}
+
+// GetPRChecks returns the CI checks of the pull request for the current
+// branch, or nil if it has none
+func (g *Git) GetPRChecks() ([]PRCheck, error) {
+	all, err := g.prChecks()
+	if err != nil || len(all) == 0 {
+		return nil, err
+	}
+	required, err := g.prChecks("--required")
+	if err != nil {
+		return nil, err
+	}
+
+	isRequired := make(map[string]bool)
+	for _, c := range required {
+		isRequired[c.Name] = true
+	}
+	checks := make([]PRCheck, len(all))
+	for i, c := range all {
+		checks[i] = PRCheck{
+			Name:       c.Name,
+			Workflow:   c.Workflow,
+			Status:     c.Bucket,
+			Conclusion: c.State,
+			Link:       c.Link,
+			Required:   isRequired[c.Name],
+		}
+	}
+	return checks, nil
+}
+
+// prChecks runs gh pr checks with args. gh exits nonzero when checks fail
+// or are pending, so its output counts whenever it is there.
+func (g *Git) prChecks(args ...string) ([]prCheckJSON, error) {
+	args = append([]string{"pr", "checks", "--json", "name,workflow,state,bucket,link"}, args...)
+	stdout, stderr, err := runGH(g.context(), g.dir, args...)
+	if err != nil && strings.TrimSpace(stdout) == "" {
+		if strings.Contains(stderr, "no checks reported") || strings.Contains(stderr, "no required checks reported") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gh pr checks: %w: %s", err, stderr)
+	}
+
+	var checks []prCheckJSON
+	if err := json.Unmarshal([]byte(stdout), &checks); err != nil {
+		return nil, fmt.Errorf("parsing gh output: %w", err)
+	}
+	return checks, nil
+}
+
+// FailedChecks returns the checks that fail a PR: the failed required
+// checks, or every failed check if none are required
+func FailedChecks(checks []PRCheck) []PRCheck {
+	anyRequired := false
+	for _, c := range checks {
+		anyRequired = anyRequired || c.Required
+	}
+
+	var failed []PRCheck
+	for _, c := range checks {
+		if (c.Status == CheckFail || c.Status == CheckCancel) && (c.Required || !anyRequired) {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
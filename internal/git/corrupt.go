@@ -0,0 +1,131 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CorruptError is returned when git fails because the clone itself is
+// damaged, e.g. by a crash or a full disk, rather than because of what was
+// asked of it. Once a command fails with one, every later command on the
+// same Git returns it without running git, so a damaged clone reports one
+// short error per operation instead of a fatal error per git command.
+type CorruptError struct {
+	Dir     string // the clone's directory
+	Problem string // what is damaged, e.g. "a stale lock file"
+	Fix     string // how to repair it in place, or "" if re-cloning is the only fix
+	Err     error  // the git command that found it
+}
+
+func (e *CorruptError) Error() string {
+	return "damaged clone: " + e.Problem
+}
+
+func (e *CorruptError) Unwrap() error { return e.Err }
+
+// corruptSignatures are lowercase substrings of git output that mean the
+// clone is damaged; every substring of an entry must appear
+var corruptSignatures = []struct {
+	match   []string
+	problem string
+	fix     string
+}{
+	{[]string{"loose object", "is corrupt"}, "a corrupt object", fixFsck},
+	{[]string{"packed object", "is corrupt"}, "a corrupt object", fixFsck},
+	{[]string{"object file", "is empty"}, "an empty object file", fixFsck},
+	{[]string{"inflate: data stream error"}, "a corrupt object", fixFsck},
+	{[]string{"bad object head"}, "HEAD points to a missing object", fixFsck},
+	{[]string{"your current branch appears to be broken"}, "HEAD points to a missing object", fixFsck},
+	{[]string{"index file corrupt"}, "a corrupt index", fixIndex},
+	{[]string{"index file smaller than expected"}, "a corrupt index", fixIndex},
+	{[]string{"bad signature 0x"}, "a corrupt index", fixIndex},
+}
+
+const (
+	fixFsck  = "run git fsck in it to see what is damaged"
+	fixIndex = "run rm .git/index && git reset in it to rebuild the index; the working tree is kept"
+)
+
+// corruption returns a CorruptError if the stderr of a failed git command
+// in dir shows the clone is damaged, or nil. A lock file counts only once
+// err is a stale LockError.
+func corruption(dir, stderr string, err error) *CorruptError {
+	var lock *LockError
+	if errors.As(err, &lock) {
+		if !lock.Stale {
+			return nil
+		}
+		return &CorruptError{
+			Dir:     dir,
+			Problem: fmt.Sprintf("a stale lock file %s, created %s ago", lock.Path, lock.Age.Round(time.Second)),
+			Fix:     "if no git command is running in it, run rm " + lock.Path,
+			Err:     err,
+		}
+	}
+
+	msg := strings.ToLower(stderr)
+	for _, sig := range corruptSignatures {
+		matched := true
+		for _, s := range sig.match {
+			if !strings.Contains(msg, s) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &CorruptError{Dir: dir, Problem: sig.problem, Fix: sig.fix, Err: err}
+		}
+	}
+
+	// Outside a clone this is expected; with a .git there it is damaged
+	if dir != "" && strings.Contains(msg, "not a git repository") {
+		if info, statErr := os.Stat(filepath.Join(dir, ".git")); statErr == nil {
+			if target := worktreeGitDir(dir); !info.IsDir() && target != "" {
+				if _, statErr := os.Stat(target); statErr != nil {
+					return &CorruptError{
+						Dir:     dir,
+						Problem: "a linked worktree whose git directory " + target + " is missing",
+						Fix:     "if its main repository moved, run git worktree repair " + dir + " in it",
+						Err:     err,
+					}
+				}
+			}
+			return &CorruptError{Dir: dir, Problem: "a .git that git doesn't recognize", Err: err}
+		}
+	}
+	return nil
+}
+
+// worktreeGitDir returns the git directory the .git file of a linked
+// worktree in dir points to, or "" if .git isn't such a file
+func worktreeGitDir(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	return target
+}
+
+// quarantine holds the CorruptError that stops further commands on a clone.
+// It is shared by the copies WithContext makes.
+type quarantine struct {
+	err atomic.Pointer[CorruptError]
+}
+
+// Corrupt returns the error that found the clone damaged, or nil if no
+// command has
+func (g *Git) Corrupt() *CorruptError {
+	return g.quarantine.err.Load()
+}
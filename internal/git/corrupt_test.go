@@ -0,0 +1,164 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorruptionSignatures(t *testing.T) {
+	failed := errors.New("exit status 128")
+	for _, tt := range []struct {
+		stderr  string
+		problem string // "" for not a corruption
+	}{
+		{"error: inflate: data stream error (incorrect header check)\nerror: unable to unpack 1234 header", "a corrupt object"},
+		{"fatal: loose object 0123abcd (stored in .git/objects/01/23abcd) is corrupt", "a corrupt object"},
+		{"error: packed object 0123abcd (stored in .git/objects/pack/pack-1.pack) is corrupt", "a corrupt object"},
+		{"error: object file .git/objects/01/23abcd is empty", "an empty object file"},
+		{"fatal: bad object HEAD", "HEAD points to a missing object"},
+		{"fatal: your current branch appears to be broken", "HEAD points to a missing object"},
+		{"error: bad signature 0x00000000\nfatal: index file corrupt", "a corrupt index"},
+		{"fatal: index file smaller than expected", "a corrupt index"},
+		{"fatal: Index File Corrupt", "a corrupt index"},
+		{"error: pathspec 'nope' did not match any file(s) known to git", ""},
+		{"fatal: couldn't find remote ref feature", ""},
+		{"fatal: the packed object is fine", ""},
+	} {
+		got := corruption(t.TempDir(), tt.stderr, failed)
+		switch {
+		case tt.problem == "" && got != nil:
+			t.Errorf("corruption(%q) = %q, want none", tt.stderr, got.Problem)
+		case tt.problem != "" && got == nil:
+			t.Errorf("corruption(%q) = nil, want %q", tt.stderr, tt.problem)
+		case got != nil && got.Problem != tt.problem:
+			t.Errorf("corruption(%q) = %q, want %q", tt.stderr, got.Problem, tt.problem)
+		case got != nil && !errors.Is(got, failed):
+			t.Errorf("corruption(%q) doesn't wrap the git error", tt.stderr)
+		}
+	}
+}
+
+func TestCorruptionNotARepository(t *testing.T) {
+	stderr := "fatal: not a git repository (or any of the parent directories): .git"
+
+	if got := corruption(t.TempDir(), stderr, errors.New("failed")); got != nil {
+		t.Errorf("without a .git: %q, want none", got.Problem)
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := corruption(dir, stderr, errors.New("failed")); got == nil || got.Problem != "a .git that git doesn't recognize" {
+		t.Errorf("with a bogus .git directory: %v", got)
+	}
+
+	dir = t.TempDir()
+	missing := filepath.Join(t.TempDir(), "moved", ".git", "worktrees", "wt")
+	if err := os.WriteFile(filepath.Join(dir, ".git"), []byte("gitdir: "+missing+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := corruption(dir, stderr, errors.New("failed"))
+	if got == nil || !strings.Contains(got.Problem, "linked worktree") || !strings.Contains(got.Fix, "git worktree repair") {
+		t.Errorf("with a worktree .git pointing nowhere: %v", got)
+	}
+}
+
+func TestCorruptionLocks(t *testing.T) {
+	live := &LockError{Path: "/repo/.git/index.lock", Age: time.Second}
+	if got := corruption("/repo", "", live); got != nil {
+		t.Errorf("a lock held by a running git = %q, want none", got.Problem)
+	}
+
+	stale := &LockError{Path: "/repo/.git/index.lock", Age: time.Hour, Stale: true}
+	got := corruption("/repo", "", stale)
+	if got == nil || !strings.Contains(got.Problem, "stale lock file") || !strings.Contains(got.Fix, "rm /repo/.git/index.lock") {
+		t.Fatalf("a stale lock = %v, want a stale lock file problem", got)
+	}
+	var lock *LockError
+	if !errors.As(got, &lock) {
+		t.Error("the CorruptError of a stale lock doesn't wrap the LockError")
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	g := testRepo(t)
+	index := filepath.Join(g.dir, ".git", "index")
+	good, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(index, []byte("garbage that isn't an index"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = g.run("status", "--porcelain")
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || corrupt.Problem != "a corrupt index" {
+		t.Fatalf("git status on a garbage index = %v, want a corrupt index", err)
+	}
+	if g.Corrupt() != corrupt {
+		t.Error("Corrupt() doesn't return the error that found the damage")
+	}
+
+	// Even once repaired, the clone stays quarantined for this Git, and so
+	// for the copies WithContext makes
+	if err := os.WriteFile(index, good, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]*Git{"same": g, "WithContext": g.WithContext(context.Background())} {
+		if _, err := c.run("rev-parse", "HEAD"); !errors.As(err, &corrupt) {
+			t.Errorf("%s: a later command = %v, want the CorruptError", name, err)
+		}
+	}
+
+	// A fresh Git on the repaired clone works
+	if _, err := New(g.dir).run("status", "--porcelain"); err != nil {
+		t.Errorf("a new Git on the repaired clone: %v", err)
+	}
+}
+
+func TestQuarantineStaleLock(t *testing.T) {
+	if _, err := os.Stat("/proc"); err != nil {
+		t.Skip("no /proc to rule out a running git")
+	}
+	g := testRepo(t)
+	lock := filepath.Join(g.dir, ".git", "index.lock")
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * StaleLockAge)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, g.dir, "new.txt", "new\n")
+
+	_, err := g.run("add", "new.txt")
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || !strings.Contains(corrupt.Problem, "stale lock file") {
+		t.Fatalf("git add with an old index.lock = %v, want a stale lock file", err)
+	}
+}
+
+func TestFreshLockIsNotCorruption(t *testing.T) {
+	g := testRepo(t)
+	lock := filepath.Join(g.dir, ".git", "index.lock")
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, g.dir, "new.txt", "new\n")
+
+	_, err := g.run("add", "new.txt")
+	var lockErr *LockError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("git add with a new index.lock = %v, want a LockError", err)
+	}
+	if g.Corrupt() != nil {
+		t.Error("a fresh lock file quarantined the clone")
+	}
+}
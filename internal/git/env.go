@@ -0,0 +1,59 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Version returns the installed git version, e.g. "2.43.0"
+func Version() (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("not found on PATH")
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := execute(context.Background(), "", "git", []string{"--version"}, &stdout, &stderr); err != nil {
+		return "", fmt.Errorf("git --version: %w: %s", err, stderr.String())
+	}
+	return strings.TrimPrefix(strings.TrimSpace(stdout.String()), "git version "), nil
+}
+
+// GHVersion returns the installed gh version, e.g. "2.40.1"
+func GHVersion() (string, error) {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return "", fmt.Errorf("not found on PATH")
+	}
+
+	stdout, stderr, err := runGH(context.Background(), "", "--version")
+	if err != nil {
+		return "", fmt.Errorf("gh --version: %w: %s", err, stderr)
+	}
+
+	// First line looks like "gh version 2.40.1 (2023-12-13)"
+	line, _, _ := strings.Cut(stdout, "\n")
+	fields := strings.Fields(line)
+	if len(fields) >= 3 {
+		return fields[2], nil
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// GHAuthStatus returns an error if gh is not authenticated
+func GHAuthStatus() error {
+	if _, stderr, err := runGH(context.Background(), "", "auth", "status"); err != nil {
+		return fmt.Errorf("gh auth status: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// LsRemote checks that a remote URL is reachable
+func LsRemote(url string) error {
+	var stderr bytes.Buffer
+	if err := execute(context.Background(), "", "git", []string{"ls-remote", url, "HEAD"}, nil, &stderr); err != nil {
+		return fmt.Errorf("git ls-remote: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
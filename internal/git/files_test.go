@@ -0,0 +1,54 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+// quotedPathRepo returns a repo whose tracked files have names git quotes
+// under core.quotePath
+func quotedPathRepo(t *testing.T) *Git {
+	t.Helper()
+	g := testRepo(t)
+	runGit(t, g.dir, "config", "core.quotePath", "true")
+	for _, name := range []string{"héllo.go", "plain.go", " lead.go", "tab\tname.go", "notes.md"} {
+		writeTestFile(t, g.dir, name, "package x // needle\n")
+	}
+	runGit(t, g.dir, "add", ".")
+	runGit(t, g.dir, "commit", "-m", "files")
+	return g
+}
+
+func TestGrepFilesUnusualNames(t *testing.T) {
+	g := quotedPathRepo(t)
+	files, err := g.GrepFiles("needle", false, "*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{" lead.go", "héllo.go", "plain.go", "tab\tname.go"}
+	if !slices.Equal(files, want) {
+		t.Errorf("GrepFiles = %q, want %q", files, want)
+	}
+
+	files, err = g.GrepFiles("absent", false)
+	if err != nil || files != nil {
+		t.Errorf("GrepFiles without a match = %q, %v; want none", files, err)
+	}
+}
+
+func TestListFilesUnusualNames(t *testing.T) {
+	g := quotedPathRepo(t)
+	files, err := g.ListFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{" lead.go", "héllo.go", "notes.md", "plain.go", "tab\tname.go"}
+	if !slices.Equal(files, want) {
+		t.Errorf("ListFiles = %q, want %q", files, want)
+	}
+
+	files, err = g.ListFiles("*.txt")
+	if err != nil || files != nil {
+		t.Errorf("ListFiles without a match = %q, %v; want none", files, err)
+	}
+}
@@ -0,0 +1,201 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGHRateLimit is the default number of gh invocations allowed per second
+	DefaultGHRateLimit = 5.0
+
+	ghRateLimitBackoff    = 30 * time.Second
+	ghRateLimitMaxRetries = 3
+)
+
+// ghGate is a process-wide gate for gh invocations. It paces calls with a
+// token bucket, backs off when GitHub reports a rate limit, and counts calls
+// for reporting.
+type ghGate struct {
+	mu sync.Mutex
+
+	rate   float64 // tokens per second, 0 disables limiting
+	tokens float64
+	last   time.Time
+
+	blockedUntil time.Time // set while backing off after a rate-limit error
+
+	calls   map[string]int
+	retries int
+	waited  time.Duration
+}
+
+var gh = &ghGate{
+	rate:   DefaultGHRateLimit,
+	tokens: DefaultGHRateLimit,
+	calls:  make(map[string]int),
+}
+
+// GHMetrics summarizes gh usage for the current process
+type GHMetrics struct {
+	Calls   map[string]int // calls keyed by gh subcommand, e.g. "pr list"
+	Total   int
+	Retries int
+	Waited  time.Duration
+}
+
+// SetGHRateLimit sets the maximum number of gh invocations per second shared
+// across all repos. A value <= 0 disables limiting.
+func SetGHRateLimit(perSecond float64) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	gh.rate = perSecond
+	gh.tokens = gh.capacity()
+	gh.last = time.Time{}
+}
+
+// capacity returns how many tokens the bucket holds when full. It holds at
+// least one, or a rate below 1 per second would never allow a call.
+func (g *ghGate) capacity() float64 {
+	return max(1, g.rate)
+}
+
+// GetGHMetrics returns a snapshot of gh usage so far
+func GetGHMetrics() GHMetrics {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	m := GHMetrics{
+		Calls:   make(map[string]int, len(gh.calls)),
+		Retries: gh.retries,
+		Waited:  gh.waited,
+	}
+	for k, v := range gh.calls {
+		m.Calls[k] = v
+		m.Total += v
+	}
+	return m
+}
+
+// String formats the metrics as a single summary line
+func (m GHMetrics) String() string {
+	if m.Total == 0 {
+		return "gh calls: 0"
+	}
+
+	keys := make([]string, 0, len(m.Calls))
+	for k := range m.Calls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", k, m.Calls[k])
+	}
+
+	s := fmt.Sprintf("gh calls: %d (%s)", m.Total, strings.Join(parts, ", "))
+	if m.Retries > 0 {
+		s += fmt.Sprintf(", rate-limit retries: %d", m.Retries)
+	}
+	if m.Waited > 0 {
+		s += fmt.Sprintf(", waited: %s", m.Waited.Round(time.Millisecond))
+	}
+	return s
+}
+
+// wait blocks until a token is available and no rate-limit backoff is active
+func (g *ghGate) wait() {
+	for {
+		g.mu.Lock()
+		now := time.Now()
+
+		if now.Before(g.blockedUntil) {
+			d := g.blockedUntil.Sub(now)
+			g.waited += d
+			g.mu.Unlock()
+			time.Sleep(d)
+			continue
+		}
+
+		if g.rate <= 0 {
+			g.mu.Unlock()
+			return
+		}
+
+		if !g.last.IsZero() {
+			g.tokens += now.Sub(g.last).Seconds() * g.rate
+			if g.tokens > g.capacity() {
+				g.tokens = g.capacity()
+			}
+		}
+		g.last = now
+
+		if g.tokens >= 1 {
+			g.tokens--
+			g.mu.Unlock()
+			return
+		}
+
+		d := time.Duration((1 - g.tokens) / g.rate * float64(time.Second))
+		g.waited += d
+		g.mu.Unlock()
+		time.Sleep(d)
+	}
+}
+
+// backoff records a rate-limit hit. Only the first caller to hit the limit
+// while no backoff is active prints a message, so parallel repos produce a
+// single notice.
+func (g *ghGate) backoff(attempt int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.retries++
+	if time.Now().Before(g.blockedUntil) {
+		return
+	}
+
+	d := ghRateLimitBackoff << attempt
+	g.blockedUntil = time.Now().Add(d)
+	fmt.Fprintf(os.Stderr, "rate limited by GitHub, retrying in %s\n", d)
+}
+
+func (g *ghGate) count(args []string) {
+	key := strings.Join(args[:min(2, len(args))], " ")
+
+	g.mu.Lock()
+	g.calls[key]++
+	g.mu.Unlock()
+}
+
+// isRateLimited reports whether gh stderr indicates a GitHub rate limit
+func isRateLimited(stderr string) bool {
+	s := strings.ToLower(stderr)
+	return strings.Contains(s, "rate limit") ||
+		strings.Contains(s, "was submitted too quickly")
+}
+
+// runGH executes a gh command in dir through the shared gate, retrying with
+// exponential backoff when GitHub reports a rate limit
+func runGH(ctx context.Context, dir string, args ...string) (stdout, stderr string, err error) {
+	for attempt := 0; ; attempt++ {
+		gh.wait()
+		gh.count(args)
+
+		var outBuf, errBuf bytes.Buffer
+		err = execute(ctx, dir, "gh", args, &outBuf, &errBuf)
+		if err == nil || !isRateLimited(errBuf.String()) || attempt >= ghRateLimitMaxRetries {
+			return outBuf.String(), errBuf.String(), err
+		}
+
+		gh.backoff(attempt)
+	}
+}
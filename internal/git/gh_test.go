@@ -0,0 +1,54 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGHGateSlowRateAllowsFirstCall(t *testing.T) {
+	g := &ghGate{rate: 0.5, calls: make(map[string]int)}
+	g.tokens = g.capacity()
+
+	done := make(chan struct{})
+	go func() {
+		g.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("wait blocked with a rate below 1 per second")
+	}
+}
+
+func TestGHGateCapacity(t *testing.T) {
+	for _, tt := range []struct {
+		rate float64
+		want float64
+	}{
+		{0.5, 1},
+		{1, 1},
+		{5, 5},
+	} {
+		g := &ghGate{rate: tt.rate}
+		if got := g.capacity(); got != tt.want {
+			t.Errorf("capacity at rate %v = %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestPickPRPrefersOpen(t *testing.T) {
+	prs := []prJSON{
+		{Number: 3, State: "CLOSED"},
+		{Number: 2, State: "OPEN"},
+		{Number: 1, State: "MERGED"},
+	}
+	if got := pickPR(prs).Number; got != 2 {
+		t.Errorf("pickPR = #%d, want the open #2", got)
+	}
+
+	prs = []prJSON{{Number: 5, State: "MERGED"}, {Number: 4, State: "CLOSED"}}
+	if got := pickPR(prs).Number; got != 5 {
+		t.Errorf("pickPR without an open PR = #%d, want the newest #5", got)
+	}
+}
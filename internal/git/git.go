@@ -2,11 +2,17 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Status represents the status of a git repository
@@ -17,95 +23,221 @@ type Status struct {
 	Ahead         int
 	Behind        int
 	Files         []FileStatus
+	Detached      bool    // HEAD is not on a branch; Branch is the one being rebased, if any
+	State         string  // an operation stopped midway, e.g. StateRebase, or ""
+	Upstream      string  // upstream of Branch, e.g. "origin/feature-x", or "" if none is set
+	Remote        string  // remote of Upstream, or "" for none or a local upstream
+	LastCommit    *Commit // commit HEAD points to, if asked for; nil in a repo without commits
+	Worktree      bool    // the repo is a linked worktree of another repository
 }
 
+// Operations a repo can be stopped in the middle of, for Status.State
+const (
+	StateRebase     = "rebase"
+	StateMerge      = "merge"
+	StateCherryPick = "cherry-pick"
+	StateRevert     = "revert"
+	StateBisect     = "bisect"
+)
+
 // FileStatus represents the status of a single file
 type FileStatus struct {
-	Path   string
-	Status string // "M", "A", "D", "??" etc.
+	Path       string
+	OrigPath   string // path before a rename or copy
+	Status     string // "M", "A", "D", "R", "??" etc.
+	Index      byte   // status in the index, '.' if unchanged, '?' if untracked
+	Worktree   byte   // status in the working tree, '.' if unchanged, '?' if untracked
+	Submodule  bool
+	Conflicted bool // unmerged after a merge or rebase stopped on a conflict
 }
 
+// DefaultRemote is the primary remote name unless configured otherwise
+const DefaultRemote = "origin"
+
 // Git provides git operations for a specific directory
 type Git struct {
-	dir string
+	dir    string
+	remote string          // primary remote, used for pushing and base detection
+	ctx    context.Context // cancels or times out every command, if set
+
+	quarantine *quarantine // set once a command finds the clone damaged
 }
 
 // New creates a new Git instance for the given directory
 func New(dir string) *Git {
-	return &Git{dir: dir}
+	return NewWithRemote(dir, DefaultRemote)
+}
+
+// NewWithRemote creates a new Git instance whose primary remote is remote
+func NewWithRemote(dir, remote string) *Git {
+	return &Git{dir: dir, remote: remote, quarantine: &quarantine{}}
+}
+
+// WithContext returns a copy of g whose commands are killed once ctx is
+// done. A command stopped by the deadline of ctx fails with ErrTimeout.
+func (g *Git) WithContext(ctx context.Context) *Git {
+	c := *g
+	c.ctx = ctx
+	return &c
 }
 
-// run executes a git command and returns stdout
+// context returns the context commands run with
+func (g *Git) context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// Remote returns the primary remote name
+func (g *Git) Remote() string {
+	return g.remote
+}
+
+// run executes a git command and returns stdout. A lock file in the way
+// fails it with a LockError. In a clone found to be damaged, it returns the
+// CorruptError without running git.
 func (g *Git) run(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = g.dir
+	if corrupt := g.Corrupt(); corrupt != nil {
+		return "", corrupt
+	}
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, stderr.String())
+	if err := execute(g.context(), g.dir, "git", args, &stdout, &stderr); err != nil {
+		err = fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, stderr.String())
+		if lock := lockFailure(g.dir, stderr.String(), err); lock != nil {
+			if !lock.Stale {
+				return "", lock
+			}
+			err = lock
+		}
+		if corrupt := corruption(g.dir, stderr.String(), err); corrupt != nil {
+			g.quarantine.err.CompareAndSwap(nil, corrupt)
+			return "", g.quarantine.err.Load()
+		}
+		return "", err
 	}
 
 	return strings.TrimSpace(stdout.String()), nil
 }
 
-// Clone clones a repository to the target directory
-func Clone(url, targetDir string) error {
-	cmd := exec.Command("git", "clone", url, targetDir)
+// Clone clones a repository to the target directory, naming the remote
+// remote instead of origin if given
+func Clone(url, targetDir, remote string) error {
+	_, statErr := os.Stat(targetDir)
+	existed := statErr == nil
 
 	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+	args := []string{"clone"}
+	if remote != "" && remote != DefaultRemote {
+		args = append(args, "--origin", remote)
+	}
+	args = append(args, url, targetDir)
+	if err := execute(context.Background(), "", "git", args, nil, &stderr); err != nil {
+		// A killed clone can't clean up after itself; don't leave a
+		// half-cloned directory that looks like a repo
+		if errors.Is(err, ErrTimeout) && !existed {
+			os.RemoveAll(targetDir)
+		}
 		return fmt.Errorf("git clone: %w: %s", err, stderr.String())
 	}
 
 	return nil
 }
 
+// permissionErrors are substrings of git/ssh output that indicate the user
+// cannot access a repository
+var permissionErrors = []string{
+	"permission denied",
+	"repository not found",
+	"could not read username",
+	"authentication failed",
+	"access denied",
+	"not authorized",
+	"the requested url returned error: 403",
+	"the requested url returned error: 401",
+}
+
+// IsPermissionError reports whether err looks like an authentication or
+// authorization failure rather than some other problem
+func IsPermissionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range permissionErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // CurrentBranch returns the current branch name
 func (g *Git) CurrentBranch() (string, error) {
 	return g.run("rev-parse", "--abbrev-ref", "HEAD")
 }
 
+// headBranchKey is the per-branch git config key PushRefSpec records the
+// remote branch name in when it pushes a branch under another name
+const headBranchKey = "mergeishHead"
+
+// HeadBranch returns the remote branch that PRs for the current branch
+// come from: the one a push with a refspec named differently, if it is
+// still the branch's upstream on the primary remote, or else the current
+// branch. An upstream set any other way, such as a branch started from
+// origin/main, doesn't count.
+func (g *Git) HeadBranch() (string, error) {
+	branch, err := g.CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	head, _ := g.run("config", "--get", "branch."+branch+"."+headBranchKey)
+	if head == "" {
+		return branch, nil
+	}
+	remote, _ := g.run("config", "--get", "branch."+branch+".remote")
+	merge, _ := g.run("config", "--get", "branch."+branch+".merge")
+	if remote == g.remote && merge == "refs/heads/"+head {
+		return head, nil
+	}
+	return branch, nil
+}
+
+// HasUpstream reports whether the current branch has an upstream configured
+func (g *Git) HasUpstream() bool {
+	_, err := g.run("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	return err == nil
+}
+
 // Status returns the repository status
 func (g *Git) Status() (*Status, error) {
-	branch, err := g.CurrentBranch()
+	branch, detached, err := g.Branch()
 	if err != nil {
 		return nil, err
 	}
 
-	// Get porcelain status
-	output, err := g.run("status", "--porcelain")
+	files, err := g.fileStatuses()
 	if err != nil {
 		return nil, err
 	}
 
 	status := &Status{
-		Branch: branch,
+		Branch:     branch,
+		Detached:   detached,
+		State:      g.State(),
+		Files:      files,
+		HasChanges: len(files) > 0,
+		Worktree:   g.IsWorktree(),
 	}
-
-	// Parse file status
-	if output != "" {
-		lines := strings.Split(output, "\n")
-		for _, line := range lines {
-			if len(line) < 3 {
-				continue
-			}
-			fs := FileStatus{
-				Status: strings.TrimSpace(line[:2]),
-				Path:   line[3:],
-			}
-			status.Files = append(status.Files, fs)
-
-			// Check if staged
-			if line[0] != ' ' && line[0] != '?' {
-				status.StagedChanges = true
-			}
+	for _, f := range files {
+		if f.Index != '.' && f.Index != '?' {
+			status.StagedChanges = true
 		}
-		status.HasChanges = len(status.Files) > 0
+	}
+
+	if branch != "" {
+		status.Upstream, status.Remote = g.upstream(branch)
 	}
 
 	// Get ahead/behind
@@ -116,12 +248,238 @@ func (g *Git) Status() (*Status, error) {
 	return status, nil
 }
 
-// getAheadBehind returns how many commits ahead/behind the current branch is
+// upstream returns the upstream of branch and its remote, or empty strings
+// if none is configured or it no longer exists
+func (g *Git) upstream(branch string) (upstream, remote string) {
+	upstream, err := g.run("rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+	if err != nil {
+		return "", ""
+	}
+	remote, _ = g.run("config", "--get", "branch."+branch+".remote")
+	if remote == "." {
+		remote = ""
+	}
+	return upstream, remote
+}
+
+// fileStatuses returns the status of every changed or untracked file
+func (g *Git) fileStatuses() ([]FileStatus, error) {
+	output, err := g.run("status", "--porcelain=v2", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(output)
+}
+
+// parseStatus parses the output of git status --porcelain=v2 -z. Paths are
+// NUL-terminated, so they are never quoted, and a rename or copy entry is
+// followed by its original path.
+func parseStatus(output string) ([]FileStatus, error) {
+	var files []FileStatus
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if entry == "" {
+			continue
+		}
+
+		// Each kind has a fixed number of space-separated fields before
+		// the path, which may itself contain spaces
+		var f FileStatus
+		var parts []string
+		switch entry[0] {
+		case '1': // 1 XY sub mH mI mW hH hI path
+			parts = strings.SplitN(entry, " ", 9)
+		case '2': // 2 XY sub mH mI mW hH hI Xscore path, then origPath
+			parts = strings.SplitN(entry, " ", 10)
+			if i+1 < len(fields) {
+				i++
+				f.OrigPath = fields[i]
+			}
+		case 'u': // u XY sub m1 m2 m3 mW h1 h2 h3 path
+			parts = strings.SplitN(entry, " ", 11)
+			f.Conflicted = true
+		case '?':
+			files = append(files, FileStatus{Path: entry[2:], Status: "??", Index: '?', Worktree: '?'})
+			continue
+		default: // ignored files, and headers we didn't ask for
+			continue
+		}
+		if len(parts) < 9 || len(parts[1]) != 2 {
+			return nil, fmt.Errorf("unexpected git status entry %q", entry)
+		}
+
+		xy := parts[1]
+		f.Path = parts[len(parts)-1]
+		f.Index, f.Worktree = xy[0], xy[1]
+		f.Status = strings.TrimSpace(strings.ReplaceAll(xy, ".", " "))
+		f.Submodule = parts[2][0] == 'S'
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Branch returns the current branch. On a detached HEAD it reports detached
+// and returns the branch being rebased, or "" if there is none.
+func (g *Git) Branch() (branch string, detached bool, err error) {
+	if corrupt := g.Corrupt(); corrupt != nil {
+		return "", false, corrupt
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = execute(g.context(), g.dir, "git", []string{"symbolic-ref", "--quiet", "--short", "HEAD"}, &stdout, &stderr)
+	if err == nil {
+		return strings.TrimSpace(stdout.String()), false, nil
+	}
+
+	// Exit status 1 means HEAD is detached; anything else is a real error
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		return "", false, fmt.Errorf("git symbolic-ref HEAD: %w: %s", err, stderr.String())
+	}
+
+	if gitDir := g.gitDir(); gitDir != "" {
+		for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+			if data, err := os.ReadFile(filepath.Join(gitDir, dir, "head-name")); err == nil {
+				return strings.TrimPrefix(strings.TrimSpace(string(data)), "refs/heads/"), true, nil
+			}
+		}
+	}
+	return "", true, nil
+}
+
+// State returns the operation the repo is stopped in the middle of, usually
+// by a conflict, or "" if there is none
+func (g *Git) State() string {
+	gitDir := g.gitDir()
+	if gitDir == "" {
+		return ""
+	}
+	for _, p := range []struct{ path, state string }{
+		{"rebase-merge", StateRebase},
+		{"rebase-apply", StateRebase},
+		{"MERGE_HEAD", StateMerge},
+		{"CHERRY_PICK_HEAD", StateCherryPick},
+		{"REVERT_HEAD", StateRevert},
+		{"BISECT_LOG", StateBisect},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, p.path)); err == nil {
+			return p.state
+		}
+	}
+	return ""
+}
+
+// gitDir returns the absolute path of the git directory, or "" if it can't
+// be found
+func (g *Git) gitDir() string {
+	dir, err := g.run("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+// commonDir returns the absolute path of the git directory the repo shares
+// with its worktrees. For a linked worktree it is the main repository's,
+// which holds the objects and most refs.
+func (g *Git) commonDir() (string, error) {
+	dir, err := g.run("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(g.dir, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// IsWorktree reports whether the repo is a linked worktree, whose .git is a
+// file pointing into another repository's git directory
+func (g *Git) IsWorktree() bool {
+	gitDir := g.gitDir()
+	common, err := g.commonDir()
+	if gitDir == "" || err != nil {
+		return false
+	}
+	// git resolves symlinks in one and not the other
+	if resolved, err := filepath.EvalSymlinks(gitDir); err == nil {
+		gitDir = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(common); err == nil {
+		common = resolved
+	}
+	return gitDir != common
+}
+
+// ConflictedFiles returns the paths left unmerged by a merge or rebase
+func (g *Git) ConflictedFiles() ([]string, error) {
+	files, err := g.fileStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, f := range files {
+		if f.Conflicted {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths, nil
+}
+
+// ConflictError is returned by Pull, Merge, Rebase, and CherryPick when
+// the operation stopped on conflicts. The repo is left mid-operation for the
+// user to resolve.
+type ConflictError struct {
+	Op    string // the operation that stopped: StateMerge, StateRebase, or StateCherryPick
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s conflict in %d file(s)", e.Op, len(e.Paths))
+}
+
+// conflictOr returns a ConflictError if a failed op left conflicted files,
+// or err unchanged otherwise. The repo's state names the operation if it
+// differs from op, e.g. a pull that rebased.
+func (g *Git) conflictOr(err error, op string) error {
+	if err == nil {
+		return nil
+	}
+	paths, cerr := g.ConflictedFiles()
+	if cerr != nil || len(paths) == 0 {
+		return err
+	}
+	if state := g.State(); state != "" {
+		op = state
+	}
+	return &ConflictError{Op: op, Paths: paths}
+}
+
+// pullOp returns the operation a pull runs
+func pullOp(rebase bool) string {
+	if rebase {
+		return StateRebase
+	}
+	return StateMerge
+}
+
+// getAheadBehind returns how many commits ahead/behind the current branch
+// is compared to its upstream, or to the same branch on the primary remote
+// if no upstream is configured
 func (g *Git) getAheadBehind() (ahead, behind int, err error) {
 	output, err := g.run("rev-list", "--left-right", "--count", "@{upstream}...HEAD")
 	if err != nil {
-		// No upstream configured
-		return 0, 0, nil
+		branch, detached, berr := g.Branch()
+		if berr != nil || branch == "" || detached {
+			return 0, 0, nil
+		}
+		output, err = g.run("rev-list", "--left-right", "--count", g.remote+"/"+branch+"...HEAD")
+		if err != nil {
+			// No upstream and nothing on the remote
+			return 0, 0, nil
+		}
 	}
 
 	parts := strings.Fields(output)
@@ -141,26 +499,221 @@ func (g *Git) Pull(rebase bool) error {
 		args = append(args, "--rebase")
 	}
 	_, err := g.run(args...)
+	return g.conflictOr(err, pullOp(rebase))
+}
+
+// Merge merges branch into the current branch, with --no-ff if noFF is set.
+// On conflicts it returns a ConflictError and leaves the repo mid-merge.
+func (g *Git) Merge(branch string, noFF bool) error {
+	args := []string{"merge", "--no-edit"}
+	if noFF {
+		args = append(args, "--no-ff")
+	}
+	_, err := g.run(append(args, branch)...)
+	return g.conflictOr(err, StateMerge)
+}
+
+// MergeAbort abandons a merge in progress
+func (g *Git) MergeAbort() error {
+	_, err := g.run("merge", "--abort")
 	return err
 }
 
-// Push pushes changes to remote
-func (g *Git) Push(force bool) error {
+// Rebase rebases the current branch onto onto, reporting whether it was
+// already up to date. On conflicts it returns a ConflictError and leaves
+// the repo mid-rebase.
+func (g *Git) Rebase(onto string) (upToDate bool, err error) {
+	output, err := g.run("rebase", onto)
+	if err != nil {
+		return false, g.conflictOr(err, StateRebase)
+	}
+	// e.g. "Current branch feature-x is up to date."
+	return strings.Contains(output, " is up to date"), nil
+}
+
+// RebaseContinue continues a rebase stopped on conflicts, keeping the
+// commit messages. If it stops on conflicts again it returns a
+// ConflictError.
+func (g *Git) RebaseContinue() error {
+	_, err := g.run("-c", "core.editor=true", "rebase", "--continue")
+	return g.conflictOr(err, StateRebase)
+}
+
+// RebaseAbort abandons a rebase in progress
+func (g *Git) RebaseAbort() error {
+	_, err := g.run("rebase", "--abort")
+	return err
+}
+
+// ForceMode controls whether and how Push overwrites the remote branch
+type ForceMode int
+
+const (
+	NoForce        ForceMode = iota
+	ForceWithLease           // overwrite only if the remote is where we last saw it
+	ForceUnsafe              // overwrite unconditionally
+)
+
+// ErrCommitNotFound is returned by CherryPick when the repo has no commit
+// with the given hash
+var ErrCommitNotFound = errors.New("commit not found")
+
+// CherryPick applies commit sha to the current branch. It reports whether
+// the commit was empty there, e.g. because the change is already on the
+// branch, in which case it is skipped. On conflicts it returns a
+// ConflictError and leaves the repo mid-cherry-pick.
+func (g *Git) CherryPick(sha string) (empty bool, err error) {
+	if _, err := g.run("cat-file", "-e", sha+"^{commit}"); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, ErrCommitNotFound
+		}
+		return false, err
+	}
+
+	_, err = g.run("cherry-pick", sha)
+	if err != nil && strings.Contains(err.Error(), "cherry-pick is now empty") {
+		_, err = g.run("cherry-pick", "--skip")
+		return err == nil, err
+	}
+	return false, g.conflictOr(err, StateCherryPick)
+}
+
+// CherryPickContinue continues a cherry-pick stopped on conflicts, keeping
+// the commit message
+func (g *Git) CherryPickContinue() error {
+	_, err := g.run("-c", "core.editor=true", "cherry-pick", "--continue")
+	return g.conflictOr(err, StateCherryPick)
+}
+
+// CherryPickAbort abandons a cherry-pick in progress
+func (g *Git) CherryPickAbort() error {
+	_, err := g.run("cherry-pick", "--abort")
+	return err
+}
+
+// ErrLeaseRejected is returned when a force-with-lease push is refused
+// because the remote branch moved since the last fetch
+var ErrLeaseRejected = errors.New("remote branch moved since your last fetch; run `mergeish pull --rebase` and try again")
+
+// PullFrom pulls branch from the named remote
+func (g *Git) PullFrom(remote, branch string, rebase bool) error {
+	args := []string{"pull"}
+	if rebase {
+		args = append(args, "--rebase")
+	}
+	args = append(args, remote, branch)
+	_, err := g.run(args...)
+	return g.conflictOr(err, pullOp(rebase))
+}
+
+// Push pushes the current branch to its branch on the primary remote, as
+// named by HeadBranch. The refspec is explicit because a plain git push
+// refuses when the upstream has another name. A dry run only checks that
+// the push would succeed.
+func (g *Git) Push(mode ForceMode, dryRun bool) error {
+	head, err := g.HeadBranch()
+	if err != nil {
+		return err
+	}
+	return g.PushRefSpec(RefSpec{Src: "HEAD", Dst: head}, mode, false, dryRun)
+}
+
+// RefSpec maps a local ref to the remote branch it is pushed to
+type RefSpec struct {
+	Src string // local ref, e.g. HEAD
+	Dst string // remote branch name, without refs/heads/
+}
+
+// ParseRefSpec parses "src:dst", or just "dst" to push HEAD
+func ParseRefSpec(s string) (RefSpec, error) {
+	src, dst, ok := strings.Cut(s, ":")
+	if !ok {
+		src, dst = "HEAD", s
+	}
+	dst = strings.TrimPrefix(dst, "refs/heads/")
+	switch {
+	case strings.HasPrefix(src, "+"):
+		return RefSpec{}, fmt.Errorf("refspec %q: use --force instead of a leading +", s)
+	case src == "" || dst == "":
+		return RefSpec{}, fmt.Errorf("refspec %q: need a local ref and a remote branch", s)
+	}
+	return RefSpec{Src: src, Dst: dst}, nil
+}
+
+// String returns the refspec as git push takes it
+func (s RefSpec) String() string {
+	return s.Src + ":refs/heads/" + s.Dst
+}
+
+// PushRefSpec pushes spec to the primary remote, setting the upstream of
+// the current branch to the pushed branch if setUpstream is set. A dry run
+// only checks that the push would succeed.
+func (g *Git) PushRefSpec(spec RefSpec, mode ForceMode, setUpstream, dryRun bool) error {
 	args := []string{"push"}
-	if force {
+	if setUpstream {
+		args = append(args, "-u")
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	switch mode {
+	case ForceWithLease:
+		args = append(args, "--force-with-lease")
+	case ForceUnsafe:
 		args = append(args, "--force")
 	}
-	_, err := g.run(args...)
+
+	_, err := g.run(append(args, g.remote, spec.String())...)
+	if err != nil && mode == ForceWithLease && strings.Contains(err.Error(), "stale info") {
+		return ErrLeaseRejected
+	}
+	if err == nil && setUpstream && !dryRun {
+		g.recordHeadBranch(spec)
+	}
 	return err
 }
 
-// PushSetUpstream pushes and sets upstream for the current branch
-func (g *Git) PushSetUpstream() error {
+// recordHeadBranch notes the remote branch a push with -u sent the current
+// branch to, for HeadBranch, when it has another name
+func (g *Git) recordHeadBranch(spec RefSpec) {
+	branch, err := g.CurrentBranch()
+	if err != nil || (spec.Src != branch && spec.Src != "HEAD") {
+		return
+	}
+	key := "branch." + branch + "." + headBranchKey
+	if spec.Dst == branch {
+		g.run("config", "--unset", key)
+		return
+	}
+	g.run("config", key, spec.Dst)
+}
+
+// BehindRef returns how many commits ref has that src doesn't, or 0 if
+// ref doesn't exist
+func (g *Git) BehindRef(src, ref string) (int, error) {
+	if _, err := g.run("rev-parse", "--verify", "--quiet", ref); err != nil {
+		return 0, nil
+	}
+	output, err := g.run("rev-list", "--count", src+".."+ref)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(output)
+}
+
+// PushSetUpstream pushes and sets upstream for the current branch. A dry
+// run only checks that the push would succeed.
+func (g *Git) PushSetUpstream(dryRun bool) error {
 	branch, err := g.CurrentBranch()
 	if err != nil {
 		return err
 	}
-	_, err = g.run("push", "-u", "origin", branch)
+	args := []string{"push", "-u"}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	_, err = g.run(append(args, g.remote, branch)...)
 	return err
 }
 
@@ -176,165 +729,862 @@ func (g *Git) DeleteBranch(name string) error {
 	return err
 }
 
-// Checkout switches to a branch
-func (g *Git) Checkout(branch string) error {
-	_, err := g.run("checkout", branch)
-	return err
-}
-
-// CheckoutNewBranch creates and switches to a new branch
-func (g *Git) CheckoutNewBranch(name string) error {
-	_, err := g.run("checkout", "-b", name)
+// ForceDeleteBranch deletes a branch even if it is not fully merged
+func (g *Git) ForceDeleteBranch(name string) error {
+	_, err := g.run("branch", "-D", name)
 	return err
 }
 
-// BranchExists checks if a branch exists
-func (g *Git) BranchExists(name string) bool {
-	_, err := g.run("rev-parse", "--verify", name)
+// IsMerged reports whether branch is fully merged into target
+func (g *Git) IsMerged(branch, target string) bool {
+	_, err := g.run("merge-base", "--is-ancestor", branch, target)
 	return err == nil
 }
 
-// ListBranches returns all local branches
-func (g *Git) ListBranches() ([]string, error) {
-	output, err := g.run("branch", "--format=%(refname:short)")
+// MergedBranches returns the local branches fully merged into base. Unlike
+// git branch --merged, it lists only refs/heads, so a detached HEAD doesn't
+// show up as a branch.
+func (g *Git) MergedBranches(base string) ([]string, error) {
+	output, err := g.run("for-each-ref", "--merged="+base, "--format=%(refname:short)", "refs/heads/")
 	if err != nil {
 		return nil, err
 	}
-
 	if output == "" {
 		return nil, nil
 	}
-
 	return strings.Split(output, "\n"), nil
 }
 
-// Add stages files for commit
-func (g *Git) Add(paths ...string) error {
-	args := append([]string{"add"}, paths...)
-	_, err := g.run(args...)
+// DeleteRemoteBranch deletes a branch from the primary remote
+func (g *Git) DeleteRemoteBranch(name string) error {
+	_, err := g.run("push", g.remote, "--delete", "refs/heads/"+name)
 	return err
 }
 
-// AddAll stages all changes
-func (g *Git) AddAll() error {
-	_, err := g.run("add", "-A")
-	return err
+// HeadCommit returns the commit hash HEAD points to
+func (g *Git) HeadCommit() (string, error) {
+	return g.run("rev-parse", "HEAD")
 }
 
-// Commit creates a commit with the given message
-func (g *Git) Commit(message string) error {
-	_, err := g.run("commit", "-m", message)
+// Checkout switches to a branch
+func (g *Git) Checkout(branch string) error {
+	_, err := g.run("checkout", branch)
 	return err
 }
 
-// HasStagedChanges returns true if there are staged changes
-func (g *Git) HasStagedChanges() (bool, error) {
-	output, err := g.run("diff", "--cached", "--name-only")
-	if err != nil {
-		return false, err
-	}
-	return output != "", nil
+// CheckoutNewBranch creates and switches to a new branch
+func (g *Git) CheckoutNewBranch(name string) error {
+	return g.CheckoutNewBranchFrom(name, "")
 }
 
-// Fetch fetches from remote
-func (g *Git) Fetch() error {
-	_, err := g.run("fetch")
+// CheckoutNewBranchFrom creates a new branch at start and switches to it.
+// If start is empty, the branch is created at HEAD.
+func (g *Git) CheckoutNewBranchFrom(name, start string) error {
+	// Starting from a remote branch would otherwise make it the upstream,
+	// and a plain push or a PR would then target it
+	args := []string{"checkout", "--no-track", "-b", name}
+	if start != "" {
+		args = append(args, start)
+	}
+	_, err := g.run(args...)
 	return err
 }
 
-// IsRepo checks if the directory is a git repository
-func (g *Git) IsRepo() bool {
-	_, err := g.run("rev-parse", "--git-dir")
-	return err == nil
+// RenameBranch renames a local branch
+func (g *Git) RenameBranch(oldName, newName string) error {
+	_, err := g.run("branch", "-m", oldName, newName)
+	return err
 }
 
-// RunRaw executes an arbitrary git command and returns stdout and stderr
-func (g *Git) RunRaw(args ...string) (stdout, stderr string, err error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = g.dir
-
-	var outBuf, errBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = &errBuf
+// SetUpstream sets the upstream of a local branch, e.g. "origin/main"
+func (g *Git) SetUpstream(branch, upstream string) error {
+	_, err := g.run("branch", "--set-upstream-to="+upstream, branch)
+	return err
+}
 
-	err = cmd.Run()
-	return outBuf.String(), errBuf.String(), err
+// ConfigValue is the effective value of a git config key
+type ConfigValue struct {
+	Value  string
+	Scope  string // local, global, system, etc.; empty if the key is unset
+	Origin string // where the value came from, e.g. "file:.git/config"
 }
 
-// PRInfo represents information about a pull request
-type PRInfo struct {
-	Number int
-	Title  string
-	URL    string
-	State  string
-	Branch string
+// Set reports whether the key has a value
+func (v ConfigValue) Set() bool {
+	return v.Scope != ""
 }
 
-// GetPR returns PR info for the current branch, or nil if no PR exists
-func (g *Git) GetPR() (*PRInfo, error) {
-	branch, err := g.CurrentBranch()
-	if err != nil {
-		return nil, err
+// String returns the value, or "unset"
+func (v ConfigValue) String() string {
+	if !v.Set() {
+		return "unset"
 	}
+	return v.Value
+}
 
-	// Use gh cli to check for PR
-	cmd := exec.Command("gh", "pr", "view", "--json", "number,title,url,state,headRefName")
-	cmd.Dir = g.dir
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// No PR exists for this branch
-		if strings.Contains(stderr.String(), "no pull requests found") ||
-			strings.Contains(stderr.String(), "Could not resolve") {
-			return nil, nil
+// GetConfig returns the effective value of a config key and where it is set
+func (g *Git) GetConfig(key string) (ConfigValue, error) {
+	output, err := g.run("config", "--show-scope", "--show-origin", "--get", key)
+	if err != nil {
+		// Exit status 1 means the key is unset
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return ConfigValue{}, nil
 		}
-		return nil, fmt.Errorf("gh pr view: %w: %s", err, stderr.String())
+		return ConfigValue{}, err
 	}
 
-	// Parse JSON response
-	var result struct {
-		Number      int    `json:"number"`
-		Title       string `json:"title"`
-		URL         string `json:"url"`
-		State       string `json:"state"`
-		HeadRefName string `json:"headRefName"`
+	parts := strings.SplitN(output, "\t", 3)
+	if len(parts) != 3 {
+		return ConfigValue{}, fmt.Errorf("unexpected git config output: %q", output)
 	}
+	return ConfigValue{Scope: parts[0], Origin: parts[1], Value: parts[2]}, nil
+}
 
-	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
-		return nil, fmt.Errorf("parsing gh output: %w", err)
+// SetLocalConfig sets a config key in the repo's own .git/config
+func (g *Git) SetLocalConfig(key, value string) error {
+	_, err := g.run("config", "--local", key, value)
+	return err
+}
+
+// RemoteHeadBranch asks the remote which branch its HEAD points to
+func (g *Git) RemoteHeadBranch(remote string) (string, error) {
+	output, err := g.run("ls-remote", "--symref", remote, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+			return strings.TrimPrefix(fields[1], "refs/heads/"), nil
+		}
+	}
+
+	return "", fmt.Errorf("remote %s has no HEAD", remote)
+}
+
+// AddRemote adds a remote
+func (g *Git) AddRemote(name, url string) error {
+	_, err := g.run("remote", "add", name, url)
+	return err
+}
+
+// SetRemoteURL changes the URL of an existing remote
+func (g *Git) SetRemoteURL(name, url string) error {
+	_, err := g.run("remote", "set-url", name, url)
+	return err
+}
+
+// RemoteURL returns the URL configured for a remote
+func (g *Git) RemoteURL(remote string) (string, error) {
+	return g.run("remote", "get-url", remote)
+}
+
+// StaleRemoteRefs contacts the remote and returns the remote-tracking refs
+// that no longer exist there, without pruning them
+func (g *Git) StaleRemoteRefs(remote string) ([]string, error) {
+	output, err := g.run("remote", "prune", "--dry-run", remote)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	for _, line := range strings.Split(output, "\n") {
+		if _, ref, ok := strings.Cut(line, "[would prune] "); ok {
+			refs = append(refs, strings.TrimSpace(ref))
+		}
+	}
+	return refs, nil
+}
+
+// LocalRemoteHead returns the branch the local refs/remotes/<remote>/HEAD
+// points to, or "" if it is not set
+func (g *Git) LocalRemoteHead(remote string) string {
+	output, err := g.run("symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(output, remote+"/")
+}
+
+// SetRemoteHead points refs/remotes/<remote>/HEAD at the given branch
+func (g *Git) SetRemoteHead(remote, branch string) error {
+	_, err := g.run("remote", "set-head", remote, branch)
+	return err
+}
+
+// PruneRemote deletes remote-tracking refs that no longer exist on the remote
+func (g *Git) PruneRemote(remote string) error {
+	_, err := g.run("remote", "prune", remote)
+	return err
+}
+
+// BranchExists checks if a branch exists
+func (g *Git) BranchExists(name string) bool {
+	_, err := g.run("rev-parse", "--verify", name)
+	return err == nil
+}
+
+// ListBranches returns all local branches
+func (g *Git) ListBranches() ([]string, error) {
+	output, err := g.run("branch", "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+
+	if output == "" {
+		return nil, nil
+	}
+
+	return strings.Split(output, "\n"), nil
+}
+
+// ListRemoteBranches returns all remote-tracking branches, e.g.
+// "origin/main", leaving out symbolic refs such as origin/HEAD
+func (g *Git) ListRemoteBranches() ([]string, error) {
+	output, err := g.run("branch", "-r", "--format=%(refname:short)\t%(symref)")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, line := range strings.Split(output, "\n") {
+		name, symref, _ := strings.Cut(line, "\t")
+		if name != "" && symref == "" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// BranchInfo describes a local branch and how it compares with its upstream
+type BranchInfo struct {
+	Name            string
+	Upstream        string // e.g. "origin/main", or "" if none is set
+	Ahead           int    // commits on the branch that Upstream doesn't have
+	Behind          int    // commits on Upstream that the branch doesn't have
+	Gone            bool   // Upstream is set but no longer exists
+	IsCurrentBranch bool
+}
+
+// BranchInfo returns the upstream of a local branch and how far ahead and
+// behind it the branch is
+func (g *Git) BranchInfo(name string) (BranchInfo, error) {
+	output, err := g.run("for-each-ref", "--format=%(refname)\t%(HEAD)\t%(upstream:short)\t%(upstream:track,nobracket)", "refs/heads/"+name)
+	if err != nil {
+		return BranchInfo{}, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if fields[0] != "refs/heads/"+name {
+			continue
+		}
+		for len(fields) < 4 {
+			fields = append(fields, "")
+		}
+
+		info := BranchInfo{Name: name, IsCurrentBranch: fields[1] == "*", Upstream: fields[2]}
+		for _, track := range strings.Split(fields[3], ", ") {
+			switch {
+			case track == "gone":
+				info.Gone = true
+			case strings.HasPrefix(track, "ahead "):
+				info.Ahead, _ = strconv.Atoi(strings.TrimPrefix(track, "ahead "))
+			case strings.HasPrefix(track, "behind "):
+				info.Behind, _ = strconv.Atoi(strings.TrimPrefix(track, "behind "))
+			}
+		}
+		return info, nil
+	}
+	return BranchInfo{}, fmt.Errorf("no branch %s", name)
+}
+
+// Add stages files for commit, waiting briefly if another process holds
+// the index lock
+func (g *Git) Add(paths ...string) error {
+	args := append([]string{"add"}, paths...)
+	_, err := g.runWaitingForLock(args...)
+	return err
+}
+
+// AddAll stages all changes, waiting briefly for the index lock
+func (g *Git) AddAll() error {
+	_, err := g.runWaitingForLock("add", "-A")
+	return err
+}
+
+// Commit creates a commit with the given message, waiting briefly for the
+// index lock
+func (g *Git) Commit(message string) error {
+	_, err := g.runWaitingForLock("commit", "-m", message)
+	return err
+}
+
+// GrepFiles returns tracked text files containing pattern. With regex, the
+// pattern is an extended regular expression; otherwise it is a fixed string.
+// Paths may be glob pathspecs such as "**/*.go".
+func (g *Git) GrepFiles(pattern string, regex bool, paths ...string) ([]string, error) {
+	args := []string{"grep", "-l", "-z", "-I"}
+	if regex {
+		args = append(args, "-E")
+	} else {
+		args = append(args, "-F")
+	}
+	args = append(args, "-e", pattern)
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, p := range paths {
+			args = append(args, ":(glob)"+p)
+		}
+	}
+
+	stdout, stderr, err := g.RunRaw(args...)
+	if err != nil {
+		// Exit status 1 with no output means no matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git grep: %w: %s", err, stderr)
+	}
+	return splitNUL(stdout), nil
+}
+
+// ListFiles returns tracked files matching the given glob pathspecs, or all
+// tracked files if none are given
+func (g *Git) ListFiles(paths ...string) ([]string, error) {
+	args := []string{"ls-files", "-z"}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, p := range paths {
+			args = append(args, ":(glob)"+p)
+		}
+	}
+
+	// Not run, which would trim spaces a path begins or ends with
+	stdout, stderr, err := g.RunRaw(args...)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %w: %s", err, stderr)
+	}
+	return splitNUL(stdout), nil
+}
+
+// splitNUL splits the output of a git command given -z into paths. With
+// -z, git doesn't quote paths with unusual characters.
+func splitNUL(output string) []string {
+	output = strings.TrimSuffix(output, "\x00")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\x00")
+}
+
+// HasChanges returns true if the working tree or index has any changes,
+// including untracked files
+func (g *Git) HasChanges() (bool, error) {
+	output, err := g.run("status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// HasStagedChanges returns true if there are staged changes
+func (g *Git) HasStagedChanges() (bool, error) {
+	output, err := g.run("diff", "--cached", "--name-only")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// StagedFiles returns the paths of staged changes
+func (g *Git) StagedFiles() ([]string, error) {
+	output, err := g.run("diff", "--cached", "--name-only")
+	if err != nil || output == "" {
+		return nil, err
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// CreateTag creates a tag at HEAD. An annotated tag is created when message
+// is non-empty, otherwise a lightweight tag.
+func (g *Git) CreateTag(name, message string) error {
+	if g.TagExists(name) {
+		return fmt.Errorf("tag %s already exists", name)
+	}
+
+	args := []string{"tag"}
+	if message != "" {
+		args = append(args, "-a", name, "-m", message)
+	} else {
+		args = append(args, name)
+	}
+	_, err := g.run(args...)
+	return err
+}
+
+// DeleteTag deletes a local tag
+func (g *Git) DeleteTag(name string) error {
+	_, err := g.run("tag", "-d", name)
+	return err
+}
+
+// TagExists checks if a local tag exists
+func (g *Git) TagExists(name string) bool {
+	_, err := g.run("rev-parse", "--verify", "--quiet", "refs/tags/"+name)
+	return err == nil
+}
+
+// PushTags pushes all tags to remote
+func (g *Git) PushTags() error {
+	_, err := g.run("push", "--tags")
+	return err
+}
+
+// PushTag pushes a single tag to the primary remote
+func (g *Git) PushTag(name string) error {
+	_, err := g.run("push", g.remote, "refs/tags/"+name)
+	return err
+}
+
+// DeleteRemoteTag deletes a tag from the primary remote
+func (g *Git) DeleteRemoteTag(name string) error {
+	_, err := g.run("push", g.remote, "--delete", "refs/tags/"+name)
+	return err
+}
+
+// LatestTag returns the highest version tag, or "" if there are no tags
+func (g *Git) LatestTag() (string, error) {
+	return g.run("for-each-ref", "--sort=-version:refname", "--count=1", "--format=%(refname:short)", "refs/tags")
+}
+
+// ListTags returns all local tags
+func (g *Git) ListTags() ([]string, error) {
+	output, err := g.run("tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+
+	if output == "" {
+		return nil, nil
+	}
+
+	return strings.Split(output, "\n"), nil
+}
+
+// StashEntry represents a single stash entry
+type StashEntry struct {
+	Ref     string // e.g. stash@{0}
+	Message string
+	Time    time.Time
+}
+
+// Stash stashes local changes, including untracked files, with an optional message
+func (g *Git) Stash(message string) error {
+	args := []string{"stash", "push", "--include-untracked"}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	_, err := g.run(args...)
+	return err
+}
+
+// StashPop applies and removes the stash entry ref, e.g. stash@{1}, or the
+// most recent one if ref is ""
+func (g *Git) StashPop(ref string) error {
+	args := []string{"stash", "pop"}
+	if ref != "" {
+		args = append(args, ref)
+	}
+	_, err := g.run(args...)
+	return err
+}
+
+// StashList returns stash entries, most recent first
+func (g *Git) StashList() ([]StashEntry, error) {
+	output, err := g.run("stash", "list", "--format=%gd%x1f%gs%x1f%ct")
+	if err != nil {
+		return nil, err
+	}
+
+	if output == "" {
+		return nil, nil
+	}
+
+	var entries []StashEntry
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, "\x1f", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(parts[2], 10, 64)
+		entries = append(entries, StashEntry{
+			Ref:     parts[0],
+			Message: parts[1],
+			Time:    time.Unix(ts, 0),
+		})
+	}
+
+	return entries, nil
+}
+
+// Fetch fetches from remote
+func (g *Git) Fetch() error {
+	_, err := g.run("fetch")
+	return err
+}
+
+// FetchPrune fetches and removes remote-tracking refs that no longer exist
+// on the remote. It returns the pruned refs.
+func (g *Git) FetchPrune() ([]string, error) {
+	var stderr bytes.Buffer
+	if err := execute(g.context(), g.dir, "git", []string{"fetch", "--prune"}, nil, &stderr); err != nil {
+		return nil, fmt.Errorf("git fetch --prune: %w: %s", err, stderr.String())
+	}
+
+	// Pruned refs are reported as " - [deleted]  (none) -> origin/feature"
+	var pruned []string
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if !strings.Contains(line, "[deleted]") {
+			continue
+		}
+		if _, ref, ok := strings.Cut(line, "-> "); ok {
+			pruned = append(pruned, strings.TrimSpace(ref))
+		}
+	}
+	return pruned, nil
+}
+
+// DiffOptions controls what Diff compares
+type DiffOptions struct {
+	Staged bool   // compare the index instead of the working tree
+	Ref    string // compare against this ref or range, e.g. main..HEAD, instead of the index/HEAD
+	Stat   bool   // set Patch to a diffstat instead of the full patch
+}
+
+// DiffFileStat represents line counts for a single changed file
+type DiffFileStat struct {
+	Path       string
+	Insertions int
+	Deletions  int
+	Binary     bool
+}
+
+// Diff represents a diff and its per-file statistics
+type Diff struct {
+	Patch string
+	Files []DiffFileStat
+}
+
+// Insertions returns the total number of inserted lines
+func (d *Diff) Insertions() int {
+	n := 0
+	for _, f := range d.Files {
+		n += f.Insertions
+	}
+	return n
+}
+
+// Deletions returns the total number of deleted lines
+func (d *Diff) Deletions() int {
+	n := 0
+	for _, f := range d.Files {
+		n += f.Deletions
+	}
+	return n
+}
+
+// Diff returns the diff of the working tree, index, a ref, or a range
+func (g *Git) Diff(opts DiffOptions) (*Diff, error) {
+	args := []string{"diff"}
+	if opts.Staged {
+		args = append(args, "--cached")
+	}
+	if opts.Ref != "" {
+		args = append(args, opts.Ref)
+	}
+
+	patchArgs := args
+	if opts.Stat {
+		patchArgs = append([]string{"diff", "--stat"}, args[1:]...)
+	}
+	patch, err := g.run(patchArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	numstat, err := g.run(append(args, "--numstat")...)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{Patch: patch}
+	if numstat == "" {
+		return diff, nil
+	}
+
+	for _, line := range strings.Split(numstat, "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		fs := DiffFileStat{Path: parts[2]}
+		if parts[0] == "-" && parts[1] == "-" {
+			fs.Binary = true
+		} else {
+			fs.Insertions, _ = strconv.Atoi(parts[0])
+			fs.Deletions, _ = strconv.Atoi(parts[1])
+		}
+		diff.Files = append(diff.Files, fs)
+	}
+
+	return diff, nil
+}
+
+// IsRepo checks if the directory is a git repository
+func (g *Git) IsRepo() bool {
+	return g.CheckRepo() == nil
+}
+
+// HasRefs reports whether the repository has any branches, tags, or
+// remote-tracking refs
+func (g *Git) HasRefs() bool {
+	output, err := g.run("for-each-ref", "--count=1", "--format=%(refname)")
+	return err == nil && output != ""
+}
+
+// CheckRepo returns an error if the directory is not a git repository or
+// git could not tell
+func (g *Git) CheckRepo() error {
+	_, err := g.run("rev-parse", "--git-dir")
+	return err
+}
+
+// RunRaw executes an arbitrary git command and returns stdout and stderr
+func (g *Git) RunRaw(args ...string) (stdout, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+	err = execute(g.context(), g.dir, "git", args, &outBuf, &errBuf)
+	return outBuf.String(), errBuf.String(), err
+}
+
+// ansiRe matches the ANSI escape sequences git colors its output with
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// RunCommand runs a git command given by the user, never through a pager.
+// With color, git colors its output as it would on a terminal unless args
+// turn color off. Without, color is off, and escape sequences that args
+// asked for, e.g. with --color, are removed.
+func (g *Git) RunCommand(color bool, args ...string) (stdout, stderr string, err error) {
+	colorUI := "false"
+	if color {
+		colorUI = "always"
+	}
+	args = append([]string{"--no-pager", "-c", "core.pager=cat", "-c", "color.ui=" + colorUI}, args...)
+	stdout, stderr, err = g.RunRaw(args...)
+	if !color {
+		stdout = ansiRe.ReplaceAllString(stdout, "")
+		stderr = ansiRe.ReplaceAllString(stderr, "")
+	}
+	return stdout, stderr, err
+}
+
+// PRInfo represents information about a pull request
+type PRInfo struct {
+	Number             int
+	Title              string
+	URL                string
+	State              string
+	Branch             string
+	Checks             string // "pass", "fail", "pending", or "" if there are no checks
+	Review             string // e.g. "APPROVED", "CHANGES_REQUESTED", "REVIEW_REQUIRED"
+	Mergeable          string // "MERGEABLE", "CONFLICTING", or "UNKNOWN"
+	Draft              bool
+	Author             string // login of the PR's author
+	Labels             []string
+	RequestedReviewers []string // users and org/teams whose review is still requested
+}
+
+// prJSONFields are the fields requested from gh for PR info
+const prJSONFields = "number,title,url,state,headRefName,statusCheckRollup,reviewDecision,mergeable,isDraft,author,labels,reviewRequests"
+
+// prJSON is the gh JSON representation of a pull request
+type prJSON struct {
+	Number            int    `json:"number"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	State             string `json:"state"`
+	HeadRefName       string `json:"headRefName"`
+	ReviewDecision    string `json:"reviewDecision"`
+	Mergeable         string `json:"mergeable"`
+	IsDraft           bool   `json:"isDraft"`
+	StatusCheckRollup []struct {
+		Status     string `json:"status"`     // check runs
+		Conclusion string `json:"conclusion"` // check runs
+		State      string `json:"state"`      // status contexts
+	} `json:"statusCheckRollup"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	ReviewRequests []reviewRequestJSON `json:"reviewRequests"`
+}
+
+func (p prJSON) info() PRInfo {
+	return PRInfo{
+		Number:             p.Number,
+		Title:              p.Title,
+		URL:                p.URL,
+		State:              p.State,
+		Branch:             p.HeadRefName,
+		Checks:             p.checks(),
+		Review:             p.ReviewDecision,
+		Mergeable:          p.Mergeable,
+		Draft:              p.IsDraft,
+		Author:             p.Author.Login,
+		Labels:             p.labels(),
+		RequestedReviewers: p.requestedReviewers(),
+	}
+}
+
+// requestedReviewers returns the users and teams whose review is requested
+func (p prJSON) requestedReviewers() []string {
+	var reviewers []string
+	for _, r := range p.ReviewRequests {
+		reviewers = append(reviewers, r.reviewer())
+	}
+	return reviewers
+}
+
+// labels returns the names of the labels
+func (p prJSON) labels() []string {
+	var names []string
+	for _, l := range p.Labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// checks summarizes the status check rollup. Any failure wins over pending,
+// and pending wins over success.
+func (p prJSON) checks() string {
+	if len(p.StatusCheckRollup) == 0 {
+		return ""
+	}
+
+	result := "pass"
+	for _, c := range p.StatusCheckRollup {
+		state := c.State
+		if state == "" {
+			if c.Status != "COMPLETED" {
+				state = "PENDING"
+			} else {
+				state = c.Conclusion
+			}
+		}
+
+		switch state {
+		case "SUCCESS", "NEUTRAL", "SKIPPED":
+		case "PENDING", "EXPECTED", "QUEUED", "IN_PROGRESS":
+			result = "pending"
+		default:
+			return "fail"
+		}
+	}
+	return result
+}
+
+// GetPR returns PR info for the current branch, or nil if no PR exists
+func (g *Git) GetPR() (*PRInfo, error) {
+	branch, err := g.HeadBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	// A single list call filtered by head branch; returns an empty list
+	// rather than an error when no PR exists. A branch can have older
+	// closed or merged PRs besides the open one, so fetch a few.
+	stdout, stderr, err := runGH(g.context(), g.dir, "pr", "list", "--head", branch, "--state", "all", "--limit", "20", "--json", prJSONFields)
+	if err != nil {
+		if strings.Contains(stderr, "Could not resolve") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gh pr list: %w: %s", err, stderr)
+	}
+
+	var results []prJSON
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		return nil, fmt.Errorf("parsing gh output: %w", err)
 	}
 
-	return &PRInfo{
-		Number: result.Number,
-		Title:  result.Title,
-		URL:    result.URL,
-		State:  result.State,
-		Branch: branch,
-	}, nil
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	pr := pickPR(results).info()
+	return &pr, nil
 }
 
-// CreatePR creates a new pull request for the current branch
-func (g *Git) CreatePR(title, body, base string) (*PRInfo, error) {
+// pickPR returns the open PR of a branch if there is one, or else the
+// newest. gh lists the newest PRs first.
+func pickPR(prs []prJSON) prJSON {
+	for _, p := range prs {
+		if p.State == "OPEN" {
+			return p
+		}
+	}
+	return prs[0]
+}
+
+// PRMetadata is who to ask for review, who to assign, and which labels to
+// apply when creating a pull request
+type PRMetadata struct {
+	Reviewers []string // users or org/team names
+	Assignees []string
+	Labels    []string
+}
+
+// CreatePR creates a new pull request for the current branch, as a draft
+// if draft is set
+func (g *Git) CreatePR(title, body, base string, draft bool, meta PRMetadata) (*PRInfo, error) {
 	args := []string{"pr", "create", "--title", title}
+	if draft {
+		args = append(args, "--draft")
+	}
+	for _, r := range meta.Reviewers {
+		args = append(args, "--reviewer", r)
+	}
+	for _, a := range meta.Assignees {
+		args = append(args, "--assignee", a)
+	}
+	for _, l := range meta.Labels {
+		args = append(args, "--label", l)
+	}
 	if body != "" {
 		args = append(args, "--body", body)
 	}
 	if base != "" {
 		args = append(args, "--base", base)
 	}
+	head, err := g.HeadBranch()
+	if err != nil {
+		return nil, err
+	}
+	if branch, _ := g.CurrentBranch(); head != branch {
+		args = append(args, "--head", head)
+	}
 
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = g.dir
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("gh pr create: %w: %s", err, stderr.String())
+	if _, stderr, err := runGH(g.context(), g.dir, args...); err != nil {
+		err = fmt.Errorf("gh pr create: %w: %s", err, stderr)
+		// gh can open the PR and then fail to request a reviewer or add a label
+		if pr, _ := g.GetPR(); pr != nil && pr.State == "OPEN" {
+			return pr, err
+		}
+		return nil, err
 	}
 
 	// Get full PR info
@@ -343,35 +1593,111 @@ func (g *Git) CreatePR(title, body, base string) (*PRInfo, error) {
 
 // ClosePR closes the pull request for the current branch
 func (g *Git) ClosePR() error {
-	cmd := exec.Command("gh", "pr", "close")
-	cmd.Dir = g.dir
+	if _, stderr, err := runGH(g.context(), g.dir, "pr", "close"); err != nil {
+		return fmt.Errorf("gh pr close: %w: %s", err, stderr)
+	}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	return nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("gh pr close: %w: %s", err, stderr.String())
+// MarkPRDraft converts the pull request for the current branch to a draft
+func (g *Git) MarkPRDraft() error {
+	if _, stderr, err := runGH(g.context(), g.dir, "pr", "ready", "--undo"); err != nil {
+		return fmt.Errorf("gh pr ready --undo: %w: %s", err, stderr)
 	}
 
 	return nil
 }
 
-// GetBranchCommits returns commit messages for the current branch compared to a base branch
-// If base is empty, it tries to find the merge base with origin/main or origin/master
-func (g *Git) GetBranchCommits(base string) ([]string, error) {
-	if base == "" {
-		// Try to find the default base branch
-		if _, err := g.run("rev-parse", "--verify", "origin/main"); err == nil {
-			base = "origin/main"
-		} else if _, err := g.run("rev-parse", "--verify", "origin/master"); err == nil {
-			base = "origin/master"
-		} else {
-			return nil, fmt.Errorf("could not determine base branch")
+// MarkPRReady marks the draft pull request for the current branch as ready
+// for review
+func (g *Git) MarkPRReady() error {
+	if _, stderr, err := runGH(g.context(), g.dir, "pr", "ready"); err != nil {
+		return fmt.Errorf("gh pr ready: %w: %s", err, stderr)
+	}
+
+	return nil
+}
+
+// Strategies for MergePR, named after the gh pr merge flags
+const (
+	MergeStrategyMerge  = "merge"
+	MergeStrategySquash = "squash"
+	MergeStrategyRebase = "rebase"
+)
+
+// MergeOptions controls how MergePR merges a pull request
+type MergeOptions struct {
+	Strategy     string // one of the MergeStrategy constants
+	DeleteBranch bool   // delete the branch after merging
+	Auto         bool   // enable auto-merge instead, so GitHub merges the PR once its requirements are met
+	Admin        bool   // merge even if requirements such as checks and reviews aren't met
+}
+
+// MergePR merges the pull request for the current branch as opts says
+func (g *Git) MergePR(opts MergeOptions) error {
+	args := []string{"pr", "merge", "--" + opts.Strategy}
+	if opts.DeleteBranch {
+		args = append(args, "--delete-branch")
+	}
+	if opts.Auto {
+		args = append(args, "--auto")
+	}
+	if opts.Admin {
+		args = append(args, "--admin")
+	}
+	if _, stderr, err := runGH(g.context(), g.dir, args...); err != nil {
+		return fmt.Errorf("gh pr merge: %w: %s", err, stderr)
+	}
+
+	return nil
+}
+
+// DefaultBase returns the remote default branch to compare against,
+// <remote>/main or <remote>/master for the primary remote
+func (g *Git) DefaultBase() (string, error) {
+	for _, branch := range []string{"main", "master"} {
+		ref := g.remote + "/" + branch
+		if _, err := g.run("rev-parse", "--verify", ref); err == nil {
+			return ref, nil
 		}
 	}
+	return "", fmt.Errorf("could not determine base branch")
+}
+
+// Commit represents a single commit
+type Commit struct {
+	Hash    string
+	Author  string
+	Time    time.Time
+	Subject string
+	Body    string // message after the subject, without surrounding blank lines
+}
+
+// LogOptions controls which commits Log returns
+type LogOptions struct {
+	Ref   string // revision or range, defaults to HEAD
+	Limit int    // maximum number of commits, 0 for no limit
+	Since string // only commits newer than this date, in any format git accepts
+}
+
+// Log returns commits reachable from opts.Ref, newest first
+func (g *Git) Log(opts LogOptions) ([]Commit, error) {
+	// Fields end in a unit separator and commits in a record separator,
+	// as bodies span lines
+	args := []string{"log", "--pretty=format:%H%x1f%an%x1f%at%x1f%s%x1f%b%x1e"}
+	if opts.Limit > 0 {
+		args = append(args, "-n", strconv.Itoa(opts.Limit))
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+	if opts.Ref != "" {
+		args = append(args, opts.Ref)
+	}
+	args = append(args, "--")
 
-	// Get commits from base..HEAD
-	output, err := g.run("log", "--pretty=format:%s", base+"..HEAD")
+	output, err := g.run(args...)
 	if err != nil {
 		return nil, err
 	}
@@ -380,43 +1706,75 @@ func (g *Git) GetBranchCommits(base string) ([]string, error) {
 		return nil, nil
 	}
 
-	return strings.Split(output, "\n"), nil
-}
+	records := strings.Split(output, "\x1e")
+	commits := make([]Commit, 0, len(records))
+	for _, record := range records {
+		parts := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 5)
+		if len(parts) != 5 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(parts[2], 10, 64)
+		commits = append(commits, Commit{
+			Hash:    parts[0],
+			Author:  parts[1],
+			Time:    time.Unix(ts, 0),
+			Subject: parts[3],
+			Body:    strings.TrimSpace(parts[4]),
+		})
+	}
 
-// ListPRs lists all open PRs in the repo
-func (g *Git) ListPRs() ([]PRInfo, error) {
-	cmd := exec.Command("gh", "pr", "list", "--json", "number,title,url,state,headRefName")
-	cmd.Dir = g.dir
+	return commits, nil
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// LastCommit returns the commit HEAD points to, or nil if there are no
+// commits yet
+func (g *Git) LastCommit() (*Commit, error) {
+	commits, err := g.Log(LogOptions{Limit: 1})
+	if err != nil {
+		if !g.HasRefs() {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	return &commits[0], nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("gh pr list: %w: %s", err, stderr.String())
+// GetBranchCommits returns the commits on the current branch that are not
+// on a base branch, newest first. If base is empty, it compares against main
+// or master on the primary remote.
+func (g *Git) GetBranchCommits(base string) ([]Commit, error) {
+	if base == "" {
+		var err error
+		if base, err = g.DefaultBase(); err != nil {
+			return nil, err
+		}
 	}
 
-	var results []struct {
-		Number      int    `json:"number"`
-		Title       string `json:"title"`
-		URL         string `json:"url"`
-		State       string `json:"state"`
-		HeadRefName string `json:"headRefName"`
+	return g.Log(LogOptions{Ref: base + "..HEAD"})
+}
+
+// ListPRs lists the PRs in the repo in state: "open", "closed", "merged",
+// or "all". An empty state lists the open ones.
+func (g *Git) ListPRs(state string) ([]PRInfo, error) {
+	if state == "" {
+		state = "open"
+	}
+	stdout, stderr, err := runGH(g.context(), g.dir, "pr", "list", "--state", state, "--limit", "100", "--json", prJSONFields)
+	if err != nil {
+		return nil, fmt.Errorf("gh pr list: %w: %s", err, stderr)
 	}
 
-	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+	var results []prJSON
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
 		return nil, fmt.Errorf("parsing gh output: %w", err)
 	}
 
 	prs := make([]PRInfo, len(results))
 	for i, r := range results {
-		prs[i] = PRInfo{
-			Number: r.Number,
-			Title:  r.Title,
-			URL:    r.URL,
-			State:  r.State,
-			Branch: r.HeadRefName,
-		}
+		prs[i] = r.info()
 	}
 
 	return prs, nil
@@ -0,0 +1,93 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testRepo creates a clone of a new bare repository with one commit on
+// main pushed to origin, and returns its Git
+func testRepo(t *testing.T) *Git {
+	t.Helper()
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	dir := filepath.Join(root, "clone")
+	runGit(t, root, "init", "--bare", "-b", "main", remote)
+	runGit(t, root, "clone", remote, dir)
+	runGit(t, dir, "checkout", "-b", "main")
+	runGit(t, dir, "commit", "--allow-empty", "-m", "initial")
+	runGit(t, dir, "push", "-u", "origin", "main")
+	return New(dir)
+}
+
+// runGit runs git in dir with a fixed identity, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=Test", "-c", "user.email=test@example.com",
+		"-c", "commit.gpgsign=false", "-c", "init.defaultBranch=main",
+	}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// writeTestFile writes content to name in dir, failing the test on error
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeadBranchIgnoresStartPointUpstream(t *testing.T) {
+	g := testRepo(t)
+	// A branch that tracks origin/main, as checkout -b feat origin/main
+	// does without --no-track
+	runGit(t, g.dir, "checkout", "-b", "feat", "--track", "origin/main")
+
+	head, err := g.HeadBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != "feat" {
+		t.Errorf("HeadBranch = %q, want feat", head)
+	}
+}
+
+func TestHeadBranchAfterRefSpecPush(t *testing.T) {
+	g := testRepo(t)
+	runGit(t, g.dir, "checkout", "-b", "feat")
+
+	if err := g.PushRefSpec(RefSpec{Src: "feat", Dst: "user/feat"}, NoForce, true, false); err != nil {
+		t.Fatal(err)
+	}
+	head, err := g.HeadBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != "user/feat" {
+		t.Errorf("HeadBranch after pushing to user/feat = %q, want user/feat", head)
+	}
+
+	// Pointing the upstream elsewhere by hand drops the pushed name
+	runGit(t, g.dir, "branch", "--set-upstream-to=origin/main")
+	if head, _ := g.HeadBranch(); head != "feat" {
+		t.Errorf("HeadBranch after changing the upstream = %q, want feat", head)
+	}
+}
+
+func TestCheckoutNewBranchFromDoesNotTrack(t *testing.T) {
+	g := testRepo(t)
+	if err := g.CheckoutNewBranchFrom("feat", "origin/main"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := g.run("config", "--get", "branch.feat.merge"); err == nil {
+		t.Errorf("feat tracks %q, want no upstream", out)
+	}
+}
@@ -0,0 +1,41 @@
+package git
+
+import (
+	"strings"
+)
+
+// GitHubRepo returns the owner/name of a GitHub remote URL, in any of the
+// forms git accepts, and whether url is on GitHub at all
+func GitHubRepo(url string) (string, bool) {
+	var path string
+	switch {
+	case strings.HasPrefix(url, "git@github.com:"):
+		path = strings.TrimPrefix(url, "git@github.com:")
+	case strings.HasPrefix(url, "https://github.com/"):
+		path = strings.TrimPrefix(url, "https://github.com/")
+	case strings.HasPrefix(url, "ssh://git@github.com/"):
+		path = strings.TrimPrefix(url, "ssh://git@github.com/")
+	default:
+		return "", false
+	}
+
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	if strings.Count(path, "/") != 1 {
+		return "", false
+	}
+	return path, true
+}
+
+// CompareURL returns the GitHub page comparing head with base for a remote
+// URL, or "" if the remote isn't on GitHub. An empty base compares with
+// the repo's default branch.
+func CompareURL(url, base, head string) string {
+	repo, ok := GitHubRepo(url)
+	if !ok {
+		return ""
+	}
+	if base == "" {
+		return "https://github.com/" + repo + "/compare/" + head
+	}
+	return "https://github.com/" + repo + "/compare/" + base + "..." + head
+}
@@ -0,0 +1,127 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Issue represents a GitHub issue
+type Issue struct {
+	Number int
+	Title  string
+	URL    string
+	State  string
+	Body   string
+}
+
+// issueJSONFields are the fields requested from gh for issue info
+const issueJSONFields = "number,title,url,state,body"
+
+type issueJSON struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	State  string `json:"state"`
+	Body   string `json:"body"`
+}
+
+// FindIssue returns the most recent open issue in the GitHub repo
+// (owner/name) whose body contains marker, or nil if there is none
+func FindIssue(repo, marker string) (*Issue, error) {
+	stdout, stderr, err := runGH(context.Background(), "", "issue", "list", "-R", repo, "--state", "open", "--limit", "200", "--json", issueJSONFields)
+	if err != nil {
+		return nil, fmt.Errorf("gh issue list: %w: %s", err, stderr)
+	}
+
+	var results []issueJSON
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		return nil, fmt.Errorf("parsing gh output: %w", err)
+	}
+
+	for _, r := range results {
+		if strings.Contains(r.Body, marker) {
+			return &Issue{Number: r.Number, Title: r.Title, URL: r.URL, State: r.State, Body: r.Body}, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateIssue creates an issue in the GitHub repo (owner/name)
+func CreateIssue(repo, title, body string) (*Issue, error) {
+	stdout, stderr, err := runGH(context.Background(), "", "issue", "create", "-R", repo, "--title", title, "--body", body)
+	if err != nil {
+		return nil, fmt.Errorf("gh issue create: %w: %s", err, stderr)
+	}
+
+	// gh prints the new issue's URL, ending in its number
+	url := strings.TrimSpace(stdout)
+	number, err := strconv.Atoi(url[strings.LastIndex(url, "/")+1:])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected gh issue create output: %q", url)
+	}
+	return &Issue{Number: number, Title: title, URL: url, State: "OPEN", Body: body}, nil
+}
+
+// EditIssueBody replaces the body of an issue in the GitHub repo (owner/name)
+func EditIssueBody(repo string, number int, body string) error {
+	if _, stderr, err := runGH(context.Background(), "", "issue", "edit", strconv.Itoa(number), "-R", repo, "--body", body); err != nil {
+		return fmt.Errorf("gh issue edit: %w: %s", err, stderr)
+	}
+	return nil
+}
+
+// PRBody returns the body of the pull request for the current branch
+func (g *Git) PRBody() (string, error) {
+	stdout, stderr, err := runGH(g.context(), g.dir, "pr", "view", "--json", "body")
+	if err != nil {
+		return "", fmt.Errorf("gh pr view: %w: %s", err, stderr)
+	}
+
+	var result struct {
+		Body string `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		return "", fmt.Errorf("parsing gh output: %w", err)
+	}
+	return result.Body, nil
+}
+
+// EditPRBody replaces the body of the pull request for the current branch
+func (g *Git) EditPRBody(body string) error {
+	return g.UpdatePR("", body)
+}
+
+// UpdatePR replaces the title and body of the pull request for the current
+// branch. An empty title or body is left as it is.
+func (g *Git) UpdatePR(title, body string) error {
+	args := []string{"pr", "edit"}
+	if title != "" {
+		args = append(args, "--title", title)
+	}
+	if body != "" {
+		args = append(args, "--body", body)
+	}
+	if _, stderr, err := runGH(g.context(), g.dir, args...); err != nil {
+		return fmt.Errorf("gh pr edit: %w: %s", err, stderr)
+	}
+	return nil
+}
+
+// EditPRLabels adds and removes labels on the pull request for the current
+// branch
+func (g *Git) EditPRLabels(add, remove []string) error {
+	args := []string{"pr", "edit"}
+	for _, l := range add {
+		args = append(args, "--add-label", l)
+	}
+	for _, l := range remove {
+		args = append(args, "--remove-label", l)
+	}
+	if _, stderr, err := runGH(g.context(), g.dir, args...); err != nil {
+		return fmt.Errorf("gh pr edit: %w: %s", err, stderr)
+	}
+	return nil
+}
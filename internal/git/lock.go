@@ -0,0 +1,189 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaleLockAge is how old a lock file must be, with no git process running
+// in its clone, to count as left behind by a crash
+const StaleLockAge = 10 * time.Minute
+
+// LockError is returned when git can't take a lock file, usually
+// .git/index.lock, because it already exists. Most often another process,
+// such as an editor refreshing its git view, is holding it for a moment.
+type LockError struct {
+	Path  string        // the lock file
+	Age   time.Duration // how long ago it was created, 0 if it is gone
+	Owner string        // the process holding it open, e.g. "pid 4242 (code)", or "" if not found
+	Stale bool          // older than StaleLockAge, with no git process running in the clone
+	Err   error         // the git command that ran into it
+}
+
+func (e *LockError) Error() string {
+	var details []string
+	if e.Age > 0 {
+		details = append(details, "created "+e.Age.Round(time.Second).String()+" ago")
+	}
+	if e.Owner != "" {
+		details = append(details, "held by "+e.Owner)
+	}
+	msg := e.Path + " is held by another process"
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, ", ") + ")"
+	}
+	return msg
+}
+
+func (e *LockError) Unwrap() error { return e.Err }
+
+// lockFileRe extracts the path of the lock file git found in the way
+var lockFileRe = regexp.MustCompile(`Unable to create '([^']*\.lock)': File exists`)
+
+// lockFailure returns a LockError if the stderr of a failed git command in
+// dir shows a lock file was in the way, or nil
+func lockFailure(dir, stderr string, err error) *LockError {
+	m := lockFileRe.FindStringSubmatch(stderr)
+	if m == nil {
+		return nil
+	}
+	path := m[1]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	lock := inspectLock(dir, path)
+	lock.Err = err
+	return lock
+}
+
+// inspectLock finds out how old the lock file at path is, who holds it, and
+// whether it is stale
+func inspectLock(dir, path string) *LockError {
+	lock := &LockError{Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return lock // released since
+	}
+	lock.Age = time.Since(info.ModTime())
+
+	owner, gitRunning, known := lockHolders(dir, path)
+	lock.Owner = owner
+	lock.Stale = known && lock.Age >= StaleLockAge && owner == "" && !gitRunning
+	return lock
+}
+
+// lockHolders looks through /proc for a process with path open, and for a
+// git process running in dir. known is false where there is no /proc to
+// look through, so nothing can be ruled out.
+func lockHolders(dir, path string) (owner string, gitRunning, known bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return "", false, false
+	}
+	dir, _ = filepath.Abs(dir)
+
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		proc := filepath.Join("/proc", e.Name())
+		comm, _ := os.ReadFile(filepath.Join(proc, "comm"))
+		name := strings.TrimSpace(string(comm))
+
+		if name == "git" {
+			if cwd, err := os.Readlink(filepath.Join(proc, "cwd")); err == nil &&
+				(cwd == dir || strings.HasPrefix(cwd, dir+string(filepath.Separator))) {
+				gitRunning = true
+			}
+		}
+
+		if owner != "" {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join(proc, "fd"))
+		if err != nil {
+			continue // another user's process
+		}
+		for _, fd := range fds {
+			if target, err := os.Readlink(filepath.Join(proc, "fd", fd.Name())); err == nil && target == path {
+				owner = fmt.Sprintf("pid %d (%s)", pid, name)
+				break
+			}
+		}
+	}
+	return owner, gitRunning, true
+}
+
+var (
+	lockWaitMu sync.RWMutex
+	lockWait   = 2 * time.Second
+)
+
+// SetLockWait sets how long commands that write the index keep trying while
+// another process holds the index lock. A value <= 0 disables waiting.
+func SetLockWait(d time.Duration) {
+	lockWaitMu.Lock()
+	defer lockWaitMu.Unlock()
+	lockWait = d
+}
+
+// GetLockWait returns how long commands wait for the index lock
+func GetLockWait() time.Duration {
+	lockWaitMu.RLock()
+	defer lockWaitMu.RUnlock()
+	return lockWait
+}
+
+// runWaitingForLock runs a git command that writes the index, trying again
+// with a short backoff while another process holds a lock it needs, for up
+// to the lock wait. A stale lock is not waited for.
+func (g *Git) runWaitingForLock(args ...string) (string, error) {
+	deadline := time.Now().Add(GetLockWait())
+	delay := 50 * time.Millisecond
+	for {
+		out, err := g.run(args...)
+		var lock *LockError
+		if !errors.As(err, &lock) || lock.Stale || time.Now().Add(delay).After(deadline) {
+			return out, err
+		}
+
+		select {
+		case <-g.context().Done():
+			return out, err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 500*time.Millisecond)
+	}
+}
+
+// IndexLock returns the clone's index lock file if one exists, or nil
+func (g *Git) IndexLock() (*LockError, error) {
+	path, err := g.run("rev-parse", "--git-path", "index.lock")
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.dir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return inspectLock(g.dir, path), nil
+}
+
+// RemoveStaleLock deletes the lock file of lock, after checking again that
+// it is still stale
+func (g *Git) RemoveStaleLock(lock *LockError) error {
+	if current := inspectLock(g.dir, lock.Path); !current.Stale {
+		return fmt.Errorf("%s is no longer stale", lock.Path)
+	}
+	return os.Remove(lock.Path)
+}
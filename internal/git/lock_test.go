@@ -0,0 +1,172 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockFailure(t *testing.T) {
+	dir := t.TempDir()
+	held := filepath.Join(dir, ".git", "index.lock")
+	if err := os.MkdirAll(filepath.Dir(held), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(held, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cause := errors.New("exit status 128")
+
+	tests := []struct {
+		name   string
+		stderr string
+		path   string // "" for no LockError
+		held   bool
+	}{
+		{"relative", "fatal: Unable to create '.git/index.lock': File exists.\n\nAnother git process seems to be running", held, true},
+		{"absolute", "fatal: Unable to create '" + held + "': File exists.", held, true},
+		{"released", "fatal: Unable to create '" + filepath.Join(dir, "refs.lock") + "': File exists.", filepath.Join(dir, "refs.lock"), false},
+		{"other error", "fatal: pathspec 'x' did not match any files", "", false},
+		{"permission", "fatal: Unable to create '" + held + "': Permission denied", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock := lockFailure(dir, tt.stderr, cause)
+			if tt.path == "" {
+				if lock != nil {
+					t.Fatalf("lockFailure = %v, want nil", lock)
+				}
+				return
+			}
+			if lock == nil {
+				t.Fatal("lockFailure = nil, want a LockError")
+			}
+			if lock.Path != tt.path {
+				t.Errorf("Path = %q, want %q", lock.Path, tt.path)
+			}
+			if (lock.Age > 0) != tt.held {
+				t.Errorf("Age = %s, want it set only for a lock that still exists", lock.Age)
+			}
+			if lock.Stale {
+				t.Error("a fresh lock is Stale")
+			}
+			if !errors.Is(lock, cause) {
+				t.Error("LockError doesn't wrap the git error")
+			}
+		})
+	}
+}
+
+func TestGitRunsUntranslated(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake git is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\necho \"$LC_ALL\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+
+	var stdout bytes.Buffer
+	if err := execute(context.Background(), "", "git", nil, &stdout, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "C" {
+		t.Errorf("git ran with LC_ALL=%q, want C so its messages can be matched", got)
+	}
+}
+
+// holdIndexLock creates the index lock of g as another process would
+func holdIndexLock(t *testing.T, g *Git) string {
+	t.Helper()
+	lock := filepath.Join(g.dir, ".git", "index.lock")
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return lock
+}
+
+// setLockWait sets the lock wait for the test
+func setLockWait(t *testing.T, d time.Duration) {
+	prev := GetLockWait()
+	SetLockWait(d)
+	t.Cleanup(func() { SetLockWait(prev) })
+}
+
+func TestRunWaitingForLockReleased(t *testing.T) {
+	g := testRepo(t)
+	writeTestFile(t, g.dir, "new.txt", "new\n")
+	lock := holdIndexLock(t, g)
+	setLockWait(t, 5*time.Second)
+
+	time.AfterFunc(200*time.Millisecond, func() { os.Remove(lock) })
+	if _, err := g.runWaitingForLock("add", "new.txt"); err != nil {
+		t.Fatalf("add after the lock was released: %v", err)
+	}
+	if staged := runGit(t, g.dir, "diff", "--cached", "--name-only"); strings.TrimSpace(staged) != "new.txt" {
+		t.Errorf("staged = %q, want new.txt", staged)
+	}
+}
+
+func TestRunWaitingForLockGivesUp(t *testing.T) {
+	g := testRepo(t)
+	writeTestFile(t, g.dir, "new.txt", "new\n")
+	holdIndexLock(t, g)
+	setLockWait(t, 300*time.Millisecond)
+
+	start := time.Now()
+	_, err := g.runWaitingForLock("add", "new.txt")
+	var lock *LockError
+	if !errors.As(err, &lock) {
+		t.Fatalf("add with the lock held = %v, want a LockError", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 3*time.Second {
+		t.Errorf("gave up after %s, want about the 300ms lock wait", elapsed)
+	}
+}
+
+func TestRunWaitingForLockDisabled(t *testing.T) {
+	g := testRepo(t)
+	writeTestFile(t, g.dir, "new.txt", "new\n")
+	holdIndexLock(t, g)
+	setLockWait(t, 0)
+
+	start := time.Now()
+	if _, err := g.runWaitingForLock("add", "new.txt"); err == nil {
+		t.Fatal("add with the lock held succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s with the lock wait disabled", elapsed)
+	}
+}
+
+func TestRunWaitingForLockStale(t *testing.T) {
+	if _, err := os.Stat("/proc"); err != nil {
+		t.Skip("no /proc to rule out a running git")
+	}
+	g := testRepo(t)
+	writeTestFile(t, g.dir, "new.txt", "new\n")
+	lock := holdIndexLock(t, g)
+	old := time.Now().Add(-2 * StaleLockAge)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+	setLockWait(t, 5*time.Second)
+
+	start := time.Now()
+	_, err := g.runWaitingForLock("add", "new.txt")
+	var lockErr *LockError
+	if !errors.As(err, &lockErr) || !lockErr.Stale {
+		t.Fatalf("add with a stale lock = %v, want a stale LockError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("waited %s for a stale lock", elapsed)
+	}
+}
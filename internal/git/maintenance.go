@@ -0,0 +1,127 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MaintenanceTasks are the git maintenance tasks RunMaintenance runs
+var MaintenanceTasks = []string{"gc", "commit-graph", "prefetch"}
+
+// ErrNoMaintenance is returned when git is too old to have git maintenance
+// or the subcommand asked for
+var ErrNoMaintenance = errors.New("git maintenance is not supported by this git")
+
+// noMaintenance reports whether err means git doesn't know the maintenance
+// command, subcommand, or one of the tasks
+func noMaintenance(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "is not a git command") ||
+		strings.Contains(msg, "is not a valid task") ||
+		strings.Contains(msg, "invalid subcommand")
+}
+
+// RunMaintenance runs MaintenanceTasks in the repo. On a git without git
+// maintenance it runs git gc --auto instead and reports that it did.
+func (g *Git) RunMaintenance() (fellBack bool, err error) {
+	args := []string{"maintenance", "run"}
+	for _, task := range MaintenanceTasks {
+		args = append(args, "--task="+task)
+	}
+	_, err = g.run(args...)
+	if !noMaintenance(err) {
+		return false, err
+	}
+	_, err = g.run("gc", "--auto")
+	return true, err
+}
+
+// RegisterMaintenance adds the repo to the repos git's background
+// maintenance runs in
+func (g *Git) RegisterMaintenance() error {
+	_, err := g.run("maintenance", "register")
+	if noMaintenance(err) {
+		return ErrNoMaintenance
+	}
+	return err
+}
+
+// UnregisterMaintenance removes the repo from git's background maintenance.
+// It reports false if the repo wasn't registered.
+func (g *Git) UnregisterMaintenance() (bool, error) {
+	_, err := g.run("maintenance", "unregister")
+	switch {
+	case noMaintenance(err):
+		return false, ErrNoMaintenance
+	case err != nil && strings.Contains(err.Error(), "is not registered"):
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GitDirSize returns the size in bytes of the files in the repo's git
+// directory. For a linked worktree, that is the main repository's, where
+// the objects are.
+func (g *Git) GitDirSize() (int64, error) {
+	dir, err := g.commonDir()
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// gc can delete files while we walk
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// ObjectCounts is how the repo's objects are stored, from git count-objects
+type ObjectCounts struct {
+	Loose int // objects not in a pack
+	Packs int // pack files
+}
+
+// CountObjects returns how many loose objects and packs the repo has
+func (g *Git) CountObjects() (ObjectCounts, error) {
+	output, err := g.run("count-objects", "-v")
+	if err != nil {
+		return ObjectCounts{}, err
+	}
+
+	var counts ObjectCounts
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "count":
+			counts.Loose = n
+		case "packs":
+			counts.Packs = n
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/willnewby/mergeish/internal/metrics"
+	"github.com/willnewby/mergeish/internal/retry"
+)
+
+// transientErrors are substrings of git output that indicate a network
+// failure worth retrying
+var transientErrors = []string{
+	"early eof",
+	"unexpected eof",
+	"rpc failed",
+	"the remote end hung up unexpectedly",
+	"connection reset",
+	"connection timed out",
+	"operation timed out",
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"unexpected disconnect",
+	"returned error: 502",
+	"returned error: 503",
+	"returned error: 504",
+}
+
+// RetryPolicy controls how network operations are retried
+type RetryPolicy struct {
+	Retries int           // retries after the first attempt
+	Backoff time.Duration // delay before the first retry, doubled each time
+}
+
+// IsTransientError reports whether err looks like a temporary network
+// failure. Permission errors and timeouts are never transient.
+func IsTransientError(err error) bool {
+	if err == nil || IsPermissionError(err) || errors.Is(err, ErrTimeout) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range transientErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry runs fn, retrying with exponential backoff while it fails with a
+// transient error. It returns the number of attempts made and the last error.
+func Retry(policy RetryPolicy, fn func() error) (int, error) {
+	attempts := 0
+	err := retry.Do(policy.Retries, policy.Backoff, func() error {
+		if attempts > 0 {
+			metrics.CountRetry()
+		}
+		attempts++
+		err := fn()
+		if err != nil && !IsTransientError(err) {
+			return retry.Stop(err)
+		}
+		return err
+	})
+	return attempts, err
+}
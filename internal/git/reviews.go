@@ -0,0 +1,106 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Values for PRReview.State, as GitHub reports them
+const (
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+	ReviewCommented        = "COMMENTED"
+	ReviewDismissed        = "DISMISSED"
+)
+
+// PRReview is the latest review of a pull request by one reviewer
+type PRReview struct {
+	Author string
+	State  string // one of the Review constants
+}
+
+// PRReviews is the review status of a pull request
+type PRReviews struct {
+	Decision  string     // e.g. "APPROVED", "CHANGES_REQUESTED", "REVIEW_REQUIRED", or "" if no review is required
+	Reviews   []PRReview // latest review of each reviewer, in the order they were first given
+	Requested []string   // reviewers whose review is still requested
+}
+
+// reviewRequestJSON is a requested reviewer as reported by gh: a user with
+// a login, or a team with a slug
+type reviewRequestJSON struct {
+	TypeName     string `json:"__typename"`
+	Login        string `json:"login"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Organization struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+// reviewer returns the user's login, or the team as org/team
+func (r reviewRequestJSON) reviewer() string {
+	switch {
+	case r.Login != "":
+		return r.Login
+	case r.Slug != "" && r.Organization.Login != "":
+		return r.Organization.Login + "/" + r.Slug
+	case r.Slug != "":
+		return r.Slug
+	}
+	return r.Name
+}
+
+// GetPRReviews returns the review status of the pull request for the
+// current branch
+func (g *Git) GetPRReviews() (*PRReviews, error) {
+	stdout, stderr, err := runGH(g.context(), g.dir, "pr", "view", "--json", "reviewDecision,reviews,reviewRequests")
+	if err != nil {
+		return nil, fmt.Errorf("gh pr view: %w: %s", err, stderr)
+	}
+
+	var result struct {
+		ReviewDecision string `json:"reviewDecision"`
+		Reviews        []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			State string `json:"state"`
+		} `json:"reviews"`
+		ReviewRequests []reviewRequestJSON `json:"reviewRequests"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		return nil, fmt.Errorf("parsing gh output: %w", err)
+	}
+
+	reviews := &PRReviews{Decision: result.ReviewDecision}
+	latest := make(map[string]int) // author -> index in reviews.Reviews
+	for _, r := range result.Reviews {
+		// Comments don't change an approval or a request for changes
+		if i, ok := latest[r.Author.Login]; ok {
+			if r.State != ReviewCommented {
+				reviews.Reviews[i].State = r.State
+			}
+			continue
+		}
+		latest[r.Author.Login] = len(reviews.Reviews)
+		reviews.Reviews = append(reviews.Reviews, PRReview{Author: r.Author.Login, State: r.State})
+	}
+	for _, r := range result.ReviewRequests {
+		reviews.Requested = append(reviews.Requested, r.reviewer())
+	}
+	return reviews, nil
+}
+
+// RequestReviewers asks users and teams (org/team) to review the pull
+// request for the current branch
+func (g *Git) RequestReviewers(users, teams []string) error {
+	args := []string{"pr", "edit"}
+	for _, r := range append(append([]string{}, users...), teams...) {
+		args = append(args, "--add-reviewer", r)
+	}
+	if _, stderr, err := runGH(g.context(), g.dir, args...); err != nil {
+		return fmt.Errorf("gh pr edit: %w: %s", err, stderr)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package git
+
+import "sync"
+
+// spawnGate bounds how many git and gh processes run at once. It starts
+// unbounded, and only gets a limit, which only shrinks, when the system runs
+// out of processes or file descriptors.
+type spawnGate struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int // 0 while unbounded
+	active int
+}
+
+var spawns = newSpawnGate()
+
+func newSpawnGate() *spawnGate {
+	g := &spawnGate{}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// acquire waits for a free slot and returns the limit it was granted under
+func (g *spawnGate) acquire() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.limit > 0 && g.active >= g.limit {
+		g.cond.Wait()
+	}
+	g.active++
+	return g.limit
+}
+
+func (g *spawnGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active--
+	g.cond.Broadcast()
+}
+
+// reduce lowers the limit after a process granted a slot under seen, and
+// since released, couldn't start for lack of resources: to half of the
+// processes running then, or to half of seen, unless another process
+// already lowered it since. It reports whether the limit is now lower than
+// seen, so starting the process again is worthwhile.
+func (g *spawnGate) reduce(seen int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case seen == 0 && g.limit == 0:
+		g.limit = max((g.active+1)/2, 1)
+	case g.limit == seen && g.limit > 1:
+		g.limit /= 2
+	}
+	return seen == 0 || g.limit < seen
+}
+
+// ProcessLimit returns how many git and gh processes may run at once, or 0
+// if mergeish hasn't run out of processes or file descriptors
+func ProcessLimit() int {
+	spawns.mu.Lock()
+	defer spawns.mu.Unlock()
+	return spawns.limit
+}
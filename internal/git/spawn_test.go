@@ -0,0 +1,74 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpawnGateReduce(t *testing.T) {
+	g := newSpawnGate()
+	for range 4 {
+		g.acquire()
+	}
+	// The fifth process fails to start while four others run
+	seen := g.acquire()
+	g.release()
+	if !g.reduce(seen) {
+		t.Fatal("reduce from unbounded = false, want a retry")
+	}
+	if g.limit != 2 {
+		t.Fatalf("limit after the first reduction = %d, want half of the 5 running", g.limit)
+	}
+
+	// Another process that started under no limit fails too; the limit
+	// is already lower, so it retries without lowering it again
+	if !g.reduce(0) {
+		t.Error("reduce by a process that saw no limit = false, want a retry")
+	}
+	if g.limit != 2 {
+		t.Errorf("limit = %d after a second failure under the old limit, want 2", g.limit)
+	}
+
+	if !g.reduce(2) || g.limit != 1 {
+		t.Errorf("reduce(2): limit = %d, want 1", g.limit)
+	}
+	if g.reduce(1) {
+		t.Error("reduce(1) = true, want no retry once processes run one at a time")
+	}
+	if g.limit != 1 {
+		t.Errorf("limit = %d, want it to stay at 1", g.limit)
+	}
+}
+
+func TestSpawnGateAcquireWaits(t *testing.T) {
+	g := newSpawnGate()
+	g.limit = 1
+	g.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		g.acquire()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire didn't wait for a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire still waiting after a slot was released")
+	}
+}
+
+func TestSpawnGateUnboundedByDefault(t *testing.T) {
+	g := newSpawnGate()
+	for range 100 {
+		if seen := g.acquire(); seen != 0 {
+			t.Fatalf("acquire = %d, want 0 while unbounded", seen)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// porcelain joins git status --porcelain=v2 -z entries as git writes them
+func porcelain(entries ...string) string {
+	return strings.Join(entries, "\x00") + "\x00"
+}
+
+func TestParseStatus(t *testing.T) {
+	const h = "842a9062cdaef047d7b9549840dc71b7e7a5c212"
+	for _, tt := range []struct {
+		name   string
+		output string
+		want   []FileStatus
+	}{
+		{
+			name:   "modified in the index and the working tree",
+			output: porcelain("1 MM N... 100644 100644 100644 " + h + " " + h + " mod.txt"),
+			want:   []FileStatus{{Path: "mod.txt", Status: "MM", Index: 'M', Worktree: 'M'}},
+		},
+		{
+			name:   "modified in the working tree only",
+			output: porcelain("1 .M N... 100644 100644 100644 " + h + " " + h + " mod.txt"),
+			want:   []FileStatus{{Path: "mod.txt", Status: "M", Index: '.', Worktree: 'M'}},
+		},
+		{
+			name:   "rename",
+			output: porcelain("2 R. N... 100644 100644 100644 "+h+" "+h+" R100 renamed.txt", "orig.txt"),
+			want:   []FileStatus{{Path: "renamed.txt", OrigPath: "orig.txt", Status: "R", Index: 'R', Worktree: '.'}},
+		},
+		{
+			name:   "copy",
+			output: porcelain("2 C. N... 100644 100644 100644 "+h+" "+h+" C100 copy.txt", "orig.txt"),
+			want:   []FileStatus{{Path: "copy.txt", OrigPath: "orig.txt", Status: "C", Index: 'C', Worktree: '.'}},
+		},
+		{
+			name:   "rename of paths with spaces",
+			output: porcelain("2 R. N... 100644 100644 100644 "+h+" "+h+" R087 new name.txt", "old name.txt"),
+			want:   []FileStatus{{Path: "new name.txt", OrigPath: "old name.txt", Status: "R", Index: 'R', Worktree: '.'}},
+		},
+		{
+			// With -z git doesn't quote paths, so they arrive as UTF-8
+			name: "unicode paths",
+			output: porcelain(
+				"1 .M N... 100644 100644 100644 "+h+" "+h+" héllo wörld.txt",
+				"? ünï new.txt",
+				"2 R. N... 100644 100644 100644 "+h+" "+h+" R100 日本/新.txt", "日本/旧.txt",
+			),
+			want: []FileStatus{
+				{Path: "héllo wörld.txt", Status: "M", Index: '.', Worktree: 'M'},
+				{Path: "ünï new.txt", Status: "??", Index: '?', Worktree: '?'},
+				{Path: "日本/新.txt", OrigPath: "日本/旧.txt", Status: "R", Index: 'R', Worktree: '.'},
+			},
+		},
+		{
+			name:   "submodule with new commits and modified content",
+			output: porcelain("1 .M S.M. 160000 160000 160000 " + h + " " + h + " sub"),
+			want:   []FileStatus{{Path: "sub", Status: "M", Index: '.', Worktree: 'M', Submodule: true}},
+		},
+		{
+			name:   "conflict",
+			output: porcelain("u UU N... 100644 100644 100644 100644 " + h + " " + h + " " + h + " conflict.go"),
+			want:   []FileStatus{{Path: "conflict.go", Status: "UU", Index: 'U', Worktree: 'U', Conflicted: true}},
+		},
+		{
+			name:   "ignored files are left out",
+			output: porcelain("! build/", "? new.txt"),
+			want:   []FileStatus{{Path: "new.txt", Status: "??", Index: '?', Worktree: '?'}},
+		},
+		{
+			name:   "clean",
+			output: "",
+			want:   nil,
+		},
+		{
+			// As git lists a repo with all of these at once
+			name: "mixed",
+			output: porcelain(
+				"2 C. N... 100644 100644 100644 "+h+" "+h+" C100 copy.txt", "orig.txt",
+				"1 .M N... 100644 100644 100644 "+h+" "+h+" héllo wörld.txt",
+				"1 MM N... 100644 100644 100644 "+h+" "+h+" mod.txt",
+				"2 R. N... 100644 100644 100644 "+h+" "+h+" R100 renamed.txt", "orig.txt",
+				"1 .M S.M. 160000 160000 160000 "+h+" "+h+" sub",
+				"? ünï new.txt",
+			),
+			want: []FileStatus{
+				{Path: "copy.txt", OrigPath: "orig.txt", Status: "C", Index: 'C', Worktree: '.'},
+				{Path: "héllo wörld.txt", Status: "M", Index: '.', Worktree: 'M'},
+				{Path: "mod.txt", Status: "MM", Index: 'M', Worktree: 'M'},
+				{Path: "renamed.txt", OrigPath: "orig.txt", Status: "R", Index: 'R', Worktree: '.'},
+				{Path: "sub", Status: "M", Index: '.', Worktree: 'M', Submodule: true},
+				{Path: "ünï new.txt", Status: "??", Index: '?', Worktree: '?'},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatus(tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStatus =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusMalformed(t *testing.T) {
+	for _, output := range []string{
+		porcelain("1 M"),
+		porcelain("2 R. N... 100644"),
+	} {
+		if _, err := parseStatus(output); err == nil {
+			t.Errorf("parseStatus(%q) succeeded, want an error", output)
+		}
+	}
+}
+
+func TestStatusFromRepo(t *testing.T) {
+	g := testRepo(t)
+	writeTestFile(t, g.dir, "orig.txt", strings.Repeat("hello world content here\n", 20))
+	writeTestFile(t, g.dir, "héllo.txt", "a\n")
+	runGit(t, g.dir, "add", ".")
+	runGit(t, g.dir, "commit", "-m", "files")
+	runGit(t, g.dir, "mv", "orig.txt", "renamed.txt")
+	writeTestFile(t, g.dir, "héllo.txt", "b\n")
+	writeTestFile(t, g.dir, "ünï.txt", "new\n")
+
+	status, err := g.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileStatus{
+		{Path: "héllo.txt", Status: "M", Index: '.', Worktree: 'M'},
+		{Path: "renamed.txt", OrigPath: "orig.txt", Status: "R", Index: 'R', Worktree: '.'},
+		{Path: "ünï.txt", Status: "??", Index: '?', Worktree: '?'},
+	}
+	if !reflect.DeepEqual(status.Files, want) {
+		t.Errorf("Status files =\n%+v\nwant\n%+v", status.Files, want)
+	}
+}
@@ -0,0 +1,122 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/willnewby/mergeish/internal/metrics"
+)
+
+// ErrTimeout is returned when a git or gh process is killed for running
+// longer than the configured timeout, or past the deadline of the context
+// it was run with. It matches context.DeadlineExceeded with errors.Is.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "timed out" }
+
+func (timeoutError) Is(target error) bool { return target == context.DeadlineExceeded }
+
+// ErrResourceExhausted is returned when a git or gh process can't be
+// started because the system is out of processes, memory, or file
+// descriptors, even after running fewer processes at once.
+var ErrResourceExhausted = errors.New("out of processes or file descriptors")
+
+// killWait is how long to wait for output pipes to close after a timed out
+// process is killed, in case it left children (e.g. ssh) holding them open
+const killWait = 2 * time.Second
+
+var (
+	timeoutMu sync.RWMutex
+	timeout   time.Duration
+)
+
+// SetTimeout sets how long a single git or gh invocation may run before it
+// is killed. A value <= 0 disables the limit.
+func SetTimeout(d time.Duration) {
+	timeoutMu.Lock()
+	defer timeoutMu.Unlock()
+	timeout = d
+}
+
+// GetTimeout returns the current per-invocation timeout, 0 if unlimited
+func GetTimeout() time.Duration {
+	timeoutMu.RLock()
+	defer timeoutMu.RUnlock()
+	return timeout
+}
+
+// execute runs program in dir, killing it if it outlives the timeout or
+// parent is done. If the process can't be started for lack of resources,
+// it is started again once fewer processes run at once.
+func execute(parent context.Context, dir, program string, args []string, stdout, stderr io.Writer) error {
+	for {
+		seen := spawns.acquire()
+		err := executeOnce(parent, dir, program, args, stdout, stderr)
+		spawns.release()
+		if !errors.Is(err, ErrResourceExhausted) || !spawns.reduce(seen) {
+			return err
+		}
+	}
+}
+
+// executeOnce runs program once for execute
+func executeOnce(parent context.Context, dir, program string, args []string, stdout, stderr io.Writer) error {
+	limit := GetTimeout()
+
+	ctx := parent
+	if limit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, program, args...)
+	cmd.Dir = dir
+	if program == "git" {
+		// Errors are told apart by their messages, which git translates
+		cmd.Env = append(os.Environ(), "LC_ALL=C")
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = killWait
+	metrics.CountSubprocess(program)
+
+	err := cmd.Run()
+	if perr := parent.Err(); perr != nil {
+		if errors.Is(perr, context.DeadlineExceeded) {
+			return ErrTimeout
+		}
+		return perr
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimeout, limit)
+	}
+	if isResourceError(err) {
+		return fmt.Errorf("%w: %v", ErrResourceExhausted, err)
+	}
+	return err
+}
+
+// isResourceError reports whether err is a failure to start a process for
+// lack of system resources. Errors from a process that ran are never one.
+func isResourceError(err error) bool {
+	var exitErr *exec.ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return false
+	}
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.EMFILE, syscall.ENFILE, syscall.ENOMEM} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
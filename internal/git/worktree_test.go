@@ -0,0 +1,102 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testWorktree adds a linked worktree of g on a new branch and returns its
+// Git
+func testWorktree(t *testing.T, g *Git) *Git {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "linked")
+	runGit(t, g.dir, "worktree", "add", "-b", "wt", dir)
+	return New(dir)
+}
+
+// sameDir reports whether a and b are the same directory, following
+// symlinks such as the one macOS puts in front of the temp dir
+func sameDir(t *testing.T, a, b string) bool {
+	t.Helper()
+	ra, err := filepath.EvalSymlinks(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, err := filepath.EvalSymlinks(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ra == rb
+}
+
+func TestIsWorktree(t *testing.T) {
+	main := testRepo(t)
+	linked := testWorktree(t, main)
+
+	if main.IsWorktree() {
+		t.Error("IsWorktree = true for the main repository")
+	}
+	if !linked.IsWorktree() {
+		t.Error("IsWorktree = false for a linked worktree")
+	}
+	if New(t.TempDir()).IsWorktree() {
+		t.Error("IsWorktree = true for a directory that isn't a repository")
+	}
+
+	// The linked worktree's .git is a file pointing into the main repo
+	info, err := os.Lstat(filepath.Join(linked.dir, ".git"))
+	if err != nil || info.IsDir() {
+		t.Fatalf("the linked worktree's .git isn't a file: %v", err)
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	main := testRepo(t)
+	linked := testWorktree(t, main)
+	want := filepath.Join(main.dir, ".git")
+
+	for name, g := range map[string]*Git{"main": main, "linked": linked} {
+		got, err := g.commonDir()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !filepath.IsAbs(got) {
+			t.Errorf("%s: commonDir = %q, want an absolute path", name, got)
+		}
+		if !sameDir(t, got, want) {
+			t.Errorf("%s: commonDir = %q, want %q", name, got, want)
+		}
+	}
+
+	// The linked worktree has its own git directory inside the common one
+	if gitDir := linked.gitDir(); !strings.Contains(gitDir, filepath.Join(".git", "worktrees")) {
+		t.Errorf("linked gitDir = %q, want one under .git/worktrees", gitDir)
+	}
+}
+
+func TestGitDirSizeOfWorktree(t *testing.T) {
+	main := testRepo(t)
+	writeTestFile(t, main.dir, "big.txt", strings.Repeat("some content that takes space\n", 2000))
+	runGit(t, main.dir, "add", "big.txt")
+	runGit(t, main.dir, "commit", "-m", "big")
+	linked := testWorktree(t, main)
+
+	mainSize, err := main.GitDirSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkedSize, err := linked.GitDirSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mainSize == 0 {
+		t.Fatal("GitDirSize of the main repository = 0")
+	}
+	// Both measure the shared git directory, which holds the objects; the
+	// linked worktree's .git file alone is a few bytes
+	if linkedSize != mainSize {
+		t.Errorf("GitDirSize of the linked worktree = %d, want the main repository's %d", linkedSize, mainSize)
+	}
+}
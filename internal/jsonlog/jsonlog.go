@@ -0,0 +1,95 @@
+// Package jsonlog emits structured events as JSON lines, one per repo
+// operation, workspace operation, and command, for log pipelines.
+package jsonlog
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event kinds
+const (
+	KindRepo      = "repo"      // one repo's part in an operation
+	KindOperation = "operation" // a workspace-wide operation, such as a pull
+	KindCommand   = "command"   // the whole mergeish command
+)
+
+// Event is a single JSON line
+type Event struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"event"`
+	Command    string    `json:"command"`
+	Operation  string    `json:"operation,omitempty"`
+	Repo       string    `json:"repo,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Status     string    `json:"status"` // "ok" or "failed"
+	Error      string    `json:"error,omitempty"`
+	OK         *int      `json:"ok,omitempty"`     // repos that succeeded, for operations
+	Failed     *int      `json:"failed,omitempty"` // repos that failed, for operations
+}
+
+type logger struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	command string
+}
+
+var global = &logger{}
+
+// Start sends events for command to w. Until it is called, nothing is
+// logged.
+func Start(w io.Writer, command string) {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	global.enc = json.NewEncoder(w)
+	global.command = command
+}
+
+// Enabled reports whether events are being logged
+func Enabled() bool {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	return global.enc != nil
+}
+
+// Repo logs one repo's part in an operation
+func Repo(op, repo string, d time.Duration, err error) {
+	emit(Event{Kind: KindRepo, Operation: op, Repo: repo}, d, err)
+}
+
+// Operation logs a workspace-wide operation once every repo has finished
+func Operation(op string, d time.Duration, ok, failed int) {
+	e := Event{Kind: KindOperation, Operation: op, OK: &ok, Failed: &failed}
+	if failed > 0 {
+		e.Status = "failed"
+	}
+	emit(e, d, nil)
+}
+
+// Command logs the end of the command
+func Command(d time.Duration, err error) {
+	emit(Event{Kind: KindCommand}, d, err)
+}
+
+func emit(e Event, d time.Duration, err error) {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	if global.enc == nil {
+		return
+	}
+
+	e.Time = time.Now().UTC()
+	e.Command = global.command
+	e.DurationMS = d.Milliseconds()
+	if err != nil {
+		e.Status = "failed"
+		e.Error = err.Error()
+	}
+	if e.Status == "" {
+		e.Status = "ok"
+	}
+	// Write errors are ignored; logging must not fail the command
+	_ = global.enc.Encode(e)
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the version of the metrics document written by Write.
+// It is bumped whenever a field is renamed or removed.
+const SchemaVersion = 1
+
+// RepoTiming records a single repo's part in an operation
+type RepoTiming struct {
+	Repo     string
+	Duration time.Duration
+	Err      error
+}
+
+// Operation records one workspace-wide operation, such as a pull across all repos
+type Operation struct {
+	Name          string
+	Start         time.Time
+	Duration      time.Duration
+	Repos         []RepoTiming
+	ParallelLimit int // git processes run at once after running out of resources, 0 if not reduced during the operation
+}
+
+// Outcomes counts repo results by outcome
+func (o *Operation) Outcomes() (ok, failed int) {
+	for _, r := range o.Repos {
+		if r.Err != nil {
+			failed++
+		} else {
+			ok++
+		}
+	}
+	return ok, failed
+}
+
+// Snapshot is a copy of everything recorded so far
+type Snapshot struct {
+	Start        time.Time
+	Operations   []Operation
+	Subprocesses map[string]int // keyed by program, e.g. "git" or "gh"
+	Retries      int            // retries of transient network failures
+}
+
+type recorder struct {
+	mu           sync.Mutex
+	start        time.Time
+	operations   []*Operation
+	subprocesses map[string]int
+	retries      int
+}
+
+var global = &recorder{
+	start:        time.Now(),
+	subprocesses: make(map[string]int),
+}
+
+// StartOperation begins timing an operation. Call the returned function with
+// per-repo timings when the operation finishes, and the reduced parallel
+// limit if it had to run fewer git processes at once (0 otherwise).
+func StartOperation(name string) func(repos []RepoTiming, parallelLimit int) {
+	op := &Operation{Name: name, Start: time.Now()}
+
+	return func(repos []RepoTiming, parallelLimit int) {
+		op.Duration = time.Since(op.Start)
+		op.ParallelLimit = parallelLimit
+		op.Repos = append([]RepoTiming(nil), repos...)
+		sort.SliceStable(op.Repos, func(i, j int) bool {
+			return op.Repos[i].Repo < op.Repos[j].Repo
+		})
+
+		global.mu.Lock()
+		global.operations = append(global.operations, op)
+		global.mu.Unlock()
+	}
+}
+
+// CountSubprocess records that a program was executed
+func CountSubprocess(program string) {
+	global.mu.Lock()
+	global.subprocesses[program]++
+	global.mu.Unlock()
+}
+
+// CountRetry records a retry of a failed network operation
+func CountRetry() {
+	global.mu.Lock()
+	global.retries++
+	global.mu.Unlock()
+}
+
+// Get returns a snapshot of everything recorded so far
+func Get() Snapshot {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+
+	s := Snapshot{
+		Start:        global.start,
+		Operations:   make([]Operation, len(global.operations)),
+		Subprocesses: make(map[string]int, len(global.subprocesses)),
+		Retries:      global.retries,
+	}
+	for i, op := range global.operations {
+		s.Operations[i] = *op
+	}
+	for k, v := range global.subprocesses {
+		s.Subprocesses[k] = v
+	}
+	return s
+}
@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats accepted by WriteFile
+const (
+	FormatJSON       = "json"
+	FormatPrometheus = "prometheus"
+)
+
+// Run describes the command a metrics document is written for
+type Run struct {
+	Command string
+	Err     error
+	GH      GHStats
+}
+
+// GHStats summarizes gh usage for a run
+type GHStats struct {
+	Calls            map[string]int
+	RateLimitRetries int
+	Waited           time.Duration
+}
+
+// CheckFormat returns an error if WriteFile doesn't support format
+func CheckFormat(format string) error {
+	switch format {
+	case FormatJSON, FormatPrometheus, "":
+		return nil
+	}
+	return fmt.Errorf("unsupported metrics format %q (use json or prometheus)", format)
+}
+
+// WriteFile writes the metrics for run to path in the given format
+func WriteFile(path, format string, run Run) error {
+	var buf bytes.Buffer
+	var err error
+
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	if format == FormatPrometheus {
+		err = WritePrometheus(&buf, run, Get())
+	} else {
+		err = WriteJSON(&buf, run, Get())
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing metrics file: %w", err)
+	}
+	return nil
+}
+
+type jsonDocument struct {
+	SchemaVersion int             `json:"schema_version"`
+	Command       string          `json:"command"`
+	StartedAt     time.Time       `json:"started_at"`
+	DurationMS    int64           `json:"duration_ms"`
+	Success       bool            `json:"success"`
+	Error         string          `json:"error,omitempty"`
+	Outcomes      jsonOutcomes    `json:"outcomes"`
+	Operations    []jsonOperation `json:"operations"`
+	Subprocesses  map[string]int  `json:"subprocesses"`
+	Retries       int             `json:"retries"`
+	GH            jsonGH          `json:"gh"`
+}
+
+type jsonOutcomes struct {
+	OK     int `json:"ok"`
+	Failed int `json:"failed"`
+}
+
+type jsonOperation struct {
+	Name          string       `json:"name"`
+	StartedAt     time.Time    `json:"started_at"`
+	DurationMS    int64        `json:"duration_ms"`
+	Outcomes      jsonOutcomes `json:"outcomes"`
+	Repos         []jsonRepo   `json:"repos"`
+	ParallelLimit int          `json:"parallel_limit,omitempty"`
+}
+
+type jsonRepo struct {
+	Repo       string `json:"repo"`
+	DurationMS int64  `json:"duration_ms"`
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+}
+
+type jsonGH struct {
+	Calls            map[string]int `json:"calls"`
+	RateLimitRetries int            `json:"rate_limit_retries"`
+	WaitedMS         int64          `json:"waited_ms"`
+}
+
+// WriteJSON writes a versioned JSON metrics document
+func WriteJSON(w io.Writer, run Run, snap Snapshot) error {
+	doc := jsonDocument{
+		SchemaVersion: SchemaVersion,
+		Command:       run.Command,
+		StartedAt:     snap.Start.UTC(),
+		DurationMS:    time.Since(snap.Start).Milliseconds(),
+		Success:       run.Err == nil,
+		Operations:    []jsonOperation{},
+		Subprocesses:  snap.Subprocesses,
+		Retries:       snap.Retries,
+		GH: jsonGH{
+			Calls:            run.GH.Calls,
+			RateLimitRetries: run.GH.RateLimitRetries,
+			WaitedMS:         run.GH.Waited.Milliseconds(),
+		},
+	}
+	if run.Err != nil {
+		doc.Error = run.Err.Error()
+	}
+	if doc.GH.Calls == nil {
+		doc.GH.Calls = map[string]int{}
+	}
+
+	for _, op := range snap.Operations {
+		ok, failed := op.Outcomes()
+		jop := jsonOperation{
+			Name:          op.Name,
+			StartedAt:     op.Start.UTC(),
+			DurationMS:    op.Duration.Milliseconds(),
+			Outcomes:      jsonOutcomes{OK: ok, Failed: failed},
+			Repos:         make([]jsonRepo, len(op.Repos)),
+			ParallelLimit: op.ParallelLimit,
+		}
+		for i, r := range op.Repos {
+			jr := jsonRepo{Repo: r.Repo, DurationMS: r.Duration.Milliseconds(), Outcome: "ok"}
+			if r.Err != nil {
+				jr.Outcome = "failed"
+				jr.Error = r.Err.Error()
+			}
+			jop.Repos[i] = jr
+		}
+		doc.Operations = append(doc.Operations, jop)
+		doc.Outcomes.OK += ok
+		doc.Outcomes.Failed += failed
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// WritePrometheus writes metrics in the Prometheus text exposition format,
+// suitable for the node_exporter textfile collector
+func WritePrometheus(w io.Writer, run Run, snap Snapshot) error {
+	var b strings.Builder
+	cmd := promEscape(run.Command)
+
+	success := 0
+	if run.Err == nil {
+		success = 1
+	}
+
+	writeMetric(&b, "mergeish_run_duration_seconds", "gauge", "Wall time of the mergeish run.")
+	fmt.Fprintf(&b, "mergeish_run_duration_seconds{command=\"%s\"} %g\n", cmd, time.Since(snap.Start).Seconds())
+	writeMetric(&b, "mergeish_run_success", "gauge", "Whether the mergeish run succeeded.")
+	fmt.Fprintf(&b, "mergeish_run_success{command=\"%s\"} %d\n", cmd, success)
+	writeMetric(&b, "mergeish_run_timestamp_seconds", "gauge", "Unix time the mergeish run started.")
+	fmt.Fprintf(&b, "mergeish_run_timestamp_seconds{command=\"%s\"} %d\n", cmd, snap.Start.Unix())
+
+	writeMetric(&b, "mergeish_operation_duration_seconds", "gauge", "Wall time of each workspace operation.")
+	for _, op := range snap.Operations {
+		fmt.Fprintf(&b, "mergeish_operation_duration_seconds{command=\"%s\",operation=\"%s\"} %g\n",
+			cmd, promEscape(op.Name), op.Duration.Seconds())
+	}
+
+	writeMetric(&b, "mergeish_repo_duration_seconds", "gauge", "Time spent on each repo per operation.")
+	for _, op := range snap.Operations {
+		for _, r := range op.Repos {
+			outcome := "ok"
+			if r.Err != nil {
+				outcome = "failed"
+			}
+			fmt.Fprintf(&b, "mergeish_repo_duration_seconds{command=\"%s\",operation=\"%s\",repo=\"%s\",outcome=\"%s\"} %g\n",
+				cmd, promEscape(op.Name), promEscape(r.Repo), outcome, r.Duration.Seconds())
+		}
+	}
+
+	writeMetric(&b, "mergeish_repo_outcomes", "gauge", "Repo results by outcome.")
+	ok, failed := 0, 0
+	for _, op := range snap.Operations {
+		o, f := op.Outcomes()
+		ok += o
+		failed += f
+	}
+	fmt.Fprintf(&b, "mergeish_repo_outcomes{command=\"%s\",outcome=\"ok\"} %d\n", cmd, ok)
+	fmt.Fprintf(&b, "mergeish_repo_outcomes{command=\"%s\",outcome=\"failed\"} %d\n", cmd, failed)
+
+	writeMetric(&b, "mergeish_subprocesses", "gauge", "Subprocesses executed, by program.")
+	for _, k := range sortedKeys(snap.Subprocesses) {
+		fmt.Fprintf(&b, "mergeish_subprocesses{command=\"%s\",program=\"%s\"} %d\n", cmd, promEscape(k), snap.Subprocesses[k])
+	}
+
+	writeMetric(&b, "mergeish_retries", "gauge", "Retries of transient network failures.")
+	fmt.Fprintf(&b, "mergeish_retries{command=\"%s\"} %d\n", cmd, snap.Retries)
+
+	writeMetric(&b, "mergeish_gh_calls", "gauge", "gh invocations, by subcommand.")
+	for _, k := range sortedKeys(run.GH.Calls) {
+		fmt.Fprintf(&b, "mergeish_gh_calls{command=\"%s\",subcommand=\"%s\"} %d\n", cmd, promEscape(k), run.GH.Calls[k])
+	}
+	writeMetric(&b, "mergeish_gh_rate_limit_retries", "gauge", "gh calls retried after a GitHub rate limit.")
+	fmt.Fprintf(&b, "mergeish_gh_rate_limit_retries{command=\"%s\"} %d\n", cmd, run.GH.RateLimitRetries)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMetric(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promEscape escapes s for a quoted label value. The Prometheus text
+// format only escapes backslash, double quote, and newline; other
+// characters, including non-ASCII ones, are written as they are.
+func promEscape(s string) string {
+	return promEscaper.Replace(s)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
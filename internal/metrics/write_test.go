@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPromEscape(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"mergeish pull", "mergeish pull"},
+		{`C:\repos\api`, `C:\\repos\\api`},
+		{`say "hi"`, `say \"hi\"`},
+		{"two\nlines", `two\nlines`},
+		{"tab\there", "tab\there"},
+		{"café/naïve", "café/naïve"},
+		{"\x01ctl", "\x01ctl"},
+	} {
+		if got := promEscape(tt.in); got != tt.want {
+			t.Errorf("promEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWritePrometheusLabels(t *testing.T) {
+	snap := Snapshot{
+		Start: time.Now(),
+		Operations: []Operation{{
+			Name: "pull",
+			Repos: []RepoTiming{
+				{Repo: "team/café", Duration: time.Second},
+				{Repo: `odd"name\`, Duration: time.Second, Err: errors.New("failed")},
+			},
+		}},
+	}
+	var b strings.Builder
+	if err := WritePrometheus(&b, Run{Command: "mergeish pull"}, snap); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`mergeish_run_success{command="mergeish pull"} 1`,
+		`repo="team/café",outcome="ok"`,
+		`repo="odd\"name\\",outcome="failed"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `\u00e9`) || strings.Contains(out, `\xc3`) {
+		t.Errorf("output escapes non-ASCII characters:\n%s", out)
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatPrometheus, ""} {
+		if err := CheckFormat(format); err != nil {
+			t.Errorf("CheckFormat(%q) = %v", format, err)
+		}
+	}
+	if err := CheckFormat("csv"); err == nil {
+		t.Error("CheckFormat(csv) = nil, want an error")
+	}
+}
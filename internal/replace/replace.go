@@ -0,0 +1,170 @@
+package replace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxFileSize is the largest file, in bytes, that will be edited
+const DefaultMaxFileSize = 1 << 20
+
+// binaryCheckSize is how many leading bytes are inspected for binary content
+const binaryCheckSize = 8000
+
+// Replacer performs a search-and-replace on file contents, line by line.
+// Line endings are preserved exactly.
+type Replacer struct {
+	Pattern     string
+	Replacement string
+	Regex       bool
+	MaxFileSize int64
+
+	re *regexp.Regexp
+}
+
+// LineChange represents a single edited line
+type LineChange struct {
+	Line int // 1-based line number
+	Old  string
+	New  string
+}
+
+// FileEdit represents the proposed edit of a single file
+type FileEdit struct {
+	Path    string // path relative to the repo root
+	Changes []LineChange
+	Content []byte // the full new content
+	root    string
+	mode    os.FileMode
+}
+
+// New creates a Replacer. With regex, the pattern is a Go regular expression
+// and the replacement may reference groups as $1 or ${name}.
+func New(pattern, replacement string, regex bool) (*Replacer, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern required")
+	}
+
+	r := &Replacer{
+		Pattern:     pattern,
+		Replacement: replacement,
+		Regex:       regex,
+		MaxFileSize: DefaultMaxFileSize,
+	}
+
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.re = re
+	}
+
+	return r, nil
+}
+
+// Replace applies the replacement to content and returns the new content and
+// the changed lines. Content is returned unchanged if nothing matched.
+func (r *Replacer) Replace(content []byte) ([]byte, []LineChange) {
+	var out bytes.Buffer
+	var changes []LineChange
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for i, line := range lines {
+		body, ending := splitLineEnding(line)
+		replaced := r.replaceLine(body)
+		if !bytes.Equal(replaced, body) {
+			changes = append(changes, LineChange{
+				Line: i + 1,
+				Old:  string(body),
+				New:  string(replaced),
+			})
+		}
+		out.Write(replaced)
+		out.Write(ending)
+	}
+
+	if len(changes) == 0 {
+		return content, nil
+	}
+	return out.Bytes(), changes
+}
+
+// EditFile computes the edit for the file at path relative to root. It
+// returns nil if the file has no matches, is binary, or exceeds MaxFileSize.
+func (r *Replacer) EditFile(root, path string) (*FileEdit, error) {
+	fullPath := filepath.Join(root, path)
+	info, err := os.Lstat(fullPath)
+	if os.IsNotExist(err) {
+		return nil, nil // tracked but deleted in the working tree
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	if r.MaxFileSize > 0 && info.Size() > r.MaxFileSize {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if IsBinary(content) {
+		return nil, nil
+	}
+
+	newContent, changes := r.Replace(content)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	return &FileEdit{
+		Path:    path,
+		Changes: changes,
+		Content: newContent,
+		root:    root,
+		mode:    info.Mode().Perm(),
+	}, nil
+}
+
+// Apply writes the edited content back to disk
+func (e *FileEdit) Apply() error {
+	return os.WriteFile(filepath.Join(e.root, e.Path), e.Content, e.mode)
+}
+
+// IsBinary reports whether content looks like binary data
+func IsBinary(content []byte) bool {
+	n := len(content)
+	if n > binaryCheckSize {
+		n = binaryCheckSize
+	}
+	return bytes.IndexByte(content[:n], 0) >= 0
+}
+
+func (r *Replacer) replaceLine(line []byte) []byte {
+	if r.re != nil {
+		return r.re.ReplaceAll(line, []byte(r.Replacement))
+	}
+	if !bytes.Contains(line, []byte(r.Pattern)) {
+		return line
+	}
+	return []byte(strings.ReplaceAll(string(line), r.Pattern, r.Replacement))
+}
+
+// splitLineEnding separates a line's trailing "\n" or "\r\n" from its body
+func splitLineEnding(line []byte) (body, ending []byte) {
+	if bytes.HasSuffix(line, []byte("\r\n")) {
+		return line[:len(line)-2], line[len(line)-2:]
+	}
+	if bytes.HasSuffix(line, []byte("\n")) {
+		return line[:len(line)-1], line[len(line)-1:]
+	}
+	return line, nil
+}
@@ -0,0 +1,194 @@
+package replace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		pattern     string
+		replacement string
+		regex       bool
+		content     string
+		want        string
+		changed     []int
+	}{
+		{
+			name:        "fixed string",
+			pattern:     "Old",
+			replacement: "New",
+			content:     "Old\nkeep\nOldOld\n",
+			want:        "New\nkeep\nNewNew\n",
+			changed:     []int{1, 3},
+		},
+		{
+			name:        "fixed string is not a regex",
+			pattern:     "a.c",
+			replacement: "x",
+			content:     "abc a.c\n",
+			want:        "abc x\n",
+			changed:     []int{1},
+		},
+		{
+			name:        "numbered groups",
+			pattern:     `v(\d+)\.Client`,
+			replacement: "v${1}.NewClient",
+			regex:       true,
+			content:     "v1.Client\nv22.Client()\n",
+			want:        "v1.NewClient\nv22.NewClient()\n",
+			changed:     []int{1, 2},
+		},
+		{
+			name:        "named groups",
+			pattern:     `(?P<key>\w+)=(?P<value>\w+)`,
+			replacement: "${value}=${key}",
+			regex:       true,
+			content:     "a=b\n",
+			want:        "b=a\n",
+			changed:     []int{1},
+		},
+		{
+			name:        "multi-byte text",
+			pattern:     "héllo",
+			replacement: "こんにちは",
+			content:     "say héllo, héllo 👋\n",
+			want:        "say こんにちは, こんにちは 👋\n",
+			changed:     []int{1},
+		},
+		{
+			name:        "multi-byte regex",
+			pattern:     `ü+`,
+			replacement: "u",
+			regex:       true,
+			content:     "grüüß\n",
+			want:        "gruß\n",
+			changed:     []int{1},
+		},
+		{
+			name:        "CRLF endings are kept",
+			pattern:     "a",
+			replacement: "b",
+			content:     "a\r\nc\r\na\r\n",
+			want:        "b\r\nc\r\nb\r\n",
+			changed:     []int{1, 3},
+		},
+		{
+			name:        "mixed endings and no final newline",
+			pattern:     "a",
+			replacement: "b",
+			content:     "a\r\na\na",
+			want:        "b\r\nb\nb",
+			changed:     []int{1, 2, 3},
+		},
+		{
+			name:        "regex can't eat the line ending",
+			pattern:     `a\s*`,
+			replacement: "b",
+			regex:       true,
+			content:     "a  \r\nc\n",
+			want:        "b\r\nc\n",
+			changed:     []int{1},
+		},
+		{
+			name:        "no match",
+			pattern:     "zzz",
+			replacement: "y",
+			content:     "abc\r\n",
+			want:        "abc\r\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.pattern, tt.replacement, tt.regex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, changes := r.Replace([]byte(tt.content))
+			if string(got) != tt.want {
+				t.Errorf("Replace = %q, want %q", got, tt.want)
+			}
+			var lines []int
+			for _, c := range changes {
+				lines = append(lines, c.Line)
+			}
+			if len(lines) != len(tt.changed) {
+				t.Fatalf("changed lines %v, want %v", lines, tt.changed)
+			}
+			for i := range lines {
+				if lines[i] != tt.changed[i] {
+					t.Errorf("changed lines %v, want %v", lines, tt.changed)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceLineChange(t *testing.T) {
+	r, err := New("x", "y", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, changes := r.Replace([]byte("a\r\nx1\r\n"))
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	want := LineChange{Line: 2, Old: "x1", New: "y1"}
+	if changes[0] != want {
+		t.Errorf("change = %+v, want %+v", changes[0], want)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New("", "x", false); err == nil {
+		t.Error("New with an empty pattern succeeded")
+	}
+	if _, err := New("(", "x", true); err == nil {
+		t.Error("New with an invalid regex succeeded")
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string, content []byte) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), content, 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("text.txt", []byte("Old\r\n"))
+	write("binary.bin", []byte("Old\x00"))
+	write("big.txt", []byte("Old and more\n"))
+
+	r, err := New("Old", "New", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.MaxFileSize = 10
+
+	edit, err := r.EditFile(root, "text.txt")
+	if err != nil || edit == nil {
+		t.Fatalf("EditFile(text.txt) = %v, %v", edit, err)
+	}
+	if err := edit.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "text.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "New\r\n" {
+		t.Errorf("text.txt = %q, want %q", data, "New\r\n")
+	}
+	if info, _ := os.Stat(filepath.Join(root, "text.txt")); info.Mode().Perm() != 0o640 {
+		t.Errorf("text.txt mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	for _, name := range []string{"binary.bin", "big.txt", "missing.txt"} {
+		if edit, err := r.EditFile(root, name); edit != nil || err != nil {
+			t.Errorf("EditFile(%s) = %v, %v, want nothing", name, edit, err)
+		}
+	}
+}
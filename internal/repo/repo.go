@@ -1,9 +1,11 @@
 package repo
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/willnewby/mergeish/internal/config"
 	"github.com/willnewby/mergeish/internal/git"
@@ -13,16 +15,27 @@ import (
 type Repo struct {
 	Config   config.RepoConfig
 	FullPath string
+	Settings config.Settings // top-level settings with this repo's overrides
+	NoAccess bool            // a previous clone failed because the user lacks access
+	Retry    git.RetryPolicy // retry policy for network operations
+	Attempts int             // attempts made by the last network operation
 	git      *git.Git
 }
 
+// retry runs a network operation under the repo's retry policy
+func (r *Repo) retry(fn func() error) error {
+	attempts, err := git.Retry(r.Retry, fn)
+	r.Attempts = attempts
+	return err
+}
+
 // New creates a new Repo from config and workspace root
 func New(cfg config.RepoConfig, workspaceRoot string) *Repo {
 	fullPath := filepath.Join(workspaceRoot, cfg.Path)
 	return &Repo{
 		Config:   cfg,
 		FullPath: fullPath,
-		git:      git.New(fullPath),
+		git:      git.NewWithRemote(fullPath, cfg.RemoteName()),
 	}
 }
 
@@ -31,6 +44,11 @@ func (r *Repo) Name() string {
 	return r.Config.Path
 }
 
+// Remote returns the name of the repo's primary remote
+func (r *Repo) Remote() string {
+	return r.git.Remote()
+}
+
 // Exists checks if the repo directory exists
 func (r *Repo) Exists() bool {
 	info, err := os.Stat(r.FullPath)
@@ -42,15 +60,141 @@ func (r *Repo) IsCloned() bool {
 	return r.Exists() && r.git.IsRepo()
 }
 
-// Clone clones the repository
-func (r *Repo) Clone() error {
+// CheckRepo returns why the repo's directory is not a git repository, or
+// nil if it is one
+func (r *Repo) CheckRepo() error {
+	return r.git.CheckRepo()
+}
+
+// Corrupt returns the error that found the clone damaged, or nil if no
+// git command has
+func (r *Repo) Corrupt() *git.CorruptError {
+	return r.git.Corrupt()
+}
+
+// Clone clones the repository. A directory already at the repo's path
+// that isn't a clone is an error, or with force is deleted first.
+func (r *Repo) Clone(force bool) error {
+	if err := r.clearTarget(force); err != nil {
+		return err
+	}
+
 	// Ensure parent directory exists
 	parent := filepath.Dir(r.FullPath)
 	if err := os.MkdirAll(parent, 0755); err != nil {
 		return fmt.Errorf("creating parent directory: %w", err)
 	}
 
-	return git.Clone(r.Config.URL, r.FullPath)
+	err := r.retry(func() error {
+		return git.Clone(r.Config.URL, r.FullPath, r.Remote())
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.SyncRemotes()
+}
+
+// clearTarget makes sure nothing is in the way of cloning into the repo's
+// path. An empty directory is fine; anything else, such as what an
+// interrupted clone leaves behind, is removed if force is set.
+func (r *Repo) clearTarget(force bool) error {
+	entries, err := os.ReadDir(r.FullPath)
+	if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Never delete a repo that only failed the check because git couldn't
+	// start or was killed
+	checkErr := r.CheckRepo()
+	if errors.Is(checkErr, git.ErrResourceExhausted) || errors.Is(checkErr, git.ErrTimeout) {
+		return checkErr
+	}
+
+	if !force {
+		var corrupt *git.CorruptError
+		if errors.As(checkErr, &corrupt) {
+			return fmt.Errorf("%s is a damaged clone (%s); run mergeish reclone %s to move it aside and clone it again", r.FullPath, corrupt.Problem, r.Name())
+		}
+		if _, err := os.Stat(filepath.Join(r.FullPath, ".git")); err == nil {
+			return fmt.Errorf("%s holds an interrupted clone; rerun with --force to remove it and clone again", r.FullPath)
+		}
+		return fmt.Errorf("%s exists and is not empty; move it aside, or rerun with --force to delete it", r.FullPath)
+	}
+	if err := os.RemoveAll(r.FullPath); err != nil {
+		return fmt.Errorf("removing %s: %w", r.FullPath, err)
+	}
+	return nil
+}
+
+// Reclone moves the clone to aside, keeping it for anything not pushed,
+// and clones the repository again
+func (r *Repo) Reclone(aside string) error {
+	if _, err := os.Stat(aside); err == nil {
+		return fmt.Errorf("%s already exists", aside)
+	}
+	if err := os.Rename(r.FullPath, aside); err != nil {
+		return fmt.Errorf("moving %s aside: %w", r.FullPath, err)
+	}
+	r.git = git.NewWithRemote(r.FullPath, r.Remote()) // drop the damaged clone's quarantine
+	return r.Clone(false)
+}
+
+// Leftover reports whether Clone with force would delete the repo's
+// directory: it is not empty and is either not a clone or an incomplete one
+func (r *Repo) Leftover() bool {
+	entries, err := os.ReadDir(r.FullPath)
+	if err != nil || len(entries) == 0 {
+		return false
+	}
+	return !r.IsCloned() || r.IncompleteClone()
+}
+
+// IncompleteClone reports whether the repo looks like a clone that was
+// killed partway: a git repository without a single ref or any files
+// besides .git. A finished clone of an empty remote looks the same, so
+// this is only a hint that cloning again is safe.
+func (r *Repo) IncompleteClone() bool {
+	entries, err := os.ReadDir(r.FullPath)
+	if err != nil || len(entries) != 1 || entries[0].Name() != ".git" {
+		return false
+	}
+	return !r.git.HasRefs()
+}
+
+// SyncRemotes adds the configured extra remotes that are missing and
+// updates the URL of any that differ
+func (r *Repo) SyncRemotes() error {
+	names := make([]string, 0, len(r.Config.Remotes))
+	for name := range r.Config.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		url := r.Config.Remotes[name]
+		current, err := r.git.RemoteURL(name)
+		switch {
+		case err != nil:
+			err = r.git.AddRemote(name, url)
+		case current != url:
+			err = r.git.SetRemoteURL(name, url)
+		}
+		if err != nil {
+			return fmt.Errorf("remote %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CheckRemote checks that the configured URL is reachable
+func (r *Repo) CheckRemote() error {
+	return r.retry(func() error {
+		return git.LsRemote(r.Config.URL)
+	})
 }
 
 // Status returns the repository status
@@ -61,24 +205,119 @@ func (r *Repo) Status() (*git.Status, error) {
 	return r.git.Status()
 }
 
+// LastCommit returns the commit HEAD points to, or nil if there are no
+// commits yet
+func (r *Repo) LastCommit() (*git.Commit, error) {
+	return r.git.LastCommit()
+}
+
 // CurrentBranch returns the current branch
 func (r *Repo) CurrentBranch() (string, error) {
 	return r.git.CurrentBranch()
 }
 
+// Branch returns the current branch, or the branch being rebased on a
+// detached HEAD, and whether HEAD is detached
+func (r *Repo) Branch() (string, bool, error) {
+	return r.git.Branch()
+}
+
 // Pull pulls changes from remote
 func (r *Repo) Pull(rebase bool) error {
-	return r.git.Pull(rebase)
+	return r.retry(func() error {
+		return r.git.Pull(rebase)
+	})
+}
+
+// State returns the operation the repo is stopped in the middle of, e.g.
+// git.StateMerge, or "" if none
+func (r *Repo) State() string {
+	return r.git.State()
+}
+
+// Merge merges branch into the current branch
+func (r *Repo) Merge(branch string, noFF bool) error {
+	return r.git.Merge(branch, noFF)
+}
+
+// MergeAbort abandons a merge in progress
+func (r *Repo) MergeAbort() error {
+	return r.git.MergeAbort()
+}
+
+// Rebase rebases the current branch onto onto, reporting whether it was
+// already up to date
+func (r *Repo) Rebase(onto string) (bool, error) {
+	return r.git.Rebase(onto)
+}
+
+// RebaseContinue continues a rebase stopped on conflicts
+func (r *Repo) RebaseContinue() error {
+	return r.git.RebaseContinue()
+}
+
+// RebaseAbort abandons a rebase in progress
+func (r *Repo) RebaseAbort() error {
+	return r.git.RebaseAbort()
+}
+
+// CherryPick applies commit sha to the current branch, reporting whether
+// it was empty there and skipped
+func (r *Repo) CherryPick(sha string) (bool, error) {
+	return r.git.CherryPick(sha)
+}
+
+// CherryPickContinue continues a cherry-pick stopped on conflicts
+func (r *Repo) CherryPickContinue() error {
+	return r.git.CherryPickContinue()
+}
+
+// CherryPickAbort abandons a cherry-pick in progress
+func (r *Repo) CherryPickAbort() error {
+	return r.git.CherryPickAbort()
+}
+
+// PullFrom pulls the current branch from the named remote
+func (r *Repo) PullFrom(remote string, rebase bool) error {
+	branch, err := r.git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	return r.retry(func() error {
+		return r.git.PullFrom(remote, branch, rebase)
+	})
 }
 
 // Push pushes changes to remote
-func (r *Repo) Push(force bool) error {
-	return r.git.Push(force)
+func (r *Repo) Push(mode git.ForceMode, dryRun bool) error {
+	return r.retry(func() error {
+		return r.git.Push(mode, dryRun)
+	})
+}
+
+// PushRefSpec pushes spec to the primary remote, optionally setting the
+// upstream to the pushed branch
+func (r *Repo) PushRefSpec(spec git.RefSpec, mode git.ForceMode, setUpstream, dryRun bool) error {
+	return r.retry(func() error {
+		return r.git.PushRefSpec(spec, mode, setUpstream, dryRun)
+	})
+}
+
+// BehindRef returns how many commits ref has that src doesn't
+func (r *Repo) BehindRef(src, ref string) (int, error) {
+	return r.git.BehindRef(src, ref)
 }
 
 // PushSetUpstream pushes and sets upstream
-func (r *Repo) PushSetUpstream() error {
-	return r.git.PushSetUpstream()
+func (r *Repo) PushSetUpstream(dryRun bool) error {
+	return r.retry(func() error {
+		return r.git.PushSetUpstream(dryRun)
+	})
+}
+
+// HasUpstream reports whether the current branch has an upstream configured
+func (r *Repo) HasUpstream() bool {
+	return r.git.HasUpstream()
 }
 
 // CreateBranch creates a new branch
@@ -91,6 +330,33 @@ func (r *Repo) DeleteBranch(name string) error {
 	return r.git.DeleteBranch(name)
 }
 
+// ForceDeleteBranch deletes a branch even if it is not fully merged
+func (r *Repo) ForceDeleteBranch(name string) error {
+	return r.git.ForceDeleteBranch(name)
+}
+
+// IsMerged reports whether branch is fully merged into target
+func (r *Repo) IsMerged(branch, target string) bool {
+	return r.git.IsMerged(branch, target)
+}
+
+// MergedBranches returns the local branches fully merged into base
+func (r *Repo) MergedBranches(base string) ([]string, error) {
+	return r.git.MergedBranches(base)
+}
+
+// DeleteRemoteBranch deletes a branch from the primary remote
+func (r *Repo) DeleteRemoteBranch(name string) error {
+	return r.retry(func() error {
+		return r.git.DeleteRemoteBranch(name)
+	})
+}
+
+// HeadCommit returns the commit hash HEAD points to
+func (r *Repo) HeadCommit() (string, error) {
+	return r.git.HeadCommit()
+}
+
 // Checkout switches to a branch
 func (r *Repo) Checkout(branch string) error {
 	return r.git.Checkout(branch)
@@ -101,6 +367,51 @@ func (r *Repo) CheckoutNewBranch(name string) error {
 	return r.git.CheckoutNewBranch(name)
 }
 
+// CheckoutNewBranchFrom creates a new branch at start and switches to it
+func (r *Repo) CheckoutNewBranchFrom(name, start string) error {
+	return r.git.CheckoutNewBranchFrom(name, start)
+}
+
+// RenameBranch renames a local branch
+func (r *Repo) RenameBranch(oldName, newName string) error {
+	return r.git.RenameBranch(oldName, newName)
+}
+
+// SetUpstream sets the upstream of a local branch
+func (r *Repo) SetUpstream(branch, upstream string) error {
+	return r.git.SetUpstream(branch, upstream)
+}
+
+// GetConfig returns the effective value of a git config key
+func (r *Repo) GetConfig(key string) (git.ConfigValue, error) {
+	return r.git.GetConfig(key)
+}
+
+// SetLocalConfig sets a git config key in the repo's own .git/config
+func (r *Repo) SetLocalConfig(key, value string) error {
+	return r.git.SetLocalConfig(key, value)
+}
+
+// RemoteHeadBranch asks the remote which branch its HEAD points to
+func (r *Repo) RemoteHeadBranch(remote string) (string, error) {
+	return r.git.RemoteHeadBranch(remote)
+}
+
+// LocalRemoteHead returns the branch the local remote HEAD points to
+func (r *Repo) LocalRemoteHead(remote string) string {
+	return r.git.LocalRemoteHead(remote)
+}
+
+// SetRemoteHead points the local remote HEAD at the given branch
+func (r *Repo) SetRemoteHead(remote, branch string) error {
+	return r.git.SetRemoteHead(remote, branch)
+}
+
+// PruneRemote deletes stale remote-tracking refs
+func (r *Repo) PruneRemote(remote string) error {
+	return r.git.PruneRemote(remote)
+}
+
 // BranchExists checks if a branch exists
 func (r *Repo) BranchExists(name string) bool {
 	return r.git.BranchExists(name)
@@ -111,6 +422,37 @@ func (r *Repo) ListBranches() ([]string, error) {
 	return r.git.ListBranches()
 }
 
+// ListRemoteBranches returns all remote-tracking branches
+func (r *Repo) ListRemoteBranches() ([]string, error) {
+	return r.git.ListRemoteBranches()
+}
+
+// BranchInfo returns the upstream of a local branch and how far ahead and
+// behind it the branch is
+func (r *Repo) BranchInfo(name string) (git.BranchInfo, error) {
+	return r.git.BranchInfo(name)
+}
+
+// Add stages the given paths
+func (r *Repo) Add(paths ...string) error {
+	return r.git.Add(paths...)
+}
+
+// GrepFiles returns tracked text files containing pattern
+func (r *Repo) GrepFiles(pattern string, regex bool, paths ...string) ([]string, error) {
+	return r.git.GrepFiles(pattern, regex, paths...)
+}
+
+// ListFiles returns tracked files matching the given glob pathspecs
+func (r *Repo) ListFiles(paths ...string) ([]string, error) {
+	return r.git.ListFiles(paths...)
+}
+
+// StagedFiles returns the paths of staged changes
+func (r *Repo) StagedFiles() ([]string, error) {
+	return r.git.StagedFiles()
+}
+
 // AddAll stages all changes
 func (r *Repo) AddAll() error {
 	return r.git.AddAll()
@@ -121,19 +463,112 @@ func (r *Repo) Commit(message string) error {
 	return r.git.Commit(message)
 }
 
+// IndexLock returns the clone's index lock file if one exists, or nil
+func (r *Repo) IndexLock() (*git.LockError, error) {
+	return r.git.IndexLock()
+}
+
+// RemoveStaleLock deletes a lock file found stale by IndexLock, if it still is
+func (r *Repo) RemoveStaleLock(lock *git.LockError) error {
+	return r.git.RemoveStaleLock(lock)
+}
+
+// HasChanges returns true if the repo has any uncommitted changes
+func (r *Repo) HasChanges() (bool, error) {
+	return r.git.HasChanges()
+}
+
 // HasStagedChanges returns true if there are staged changes
 func (r *Repo) HasStagedChanges() (bool, error) {
 	return r.git.HasStagedChanges()
 }
 
+// CreateTag creates a tag at HEAD
+func (r *Repo) CreateTag(name, message string) error {
+	return r.git.CreateTag(name, message)
+}
+
+// DeleteTag deletes a local tag
+func (r *Repo) DeleteTag(name string) error {
+	return r.git.DeleteTag(name)
+}
+
+// TagExists checks if a local tag exists
+func (r *Repo) TagExists(name string) bool {
+	return r.git.TagExists(name)
+}
+
+// PushTags pushes all tags to remote
+func (r *Repo) PushTags() error {
+	return r.retry(r.git.PushTags)
+}
+
+// PushTag pushes a single tag to the primary remote
+func (r *Repo) PushTag(name string) error {
+	return r.retry(func() error {
+		return r.git.PushTag(name)
+	})
+}
+
+// DeleteRemoteTag deletes a tag from the primary remote
+func (r *Repo) DeleteRemoteTag(name string) error {
+	return r.retry(func() error {
+		return r.git.DeleteRemoteTag(name)
+	})
+}
+
+// ListTags returns all local tags
+func (r *Repo) ListTags() ([]string, error) {
+	return r.git.ListTags()
+}
+
+// LatestTag returns the highest version tag, or "" if there are no tags
+func (r *Repo) LatestTag() (string, error) {
+	return r.git.LatestTag()
+}
+
+// Stash stashes local changes
+func (r *Repo) Stash(message string) error {
+	return r.git.Stash(message)
+}
+
+// StashPop applies and removes the stash entry ref, or the most recent one
+// if ref is ""
+func (r *Repo) StashPop(ref string) error {
+	return r.git.StashPop(ref)
+}
+
+// StashList returns stash entries, most recent first
+func (r *Repo) StashList() ([]git.StashEntry, error) {
+	return r.git.StashList()
+}
+
 // Fetch fetches from remote
 func (r *Repo) Fetch() error {
-	return r.git.Fetch()
+	return r.retry(r.git.Fetch)
+}
+
+// FetchPrune fetches and prunes deleted remote branches, returning the
+// pruned refs
+func (r *Repo) FetchPrune() ([]string, error) {
+	var pruned []string
+	err := r.retry(func() error {
+		var err error
+		pruned, err = r.git.FetchPrune()
+		return err
+	})
+	return pruned, err
 }
 
-// RunGit executes an arbitrary git command and returns stdout, stderr, and error
-func (r *Repo) RunGit(args ...string) (stdout, stderr string, err error) {
-	return r.git.RunRaw(args...)
+// Diff returns the diff for the repo
+func (r *Repo) Diff(opts git.DiffOptions) (*git.Diff, error) {
+	return r.git.Diff(opts)
+}
+
+// RunGit executes an arbitrary git command and returns stdout, stderr, and
+// error. The output is colored only if color is set.
+func (r *Repo) RunGit(color bool, args ...string) (stdout, stderr string, err error) {
+	return r.git.RunCommand(color, args...)
 }
 
 // GetPR returns PR info for the current branch
@@ -141,9 +576,19 @@ func (r *Repo) GetPR() (*git.PRInfo, error) {
 	return r.git.GetPR()
 }
 
+// ListPRs lists the repo's PRs in state, e.g. "open" or "all"
+func (r *Repo) ListPRs(state string) ([]git.PRInfo, error) {
+	return r.git.ListPRs(state)
+}
+
+// GetPRChecks returns the CI checks of the PR for the current branch
+func (r *Repo) GetPRChecks() ([]git.PRCheck, error) {
+	return r.git.GetPRChecks()
+}
+
 // CreatePR creates a new pull request
-func (r *Repo) CreatePR(title, body, base string) (*git.PRInfo, error) {
-	return r.git.CreatePR(title, body, base)
+func (r *Repo) CreatePR(title, body, base string, draft bool, meta git.PRMetadata) (*git.PRInfo, error) {
+	return r.git.CreatePR(title, body, base, draft, meta)
 }
 
 // ClosePR closes the pull request for the current branch
@@ -151,7 +596,98 @@ func (r *Repo) ClosePR() error {
 	return r.git.ClosePR()
 }
 
-// GetBranchCommits returns commit messages for the current branch
-func (r *Repo) GetBranchCommits(base string) ([]string, error) {
+// MarkPRDraft converts the pull request for the current branch to a draft
+func (r *Repo) MarkPRDraft() error {
+	return r.git.MarkPRDraft()
+}
+
+// MarkPRReady marks the draft pull request for the current branch as ready
+// for review
+func (r *Repo) MarkPRReady() error {
+	return r.git.MarkPRReady()
+}
+
+// MergePR merges the pull request for the current branch, or enables
+// auto-merge for it
+func (r *Repo) MergePR(opts git.MergeOptions) error {
+	return r.git.MergePR(opts)
+}
+
+// PRBody returns the body of the pull request for the current branch
+func (r *Repo) PRBody() (string, error) {
+	return r.git.PRBody()
+}
+
+// EditPRBody replaces the body of the pull request for the current branch
+func (r *Repo) EditPRBody(body string) error {
+	return r.git.EditPRBody(body)
+}
+
+// UpdatePR replaces the title and body of the pull request for the current
+// branch, leaving an empty one as it is
+func (r *Repo) UpdatePR(title, body string) error {
+	return r.git.UpdatePR(title, body)
+}
+
+// GetPRReviews returns the review status of the pull request for the
+// current branch
+func (r *Repo) GetPRReviews() (*git.PRReviews, error) {
+	return r.git.GetPRReviews()
+}
+
+// RequestReviewers asks users and teams to review the pull request for the
+// current branch
+func (r *Repo) RequestReviewers(users, teams []string) error {
+	return r.git.RequestReviewers(users, teams)
+}
+
+// EditPRLabels adds and removes labels on the pull request for the current
+// branch
+func (r *Repo) EditPRLabels(add, remove []string) error {
+	return r.git.EditPRLabels(add, remove)
+}
+
+// DefaultBase returns the remote default branch to compare against
+func (r *Repo) DefaultBase() (string, error) {
+	return r.git.DefaultBase()
+}
+
+// Log returns commits for the repo
+func (r *Repo) Log(opts git.LogOptions) ([]git.Commit, error) {
+	return r.git.Log(opts)
+}
+
+// GetBranchCommits returns the commits on the current branch not on base
+func (r *Repo) GetBranchCommits(base string) ([]git.Commit, error) {
 	return r.git.GetBranchCommits(base)
 }
+
+// RunMaintenance runs git maintenance in the repo, or git gc --auto on a
+// git without it, retrying as a fetch would since it prefetches
+func (r *Repo) RunMaintenance() (fellBack bool, err error) {
+	err = r.retry(func() error {
+		fellBack, err = r.git.RunMaintenance()
+		return err
+	})
+	return fellBack, err
+}
+
+// RegisterMaintenance enrolls the repo in git's background maintenance
+func (r *Repo) RegisterMaintenance() error {
+	return r.git.RegisterMaintenance()
+}
+
+// UnregisterMaintenance removes the repo from git's background maintenance
+func (r *Repo) UnregisterMaintenance() (bool, error) {
+	return r.git.UnregisterMaintenance()
+}
+
+// GitDirSize returns the size in bytes of the repo's git directory
+func (r *Repo) GitDirSize() (int64, error) {
+	return r.git.GitDirSize()
+}
+
+// CountObjects returns how many loose objects and packs the repo has
+func (r *Repo) CountObjects() (git.ObjectCounts, error) {
+	return r.git.CountObjects()
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/config"
+)
+
+// newTestRepo returns a repo at path under a new workspace root, cloned
+// from a new bare remote with one commit. Nothing is at path yet.
+func newTestRepo(t *testing.T, path string) *Repo {
+	t.Helper()
+	home := t.TempDir()
+	gitconfig := filepath.Join(home, ".gitconfig")
+	if err := os.WriteFile(gitconfig, []byte("[user]\n\tname = Test\n\temail = test@example.com\n[commit]\n\tgpgsign = false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gitconfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	seed := filepath.Join(t.TempDir(), "seed")
+	runGit(t, "", "init", "-q", "--bare", "-b", "main", remote)
+	runGit(t, "", "init", "-q", "-b", "main", seed)
+	runGit(t, seed, "commit", "-q", "--allow-empty", "-m", "initial")
+	runGit(t, seed, "push", "-q", remote, "main")
+
+	return New(config.RepoConfig{URL: remote, Path: path}, t.TempDir())
+}
+
+// runGit runs git in dir, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// mkdir creates the repo's directory with the given files in it
+func mkdir(t *testing.T, r *Repo, files ...string) {
+	t.Helper()
+	if err := os.MkdirAll(r.FullPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(r.FullPath, f), []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClearTarget(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		setup    func(t *testing.T, r *Repo)
+		leftover bool
+		err      string // in the error without force, "" for none
+	}{
+		{
+			name:  "missing",
+			setup: func(*testing.T, *Repo) {},
+		},
+		{
+			name:  "empty directory",
+			setup: func(t *testing.T, r *Repo) { mkdir(t, r) },
+		},
+		{
+			name:     "other files",
+			setup:    func(t *testing.T, r *Repo) { mkdir(t, r, "notes.txt") },
+			leftover: true,
+			err:      "exists and is not empty",
+		},
+		{
+			name: "interrupted clone",
+			setup: func(t *testing.T, r *Repo) {
+				runGit(t, "", "init", "-q", r.FullPath)
+			},
+			leftover: true,
+			err:      "interrupted clone",
+		},
+		{
+			// Could be new work in a repo without commits, so it isn't
+			// treated as a leftover, but force still clears it
+			name: "repository without commits but with files",
+			setup: func(t *testing.T, r *Repo) {
+				runGit(t, "", "init", "-q", r.FullPath)
+				mkdir(t, r, "README.md")
+			},
+			err: "interrupted clone",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t, "api")
+			tt.setup(t, r)
+
+			if got := r.Leftover(); got != tt.leftover {
+				t.Errorf("Leftover = %v, want %v", got, tt.leftover)
+			}
+
+			err := r.clearTarget(false)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("clearTarget = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("clearTarget = %v, want an error with %q", err, tt.err)
+			}
+			if tt.err != "" {
+				if _, statErr := os.Stat(r.FullPath); statErr != nil {
+					t.Errorf("clearTarget without force removed %s", r.FullPath)
+				}
+			}
+
+			if err := r.clearTarget(true); err != nil {
+				t.Fatalf("clearTarget with force = %v", err)
+			}
+			if tt.err != "" {
+				if _, statErr := os.Stat(r.FullPath); !os.IsNotExist(statErr) {
+					t.Errorf("clearTarget with force left %s", r.FullPath)
+				}
+			}
+		})
+	}
+}
+
+func TestClearTargetKeepsClone(t *testing.T) {
+	r := newTestRepo(t, "api")
+	if err := r.Clone(false); err != nil {
+		t.Fatal(err)
+	}
+	if r.Leftover() {
+		t.Error("Leftover = true for a complete clone")
+	}
+	// A clone with local files is still a clone, even if clearTarget would
+	// refuse it without force
+	mkdir(t, r, "local.txt")
+	if r.Leftover() {
+		t.Error("Leftover = true for a clone with local files")
+	}
+}
+
+func TestCloneForceOverLeftover(t *testing.T) {
+	r := newTestRepo(t, "api")
+	runGit(t, "", "init", "-q", r.FullPath)
+
+	if err := r.Clone(false); err == nil {
+		t.Fatal("Clone without force over an interrupted clone succeeded")
+	}
+	if err := r.Clone(true); err != nil {
+		t.Fatalf("Clone with force = %v", err)
+	}
+	if !r.IsCloned() || r.IncompleteClone() || r.Leftover() {
+		t.Error("the clone after force isn't complete")
+	}
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a problem found by Validate
+type ValidationIssue struct {
+	Severity string
+	Message  string
+}
+
+// Validate checks that the repo on disk matches its config: the directory
+// is a git repo, the primary remote points at the configured URL and is
+// reachable with no stale remote-tracking refs
+func (r *Repo) Validate() []ValidationIssue {
+	if !r.Exists() {
+		return []ValidationIssue{{Severity: SeverityError, Message: "directory does not exist"}}
+	}
+	if !r.git.IsRepo() {
+		return []ValidationIssue{{Severity: SeverityError, Message: "directory is not a git repository"}}
+	}
+
+	remote := r.Remote()
+	url, err := r.git.RemoteURL(remote)
+	if err != nil {
+		return []ValidationIssue{{Severity: SeverityError, Message: fmt.Sprintf("no %s remote", remote)}}
+	}
+
+	var issues []ValidationIssue
+	if !sameURL(url, r.Config.URL) {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("remote URL mismatch: %s is %s, config has %s", remote, url, r.Config.URL),
+		})
+	}
+
+	stale, err := r.git.StaleRemoteRefs(remote)
+	if err != nil {
+		issues = append(issues, ValidationIssue{Severity: SeverityError, Message: remote + " is unreachable"})
+	} else if len(stale) > 0 {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("remote refs no longer on %s: %s", remote, strings.Join(stale, ", ")),
+		})
+	}
+
+	return issues
+}
+
+// sameURL compares git URLs, ignoring a trailing slash or .git suffix
+func sameURL(a, b string) bool {
+	normalize := func(u string) string {
+		u = strings.TrimSuffix(u, "/")
+		return strings.TrimSuffix(u, ".git")
+	}
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,45 @@
+// Package retry runs operations again after failures, with exponential
+// backoff between attempts.
+package retry
+
+import (
+	"errors"
+	"time"
+)
+
+// sleep waits between attempts; tests replace it
+var sleep = time.Sleep
+
+// stopError marks an error that should not be retried
+type stopError struct {
+	err error
+}
+
+func (e stopError) Error() string { return e.err.Error() }
+func (e stopError) Unwrap() error { return e.err }
+
+// Stop wraps err so that Do returns it at once instead of retrying
+func Stop(err error) error {
+	if err == nil {
+		return nil
+	}
+	return stopError{err}
+}
+
+// Do runs fn, retrying it up to n times while it fails. The first retry
+// waits delay, and each one after that waits twice as long as the last.
+// An error wrapped with Stop ends the retries and is returned unwrapped.
+func Do(n int, delay time.Duration, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var stop stopError
+		if errors.As(err, &stop) {
+			return stop.err
+		}
+		if err == nil || attempt >= n {
+			return err
+		}
+		sleep(delay)
+		delay *= 2
+	}
+}
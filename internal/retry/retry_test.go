@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeSleep records the delays Do waits instead of waiting
+func fakeSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	t.Cleanup(func() { sleep = orig })
+	return &delays
+}
+
+// failing returns an fn that fails the first n calls, and a count of calls
+func failing(n int, err error) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+func TestDoSucceedsFirstTime(t *testing.T) {
+	delays := fakeSleep(t)
+	fn, calls := failing(0, nil)
+	if err := Do(3, time.Second, fn); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 || len(*delays) != 0 {
+		t.Errorf("calls = %d, delays = %v, want 1 call and no waiting", *calls, *delays)
+	}
+}
+
+func TestDoRetriesWithBackoff(t *testing.T) {
+	delays := fakeSleep(t)
+	fn, calls := failing(3, errors.New("flaky"))
+	if err := Do(3, time.Second, fn); err != nil {
+		t.Fatalf("Do = %v, want success on the last retry", err)
+	}
+	if *calls != 4 {
+		t.Errorf("calls = %d, want 4", *calls)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if !reflect.DeepEqual(*delays, want) {
+		t.Errorf("delays = %v, want %v", *delays, want)
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	fakeSleep(t)
+	errFlaky := errors.New("flaky")
+	fn, calls := failing(10, errFlaky)
+	if err := Do(2, time.Millisecond, fn); !errors.Is(err, errFlaky) {
+		t.Errorf("Do = %v, want the last error", err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+}
+
+func TestDoNoRetries(t *testing.T) {
+	delays := fakeSleep(t)
+	fn, calls := failing(1, errors.New("flaky"))
+	if err := Do(0, 0, fn); err == nil {
+		t.Error("Do with no retries succeeded")
+	}
+	if *calls != 1 || len(*delays) != 0 {
+		t.Errorf("calls = %d, delays = %v, want 1 call and no waiting", *calls, *delays)
+	}
+}
+
+func TestDoStop(t *testing.T) {
+	delays := fakeSleep(t)
+	errFatal := errors.New("fatal")
+	fn, calls := failing(10, Stop(errFatal))
+	err := Do(5, time.Second, fn)
+	if err != errFatal {
+		t.Errorf("Do = %#v, want the unwrapped error", err)
+	}
+	if *calls != 1 || len(*delays) != 0 {
+		t.Errorf("calls = %d, delays = %v, want 1 call and no waiting", *calls, *delays)
+	}
+}
+
+func TestDoStopWrapped(t *testing.T) {
+	fakeSleep(t)
+	errFatal := errors.New("fatal")
+	fn, calls := failing(10, fmt.Errorf("context: %w", Stop(errFatal)))
+	if err := Do(5, time.Second, fn); err != errFatal {
+		t.Errorf("Do = %v, want the error Stop wrapped", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestStopNil(t *testing.T) {
+	if Stop(nil) != nil {
+		t.Error("Stop(nil) != nil")
+	}
+}
@@ -0,0 +1,156 @@
+package workspace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// BranchInfo is metadata describing a cross-repo branch, kept in the
+// workspace state
+type BranchInfo struct {
+	Description string `json:"description,omitempty"`
+	Ticket      string `json:"ticket,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// IsZero reports whether the branch has no metadata
+func (b BranchInfo) IsZero() bool {
+	return b == BranchInfo{}
+}
+
+// String summarizes the metadata on one line, e.g.
+// "JIRA-123: New auth flow (owner: alice)"
+func (b BranchInfo) String() string {
+	var parts []string
+	switch {
+	case b.Ticket != "" && b.Description != "":
+		parts = append(parts, b.Ticket+": "+b.Description)
+	case b.Ticket != "":
+		parts = append(parts, b.Ticket)
+	case b.Description != "":
+		parts = append(parts, b.Description)
+	}
+	if b.Owner != "" {
+		parts = append(parts, "(owner: "+b.Owner+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// BranchInfo returns the metadata for branch, which is empty if none was set
+func (w *Workspace) BranchInfo(branch string) (BranchInfo, error) {
+	st, err := w.loadState()
+	if err != nil {
+		return BranchInfo{}, err
+	}
+	return st.Branches[branch], nil
+}
+
+// SetBranchInfo saves the metadata for branch. Empty metadata removes the
+// branch from the state.
+func (w *Workspace) SetBranchInfo(branch string, info BranchInfo) error {
+	st, err := w.loadState()
+	if err != nil {
+		return err
+	}
+
+	if info.IsZero() {
+		delete(st.Branches, branch)
+	} else {
+		if st.Branches == nil {
+			st.Branches = make(map[string]BranchInfo)
+		}
+		st.Branches[branch] = info
+	}
+	return w.saveState(st)
+}
+
+// PruneBranchInfo removes the metadata of branches that no cloned repo in
+// the config has any more, returning the removed branch names. Every repo is
+// checked, even in a filtered workspace. Nothing is removed when no repo is
+// cloned, since there is nothing to check against.
+func (w *Workspace) PruneBranchInfo() ([]string, error) {
+	st, err := w.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var cloned []*repo.Repo
+	for _, rc := range w.Config.Repos {
+		if r := repo.New(rc, w.Root); r.IsCloned() {
+			cloned = append(cloned, r)
+		}
+	}
+	if len(st.Branches) == 0 || len(cloned) == 0 {
+		return nil, nil
+	}
+
+	var pruned []string
+	for branch := range st.Branches {
+		exists := false
+		for _, r := range cloned {
+			if r.BranchExists("refs/heads/" + branch) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			delete(st.Branches, branch)
+			pruned = append(pruned, branch)
+		}
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+	sort.Strings(pruned)
+
+	if err := w.saveState(st); err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
+
+// PRTitle applies settings.pr.title_template to title for a branch with a
+// ticket. Branches without a ticket keep title as given.
+func (w *Workspace) PRTitle(info BranchInfo, branch, title string) string {
+	if info.Ticket == "" {
+		return title
+	}
+	template := w.Config.Settings.PR.TitleTemplate
+	if template == "" {
+		template = "{ticket}: {title}"
+	}
+	return strings.NewReplacer(
+		"{title}", title,
+		"{ticket}", info.Ticket,
+		"{description}", info.Description,
+		"{owner}", info.Owner,
+		"{branch}", branch,
+	).Replace(template)
+}
+
+// PRBody prepends the ticket and description of a branch to body
+func (w *Workspace) PRBody(info BranchInfo, body string) string {
+	var lines []string
+	if info.Ticket != "" {
+		ticket := info.Ticket
+		if url := w.Config.Settings.PR.TicketURL; url != "" {
+			ticket = fmt.Sprintf("[%s](%s)", info.Ticket, strings.ReplaceAll(url, "{ticket}", info.Ticket))
+		}
+		lines = append(lines, "Ticket: "+ticket)
+	}
+	if info.Description != "" {
+		lines = append(lines, info.Description)
+	}
+	if len(lines) == 0 {
+		return body
+	}
+
+	header := strings.Join(lines, "\n\n")
+	if body == "" {
+		return header
+	}
+	return header + "\n\n" + body
+}
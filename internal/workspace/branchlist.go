@@ -0,0 +1,107 @@
+package workspace
+
+import (
+	"sort"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// BranchListResult holds the branches of a single repo
+type BranchListResult struct {
+	Repo   *repo.Repo
+	Local  []git.BranchInfo // local branches, with their upstreams
+	Remote []string         // remote-tracking branches, e.g. origin/main
+	Error  error
+}
+
+// BranchGroup is a branch name and the repos that have it
+type BranchGroup struct {
+	Name     string
+	Remote   bool             // a remote-tracking branch, e.g. origin/main
+	Repos    []*repo.Repo     // repos with the branch, in workspace order
+	Branches []git.BranchInfo // the branch in each of Repos; only Name is set for remote branches
+}
+
+// ListBranches lists the local branches of every repo with their tracking
+// status if local is set, and the remote-tracking branches if remote is
+func (w *Workspace) ListBranches(local, remote bool) []BranchListResult {
+	results := make([]BranchListResult, len(w.Repos))
+
+	w.each("branch list", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		res.Error = listBranches(r, local, remote, res)
+		return res.Error
+	})
+
+	return results
+}
+
+func listBranches(r *repo.Repo, local, remote bool, res *BranchListResult) error {
+	if !r.IsCloned() {
+		return notCloned(r)
+	}
+
+	if local {
+		names, err := r.ListBranches()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			info, err := r.BranchInfo(name)
+			if err != nil {
+				return err
+			}
+			res.Local = append(res.Local, info)
+		}
+	}
+
+	if remote {
+		names, err := r.ListRemoteBranches()
+		if err != nil {
+			return err
+		}
+		res.Remote = names
+	}
+	return nil
+}
+
+// GroupBranches merges the branches of every repo by name: local branches
+// sorted by name, then remote-tracking branches sorted by name. Repos that
+// failed are left out.
+func GroupBranches(results []BranchListResult) []BranchGroup {
+	var local, remote []BranchGroup
+	localIndex := make(map[string]int)
+	remoteIndex := make(map[string]int)
+
+	for _, res := range results {
+		if res.Error != nil {
+			continue
+		}
+		for _, info := range res.Local {
+			i, ok := localIndex[info.Name]
+			if !ok {
+				i = len(local)
+				localIndex[info.Name] = i
+				local = append(local, BranchGroup{Name: info.Name})
+			}
+			local[i].Repos = append(local[i].Repos, res.Repo)
+			local[i].Branches = append(local[i].Branches, info)
+		}
+		for _, name := range res.Remote {
+			i, ok := remoteIndex[name]
+			if !ok {
+				i = len(remote)
+				remoteIndex[name] = i
+				remote = append(remote, BranchGroup{Name: name, Remote: true})
+			}
+			remote[i].Repos = append(remote[i].Repos, res.Repo)
+			remote[i].Branches = append(remote[i].Branches, git.BranchInfo{Name: name})
+		}
+	}
+
+	sort.Slice(local, func(i, j int) bool { return local[i].Name < local[j].Name })
+	sort.Slice(remote, func(i, j int) bool { return remote[i].Name < remote[j].Name })
+	return append(local, remote...)
+}
@@ -0,0 +1,131 @@
+package workspace
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// CheckpointMaxAge is how long after its last progress a checkpoint can
+// still be resumed
+const CheckpointMaxAge = 24 * time.Hour
+
+// Checkpoint is the saved progress of a run of a command that did not
+// finish in every repo, so a later run can skip the repos it did
+type Checkpoint struct {
+	Started time.Time `json:"started"` // when the first of the resumed runs started
+	Updated time.Time `json:"updated"` // when a repo last finished
+	Done    []string  `json:"done"`    // paths of the repos finished
+}
+
+// Stale reports whether c is too old to resume
+func (c *Checkpoint) Stale() bool {
+	return time.Since(c.Updated) > CheckpointMaxAge
+}
+
+// checkpointRun records the repos a run finishes in the state file as
+// they finish, so the progress survives the run being killed
+type checkpointRun struct {
+	w       *Workspace
+	command string
+	mu      sync.Mutex
+	cp      Checkpoint
+	err     error // first error saving the checkpoint
+}
+
+// LoadCheckpoint returns the saved checkpoint of command, or nil if its
+// last run finished in every repo
+func (w *Workspace) LoadCheckpoint(command string) (*Checkpoint, error) {
+	st, err := w.loadState()
+	if err != nil {
+		return nil, err
+	}
+	return st.Checkpoints[command], nil
+}
+
+// StartCheckpoint starts recording a run of command, replacing its saved
+// checkpoint. With a previous checkpoint to resume, the repos it finished
+// count as finished in this run too. It returns the workspace narrowed to
+// the repos left to run, which records each repo it finishes.
+func (w *Workspace) StartCheckpoint(command string, resume *Checkpoint) (*Workspace, error) {
+	run := &checkpointRun{w: w, command: command, cp: Checkpoint{Started: time.Now()}}
+	done := make(map[string]bool)
+	if resume != nil {
+		run.cp.Started = resume.Started
+		for _, path := range resume.Done {
+			done[path] = true
+		}
+	}
+
+	rest := w.Filter(func(r *repo.Repo) bool { return !done[r.Config.Path] })
+	for _, r := range w.Repos {
+		if done[r.Config.Path] {
+			run.cp.Done = append(run.cp.Done, r.Config.Path)
+		}
+	}
+	run.cp.Updated = time.Now()
+	if err := run.save(); err != nil {
+		return nil, err
+	}
+
+	rest.checkpoint = run
+	return rest, nil
+}
+
+// FinishCheckpoint ends the run started by StartCheckpoint. The
+// checkpoint is removed if every repo finished, and kept for a later run
+// to resume otherwise.
+func (w *Workspace) FinishCheckpoint(failed bool) error {
+	run := w.checkpoint
+	if run == nil {
+		return nil
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.err != nil {
+		return run.err
+	}
+	if failed {
+		return nil
+	}
+
+	st, err := w.loadState()
+	if err != nil {
+		return err
+	}
+	delete(st.Checkpoints, run.command)
+	return w.saveState(st)
+}
+
+// finished records that r finished
+func (run *checkpointRun) finished(r *repo.Repo) {
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	run.cp.Done = append(run.cp.Done, r.Config.Path)
+	run.cp.Updated = time.Now()
+	if err := run.save(); err != nil && run.err == nil {
+		run.err = err
+	}
+}
+
+// save writes the checkpoint into the state file
+func (run *checkpointRun) save() error {
+	st, err := run.w.loadState()
+	if err != nil {
+		return err
+	}
+	if st.Checkpoints == nil {
+		st.Checkpoints = make(map[string]*Checkpoint)
+	}
+	cp := run.cp
+	cp.Done = append([]string(nil), run.cp.Done...)
+	sort.Strings(cp.Done)
+	st.Checkpoints[run.command] = &cp
+	return run.w.saveState(st)
+}
@@ -0,0 +1,47 @@
+package workspace
+
+import (
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// CherryPick applies commit sha to the current branch of every repo. Repos
+// where the commit changes nothing are marked in Skipped, and repos without
+// it fail with git.ErrCommitNotFound. A repo that hits conflicts fails with
+// a git.ConflictError and is left mid-cherry-pick to be continued or
+// aborted.
+func (w *Workspace) CherryPick(sha string) []Result {
+	results := make([]Result, len(w.Repos))
+	durations := w.each("cherry-pick", func(i int, r *repo.Repo) error {
+		results[i] = Result{Repo: r}
+		if !r.IsCloned() {
+			results[i].Error = notCloned(r)
+			return results[i].Error
+		}
+		empty, err := r.CherryPick(sha)
+		results[i].Error = err
+		if empty {
+			results[i].Skipped = "already applied"
+		}
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// CherryPickContinue continues the cherry-pick in progress in every repo,
+// once its conflicts are resolved. Repos that aren't mid-cherry-pick are
+// skipped, with the reason in Skipped.
+func (w *Workspace) CherryPickContinue() []Result {
+	return w.eachStopped("cherry-pick continue", git.StateCherryPick, (*repo.Repo).CherryPickContinue)
+}
+
+// CherryPickAbort abandons the cherry-pick in progress in every repo.
+// Repos that aren't mid-cherry-pick are skipped, with the reason in
+// Skipped.
+func (w *Workspace) CherryPickAbort() []Result {
+	return w.eachStopped("cherry-pick abort", git.StateCherryPick, (*repo.Repo).CherryPickAbort)
+}
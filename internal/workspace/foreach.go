@@ -0,0 +1,61 @@
+package workspace
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// ErrNotRun is the error of repos ForEachShell didn't run the command in,
+// because it stopped at an earlier failure
+var ErrNotRun = errors.New("not run, stopped after an earlier failure")
+
+// ForEachShell runs command with sh -c in the directory of every repo,
+// capturing its output. MERGEISH_REPO is set to the repo's path in the
+// config and MERGEISH_ROOT to the workspace root. With stopOnError, the
+// repos run one at a time in order, and those after the first failure
+// fail with ErrNotRun.
+func (w *Workspace) ForEachShell(command string, stopOnError bool) []GitResult {
+	if stopOnError {
+		results := make([]GitResult, 0, len(w.Repos))
+		failed := false
+		for _, r := range w.Repos {
+			if failed {
+				results = append(results, GitResult{Repo: r, Error: ErrNotRun})
+				continue
+			}
+			one := w.Filter(func(other *repo.Repo) bool { return other == r })
+			res := one.ForEachShell(command, false)[0]
+			failed = res.Error != nil
+			results = append(results, res)
+		}
+		return results
+	}
+
+	results := make([]GitResult, len(w.Repos))
+
+	durations := w.each("foreach", func(i int, r *repo.Repo) error {
+		if !r.IsCloned() {
+			results[i] = GitResult{Repo: r, Error: notCloned(r)}
+			return results[i].Error
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = r.FullPath
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.Env = append(os.Environ(), "MERGEISH_REPO="+r.Config.Path, "MERGEISH_ROOT="+w.Root)
+		err := cmd.Run()
+		results[i] = GitResult{Repo: r, Stdout: stdout.String(), Stderr: stderr.String(), Error: err}
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
@@ -0,0 +1,173 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// DefaultConfigAuditKeys are git config keys that commonly make the same
+// command behave differently from one repo to the next
+var DefaultConfigAuditKeys = []string{
+	"pull.rebase",
+	"pull.ff",
+	"push.default",
+	"core.autocrlf",
+	"commit.gpgsign",
+	"fetch.prune",
+}
+
+// configRequirements flags config values that break how mergeish drives
+// git. Each returns a reason, or "" if the value is fine.
+var configRequirements = map[string]func(value string) string{
+	"push.default": func(value string) string {
+		switch value {
+		case "nothing":
+			return "mergeish push needs push.default to push the current branch"
+		case "matching":
+			return "mergeish push would push every matching branch, not just the current one"
+		}
+		return ""
+	},
+}
+
+// ConfigAuditResult holds the audited git config of a single repo
+type ConfigAuditResult struct {
+	Repo   *repo.Repo
+	Values map[string]git.ConfigValue // keyed by config key
+	Error  error
+}
+
+// ConfigFinding is a repo whose value for a key needs attention
+type ConfigFinding struct {
+	Repo   *repo.Repo
+	Key    string
+	Value  git.ConfigValue
+	Reason string
+}
+
+// ConfigAudit compares git config across the workspace
+type ConfigAudit struct {
+	Keys     []string
+	Results  []ConfigAuditResult
+	Majority map[string]string // most common value per key, "" for unset
+	Findings []ConfigFinding
+}
+
+// ConfigAuditKeys returns the default audit keys followed by any added in
+// settings.config_audit_keys
+func (w *Workspace) ConfigAuditKeys() []string {
+	keys := append([]string(nil), DefaultConfigAuditKeys...)
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		seen[k] = true
+	}
+	for _, k := range w.Config.Settings.ConfigAuditKeys {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// AuditGitConfig reads the audit keys in every repo and reports repos that
+// differ from the workspace majority or use a value mergeish can't work with
+func (w *Workspace) AuditGitConfig() ConfigAudit {
+	audit := ConfigAudit{
+		Keys:     w.ConfigAuditKeys(),
+		Results:  make([]ConfigAuditResult, len(w.Repos)),
+		Majority: make(map[string]string),
+	}
+
+	w.each("git-config audit", func(i int, r *repo.Repo) error {
+		res := &audit.Results[i]
+		res.Repo = r
+		res.Error = auditRepo(r, audit.Keys, res)
+		return res.Error
+	})
+
+	for _, key := range audit.Keys {
+		problem := func(v git.ConfigValue) string {
+			if check := configRequirements[key]; check != nil && v.Set() {
+				return check(v.Value)
+			}
+			return ""
+		}
+
+		// Values mergeish can't work with don't count towards the majority
+		counts := make(map[string]int)
+		var order []string
+		for _, res := range audit.Results {
+			if res.Error != nil || problem(res.Values[key]) != "" {
+				continue
+			}
+			v := res.Values[key].Value
+			if counts[v] == 0 {
+				order = append(order, v)
+			}
+			counts[v]++
+		}
+
+		// Ties go to the value seen first, in config order
+		majority, best := "", 0
+		for _, v := range order {
+			if counts[v] > best {
+				majority, best = v, counts[v]
+			}
+		}
+		audit.Majority[key] = majority
+
+		for _, res := range audit.Results {
+			if res.Error != nil {
+				continue
+			}
+			value := res.Values[key]
+			reason := problem(value)
+			if reason == "" && len(counts) > 1 && value.Value != majority {
+				reason = fmt.Sprintf("differs from most repos (%s)", displayConfigValue(majority))
+			}
+			if reason != "" {
+				audit.Findings = append(audit.Findings, ConfigFinding{Repo: res.Repo, Key: key, Value: value, Reason: reason})
+			}
+		}
+	}
+
+	return audit
+}
+
+func auditRepo(r *repo.Repo, keys []string, res *ConfigAuditResult) error {
+	if !r.IsCloned() {
+		return notCloned(r)
+	}
+
+	res.Values = make(map[string]git.ConfigValue, len(keys))
+	for _, key := range keys {
+		v, err := r.GetConfig(key)
+		if err != nil {
+			return err
+		}
+		res.Values[key] = v
+	}
+	return nil
+}
+
+// displayConfigValue formats a config value for output, showing unset
+// values explicitly
+func displayConfigValue(value string) string {
+	if value == "" {
+		return "unset"
+	}
+	return value
+}
+
+// SetGitConfig sets key to value in the local git config of every repo
+func (w *Workspace) SetGitConfig(key, value string) []Result {
+	return w.forEach("git-config fix", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return r.SetLocalConfig(key, value)
+	})
+}
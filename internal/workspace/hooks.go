@@ -0,0 +1,63 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// HookError is returned when a hook without continue_on_error fails. A
+// failed pre- hook stops the operation before it touches any repo.
+type HookError struct {
+	Name string // e.g. "pre-push"
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %v", e.Name, e.Err)
+}
+
+func (e *HookError) Unwrap() error { return e.Err }
+
+// RunHook runs the named hook from the config in the workspace root, with
+// its output going to mergeish's own. It does nothing if the hook isn't
+// defined. A failure is returned as a HookError, unless the hook has
+// continue_on_error, in which case a warning is printed instead.
+func (w *Workspace) RunHook(name string) error {
+	hook, ok := w.Config.Hooks[name]
+	if !ok {
+		return nil
+	}
+
+	cmd := exec.Command("sh", "-c", hook.Command)
+	cmd.Dir = w.Root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "MERGEISH_HOOK="+name, "MERGEISH_ROOT="+w.Root)
+	if err := cmd.Run(); err != nil {
+		if hook.ContinueOnError {
+			fmt.Fprintf(os.Stderr, "⚠ Warning: %s hook failed: %v\n", name, err)
+			return nil
+		}
+		return &HookError{Name: name, Err: err}
+	}
+	return nil
+}
+
+// withHooks runs the pre- hook of op, then fn, then the post- hook if fn
+// succeeded in every repo. If the pre- hook fails, fn doesn't run.
+func (w *Workspace) withHooks(op string, fn func() ([]Result, error)) ([]Result, error) {
+	if err := w.RunHook("pre-" + op); err != nil {
+		return nil, err
+	}
+	results, err := fn()
+	if err != nil {
+		return results, err
+	}
+	for _, r := range results {
+		if r.Error != nil {
+			return results, nil
+		}
+	}
+	return results, w.RunHook("post-" + op)
+}
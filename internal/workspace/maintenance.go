@@ -0,0 +1,120 @@
+package workspace
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// MaintenanceJobs is how many repos maintenance runs in at once unless
+// --jobs is given. gc is heavy on disk, so it ignores settings.max_jobs.
+const MaintenanceJobs = 2
+
+// Loose objects or packs past which RunMaintenance with ifNeeded considers a
+// repo in need of maintenance. git gc --auto waits for 6700 loose objects
+// and 50 packs, by which point status is already slow in a large repo.
+const (
+	maintenanceLooseLimit = 1000
+	maintenancePackLimit  = 20
+)
+
+// maintenanceConfigMu serializes registering and unregistering, which
+// both write the global git config and fail if another holds its lock
+var maintenanceConfigMu sync.Mutex
+
+// MaintenanceResult is the outcome of maintenance in a single repo
+type MaintenanceResult struct {
+	Repo     *repo.Repo
+	Before   int64  // size of the git directory in bytes before maintenance
+	After    int64  // and after it
+	FellBack bool   // git has no git maintenance, so git gc --auto ran instead
+	Skipped  string // why nothing was done, e.g. "not registered"
+	Error    error
+	Duration time.Duration
+}
+
+// RunMaintenance runs git maintenance (gc, commit-graph, and prefetch) in
+// every repo, measuring the git directory before and after. With ifNeeded,
+// repos with few loose objects and packs are skipped.
+func (w *Workspace) RunMaintenance(ifNeeded bool) []MaintenanceResult {
+	results := make([]MaintenanceResult, len(w.Repos))
+
+	durations := w.each("maintenance run", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		if !r.IsCloned() {
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		if ifNeeded {
+			counts, err := r.CountObjects()
+			if err != nil {
+				res.Error = err
+				return err
+			}
+			if counts.Loose < maintenanceLooseLimit && counts.Packs < maintenancePackLimit {
+				res.Skipped = fmt.Sprintf("not needed (%d loose object(s), %d pack(s))", counts.Loose, counts.Packs)
+				return nil
+			}
+		}
+
+		if res.Before, res.Error = r.GitDirSize(); res.Error != nil {
+			return res.Error
+		}
+		if res.FellBack, res.Error = r.RunMaintenance(); res.Error != nil {
+			return res.Error
+		}
+		res.After, res.Error = r.GitDirSize()
+		return res.Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// RegisterMaintenance enrolls every repo in git's background maintenance.
+// git only runs it once scheduled with git maintenance start.
+func (w *Workspace) RegisterMaintenance() []Result {
+	return w.forEach("maintenance register", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		maintenanceConfigMu.Lock()
+		defer maintenanceConfigMu.Unlock()
+		return r.RegisterMaintenance()
+	})
+}
+
+// UnregisterMaintenance removes every repo from git's background
+// maintenance. Repos that weren't registered are skipped.
+func (w *Workspace) UnregisterMaintenance() []MaintenanceResult {
+	results := make([]MaintenanceResult, len(w.Repos))
+
+	durations := w.each("maintenance unregister", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		if !r.IsCloned() {
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		maintenanceConfigMu.Lock()
+		registered, err := r.UnregisterMaintenance()
+		maintenanceConfigMu.Unlock()
+		if err == nil && !registered {
+			res.Skipped = "not registered"
+		}
+		res.Error = err
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
@@ -0,0 +1,46 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// Merge merges branch into the current branch of every repo, with --no-ff
+// if noFF is set. A repo that hits conflicts fails with a git.ConflictError
+// and is left mid-merge to be resolved or aborted.
+func (w *Workspace) Merge(branch string, noFF bool) []Result {
+	return w.forEach("merge", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		if !r.BranchExists(branch) {
+			return fmt.Errorf("%s does not exist", branch)
+		}
+		return r.Merge(branch, noFF)
+	})
+}
+
+// MergeAbort abandons the merge in progress in every repo. Repos that
+// aren't mid-merge are skipped, with the reason in Skipped.
+func (w *Workspace) MergeAbort() []Result {
+	results := make([]Result, len(w.Repos))
+	durations := w.each("merge abort", func(i int, r *repo.Repo) error {
+		results[i] = Result{Repo: r}
+		switch {
+		case !r.IsCloned():
+			results[i].Error = notCloned(r)
+		case r.State() != git.StateMerge:
+			results[i].Skipped = "no merge in progress"
+		default:
+			results[i].Error = r.MergeAbort()
+		}
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
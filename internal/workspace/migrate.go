@@ -0,0 +1,140 @@
+package workspace
+
+import (
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// DefaultBranchPlan describes how a repo will be migrated to its remote's
+// current default branch
+type DefaultBranchPlan struct {
+	Repo        *repo.Repo
+	OldBranch   string // previous default branch name, "" if unknown
+	NewBranch   string // branch the remote HEAD points to now
+	SetHead     bool   // <remote>/HEAD needs to point at NewBranch
+	RenameLocal bool   // local OldBranch will be renamed to NewBranch
+	PruneStale  bool   // <remote>/OldBranch is still present locally
+	Override    bool   // the repo's settings.default_branch names OldBranch
+	Error       error
+}
+
+// NeedsChanges reports whether the plan has anything to do in the clone.
+// Override is left to the caller, which owns the config.
+func (p *DefaultBranchPlan) NeedsChanges() bool {
+	return p.Error == nil && (p.SetHead || p.RenameLocal || p.PruneStale)
+}
+
+// PlanDefaultBranchMigration inspects each repo's remote HEAD and works out
+// what is needed to follow a default branch rename such as master → main.
+// Nothing is changed.
+func (w *Workspace) PlanDefaultBranchMigration() []DefaultBranchPlan {
+	plans := make([]DefaultBranchPlan, len(w.Repos))
+	for i, r := range w.Repos {
+		plans[i].Repo = r
+	}
+
+	// Each call only writes its own repo's plan
+	w.forEach("migrate plan", func(r *repo.Repo) error {
+		p := planFor(plans, r)
+		if !r.IsCloned() {
+			p.Error = notCloned(r)
+			return nil
+		}
+
+		newBranch, err := r.RemoteHeadBranch(r.Remote())
+		if err != nil {
+			p.Error = err
+			return nil
+		}
+		p.NewBranch = newBranch
+
+		localHead := r.LocalRemoteHead(r.Remote())
+		p.SetHead = localHead != newBranch
+
+		// The old name is whatever <remote>/HEAD used to point at, falling
+		// back to the conventional master/main pair
+		old := ""
+		if localHead != "" && localHead != newBranch {
+			old = localHead
+		} else {
+			for _, candidate := range []string{"master", "main"} {
+				if candidate != newBranch && r.BranchExists("refs/heads/"+candidate) {
+					old = candidate
+					break
+				}
+			}
+		}
+		p.OldBranch = old
+
+		if old != "" {
+			p.Override = r.Config.Settings != nil && r.Config.Settings.DefaultBranch == old
+			p.RenameLocal = r.BranchExists("refs/heads/"+old) && !r.BranchExists("refs/heads/"+newBranch)
+			p.PruneStale = r.BranchExists("refs/remotes/" + r.Remote() + "/" + old)
+		}
+		return nil
+	})
+
+	return plans
+}
+
+// MigrateDefaultBranch applies the given plans. Plans with errors or nothing
+// to do are skipped.
+func (w *Workspace) MigrateDefaultBranch(plans []DefaultBranchPlan) []Result {
+	var pending []DefaultBranchPlan
+	for _, p := range plans {
+		if p.NeedsChanges() {
+			pending = append(pending, p)
+		}
+	}
+
+	sub := w.Filter(func(r *repo.Repo) bool {
+		for _, p := range pending {
+			if p.Repo == r {
+				return true
+			}
+		}
+		return false
+	})
+
+	return sub.forEach("migrate", func(r *repo.Repo) error {
+		p := planFor(pending, r)
+
+		// set-head needs the new remote branch locally
+		if !r.BranchExists("refs/remotes/" + r.Remote() + "/" + p.NewBranch) {
+			if err := r.Fetch(); err != nil {
+				return err
+			}
+		}
+
+		if p.SetHead {
+			if err := r.SetRemoteHead(r.Remote(), p.NewBranch); err != nil {
+				return err
+			}
+		}
+
+		if p.RenameLocal {
+			if err := r.RenameBranch(p.OldBranch, p.NewBranch); err != nil {
+				return err
+			}
+			if err := r.SetUpstream(p.NewBranch, r.Remote()+"/"+p.NewBranch); err != nil {
+				return err
+			}
+		}
+
+		if p.PruneStale {
+			if err := r.PruneRemote(r.Remote()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func planFor(plans []DefaultBranchPlan, r *repo.Repo) *DefaultBranchPlan {
+	for i := range plans {
+		if plans[i].Repo == r {
+			return &plans[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package workspace
+
+import "runtime"
+
+// fdsPerRepo is a generous estimate of the file descriptors mergeish holds
+// open for each repo while its git process runs
+const fdsPerRepo = 16
+
+// defaultMaxJobs caps the derived limit, so a big workspace doesn't open more
+// SSH connections at once than servers commonly allow
+const defaultMaxJobs = 8
+
+// maxJobs returns how many repos may run at once: MaxJobs, or else a limit
+// derived from the CPU count and the open file limit
+func (w *Workspace) maxJobs() int {
+	if w.MaxJobs > 0 {
+		return w.MaxJobs
+	}
+
+	// git spends most of its time waiting on disk and network, so run
+	// several per CPU
+	n := min(4*runtime.NumCPU(), defaultMaxJobs)
+	if files := openFileLimit(); files > 0 && files/fdsPerRepo < n {
+		n = files / fdsPerRepo
+	}
+	return max(n, 1)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package workspace
+
+// openFileLimit returns 0: there is no open file limit to detect here
+func openFileLimit() int {
+	return 0
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package workspace
+
+import "syscall"
+
+// openFileLimit returns the soft limit on open files, or 0 if unknown or
+// too high to matter
+func openFileLimit() int {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil || rl.Cur > 1<<30 {
+		return 0
+	}
+	return int(rl.Cur)
+}
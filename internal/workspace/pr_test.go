@@ -0,0 +1,81 @@
+package workspace
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+func TestResolveBase(t *testing.T) {
+	ws := testWorkspace(t, "a")
+	r := ws.Repos[0]
+
+	if got := ws.ResolveBase(r, ""); got != "" {
+		t.Errorf("ResolveBase without origin/HEAD = %q, want \"\"", got)
+	}
+
+	runGit(t, r.FullPath, "remote", "set-head", "origin", "main")
+	if got := ws.ResolveBase(r, ""); got != "main" {
+		t.Errorf("ResolveBase with origin/HEAD = %q, want main", got)
+	}
+
+	r.Config.PRBase = "develop"
+	if got := ws.ResolveBase(r, ""); got != "develop" {
+		t.Errorf("ResolveBase with pr_base = %q, want develop", got)
+	}
+	if got := ws.ResolveBase(r, "release"); got != "release" {
+		t.Errorf("ResolveBase with an override = %q, want release", got)
+	}
+}
+
+func TestCreatePRsOmitsUnknownBase(t *testing.T) {
+	log := fakeGH(t)
+	ws := testWorkspace(t, "a")
+	dir := ws.Repos[0].FullPath
+	runGit(t, dir, "checkout", "-q", "-b", "feat")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "change")
+
+	results, err := ws.CreatePRs("T", func(*repo.Repo) string { return "" }, PRCreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Error != nil || r.PR == nil || !r.Pushed {
+		t.Fatalf("CreatePRs = %+v, want the branch pushed and a PR", r)
+	}
+
+	for _, call := range ghCalls(t, log) {
+		if strings.HasPrefix(call, "pr create") && strings.Contains(call, "--base") {
+			t.Errorf("gh %s: want no --base when origin/HEAD isn't known", call)
+		}
+	}
+}
+
+func TestCreatePRsMixedBase(t *testing.T) {
+	log := fakeGH(t)
+	ws := testWorkspace(t, "a", "b")
+	for _, r := range ws.Repos {
+		runGit(t, r.FullPath, "remote", "set-head", "origin", "main")
+	}
+	ws.Repos[1].Config.PRBase = "develop"
+
+	_, err := ws.CreatePRs("T", func(*repo.Repo) string { return "" }, PRCreateOptions{})
+	var mixed *MixedBaseError
+	if !errors.As(err, &mixed) {
+		t.Fatalf("CreatePRs = %v, want a MixedBaseError", err)
+	}
+	if len(mixed.Groups["main"]) != 1 || len(mixed.Groups["develop"]) != 1 {
+		t.Errorf("groups = %v, want a and b apart", mixed.Groups)
+	}
+	if calls := ghCalls(t, log); len(calls) > 0 {
+		t.Errorf("gh was called despite the mixed bases: %v", calls)
+	}
+
+	// One base for all, or allowing the mix, gets past the check
+	for _, opts := range []PRCreateOptions{{Base: "main"}, {AllowMixedBase: true}} {
+		if _, err := ws.CreatePRs("T", func(*repo.Repo) string { return "" }, opts); err != nil {
+			t.Errorf("CreatePRs(%+v) = %v, want no error", opts, err)
+		}
+	}
+}
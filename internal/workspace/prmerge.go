@@ -0,0 +1,132 @@
+package workspace
+
+import (
+	"strings"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// MergeCheck is whether the PR for the current branch of a single repo can
+// be merged, as found by CheckMerge
+type MergeCheck struct {
+	Repo   *repo.Repo
+	PR     *git.PRInfo // nil if the branch has no PR
+	NoPRs  bool        // the repo has no PR provider and was skipped
+	Reason string      // why the PR can't be merged, e.g. "is a draft", or "" if it can
+	Error  error
+}
+
+// Blocked reports whether the check stops the merge: it failed, or found a
+// PR that can't be merged. Repos without a PR and merged PRs don't block.
+func (c MergeCheck) Blocked() bool {
+	return c.Error != nil || (c.PR != nil && c.PR.State != "MERGED" && c.Reason != "")
+}
+
+// Open reports whether the check found an open PR, which MergePRs merges
+func (c MergeCheck) Open() bool {
+	return c.Error == nil && c.PR != nil && c.PR.State == "OPEN"
+}
+
+// MergePROptions controls how MergePRs merges pull requests
+type MergePROptions struct {
+	git.MergeOptions
+	Force bool // merge the open PRs even if some checks are blocked
+}
+
+// NotMergeableError is returned by MergePRs when some PRs can't be merged
+// and Force isn't set. No PR is merged.
+type NotMergeableError struct {
+	Blocked []MergeCheck
+}
+
+func (e *NotMergeableError) Error() string {
+	names := make([]string, len(e.Blocked))
+	for i, c := range e.Blocked {
+		names[i] = c.Repo.Name()
+	}
+	return "PRs not ready to merge: " + strings.Join(names, ", ")
+}
+
+// CheckMerge looks up the PR for the current branch of every repo and
+// whether it can be merged as opts says, for MergePRs. Nothing is merged.
+func (w *Workspace) CheckMerge(opts git.MergeOptions) []MergeCheck {
+	prs := w.GetPRs()
+	checks := make([]MergeCheck, len(prs))
+	for i, r := range prs {
+		checks[i] = MergeCheck{Repo: r.Repo, PR: r.PR, NoPRs: r.NoPRs, Error: r.Error}
+		if r.Error == nil && r.PR != nil && r.PR.State != "MERGED" {
+			checks[i].Reason = notMergeable(r.PR, opts)
+		}
+	}
+	return checks
+}
+
+// notMergeable returns why pr can't be merged as opts says, or "" if it
+// can. With auto-merge, pending checks are fine, as GitHub waits for them.
+// As admin, no checks count, as the merge bypasses them.
+func notMergeable(pr *git.PRInfo, opts git.MergeOptions) string {
+	switch {
+	case pr.State != "OPEN":
+		return "is " + strings.ToLower(pr.State)
+	case pr.Draft:
+		return "is a draft"
+	case pr.Checks == "fail" && !opts.Admin:
+		return "has failing checks"
+	case pr.Checks == "pending" && !opts.Auto && !opts.Admin:
+		return "has pending checks"
+	case pr.Mergeable == "CONFLICTING":
+		return "has merge conflicts"
+	}
+	return ""
+}
+
+// VerifyMerge returns a *NotMergeableError if a check failed or found a PR
+// that can't be merged as opts says, unless opts.Force is set. The reasons
+// are worked out again, in case the checks were made with other options.
+func VerifyMerge(checks []MergeCheck, opts MergePROptions) error {
+	var blocked []MergeCheck
+	for _, c := range checks {
+		if c.Error == nil && c.PR != nil && c.PR.State != "MERGED" {
+			c.Reason = notMergeable(c.PR, opts.MergeOptions)
+		}
+		if c.Blocked() {
+			blocked = append(blocked, c)
+		}
+	}
+	if len(blocked) > 0 && !opts.Force {
+		return &NotMergeableError{Blocked: blocked}
+	}
+	return nil
+}
+
+// MergePRs merges the open PRs found by CheckMerge as opts says, or with
+// opts.Auto enables auto-merge for them. Every PR is verified first with
+// VerifyMerge, and if that fails nothing is merged. The results cover only
+// the repos whose PR was merged.
+func (w *Workspace) MergePRs(checks []MergeCheck, opts MergePROptions) ([]PRResult, error) {
+	if err := VerifyMerge(checks, opts); err != nil {
+		return nil, err
+	}
+
+	open := make(map[*repo.Repo]bool)
+	for _, c := range checks {
+		open[c.Repo] = c.Open()
+	}
+	sub := w.Filter(func(r *repo.Repo) bool { return open[r] })
+	results := make([]PRResult, len(sub.Repos))
+	durations := sub.each("pr merge", func(i int, r *repo.Repo) error {
+		results[i] = PRResult{Repo: r}
+		if !r.IsCloned() {
+			results[i].Error = notCloned(r)
+		} else {
+			results[i].Error = r.MergePR(opts.MergeOptions)
+		}
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results, nil
+}
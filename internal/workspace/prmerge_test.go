@@ -0,0 +1,135 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/git"
+)
+
+// fakePRs puts a gh on PATH that lists the PR JSON of prs, keyed by repo
+// directory name, and logs its calls. Repos not in prs have no PR.
+func fakePRs(t *testing.T, prs map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, pr := range prs {
+		if err := os.WriteFile(filepath.Join(dir, "pr-"+name), []byte("["+pr+"]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log := filepath.Join(dir, "log")
+	script := `#!/bin/sh
+echo "$(basename "$PWD") $*" >> ` + log + `
+prs=` + dir + `/pr-$(basename "$PWD")
+case "$1 $2" in
+"pr list") if [ -f "$prs" ]; then cat "$prs"; else echo '[]'; fi;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// merged returns the repos gh merged a PR in, from the log of fakePRs
+func merged(t *testing.T, log string) []string {
+	t.Helper()
+	var repos []string
+	for _, call := range ghCalls(t, log) {
+		if name, args, _ := strings.Cut(call, " "); strings.HasPrefix(args, "pr merge") {
+			repos = append(repos, name)
+		}
+	}
+	slices.Sort(repos)
+	return repos
+}
+
+const (
+	readyPR   = `{"number":1,"state":"OPEN","mergeable":"MERGEABLE"}`
+	draftPR   = `{"number":2,"state":"OPEN","isDraft":true}`
+	pendingPR = `{"number":3,"state":"OPEN","statusCheckRollup":[{"status":"IN_PROGRESS"}]}`
+	mergedPR  = `{"number":4,"state":"MERGED"}`
+)
+
+func TestMergePRsRefusesUnlessAllReady(t *testing.T) {
+	log := fakePRs(t, map[string]string{"a": readyPR, "b": draftPR, "c": mergedPR})
+	ws := testWorkspace(t, "a", "b", "c", "d")
+	opts := MergePROptions{MergeOptions: git.MergeOptions{Strategy: git.MergeStrategySquash}}
+
+	checks := ws.CheckMerge(opts.MergeOptions)
+	results, err := ws.MergePRs(checks, opts)
+	var notMergeable *NotMergeableError
+	if !errors.As(err, &notMergeable) {
+		t.Fatalf("MergePRs with a draft = %v, want a NotMergeableError", err)
+	}
+	if len(notMergeable.Blocked) != 1 || notMergeable.Blocked[0].Repo.Name() != "b" || notMergeable.Blocked[0].Reason != "is a draft" {
+		t.Errorf("Blocked = %+v, want b as a draft", notMergeable.Blocked)
+	}
+	if results != nil || merged(t, log) != nil {
+		t.Errorf("MergePRs merged %v though a PR wasn't ready", merged(t, log))
+	}
+
+	opts.Force = true
+	results, err = ws.MergePRs(checks, opts)
+	if err != nil {
+		t.Fatalf("MergePRs with --force: %v", err)
+	}
+	// The draft is open, so --force merges it too; the merged PR and the
+	// repo without one are skipped
+	if got := merged(t, log); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("merged %v with --force, want the open PRs a and b", got)
+	}
+	if len(results) != 2 {
+		t.Errorf("MergePRs returned %d results, want one per merged repo", len(results))
+	}
+}
+
+func TestMergePRsVerifiesWithItsOptions(t *testing.T) {
+	log := fakePRs(t, map[string]string{"a": readyPR, "b": pendingPR})
+	ws := testWorkspace(t, "a", "b")
+
+	// Checked for auto-merge, where pending checks are fine, but merged
+	// right away
+	checks := ws.CheckMerge(git.MergeOptions{Auto: true})
+	for _, c := range checks {
+		if c.Blocked() {
+			t.Fatalf("%s blocked for auto-merge: %s", c.Repo.Name(), c.Reason)
+		}
+	}
+	_, err := ws.MergePRs(checks, MergePROptions{MergeOptions: git.MergeOptions{Strategy: git.MergeStrategyMerge}})
+	var notMergeable *NotMergeableError
+	if !errors.As(err, &notMergeable) || notMergeable.Blocked[0].Reason != "has pending checks" {
+		t.Fatalf("MergePRs of a pending PR = %v, want it blocked for pending checks", err)
+	}
+	if got := merged(t, log); got != nil {
+		t.Errorf("merged %v", got)
+	}
+
+	if _, err := ws.MergePRs(checks, MergePROptions{MergeOptions: git.MergeOptions{Strategy: git.MergeStrategyMerge, Auto: true}}); err != nil {
+		t.Fatalf("MergePRs with auto-merge: %v", err)
+	}
+	if got := merged(t, log); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("auto-merge enabled for %v, want a and b", got)
+	}
+}
+
+func TestMergePRsCheckFailureBlocks(t *testing.T) {
+	log := fakePRs(t, map[string]string{"a": readyPR})
+	ws := testWorkspace(t, "a", "b")
+	checks := ws.CheckMerge(git.MergeOptions{})
+	checks[1].Error = errors.New("gh pr list: exit status 1")
+
+	_, err := ws.MergePRs(checks, MergePROptions{MergeOptions: git.MergeOptions{Strategy: git.MergeStrategyMerge}})
+	var notMergeable *NotMergeableError
+	if !errors.As(err, &notMergeable) || notMergeable.Blocked[0].Repo.Name() != "b" {
+		t.Fatalf("MergePRs after a failed check = %v, want b blocking", err)
+	}
+	if got := merged(t, log); got != nil {
+		t.Errorf("merged %v though a repo couldn't be checked", got)
+	}
+}
@@ -0,0 +1,72 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// PRTemplatePaths are where GitHub looks for a repo's pull request
+// template, in the order PRTemplate tries them
+var PRTemplatePaths = []string{
+	".github/PULL_REQUEST_TEMPLATE.md",
+	".github/pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+}
+
+// CommitsPlaceholder marks where RenderPRTemplate puts the commit list
+const CommitsPlaceholder = "{{commits}}"
+
+// PRTemplate returns the pull request template of r: the first of
+// PRTemplatePaths in the repo, or else settings.pr.template_file, relative
+// to the workspace root. It returns "" if the repo has none and no
+// template_file is set. Line endings are normalized to \n.
+func (w *Workspace) PRTemplate(r *repo.Repo) (string, error) {
+	for _, p := range PRTemplatePaths {
+		data, err := os.ReadFile(filepath.Join(r.FullPath, filepath.FromSlash(p)))
+		if err == nil {
+			return normalizeNewlines(string(data)), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	file := w.Config.Settings.PR.TemplateFile
+	if file == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(w.Root, file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("reading settings.pr.template_file: %w", err)
+	}
+	return normalizeNewlines(string(data)), nil
+}
+
+// RenderPRTemplate puts commits in place of CommitsPlaceholder in
+// template, or after the template if it has no placeholder
+func RenderPRTemplate(template, commits string) string {
+	if strings.Contains(template, CommitsPlaceholder) {
+		return strings.ReplaceAll(template, CommitsPlaceholder, strings.TrimSpace(commits))
+	}
+	if commits == "" {
+		return template
+	}
+	return strings.TrimRight(template, "\n") + "\n\n" + commits
+}
+
+// normalizeNewlines turns \r\n and lone \r line endings into \n
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
@@ -0,0 +1,173 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/config"
+)
+
+// templateWorkspace creates a workspace with repos api and web as plain
+// directories, which is all PRTemplate needs
+func templateWorkspace(t *testing.T, templateFile string) *Workspace {
+	t.Helper()
+	root := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Settings.PR.TemplateFile = templateFile
+	for _, p := range []string{"api", "web"} {
+		if err := os.MkdirAll(filepath.Join(root, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		cfg.Repos = append(cfg.Repos, config.RepoConfig{URL: "git@github.com:org/" + p + ".git", Path: p})
+	}
+	return New(cfg, root)
+}
+
+func TestPRTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		files        map[string]string // relative to the repo, or to the root with a / prefix
+		templateFile string
+		want         string
+		wantErr      string
+	}{
+		{
+			name: "no template and no template_file",
+			want: "",
+		},
+		{
+			name:  "repo template",
+			files: map[string]string{".github/PULL_REQUEST_TEMPLATE.md": "## Why\n"},
+			want:  "## Why\n",
+		},
+		{
+			name:  "lower-case repo template",
+			files: map[string]string{".github/pull_request_template.md": "lower\n"},
+			want:  "lower\n",
+		},
+		{
+			name: "first of PRTemplatePaths wins",
+			files: map[string]string{
+				"docs/PULL_REQUEST_TEMPLATE.md":    "docs\n",
+				".github/PULL_REQUEST_TEMPLATE.md": "github\n",
+				"PULL_REQUEST_TEMPLATE.md":         "root\n",
+			},
+			want: "github\n",
+		},
+		{
+			name:  "CRLF repo template",
+			files: map[string]string{".github/PULL_REQUEST_TEMPLATE.md": "## Why\r\n\r\n{{commits}}\r\n"},
+			want:  "## Why\n\n{{commits}}\n",
+		},
+		{
+			name:  "lone CR line endings",
+			files: map[string]string{".github/PULL_REQUEST_TEMPLATE.md": "a\rb\r"},
+			want:  "a\nb\n",
+		},
+		{
+			name:         "template_file when the repo has none",
+			files:        map[string]string{"/templates/pr.md": "shared\r\n"},
+			templateFile: "templates/pr.md",
+			want:         "shared\n",
+		},
+		{
+			name: "repo template before template_file",
+			files: map[string]string{
+				".github/PULL_REQUEST_TEMPLATE.md": "repo\n",
+				"/templates/pr.md":                 "shared\n",
+			},
+			templateFile: "templates/pr.md",
+			want:         "repo\n",
+		},
+		{
+			name:         "missing template_file",
+			templateFile: "templates/missing.md",
+			wantErr:      "reading settings.pr.template_file",
+		},
+		{
+			name:  "empty repo template",
+			files: map[string]string{".github/PULL_REQUEST_TEMPLATE.md": ""},
+			want:  "",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := templateWorkspace(t, tt.templateFile)
+			r := ws.Repos[0]
+			for path, content := range tt.files {
+				if rootPath, ok := strings.CutPrefix(path, "/"); ok {
+					writeFile(t, ws.Root, rootPath, content)
+				} else {
+					writeFile(t, r.FullPath, path, content)
+				}
+			}
+
+			got, err := ws.PRTemplate(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PRTemplate error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("PRTemplate = %q, want %q", got, tt.want)
+			}
+
+			// The other repo has no template of its own
+			if len(tt.files) > 0 && tt.templateFile == "" {
+				if other, err := ws.PRTemplate(ws.Repos[1]); err != nil || other != "" {
+					t.Errorf("PRTemplate of the repo without one = %q, %v", other, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPRTemplateAbsoluteTemplateFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pr.md")
+	if err := os.WriteFile(file, []byte("abs\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ws := templateWorkspace(t, file)
+	got, err := ws.PRTemplate(ws.Repos[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "abs\n" {
+		t.Errorf("PRTemplate = %q, want %q", got, "abs\n")
+	}
+}
+
+func TestPRTemplateUnreadable(t *testing.T) {
+	ws := templateWorkspace(t, "")
+	r := ws.Repos[0]
+	// A directory where the template should be is an error, not a missing file
+	if err := os.MkdirAll(filepath.Join(r.FullPath, ".github", "PULL_REQUEST_TEMPLATE.md"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.PRTemplate(r); err == nil {
+		t.Error("PRTemplate succeeded with a directory in place of the template")
+	}
+}
+
+func TestRenderPRTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		name, template, commits, want string
+	}{
+		{"placeholder", "## Why\n\n{{commits}}\n\n## Test\n", "- a\n- b\n", "## Why\n\n- a\n- b\n\n## Test\n"},
+		{"placeholder without commits", "## Why\n{{commits}}\n", "", "## Why\n\n"},
+		{"every placeholder", "{{commits}}\n---\n{{commits}}", "- a\n", "- a\n---\n- a"},
+		{"no placeholder", "## Why\n\n\n", "- a\n", "## Why\n\n- a\n"},
+		{"no placeholder or commits", "## Why\n", "", "## Why\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderPRTemplate(tt.template, tt.commits); got != tt.want {
+				t.Errorf("RenderPRTemplate(%q, %q) = %q, want %q", tt.template, tt.commits, got, tt.want)
+			}
+		})
+	}
+}
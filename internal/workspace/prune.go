@@ -0,0 +1,105 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// PruneResult describes the merged branches of a single repo, and once
+// PruneBranches has run, which of them were deleted
+type PruneResult struct {
+	Repo          *repo.Repo
+	Base          string   // ref the branches are merged into
+	Branches      []string // merged branches to delete
+	Deleted       []string // branches deleted locally
+	RemoteDeleted []string // branches deleted from the primary remote
+	Error         error
+}
+
+// MergedBranches finds the local branches of every repo that are fully
+// merged into its default branch, for PruneBranches to delete. The current
+// branch, the default branch, and protected branches are left out.
+func (w *Workspace) MergedBranches() []PruneResult {
+	protected := make(map[string]bool)
+	for _, b := range w.Config.Settings.ProtectedBranches {
+		protected[b] = true
+	}
+
+	results := make([]PruneResult, len(w.Repos))
+	w.each("branch prune check", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		if !r.IsCloned() {
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		current, _, err := r.Branch()
+		if err != nil {
+			res.Error = err
+			return err
+		}
+
+		// Compare with the remote's default branch, which is where PRs are
+		// merged, if it is known
+		def := w.DefaultBranch(r)
+		res.Base = def
+		if remoteDef := r.Remote() + "/" + def; r.BranchExists(remoteDef) {
+			res.Base = remoteDef
+		}
+
+		merged, err := r.MergedBranches(res.Base)
+		if err != nil {
+			res.Error = err
+			return err
+		}
+		for _, b := range merged {
+			if b != current && b != def && !protected[b] {
+				res.Branches = append(res.Branches, b)
+			}
+		}
+		return nil
+	})
+	return results
+}
+
+// PruneBranches deletes the branches found by MergedBranches, and with
+// remote also deletes them from the primary remote where they still exist
+func (w *Workspace) PruneBranches(plan []PruneResult, remote bool) []PruneResult {
+	results := make([]PruneResult, len(plan))
+	copy(results, plan)
+
+	byRepo := make(map[*repo.Repo]int, len(plan))
+	for i, p := range plan {
+		byRepo[p.Repo] = i
+	}
+	withBranches := w.Filter(func(r *repo.Repo) bool {
+		i, ok := byRepo[r]
+		return ok && plan[i].Error == nil && len(plan[i].Branches) > 0
+	})
+
+	withBranches.each("branch prune", func(_ int, r *repo.Repo) error {
+		res := &results[byRepo[r]]
+		var errs []error
+		for _, b := range res.Branches {
+			if err := r.DeleteBranch(b); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", b, err))
+				continue
+			}
+			res.Deleted = append(res.Deleted, b)
+
+			if remote && r.BranchExists(r.Remote()+"/"+b) {
+				if err := r.DeleteRemoteBranch(b); err != nil {
+					errs = append(errs, fmt.Errorf("%s on %s: %w", b, r.Remote(), err))
+					continue
+				}
+				res.RemoteDeleted = append(res.RemoteDeleted, b)
+			}
+		}
+		res.Error = errors.Join(errs...)
+		return res.Error
+	})
+	return results
+}
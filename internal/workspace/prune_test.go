@@ -0,0 +1,27 @@
+package workspace
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMergedBranchesDetachedHead(t *testing.T) {
+	w := testWorkspace(t, "api")
+	dir := filepath.Join(w.Root, "api")
+	runGit(t, dir, "branch", "done")
+	runGit(t, dir, "checkout", "-q", "--detach", "main")
+
+	res := w.MergedBranches()[0]
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if want := []string{"done"}; !slices.Equal(res.Branches, want) {
+		t.Errorf("Branches on a detached HEAD = %q, want %q", res.Branches, want)
+	}
+
+	pruned := w.PruneBranches([]PruneResult{res}, false)[0]
+	if pruned.Error != nil {
+		t.Fatalf("PruneBranches on a detached HEAD: %v", pruned.Error)
+	}
+}
@@ -0,0 +1,58 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/git"
+)
+
+func TestPushForcesBranchWithoutUpstream(t *testing.T) {
+	ws := testWorkspace(t, "a")
+	dir := ws.Repos[0].FullPath
+
+	// feat exists on the remote with other history, and the local feat
+	// has no upstream
+	runGit(t, dir, "checkout", "-q", "-b", "feat")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "theirs")
+	runGit(t, dir, "push", "-q", "origin", "feat")
+	runGit(t, dir, "reset", "-q", "--hard", "main")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "ours")
+	ours := runGit(t, dir, "rev-parse", "HEAD")
+
+	results, err := ws.Push(PushOptions{Mode: git.ForceUnsafe, NoVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Error != nil || !r.SetUpstream {
+		t.Fatalf("Push = %+v, want a forced push that sets the upstream", r)
+	}
+	if got := runGit(t, dir, "rev-parse", "origin/feat"); got != ours {
+		t.Errorf("origin/feat = %s, want the forced %s", got, ours)
+	}
+}
+
+func TestPushToDifferentlyNamedUpstream(t *testing.T) {
+	ws := testWorkspace(t, "a")
+	dir := ws.Repos[0].FullPath
+	main := runGit(t, dir, "rev-parse", "origin/main")
+
+	// A branch that tracks origin/main, which a plain git push refuses
+	runGit(t, dir, "checkout", "-q", "--track", "-b", "feat", "origin/main")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "change")
+	feat := runGit(t, dir, "rev-parse", "HEAD")
+
+	results, err := ws.Push(PushOptions{NoVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Error != nil {
+		t.Fatalf("Push = %v", r.Error)
+	}
+	runGit(t, dir, "fetch", "-q")
+	if got := runGit(t, dir, "rev-parse", "origin/feat"); got != feat {
+		t.Errorf("origin/feat = %s, want %s", got, feat)
+	}
+	if got := runGit(t, dir, "rev-parse", "origin/main"); got != main {
+		t.Errorf("origin/main moved to %s", got)
+	}
+}
@@ -0,0 +1,73 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// Rebase rebases the current branch of every repo onto onto. Repos that
+// were already up to date are marked in Skipped. A repo that hits conflicts
+// fails with a git.ConflictError and is left mid-rebase to be continued or
+// aborted.
+func (w *Workspace) Rebase(onto string) []Result {
+	results := make([]Result, len(w.Repos))
+	durations := w.each("rebase", func(i int, r *repo.Repo) error {
+		results[i] = Result{Repo: r}
+		switch {
+		case !r.IsCloned():
+			results[i].Error = notCloned(r)
+		case !r.BranchExists(onto):
+			results[i].Error = fmt.Errorf("%s does not exist", onto)
+		default:
+			upToDate, err := r.Rebase(onto)
+			results[i].Error = err
+			if upToDate {
+				results[i].Skipped = "already up to date"
+			}
+		}
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// RebaseContinue continues the rebase in progress in every repo, once its
+// conflicts are resolved. Repos that aren't mid-rebase are skipped, with
+// the reason in Skipped.
+func (w *Workspace) RebaseContinue() []Result {
+	return w.eachStopped("rebase continue", git.StateRebase, (*repo.Repo).RebaseContinue)
+}
+
+// RebaseAbort abandons the rebase in progress in every repo. Repos that
+// aren't mid-rebase are skipped, with the reason in Skipped.
+func (w *Workspace) RebaseAbort() []Result {
+	return w.eachStopped("rebase abort", git.StateRebase, (*repo.Repo).RebaseAbort)
+}
+
+// eachStopped runs fn in every repo stopped in the middle of state, e.g.
+// git.StateRebase, skipping the others
+func (w *Workspace) eachStopped(op, state string, fn func(*repo.Repo) error) []Result {
+	results := make([]Result, len(w.Repos))
+	durations := w.each(op, func(i int, r *repo.Repo) error {
+		results[i] = Result{Repo: r}
+		switch {
+		case !r.IsCloned():
+			results[i].Error = notCloned(r)
+		case r.State() != state:
+			results[i].Skipped = fmt.Sprintf("no %s in progress", state)
+		default:
+			results[i].Error = fn(r)
+		}
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
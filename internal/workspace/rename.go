@@ -0,0 +1,66 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// RenameBranch renames a branch in every repo, locally and on the primary
+// remote: the branch is renamed, pushed under its new name with upstream
+// tracking, and only then deleted from the remote under its old name.
+// Repos without the branch are skipped, with the reason in Skipped.
+func (w *Workspace) RenameBranch(oldName, newName string) []Result {
+	protected := make(map[string]bool)
+	for _, b := range w.Config.Settings.ProtectedBranches {
+		protected[b] = true
+	}
+
+	results := make([]Result, len(w.Repos))
+	durations := w.each("branch rename", func(i int, r *repo.Repo) error {
+		r.Attempts = 0
+		skipped, err := renameBranch(r, oldName, newName, protected)
+		results[i] = Result{Repo: r, Error: err, Attempts: r.Attempts, Skipped: skipped}
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// renameBranch renames oldName in r, returning why r was skipped if it was
+func renameBranch(r *repo.Repo, oldName, newName string, protected map[string]bool) (string, error) {
+	if !r.IsCloned() {
+		return "", notCloned(r)
+	}
+	if !r.BranchExists("refs/heads/" + oldName) {
+		return fmt.Sprintf("no branch %s", oldName), nil
+	}
+	for _, b := range []string{oldName, newName} {
+		if protected[b] {
+			return "", fmt.Errorf("%s is a protected branch", b)
+		}
+	}
+	if r.BranchExists("refs/heads/" + newName) {
+		return "", fmt.Errorf("branch %s already exists", newName)
+	}
+
+	// Only delete the old remote branch if it was pushed
+	pushed := r.BranchExists("refs/remotes/" + r.Remote() + "/" + oldName)
+
+	if err := r.RenameBranch(oldName, newName); err != nil {
+		return "", err
+	}
+	if err := r.PushRefSpec(git.RefSpec{Src: newName, Dst: newName}, git.NoForce, true, false); err != nil {
+		return "", fmt.Errorf("renamed locally, but pushing %s failed: %w", newName, err)
+	}
+	if pushed {
+		if err := r.DeleteRemoteBranch(oldName); err != nil {
+			return "", fmt.Errorf("pushed %s, but deleting %s from %s failed: %w", newName, oldName, r.Remote(), err)
+		}
+	}
+	return "", nil
+}
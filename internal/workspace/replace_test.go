@@ -0,0 +1,41 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/replace"
+)
+
+func TestFindReplacementsNonASCIIPath(t *testing.T) {
+	w := testWorkspace(t, "api")
+	dir := filepath.Join(w.Root, "api")
+	runGit(t, dir, "config", "core.quotePath", "true")
+	writeFile(t, dir, "héllo.go", "old name\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "add")
+
+	for _, regex := range []bool{false, true} {
+		rep, err := replace.New("old", "new", regex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := w.FindReplacements(rep)[0]
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		if len(res.Edits) != 1 || res.Edits[0].Path != "héllo.go" {
+			t.Fatalf("regex=%v: edits = %+v, want one in héllo.go", regex, res.Edits)
+		}
+	}
+
+	rep, _ := replace.New("old", "new", false)
+	if r := ApplyReplacements(w.FindReplacements(rep)); HasErrors(r) {
+		t.Fatalf("ApplyReplacements: %v", r[0].Error)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "héllo.go"))
+	if err != nil || string(data) != "new name\n" {
+		t.Errorf("héllo.go = %q, %v; want the replacement", data, err)
+	}
+}
@@ -0,0 +1,83 @@
+package workspace
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// names returns the names of repos
+func names(repos []*repo.Repo) []string {
+	var out []string
+	for _, r := range repos {
+		out = append(out, r.Name())
+	}
+	return out
+}
+
+func TestSelectionLimitsBranchConsistency(t *testing.T) {
+	ws := testWorkspace(t, "api", "web", "legacy")
+	runGit(t, ws.Repos[0].FullPath, "checkout", "-q", "-b", "feat")
+	runGit(t, ws.Repos[1].FullPath, "checkout", "-q", "-b", "feat")
+
+	if _, consistent, err := ws.CheckBranchConsistency(); err != nil || consistent {
+		t.Fatalf("whole workspace: consistent = %v, %v, want false", consistent, err)
+	}
+
+	sel, err := ws.Select(nil, []string{"legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, consistent, err := sel.CheckBranchConsistency()
+	if err != nil || !consistent || branch != "feat" {
+		t.Errorf("selection: CheckBranchConsistency = %q, %v, %v, want feat, true", branch, consistent, err)
+	}
+	if got := names(sel.Unselected); !reflect.DeepEqual(got, []string{"legacy"}) {
+		t.Errorf("Unselected = %v, want [legacy]", got)
+	}
+	if got := sel.UnselectedBranches(); !reflect.DeepEqual(got, map[string]int{"main": 1}) {
+		t.Errorf("UnselectedBranches = %v, want main: 1", got)
+	}
+}
+
+func TestSelectionsAccumulateUnselected(t *testing.T) {
+	ws := testWorkspace(t, "api", "web", "legacy")
+
+	byName, err := ws.FilterByName("api", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel, err := byName.Select(nil, []string{"web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(sel.Repos); !reflect.DeepEqual(got, []string{"api"}) {
+		t.Errorf("Repos = %v, want [api]", got)
+	}
+	if got := names(sel.Unselected); !reflect.DeepEqual(got, []string{"legacy", "web"}) {
+		t.Errorf("Unselected = %v, want [legacy web]", got)
+	}
+
+	// Filter narrows for an operation, not for the user, so it keeps the
+	// selection's Unselected as it is
+	one := sel.Filter(func(*repo.Repo) bool { return false })
+	if got := names(one.Unselected); !reflect.DeepEqual(got, []string{"legacy", "web"}) {
+		t.Errorf("Unselected after Filter = %v, want [legacy web]", got)
+	}
+}
+
+func TestUnselectedBranchesDetachedAndUncloned(t *testing.T) {
+	ws := testWorkspace(t, "api", "web", "docs")
+	runGit(t, ws.Repos[1].FullPath, "checkout", "-q", "--detach")
+	ws.Repos[2].FullPath += "-missing"
+
+	sel, err := ws.Select([]string{"api"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A detached HEAD counts as "", and the uncloned repo isn't counted
+	if got := sel.UnselectedBranches(); !reflect.DeepEqual(got, map[string]int{"": 1}) {
+		t.Errorf("UnselectedBranches = %v, want \"\": 1", got)
+	}
+}
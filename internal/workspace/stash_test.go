@@ -0,0 +1,66 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/git"
+)
+
+func TestIsMergeishStash(t *testing.T) {
+	for _, tt := range []struct {
+		message string
+		want    bool
+	}{
+		{"On main: mergeish", true},
+		{"On main: mergeish: wip", true},
+		{"On feat/x: mergeish autostash", true},
+		{"On (no branch): mergeish: wip", true},
+		{"On main: wip", false},
+		{"WIP on main: 1234abc initial", false},
+		{"On main: mergeishy", false},
+	} {
+		if got := isMergeishStash(git.StashEntry{Message: tt.message}); got != tt.want {
+			t.Errorf("isMergeishStash(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestStashPopLeavesUserStashes(t *testing.T) {
+	ws := testWorkspace(t, "a", "b")
+	a, b := ws.Repos[0].FullPath, ws.Repos[1].FullPath
+
+	writeFile(t, a, "mine.txt", "mergeish's\n")
+	for _, r := range ws.Stash("wip") {
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+	}
+	// The user stashes on top in a, and has only their own stash in b
+	writeFile(t, a, "user.txt", "user's\n")
+	runGit(t, a, "stash", "push", "--include-untracked", "-m", "user")
+	writeFile(t, b, "user.txt", "user's\n")
+	runGit(t, b, "stash", "push", "--include-untracked")
+
+	results := ws.StashPop()
+	if r := results[0]; r.Error != nil || !r.Changed {
+		t.Fatalf("pop in a = %+v, want the mergeish entry popped", r)
+	}
+	if r := results[1]; r.Error != nil || r.Changed {
+		t.Fatalf("pop in b = %+v, want it skipped", r)
+	}
+
+	if _, err := os.Stat(filepath.Join(a, "mine.txt")); err != nil {
+		t.Errorf("mergeish's stash wasn't restored in a: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(a, "user.txt")); err == nil {
+		t.Error("the user's stash was popped in a")
+	}
+	if got := runGit(t, a, "stash", "list", "--format=%gs"); got != "On main: user" {
+		t.Errorf("stash in a = %q, want only the user's entry", got)
+	}
+	if got := runGit(t, b, "stash", "list", "--format=%gs"); got == "" {
+		t.Error("the user's stash was popped in b")
+	}
+}
@@ -0,0 +1,98 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// StateDir is the directory, relative to the workspace root, where mergeish
+// keeps local state
+const StateDir = ".mergeish"
+
+const stateFile = "state.json"
+
+// State is local workspace state that persists between runs. It is not
+// meant to be committed.
+type State struct {
+	NoAccess    []string               `json:"no_access,omitempty"`   // repo paths the user cannot clone
+	Branches    map[string]BranchInfo  `json:"branches,omitempty"`    // metadata set with mergeish branch describe
+	Checkpoints map[string]*Checkpoint `json:"checkpoints,omitempty"` // progress of unfinished runs, by command
+}
+
+// statePath returns the path of the state file
+func (w *Workspace) statePath() string {
+	return filepath.Join(w.Root, StateDir, stateFile)
+}
+
+// loadState reads the workspace state. A missing file is an empty state.
+func (w *Workspace) loadState() (*State, error) {
+	data, err := os.ReadFile(w.statePath())
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+	return &st, nil
+}
+
+// saveState writes the workspace state
+func (w *Workspace) saveState(st *State) error {
+	if err := os.MkdirAll(filepath.Dir(w.statePath()), 0755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+
+	if err := os.WriteFile(w.statePath(), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+// applyState marks repos according to the saved state
+func (w *Workspace) applyState() error {
+	st, err := w.loadState()
+	if err != nil {
+		return err
+	}
+
+	noAccess := make(map[string]bool, len(st.NoAccess))
+	for _, p := range st.NoAccess {
+		noAccess[p] = true
+	}
+	for _, r := range w.Repos {
+		r.NoAccess = noAccess[r.Config.Path] && !r.IsCloned()
+	}
+	return nil
+}
+
+// RecordNoAccess saves which repos the user cannot access, so later
+// operations report "no access" for them rather than "not cloned"
+func (w *Workspace) RecordNoAccess() error {
+	st, err := w.loadState()
+	if err != nil {
+		return err
+	}
+
+	st.NoAccess = nil
+	for _, r := range w.Repos {
+		if r.NoAccess {
+			st.NoAccess = append(st.NoAccess, r.Config.Path)
+		}
+	}
+	sort.Strings(st.NoAccess)
+
+	return w.saveState(st)
+}
@@ -0,0 +1,92 @@
+package workspace
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/git"
+)
+
+func TestStatusPredicates(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		pred   func(*git.Status) bool
+		status git.Status
+		want   bool
+	}{
+		{"Dirty with changes", Dirty, git.Status{HasChanges: true}, true},
+		{"Dirty without", Dirty, git.Status{}, false},
+		{"Clean without changes", Clean, git.Status{}, true},
+		{"Clean with", Clean, git.Status{HasChanges: true}, false},
+		{"Ahead", Ahead, git.Status{Ahead: 1}, true},
+		{"Ahead when behind", Ahead, git.Status{Behind: 2}, false},
+		{"Behind", Behind, git.Status{Behind: 2}, true},
+		{"Behind when ahead", Behind, git.Status{Ahead: 1}, false},
+		{"InProgress in a rebase", InProgress, git.Status{State: git.StateRebase}, true},
+		{"InProgress", InProgress, git.Status{}, false},
+		{"Detached", Detached, git.Status{Detached: true}, true},
+		{"Detached on a branch", Detached, git.Status{Branch: "main"}, false},
+		{"OffBranch on another", OffBranch("main"), git.Status{Branch: "feat"}, true},
+		{"OffBranch on it", OffBranch("main"), git.Status{Branch: "main"}, false},
+		{"AnyStatus of none", AnyStatus(), git.Status{HasChanges: true}, false},
+		{"AnyStatus with a match", AnyStatus(Ahead, Dirty), git.Status{HasChanges: true}, true},
+		{"AnyStatus without", AnyStatus(Ahead, Behind), git.Status{HasChanges: true}, false},
+	} {
+		s := tt.status
+		if got := tt.pred(&s); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterStatusKeepsUnreadable(t *testing.T) {
+	w := testWorkspace(t, "api", "web", "docs")
+	results := []StatusResult{
+		{Repo: w.Repos[0], Status: &git.Status{Branch: "main"}},
+		{Repo: w.Repos[1], Error: errors.New("broken")},
+		{Repo: w.Repos[2], Status: &git.Status{Branch: "main", HasChanges: true}},
+	}
+	got := FilterStatus(results, Dirty)
+	if len(got) != 2 || got[0].Repo != w.Repos[1] || got[1].Repo != w.Repos[2] {
+		t.Errorf("FilterStatus(Dirty) = %v, want web (unreadable) and docs", statusNames(got))
+	}
+}
+
+func TestForEachStatus(t *testing.T) {
+	w := testWorkspace(t, "api", "web", "docs")
+	writeFile(t, filepath.Join(w.Root, "api"), "new.txt", "new\n")
+	runGit(t, filepath.Join(w.Root, "web"), "commit", "-q", "--allow-empty", "-m", "unpushed")
+
+	for _, tt := range []struct {
+		name string
+		pred func(*git.Status) bool
+		want []string
+	}{
+		{"dirty", Dirty, []string{"api"}},
+		{"clean", Clean, []string{"web", "docs"}},
+		{"ahead", Ahead, []string{"web"}},
+		{"behind", Behind, nil},
+		{"dirty or ahead", AnyStatus(Dirty, Ahead), []string{"api", "web"}},
+	} {
+		got := statusNames(w.ForEachStatus(tt.pred))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: ForEachStatus = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: ForEachStatus = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func statusNames(results []StatusResult) []string {
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Repo.Name())
+	}
+	return names
+}
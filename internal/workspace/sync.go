@@ -0,0 +1,128 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// SyncOptions controls Sync
+type SyncOptions struct {
+	Force bool // delete the previous branch even if it is not fully merged
+}
+
+// SyncResult describes what Sync did in a single repo
+type SyncResult struct {
+	Repo    *repo.Repo
+	Branch  string   // default branch the repo was switched to
+	Updated bool     // the default branch moved when pulling
+	Deleted string   // previous branch that was deleted, if any
+	Kept    string   // previous branch kept because it is not fully merged
+	Pruned  []string // remote-tracking refs pruned by the fetch
+	Error   error
+}
+
+// DefaultBranch returns the default branch for r: the repo's own
+// default_branch setting, the remote's HEAD, or the top-level setting, in
+// that order
+func (w *Workspace) DefaultBranch(r *repo.Repo) string {
+	if r.Config.Settings != nil && r.Config.Settings.DefaultBranch != "" {
+		return r.Config.Settings.DefaultBranch
+	}
+	if head := r.LocalRemoteHead(r.Remote()); head != "" {
+		return head
+	}
+	return r.Settings.DefaultBranch
+}
+
+// Sync brings every repo back to its default branch after a merge: fetch
+// with prune, switch to the default branch, pull, and delete the branch
+// that was checked out before
+func (w *Workspace) Sync(opts SyncOptions) []SyncResult {
+	results := make([]SyncResult, len(w.Repos))
+
+	w.each("sync", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		res.Error = w.sync(r, opts, res)
+		return res.Error
+	})
+
+	return results
+}
+
+func (w *Workspace) sync(r *repo.Repo, opts SyncOptions, res *SyncResult) error {
+	if !r.IsCloned() {
+		return notCloned(r)
+	}
+
+	prev, err := r.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	// A detached HEAD leaves no branch to delete afterwards
+	detached := prev == "HEAD"
+
+	if !w.AutoStash && !r.Settings.AutoStash {
+		dirty, err := r.HasChanges()
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("uncommitted changes on %s; commit them or use --autostash", prev)
+		}
+	}
+
+	pruned, err := r.FetchPrune()
+	if err != nil {
+		return err
+	}
+	res.Pruned = pruned
+
+	def := w.DefaultBranch(r)
+	res.Branch = def
+
+	err = w.withAutoStash(r, func() error {
+		if prev != def {
+			if err := r.Checkout(def); err != nil {
+				return err
+			}
+		}
+
+		before, err := r.HeadCommit()
+		if err != nil {
+			return err
+		}
+		if err := r.Pull(false); err != nil {
+			return err
+		}
+		after, err := r.HeadCommit()
+		if err != nil {
+			return err
+		}
+		res.Updated = before != after
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if prev == def || detached {
+		return nil
+	}
+
+	switch {
+	case opts.Force:
+		err = r.ForceDeleteBranch(prev)
+	case r.IsMerged(prev, def):
+		err = r.DeleteBranch(prev)
+	default:
+		res.Kept = prev
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Deleted = prev
+	return nil
+}
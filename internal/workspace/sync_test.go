@@ -0,0 +1,42 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncDetachedHead(t *testing.T) {
+	w := testWorkspace(t, "api")
+	dir := filepath.Join(w.Root, "api")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "second")
+	runGit(t, dir, "push", "-q")
+	runGit(t, dir, "checkout", "-q", "--detach", "HEAD~1")
+
+	res := w.Sync(SyncOptions{})[0]
+	if res.Error != nil {
+		t.Fatalf("Sync on a detached HEAD: %v", res.Error)
+	}
+	if res.Deleted != "" || res.Kept != "" {
+		t.Errorf("Sync on a detached HEAD deleted %q and kept %q, want no branch cleanup", res.Deleted, res.Kept)
+	}
+	if branch := runGit(t, dir, "rev-parse", "--abbrev-ref", "HEAD"); branch != "main" {
+		t.Errorf("branch after Sync = %q, want main", branch)
+	}
+	if subject := runGit(t, dir, "log", "-1", "--format=%s"); subject != "second" {
+		t.Errorf("main after Sync is at %q, want the pulled second commit", subject)
+	}
+}
+
+func TestSyncDeletesMergedBranch(t *testing.T) {
+	w := testWorkspace(t, "api")
+	dir := filepath.Join(w.Root, "api")
+	runGit(t, dir, "checkout", "-q", "-b", "feat")
+
+	res := w.Sync(SyncOptions{})[0]
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.Deleted != "feat" {
+		t.Errorf("Deleted = %q, want the merged feat", res.Deleted)
+	}
+}
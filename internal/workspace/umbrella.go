@@ -0,0 +1,196 @@
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// Sections mergeish owns in issue and PR bodies. Text outside them is left
+// alone, so bodies can be edited by hand.
+const (
+	umbrellaSection   = "mergeish:umbrella"
+	relatedSection    = "mergeish:related"
+	relatedPRsSection = "mergeish:related-prs"
+)
+
+// umbrellaMarker identifies the umbrella issue for a branch
+func umbrellaMarker(branch string) string {
+	return fmt.Sprintf("<!-- mergeish:umbrella-branch %s -->", branch)
+}
+
+// FindUmbrella returns the open umbrella issue for branch, or nil if there
+// is none or settings.pr.umbrella_repo is not set
+func (w *Workspace) FindUmbrella(branch string) (*git.Issue, error) {
+	umbrellaRepo := w.Config.Settings.PR.UmbrellaRepo
+	if umbrellaRepo == "" {
+		return nil, nil
+	}
+	return git.FindIssue(umbrellaRepo, umbrellaMarker(branch))
+}
+
+// UpdateUmbrella writes the checklist of prs into the umbrella issue for
+// branch. Without an existing issue, one titled title is created if create
+// is set; otherwise nothing happens and nil is returned.
+func (w *Workspace) UpdateUmbrella(branch, title string, prs []PRResult, create bool) (issue *git.Issue, created bool, err error) {
+	umbrellaRepo := w.Config.Settings.PR.UmbrellaRepo
+	if umbrellaRepo == "" {
+		return nil, false, fmt.Errorf("settings.pr.umbrella_repo is not set")
+	}
+
+	issue, err = git.FindIssue(umbrellaRepo, umbrellaMarker(branch))
+	if err != nil {
+		return nil, false, err
+	}
+
+	checklist := umbrellaChecklist(branch, prs)
+	if issue == nil {
+		if !create {
+			return nil, false, nil
+		}
+		body := umbrellaMarker(branch) + "\n" + setSection("", umbrellaSection, checklist)
+		issue, err = git.CreateIssue(umbrellaRepo, title, body)
+		return issue, true, err
+	}
+
+	body := setSection(issue.Body, umbrellaSection, checklist)
+	if body != issue.Body {
+		if err := git.EditIssueBody(umbrellaRepo, issue.Number, body); err != nil {
+			return nil, false, err
+		}
+		issue.Body = body
+	}
+	return issue, false, nil
+}
+
+// umbrellaChecklist lists every PR, checked once merged
+func umbrellaChecklist(branch string, prs []PRResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Pull requests for `%s`:\n\n", branch)
+	for _, r := range prs {
+		if r.PR == nil {
+			continue
+		}
+		box, note := " ", ""
+		switch r.PR.State {
+		case "MERGED":
+			box = "x"
+		case "CLOSED":
+			note = " (closed)"
+		}
+		fmt.Fprintf(&b, "- [%s] %s: %s%s\n", box, r.Repo.Name(), r.PR.URL, note)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// LinkUmbrella writes the umbrella issue URL into the related PRs section of
+// every PR on the current branch. PRs that already link it are left alone.
+func (w *Workspace) LinkUmbrella(url string) []Result {
+	withPRs := w.Filter(func(r *repo.Repo) bool { return r.Config.HasPRs() })
+	return withPRs.forEach("pr link", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		pr, err := r.GetPR()
+		if err != nil || pr == nil {
+			return err
+		}
+
+		body, err := r.PRBody()
+		if err != nil {
+			return err
+		}
+		updated := setSection(body, relatedSection, "Tracked in "+url)
+		if updated == body {
+			return nil
+		}
+		return r.EditPRBody(updated)
+	})
+}
+
+// LinkRelatedPRs writes a Related PRs section listing the other repos' PRs
+// into the body of every open PR in prs. The section is replaced on each
+// run, so running it again refreshes the list instead of repeating it.
+func (w *Workspace) LinkRelatedPRs(prs []PRResult) []Result {
+	byRepo := make(map[*repo.Repo]*git.PRInfo)
+	for _, r := range prs {
+		if r.PR != nil {
+			byRepo[r.Repo] = r.PR
+		}
+	}
+	open := w.Filter(func(r *repo.Repo) bool {
+		pr := byRepo[r]
+		return pr != nil && pr.State == "OPEN"
+	})
+
+	return open.forEach("pr link", func(r *repo.Repo) error {
+		list := relatedPRs(r, prs)
+		if list == "" {
+			return nil
+		}
+		body, err := r.PRBody()
+		if err != nil {
+			return err
+		}
+		updated := setSection(body, relatedPRsSection, list)
+		if updated == body {
+			return nil
+		}
+		return r.EditPRBody(updated)
+	})
+}
+
+// relatedPRs lists the PRs of the repos other than r, or returns "" if
+// there are none
+func relatedPRs(r *repo.Repo, prs []PRResult) string {
+	var b strings.Builder
+	for _, other := range prs {
+		if other.PR == nil || other.Repo == r {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", other.Repo.Name(), other.PR.URL)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Related PRs:\n\n" + strings.TrimSuffix(b.String(), "\n")
+}
+
+// setSection replaces the named section of body with content, appending
+// the section if body doesn't have one yet
+func setSection(body, name, content string) string {
+	start := "<!-- " + name + " -->"
+	end := "<!-- /" + name + " -->"
+	section := start + "\n" + content + "\n" + end
+
+	if i := strings.Index(body, start); i >= 0 {
+		if j := strings.Index(body[i:], end); j >= 0 {
+			return body[:i] + section + body[i+j+len(end):]
+		}
+	}
+
+	if body == "" {
+		return section
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + section
+}
+
+// section returns the content of the named section of body, and whether
+// body has one
+func section(body, name string) (string, bool) {
+	start := "<!-- " + name + " -->"
+	end := "<!-- /" + name + " -->"
+
+	i := strings.Index(body, start)
+	if i < 0 {
+		return "", false
+	}
+	rest := body[i+len(start):]
+	j := strings.Index(rest, end)
+	if j < 0 {
+		return "", false
+	}
+	return strings.Trim(rest[:j], "\n"), true
+}
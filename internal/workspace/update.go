@@ -0,0 +1,101 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// UpdateOptions controls Update
+type UpdateOptions struct {
+	Rebase         bool // pull with --rebase
+	Push           bool // push local commits after pulling
+	AllowProtected bool // allow pushing a protected branch
+}
+
+// UpdateResult describes each stage of Update in a single repo. A push
+// that did not run has a nil PushError and the reason in PushSkip.
+type UpdateResult struct {
+	Repo       *repo.Repo
+	FetchError error
+	PullError  error
+	Pushed     int // commits pushed
+	PushError  error
+	PushSkip   string // why the push stage did not run, if it didn't
+}
+
+// Err returns the first stage error, if any
+func (r UpdateResult) Err() error {
+	switch {
+	case r.FetchError != nil:
+		return r.FetchError
+	case r.PullError != nil:
+		return r.PullError
+	default:
+		return r.PushError
+	}
+}
+
+// Update runs the daily catch-up in every repo: fetch, pull, and optionally
+// push local commits. Each repo goes through the stages in order, so a repo
+// whose fetch or pull fails is never pushed.
+func (w *Workspace) Update(opts UpdateOptions) []UpdateResult {
+	results := make([]UpdateResult, len(w.Repos))
+
+	protected := make(map[string]bool)
+	for _, b := range w.Config.Settings.ProtectedBranches {
+		protected[b] = true
+	}
+
+	w.each("update", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		w.update(r, opts, protected, res)
+		return res.Err()
+	})
+
+	return results
+}
+
+func (w *Workspace) update(r *repo.Repo, opts UpdateOptions, protected map[string]bool, res *UpdateResult) {
+	if !r.IsCloned() {
+		res.FetchError = notCloned(r)
+		res.PushSkip = "fetch failed"
+		return
+	}
+
+	if err := r.Fetch(); err != nil {
+		res.FetchError = err
+		res.PushSkip = "fetch failed"
+		return
+	}
+
+	if err := w.withAutoStash(r, func() error { return r.Pull(opts.Rebase) }); err != nil {
+		res.PullError = err
+		res.PushSkip = "pull failed"
+		return
+	}
+
+	if !opts.Push {
+		return
+	}
+
+	status, err := r.Status()
+	if err != nil {
+		res.PushError = err
+		return
+	}
+	switch {
+	case status.Ahead == 0:
+		res.PushSkip = "nothing to push"
+	case protected[status.Branch] && !opts.AllowProtected:
+		res.PushSkip = fmt.Sprintf("%s is a protected branch", status.Branch)
+	default:
+		if err := r.Push(git.NoForce, false); err != nil {
+			res.PushError = err
+			return
+		}
+		res.Pushed = status.Ahead
+	}
+}
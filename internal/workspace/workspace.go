@@ -1,51 +1,133 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/willnewby/mergeish/internal/config"
 	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/jsonlog"
+	"github.com/willnewby/mergeish/internal/metrics"
+	"github.com/willnewby/mergeish/internal/replace"
 	"github.com/willnewby/mergeish/internal/repo"
 )
 
+var (
+	// ErrNotCloned is returned for repos that have not been cloned
+	ErrNotCloned = errors.New("not cloned")
+
+	// ErrNoAccess is returned for repos the user could not clone for lack of access
+	ErrNoAccess = errors.New("no access")
+)
+
+// notCloned returns the error to report for a repo that is not cloned
+func notCloned(r *repo.Repo) error {
+	if r.NoAccess {
+		return ErrNoAccess
+	}
+	// The check may have failed only because git couldn't start or was
+	// killed, or because the clone is damaged
+	if r.Exists() {
+		err := r.CheckRepo()
+		var corrupt *git.CorruptError
+		if errors.Is(err, git.ErrResourceExhausted) || errors.Is(err, git.ErrTimeout) || errors.As(err, &corrupt) {
+			return err
+		}
+	}
+	return ErrNotCloned
+}
+
 // Result represents the result of an operation on a single repo
 type Result struct {
-	Repo  *repo.Repo
-	Error error
+	Repo        *repo.Repo
+	Error       error
+	Attempts    int           // attempts made by network operations, 0 if none
+	SetUpstream bool          // push also set the upstream of the branch
+	PushedTo    string        // remote branch a refspec pushed to, if any
+	Duration    time.Duration // how long the operation took in this repo
+	Skipped     string        // why the repo was left alone, if it was
 }
 
 // StatusResult represents status information for a repo
 type StatusResult struct {
-	Repo   *repo.Repo
-	Status *git.Status
-	Error  error
+	Repo     *repo.Repo
+	Status   *git.Status
+	Error    error
+	Duration time.Duration // how long reading the status took
 }
 
 // Workspace manages multiple repositories
 type Workspace struct {
-	Root     string
-	Config   *config.Config
-	Repos    []*repo.Repo
-	Parallel bool
+	Root       string
+	ConfigPath string
+	Config     *config.Config
+	Repos      []*repo.Repo
+	Uncloned   []*repo.Repo // repos left out by settings.uncloned_policy
+	Unselected []*repo.Repo // repos left out by Select or FilterByName, which consistency checks don't cover
+	NoFetch    bool         // skip implicit fetches and trust existing remote refs
+	AutoStash  bool         // stash local changes around pull and checkout in every repo, regardless of settings
+	MaxJobs    int          // repos run at once, 0 to derive a limit from the system
+
+	checkpoint *checkpointRun // records finished repos, set by StartCheckpoint
+}
+
+// StashLeftError reports that an operation succeeded or failed but the
+// changes stashed before it could not be restored and remain in the stash
+type StashLeftError struct {
+	Err error // the pop error
+	Op  error // the operation's own error, if any
+}
+
+func (e *StashLeftError) Error() string {
+	if e.Op != nil {
+		return fmt.Sprintf("%v; stashed changes could not be restored and remain in the stash: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("stashed changes could not be restored and remain in the stash: %v", e.Err)
+}
+
+func (e *StashLeftError) Unwrap() []error {
+	return []error{e.Err, e.Op}
 }
 
 // New creates a new workspace from config
 func New(cfg *config.Config, root string) *Workspace {
 	repos := make([]*repo.Repo, len(cfg.Repos))
 	for i, rc := range cfg.Repos {
-		repos[i] = repo.New(rc, root)
+		r := repo.New(rc, root)
+		r.Settings = rc.EffectiveSettings(cfg.Settings)
+		r.Retry = git.RetryPolicy{
+			Retries: r.Settings.Retries,
+			Backoff: r.Settings.RetryBackoff,
+		}
+		repos[i] = r
 	}
 
+	git.SetGHRateLimit(cfg.Settings.GHRateLimit)
+	git.SetTimeout(cfg.Settings.Timeout)
+	git.SetLockWait(cfg.Settings.LockWait)
+
 	return &Workspace{
-		Root:     root,
-		Config:   cfg,
-		Repos:    repos,
-		Parallel: cfg.Settings.Parallel,
+		Root:    root,
+		Config:  cfg,
+		Repos:   repos,
+		MaxJobs: cfg.Settings.MaxJobs,
 	}
 }
 
+// Name returns settings.name, or the workspace directory name if unset
+func (w *Workspace) Name() string {
+	if w.Config.Settings.Name != "" {
+		return w.Config.Settings.Name
+	}
+	return filepath.Base(w.Root)
+}
+
 // Load loads a workspace from the config file
 func Load(configPath string) (*Workspace, error) {
 	cfg, err := config.Load(configPath)
@@ -54,326 +136,1773 @@ func Load(configPath string) (*Workspace, error) {
 	}
 
 	root := filepath.Dir(configPath)
-	return New(cfg, root), nil
+	ws := New(cfg, root)
+	ws.ConfigPath = configPath
+	if err := ws.applyState(); err != nil {
+		return nil, err
+	}
+	return ws, nil
 }
 
-// Clone clones all repositories
-func (w *Workspace) Clone() []Result {
-	return w.forEach(func(r *repo.Repo) error {
-		if r.IsCloned() {
-			return nil // Already cloned
+// ApplyUnclonedPolicy leaves repos whose directory does not exist out of
+// every operation when settings.uncloned_policy is skip or hide. They are
+// kept in Uncloned.
+func (w *Workspace) ApplyUnclonedPolicy() {
+	if w.Config.Settings.UnclonedPolicy == config.UnclonedError {
+		return
+	}
+
+	var cloned []*repo.Repo
+	for _, r := range w.Repos {
+		if r.Exists() {
+			cloned = append(cloned, r)
+		} else {
+			w.Uncloned = append(w.Uncloned, r)
 		}
-		return r.Clone()
-	})
+	}
+	w.Repos = cloned
 }
 
-// Pull pulls all repositories
-func (w *Workspace) Pull(rebase bool) []Result {
-	return w.forEach(func(r *repo.Repo) error {
-		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
+// Clone clones all repositories
+func (w *Workspace) Clone(force bool) []Result {
+	return w.forEach("clone", func(r *repo.Repo) error {
+		if r.IsCloned() && !(force && r.IncompleteClone()) {
+			return r.SyncRemotes() // Already cloned
 		}
-		return r.Pull(rebase)
+		err := r.Clone(force)
+		r.NoAccess = git.IsPermissionError(err)
+		return err
 	})
 }
 
-// Push pushes all repositories
-func (w *Workspace) Push(force bool) []Result {
-	return w.forEach(func(r *repo.Repo) error {
-		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
-		}
-		return r.Push(force)
+// Pull pulls all repositories from their upstream, or from the named remote
+// if remote is non-empty, between the pre-pull and post-pull hooks
+func (w *Workspace) Pull(remote string, rebase bool) ([]Result, error) {
+	return w.withHooks("pull", func() ([]Result, error) {
+		return w.forEach("pull", func(r *repo.Repo) error {
+			if !r.IsCloned() {
+				return notCloned(r)
+			}
+			return w.withAutoStash(r, func() error {
+				if remote != "" {
+					return r.PullFrom(remote, rebase)
+				}
+				return r.Pull(rebase)
+			})
+		}), nil
 	})
 }
 
-// Status returns status for all repositories
-func (w *Workspace) Status() []StatusResult {
-	results := make([]StatusResult, len(w.Repos))
+// withAutoStash runs fn, stashing local changes first and restoring them
+// afterwards when autostash is enabled for the repo and it has changes
+func (w *Workspace) withAutoStash(r *repo.Repo, fn func() error) error {
+	if !w.AutoStash && !r.Settings.AutoStash {
+		return fn()
+	}
 
-	if w.Parallel {
-		var wg sync.WaitGroup
-		for i, r := range w.Repos {
-			wg.Add(1)
-			go func(i int, r *repo.Repo) {
-				defer wg.Done()
-				status, err := r.Status()
-				results[i] = StatusResult{Repo: r, Status: status, Error: err}
-			}(i, r)
-		}
-		wg.Wait()
-	} else {
-		for i, r := range w.Repos {
-			status, err := r.Status()
-			results[i] = StatusResult{Repo: r, Status: status, Error: err}
-		}
+	status, err := r.Status()
+	if err != nil {
+		return err
+	}
+	if !status.HasChanges {
+		return fn()
 	}
 
-	return results
+	if err := r.Stash(autostashMessage); err != nil {
+		return fmt.Errorf("autostash: %w", err)
+	}
+
+	opErr := fn()
+	if err := r.StashPop(""); err != nil {
+		return &StashLeftError{Err: err, Op: opErr}
+	}
+	return opErr
 }
 
-// CreateBranch creates a branch on all repos
-func (w *Workspace) CreateBranch(name string) []Result {
-	return w.forEach(func(r *repo.Repo) error {
-		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
-		}
-		if r.BranchExists(name) {
-			return fmt.Errorf("branch %q already exists", name)
-		}
-		return r.CheckoutNewBranch(name)
-	})
+// PushOptions controls Push
+type PushOptions struct {
+	Mode           git.ForceMode
+	NoVerify       bool // skip the uncommitted changes and behind upstream checks
+	AllowProtected bool // skip the protected branch check
+	DryRun         bool // run git push --dry-run instead of pushing
+	SetUpstream    bool // set the upstream even if the branch has one
+
+	// RefSpecs pushes to a differently named remote branch, keyed by repo
+	// path, with "" applying to repos without their own
+	RefSpecs map[string]git.RefSpec
 }
 
-// DeleteBranch deletes a branch on all repos
-func (w *Workspace) DeleteBranch(name string) []Result {
-	return w.forEach(func(r *repo.Repo) error {
+// refSpec returns the refspec to push r with, if any
+func (o PushOptions) refSpec(r *repo.Repo) (git.RefSpec, bool) {
+	if spec, ok := o.RefSpecs[r.Config.Path]; ok {
+		return spec, true
+	}
+	spec, ok := o.RefSpecs[""]
+	return spec, ok
+}
+
+// PushCheck is the pre-flight result for a single repo
+type PushCheck struct {
+	Repo     *repo.Repo
+	Problems []string
+	Error    error
+}
+
+// PreflightError is returned by Push when pre-flight checks fail in any
+// repo. Nothing is pushed.
+type PreflightError struct {
+	Checks []PushCheck // only the repos that failed
+}
+
+func (e *PreflightError) Error() string {
+	return "pre-flight checks failed"
+}
+
+// CheckPush runs the pre-flight checks for Push in every repo: no
+// uncommitted changes, not behind upstream (skipped for force pushes), and
+// not on a protected branch. It returns only the repos that failed.
+func (w *Workspace) CheckPush(opts PushOptions) []PushCheck {
+	checkBehind := !opts.NoVerify && opts.Mode == git.NoForce
+
+	fetchErrs := make(map[*repo.Repo]error)
+	if checkBehind {
+		for _, res := range w.Refresh() {
+			if res.Error != nil {
+				fetchErrs[res.Repo] = res.Error
+			}
+		}
+	}
+
+	protected := make(map[string]bool)
+	for _, b := range w.Config.Settings.ProtectedBranches {
+		protected[b] = true
+	}
+
+	checks := make([]PushCheck, len(w.Repos))
+	w.each("push check", func(i int, r *repo.Repo) error {
+		c := &checks[i]
+		c.Repo = r
 		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
+			c.Error = notCloned(r)
+			return c.Error
 		}
-		// Can't delete current branch
-		current, err := r.CurrentBranch()
+		if err := fetchErrs[r]; err != nil {
+			c.Error = err
+			return err
+		}
+
+		status, err := r.Status()
 		if err != nil {
+			c.Error = err
 			return err
 		}
-		if current == name {
-			return fmt.Errorf("cannot delete current branch")
+		if !opts.NoVerify && status.HasChanges {
+			c.Problems = append(c.Problems, "uncommitted changes")
 		}
-		return r.DeleteBranch(name)
-	})
-}
 
-// Checkout switches all repos to a branch, creating it if it doesn't exist
-func (w *Workspace) Checkout(name string) []Result {
-	return w.forEach(func(r *repo.Repo) error {
-		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
+		// With a refspec, check the remote branch being pushed to rather
+		// than the upstream
+		target, behind := status.Branch, status.Behind
+		if spec, ok := opts.refSpec(r); ok {
+			target = spec.Dst
+			if checkBehind {
+				if behind, err = r.BehindRef(spec.Src, r.Remote()+"/"+spec.Dst); err != nil {
+					c.Error = err
+					return err
+				}
+			}
 		}
-		if r.BranchExists(name) {
-			return r.Checkout(name)
+		if checkBehind && behind > 0 {
+			c.Problems = append(c.Problems, fmt.Sprintf("behind %s (↓%d)", upstreamName(opts, r), behind))
+		}
+		if !opts.AllowProtected && protected[target] {
+			c.Problems = append(c.Problems, fmt.Sprintf("%s is a protected branch", target))
 		}
-		// Branch doesn't exist, create it
-		return r.CheckoutNewBranch(name)
+		return nil
 	})
-}
 
-// Commit commits staged changes on all repos
-func (w *Workspace) Commit(message string, addAll bool) []Result {
-	return w.forEach(func(r *repo.Repo) error {
-		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
+	var failed []PushCheck
+	for _, c := range checks {
+		if c.Error != nil || len(c.Problems) > 0 {
+			failed = append(failed, c)
 		}
+	}
+	return failed
+}
 
-		if addAll {
-			if err := r.AddAll(); err != nil {
-				return err
-			}
-		}
+// Push pushes all repositories in two phases: the pre-flight checks run in
+// every repo first, and nothing is pushed if any fail. Branches without an
+// upstream are pushed with -u. The pre-push and post-push hooks run around
+// it, except for a dry run.
+func (w *Workspace) Push(opts PushOptions) ([]Result, error) {
+	if opts.DryRun {
+		return w.push(opts)
+	}
+	return w.withHooks("push", func() ([]Result, error) {
+		return w.push(opts)
+	})
+}
 
-		hasChanges, err := r.HasStagedChanges()
-		if err != nil {
-			return err
-		}
-		if !hasChanges {
-			return nil // No changes to commit
+// push runs the pre-flight checks and pushes, for Push
+func (w *Workspace) push(opts PushOptions) ([]Result, error) {
+	if !opts.NoVerify || !opts.AllowProtected {
+		if failed := w.CheckPush(opts); len(failed) > 0 {
+			return nil, &PreflightError{Checks: failed}
 		}
+	}
 
-		return r.Commit(message)
+	results := make([]Result, len(w.Repos))
+	w.each("push", func(i int, r *repo.Repo) error {
+		r.Attempts = 0
+		res := &results[i]
+		res.Repo = r
+		res.Error = pushRepo(r, opts, res)
+		res.Attempts = r.Attempts
+		return res.Error
 	})
+	return results, nil
 }
 
-// CheckBranchConsistency checks if all repos are on the same branch
-func (w *Workspace) CheckBranchConsistency() (string, bool, error) {
-	var firstBranch string
-	consistent := true
+// upstreamName names what a push of r is checked against in messages
+func upstreamName(opts PushOptions, r *repo.Repo) string {
+	if spec, ok := opts.refSpec(r); ok {
+		return r.Remote() + "/" + spec.Dst
+	}
+	return "upstream"
+}
+
+// pushRepo pushes r, setting the upstream if its branch doesn't have one yet.
+// A repo with a refspec is pushed to the branch it names and only gets an
+// upstream with opts.SetUpstream.
+func pushRepo(r *repo.Repo, opts PushOptions, res *Result) error {
+	if !r.IsCloned() {
+		return notCloned(r)
+	}
+	if spec, ok := opts.refSpec(r); ok {
+		res.PushedTo = spec.Dst
+		res.SetUpstream = opts.SetUpstream
+		return r.PushRefSpec(spec, opts.Mode, opts.SetUpstream, opts.DryRun)
+	}
+	if opts.SetUpstream || !r.HasUpstream() {
+		branch, err := r.CurrentBranch()
+		if err != nil {
+			return err
+		}
+		res.SetUpstream = true
+		return r.PushRefSpec(git.RefSpec{Src: "HEAD", Dst: branch}, opts.Mode, true, opts.DryRun)
+	}
+	return r.Push(opts.Mode, opts.DryRun)
+}
 
+// Filter returns a workspace containing only the repos for which keep
+// returns true. The returned workspace shares config and settings.
+func (w *Workspace) Filter(keep func(*repo.Repo) bool) *Workspace {
+	filtered := *w
+	filtered.Repos = nil
 	for _, r := range w.Repos {
-		if !r.IsCloned() {
-			continue
+		if keep(r) {
+			filtered.Repos = append(filtered.Repos, r)
 		}
+	}
+	return &filtered
+}
 
-		branch, err := r.CurrentBranch()
+// FilterByName returns a copy of the workspace with only the named repos,
+// each given by path or name as in config.FindRepo. Every name must match
+// a repo in the config.
+func (w *Workspace) FilterByName(names ...string) (*Workspace, error) {
+	want := make(map[string]bool, len(names))
+	for _, name := range names {
+		i, err := w.Config.FindRepo(name)
 		if err != nil {
-			return "", false, err
+			return nil, err
 		}
+		want[w.Config.Repos[i].Path] = true
+	}
+	return w.selected(func(r *repo.Repo) bool { return want[r.Config.Path] }), nil
+}
 
-		if firstBranch == "" {
-			firstBranch = branch
-		} else if branch != firstBranch {
-			consistent = false
+// Select returns a copy of the workspace with the repos matching one of the
+// include patterns, or every repo if there are none, less those matching
+// one of the exclude patterns. Patterns are globs as in path.Match, matched
+// against each repo's name and, for patterns without a slash, the last
+// element of it. Selecting no repos is an error.
+func (w *Workspace) Select(include, exclude []string) (*Workspace, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid repo pattern %q: %w", pattern, err)
 		}
 	}
 
-	return firstBranch, consistent, nil
+	selected := w.selected(func(r *repo.Repo) bool {
+		return (len(include) == 0 || matchRepo(r, include)) && !matchRepo(r, exclude)
+	})
+	if len(selected.Repos) > 0 {
+		return selected, nil
+	}
+	if len(include) > 0 && len(w.Filter(func(r *repo.Repo) bool { return matchRepo(r, include) }).Repos) == 0 {
+		return nil, fmt.Errorf("no repos match %s", strings.Join(include, ","))
+	}
+	return nil, fmt.Errorf("%s excludes every selected repo", strings.Join(exclude, ","))
 }
 
-// forEach runs an operation on all repos
-func (w *Workspace) forEach(fn func(*repo.Repo) error) []Result {
-	results := make([]Result, len(w.Repos))
+// selected is Filter for a selection made by the user: the repos left out
+// are kept in Unselected
+func (w *Workspace) selected(keep func(*repo.Repo) bool) *Workspace {
+	selected := w.Filter(keep)
+	selected.Unselected = append([]*repo.Repo(nil), w.Unselected...)
+	for _, r := range w.Repos {
+		if !keep(r) {
+			selected.Unselected = append(selected.Unselected, r)
+		}
+	}
+	return selected
+}
 
-	if w.Parallel {
-		var wg sync.WaitGroup
-		for i, r := range w.Repos {
-			wg.Add(1)
-			go func(i int, r *repo.Repo) {
-				defer wg.Done()
-				results[i] = Result{Repo: r, Error: fn(r)}
-			}(i, r)
+// UnselectedBranches counts the cloned repos left out by a selection by the
+// branch they are on, with "" for a detached HEAD
+func (w *Workspace) UnselectedBranches() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range w.Unselected {
+		if !r.IsCloned() {
+			continue
 		}
-		wg.Wait()
-	} else {
-		for i, r := range w.Repos {
-			results[i] = Result{Repo: r, Error: fn(r)}
+		branch, _, err := r.Branch()
+		if err != nil {
+			continue
 		}
+		counts[branch]++
 	}
-
-	return results
+	return counts
 }
 
-// HasErrors checks if any results have errors
-func HasErrors(results []Result) bool {
-	for _, r := range results {
-		if r.Error != nil {
+// matchRepo reports whether r matches one of patterns, as in Select
+func matchRepo(r *repo.Repo, patterns []string) bool {
+	name := filepath.ToSlash(r.Name())
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
 			return true
 		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(name)); ok {
+				return true
+			}
+		}
 	}
 	return false
 }
 
-// GitResult represents the result of a raw git command on a single repo
-type GitResult struct {
-	Repo   *repo.Repo
-	Stdout string
-	Stderr string
-	Error  error
+// Refresh fetches all repositories so that remote-tracking refs are current.
+// Operations that want fresh refs call this before acting; it does nothing
+// when NoFetch is set.
+func (w *Workspace) Refresh() []Result {
+	if w.NoFetch {
+		return nil
+	}
+	return w.forEach("fetch", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return nil
+		}
+		return r.Fetch()
+	})
 }
 
-// RunGit executes an arbitrary git command on all repos
-func (w *Workspace) RunGit(args []string) []GitResult {
-	results := make([]GitResult, len(w.Repos))
+// Fetch fetches every cloned repository, ignoring NoFetch. Repos that are
+// not cloned are skipped.
+func (w *Workspace) Fetch() []Result {
+	cloned := w.Filter(func(r *repo.Repo) bool { return r.IsCloned() })
+	return cloned.forEach("fetch", func(r *repo.Repo) error {
+		return r.Fetch()
+	})
+}
 
-	if w.Parallel {
-		var wg sync.WaitGroup
-		for i, r := range w.Repos {
-			wg.Add(1)
-			go func(i int, r *repo.Repo) {
-				defer wg.Done()
-				if !r.IsCloned() {
-					results[i] = GitResult{Repo: r, Error: fmt.Errorf("not cloned")}
-					return
-				}
-				stdout, stderr, err := r.RunGit(args...)
-				results[i] = GitResult{Repo: r, Stdout: stdout, Stderr: stderr, Error: err}
-			}(i, r)
-		}
-		wg.Wait()
-	} else {
-		for i, r := range w.Repos {
-			if !r.IsCloned() {
-				results[i] = GitResult{Repo: r, Error: fmt.Errorf("not cloned")}
-				continue
-			}
-			stdout, stderr, err := r.RunGit(args...)
-			results[i] = GitResult{Repo: r, Stdout: stdout, Stderr: stderr, Error: err}
+// PushSetUpstream pushes all repositories and sets upstream for the current branch
+func (w *Workspace) PushSetUpstream() []Result {
+	return w.forEach("push", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
 		}
-	}
+		return r.PushSetUpstream(false)
+	})
+}
 
-	return results
+// Status returns status for all repositories
+func (w *Workspace) Status() []StatusResult {
+	return w.status(false)
 }
 
-// PRResult represents the result of a PR operation on a single repo
-type PRResult struct {
-	Repo     *repo.Repo
-	PR       *git.PRInfo
-	Existed  bool // true if PR already existed (not newly created)
-	Error    error
+// StatusWithLastCommit returns status for all repositories, including the
+// last commit of each, at the cost of another git call per repo
+func (w *Workspace) StatusWithLastCommit() []StatusResult {
+	return w.status(true)
 }
 
-// GetPRs returns PR status for all repos
-func (w *Workspace) GetPRs() []PRResult {
-	results := make([]PRResult, len(w.Repos))
+func (w *Workspace) status(lastCommit bool) []StatusResult {
+	results := make([]StatusResult, len(w.Repos))
 
-	if w.Parallel {
-		var wg sync.WaitGroup
-		for i, r := range w.Repos {
-			wg.Add(1)
-			go func(i int, r *repo.Repo) {
-				defer wg.Done()
-				if !r.IsCloned() {
-					results[i] = PRResult{Repo: r, Error: fmt.Errorf("not cloned")}
-					return
-				}
-				pr, err := r.GetPR()
-				results[i] = PRResult{Repo: r, PR: pr, Error: err}
-			}(i, r)
+	status := func(i int, r *repo.Repo) {
+		if !r.IsCloned() {
+			results[i] = StatusResult{Repo: r, Error: notCloned(r)}
+			return
 		}
-		wg.Wait()
-	} else {
-		for i, r := range w.Repos {
-			if !r.IsCloned() {
-				results[i] = PRResult{Repo: r, Error: fmt.Errorf("not cloned")}
-				continue
-			}
-			pr, err := r.GetPR()
-			results[i] = PRResult{Repo: r, PR: pr, Error: err}
+		s, err := r.Status()
+		if err == nil && lastCommit {
+			s.LastCommit, err = r.LastCommit()
 		}
+		results[i] = StatusResult{Repo: r, Status: s, Error: err}
+	}
+
+	durations := w.each("status", func(i int, r *repo.Repo) error {
+		status(i, r)
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
 	}
 
 	return results
 }
 
-// CreatePRs creates PRs for all repos on the current branch, skipping repos that already have a PR
-func (w *Workspace) CreatePRs(title, body, base string) []PRResult {
-	results := make([]PRResult, len(w.Repos))
+// ForEachStatus gathers status for all repos and returns those whose status
+// matches pred. Repos whose status could not be read are always returned, so
+// callers can report them.
+func (w *Workspace) ForEachStatus(pred func(*git.Status) bool) []StatusResult {
+	return FilterStatus(w.Status(), pred)
+}
 
-	createPR := func(i int, r *repo.Repo) {
-		if !r.IsCloned() {
-			results[i] = PRResult{Repo: r, Error: fmt.Errorf("not cloned")}
-			return
+// FilterStatus returns the results whose status matches pred, and those
+// whose status could not be read, in their original order
+func FilterStatus(results []StatusResult, pred func(*git.Status) bool) []StatusResult {
+	var matched []StatusResult
+	for _, r := range results {
+		if r.Error != nil || pred(r.Status) {
+			matched = append(matched, r)
 		}
+	}
+	return matched
+}
 
-		// Check if PR already exists
-		existingPR, err := r.GetPR()
-		if err != nil {
-			results[i] = PRResult{Repo: r, Error: fmt.Errorf("checking existing PR: %w", err)}
-			return
-		}
-		if existingPR != nil {
-			// PR already exists, return it without error
-			results[i] = PRResult{Repo: r, PR: existingPR, Existed: true, Error: nil}
-			return
-		}
+// Dirty matches repos with uncommitted changes
+func Dirty(s *git.Status) bool {
+	return s.HasChanges
+}
 
-		// Create new PR
-		pr, err := r.CreatePR(title, body, base)
-		results[i] = PRResult{Repo: r, PR: pr, Error: err}
-	}
+// Clean matches repos with no uncommitted changes
+func Clean(s *git.Status) bool {
+	return !s.HasChanges
+}
 
-	if w.Parallel {
-		var wg sync.WaitGroup
-		for i, r := range w.Repos {
-			wg.Add(1)
-			go func(i int, r *repo.Repo) {
-				defer wg.Done()
-				createPR(i, r)
-			}(i, r)
-		}
-		wg.Wait()
-	} else {
-		for i, r := range w.Repos {
-			createPR(i, r)
+// Ahead matches repos with commits not yet pushed upstream
+func Ahead(s *git.Status) bool {
+	return s.Ahead > 0
+}
+
+// Behind matches repos missing commits from upstream
+func Behind(s *git.Status) bool {
+	return s.Behind > 0
+}
+
+// InProgress matches repos stopped in the middle of a rebase, merge, or
+// other operation
+func InProgress(s *git.Status) bool {
+	return s.State != ""
+}
+
+// Detached matches repos whose HEAD is not on a branch
+func Detached(s *git.Status) bool {
+	return s.Detached
+}
+
+// OffBranch returns a predicate matching repos not on branch
+func OffBranch(branch string) func(*git.Status) bool {
+	return func(s *git.Status) bool { return s.Branch != branch }
+}
+
+// AnyStatus returns a predicate matching repos any of preds match
+func AnyStatus(preds ...func(*git.Status) bool) func(*git.Status) bool {
+	return func(s *git.Status) bool {
+		for _, pred := range preds {
+			if pred(s) {
+				return true
+			}
 		}
+		return false
 	}
+}
 
-	return results
+// CreateBranch creates a branch on all repos
+func (w *Workspace) CreateBranch(name string) []Result {
+	return w.CreateBranchFrom(name, "")
 }
 
-// ClosePRs closes PRs for all repos on the current branch
-func (w *Workspace) ClosePRs() []Result {
-	return w.forEach(func(r *repo.Repo) error {
+// CreateBranchFrom creates a branch on all repos starting at the given ref,
+// or at the current HEAD if start is empty
+func (w *Workspace) CreateBranchFrom(name, start string) []Result {
+	return w.forEach("branch", func(r *repo.Repo) error {
 		if !r.IsCloned() {
-			return fmt.Errorf("not cloned")
+			return notCloned(r)
+		}
+		if r.BranchExists(name) {
+			return fmt.Errorf("branch %q already exists", name)
+		}
+		if start != "" && !r.BranchExists(start) {
+			return fmt.Errorf("start ref %q does not exist", start)
 		}
-		return r.ClosePR()
+		return r.CheckoutNewBranchFrom(name, start)
 	})
 }
+
+// DeleteBranch deletes a branch on all repos
+func (w *Workspace) DeleteBranch(name string) []Result {
+	return w.forEach("branch delete", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		// Can't delete current branch
+		current, err := r.CurrentBranch()
+		if err != nil {
+			return err
+		}
+		if current == name {
+			return fmt.Errorf("cannot delete current branch")
+		}
+		return r.DeleteBranch(name)
+	})
+}
+
+// Checkout switches all repos to a branch, creating it if it doesn't exist
+func (w *Workspace) Checkout(name string) []Result {
+	return w.forEach("checkout", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return w.withAutoStash(r, func() error {
+			if r.BranchExists(name) {
+				return r.Checkout(name)
+			}
+			// Branch doesn't exist, create it
+			return r.CheckoutNewBranch(name)
+		})
+	})
+}
+
+// Commit commits staged changes on all repos, between the pre-commit and
+// post-commit hooks
+func (w *Workspace) Commit(message string, addAll bool) ([]Result, error) {
+	return w.withHooks("commit", func() ([]Result, error) {
+		return w.forEach("commit", func(r *repo.Repo) error {
+			if !r.IsCloned() {
+				return notCloned(r)
+			}
+
+			if addAll {
+				if err := r.AddAll(); err != nil {
+					return err
+				}
+			}
+
+			hasChanges, err := r.HasStagedChanges()
+			if err != nil {
+				return err
+			}
+			if !hasChanges {
+				return nil // No changes to commit
+			}
+
+			return r.Commit(message)
+		}), nil
+	})
+}
+
+// StaleLock is a stale index lock file in a single repo
+type StaleLock struct {
+	Repo *repo.Repo
+	Lock *git.LockError
+}
+
+// StaleLocks returns the index lock files left behind in cloned repos: old,
+// and with no git process running in the repo
+func (w *Workspace) StaleLocks() []StaleLock {
+	found := make([]*git.LockError, len(w.Repos))
+	w.each("lock check", func(i int, r *repo.Repo) error {
+		if !r.IsCloned() {
+			return nil
+		}
+		lock, err := r.IndexLock()
+		if lock != nil && lock.Stale {
+			found[i] = lock
+		}
+		return err
+	})
+
+	var locks []StaleLock
+	for i, lock := range found {
+		if lock != nil {
+			locks = append(locks, StaleLock{Repo: w.Repos[i], Lock: lock})
+		}
+	}
+	return locks
+}
+
+// PendingCommit lists the files a commit would include in a single repo
+type PendingCommit struct {
+	Repo  *repo.Repo
+	Files []string
+	Error error
+}
+
+// PendingCommits reports what Commit would commit in each repo without
+// changing anything. With addAll, every changed file counts; otherwise only
+// staged ones do.
+func (w *Workspace) PendingCommits(addAll bool) []PendingCommit {
+	results := make([]PendingCommit, len(w.Repos))
+
+	w.each("commit plan", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		res.Repo = r
+		res.Error = pendingCommit(r, addAll, res)
+		return res.Error
+	})
+
+	return results
+}
+
+func pendingCommit(r *repo.Repo, addAll bool, res *PendingCommit) error {
+	if !r.IsCloned() {
+		return notCloned(r)
+	}
+	if !addAll {
+		files, err := r.StagedFiles()
+		res.Files = files
+		return err
+	}
+
+	status, err := r.Status()
+	if err != nil {
+		return err
+	}
+	for _, f := range status.Files {
+		res.Files = append(res.Files, f.Path)
+	}
+	return nil
+}
+
+// stashTag starts the message of every stash entry mergeish makes, so
+// StashPop can tell them from the user's own
+const stashTag = "mergeish"
+
+// autostashMessage is the message of withAutoStash's stash entries
+const autostashMessage = stashTag + " autostash"
+
+// stashMessage returns the message Stash gives an entry for message
+func stashMessage(message string) string {
+	if message == "" {
+		return stashTag
+	}
+	return stashTag + ": " + message
+}
+
+// isMergeishStash reports whether Stash or withAutoStash made the entry.
+// Git records its message as "On <branch>: <message>".
+func isMergeishStash(e git.StashEntry) bool {
+	_, msg, _ := strings.Cut(e.Message, ": ")
+	return msg == stashTag || msg == autostashMessage || strings.HasPrefix(msg, stashTag+": ")
+}
+
+// Stash stashes local changes on all repos that have any. The entries are
+// tagged as mergeish's, for StashPop.
+func (w *Workspace) Stash(message string) []StashResult {
+	return w.forEachStash("stash", func(r *repo.Repo) (bool, error) {
+		status, err := r.Status()
+		if err != nil {
+			return false, err
+		}
+		if !status.HasChanges {
+			return false, nil
+		}
+		return true, r.Stash(stashMessage(message))
+	})
+}
+
+// StashPop pops the most recent stash entry mergeish made on all repos,
+// leaving the user's own entries alone. Repos without one are skipped.
+func (w *Workspace) StashPop() []StashResult {
+	return w.forEachStash("stash pop", func(r *repo.Repo) (bool, error) {
+		entries, err := r.StashList()
+		if err != nil {
+			return false, err
+		}
+		for _, e := range entries {
+			if isMergeishStash(e) {
+				return true, r.StashPop(e.Ref)
+			}
+		}
+		return false, nil
+	})
+}
+
+// StashResult represents the result of a stash operation on a single repo
+type StashResult struct {
+	Repo    *repo.Repo
+	Changed bool // false if the repo had nothing to stash or pop
+	Error   error
+}
+
+// StashListResult represents the stash entries of a single repo
+type StashListResult struct {
+	Repo    *repo.Repo
+	Entries []git.StashEntry
+	Error   error
+}
+
+// StashList returns stash entries for all repos
+func (w *Workspace) StashList() []StashListResult {
+	results := make([]StashListResult, len(w.Repos))
+
+	list := func(i int, r *repo.Repo) {
+		if !r.IsCloned() {
+			results[i] = StashListResult{Repo: r, Error: notCloned(r)}
+			return
+		}
+		entries, err := r.StashList()
+		results[i] = StashListResult{Repo: r, Entries: entries, Error: err}
+	}
+
+	w.each("stash list", func(i int, r *repo.Repo) error {
+		list(i, r)
+		return results[i].Error
+	})
+
+	return results
+}
+
+// forEachStash runs a stash operation on all cloned repos
+func (w *Workspace) forEachStash(op string, fn func(*repo.Repo) (bool, error)) []StashResult {
+	results := make([]StashResult, len(w.Repos))
+
+	w.each(op, func(i int, r *repo.Repo) error {
+		if !r.IsCloned() {
+			results[i] = StashResult{Repo: r, Error: notCloned(r)}
+			return results[i].Error
+		}
+		changed, err := fn(r)
+		results[i] = StashResult{Repo: r, Changed: changed, Error: err}
+		return err
+	})
+
+	return results
+}
+
+// CreateTag creates a tag on all repos
+func (w *Workspace) CreateTag(name, message string) []Result {
+	return w.forEach("tag create", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return r.CreateTag(name, message)
+	})
+}
+
+// DeleteTag deletes a tag on all repos
+func (w *Workspace) DeleteTag(name string) []Result {
+	return w.forEach("tag delete", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return r.DeleteTag(name)
+	})
+}
+
+// PushTags pushes tags on all repos
+func (w *Workspace) PushTags() []Result {
+	return w.forEach("tag push", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return r.PushTags()
+	})
+}
+
+// PushTag pushes a single tag on all repos
+func (w *Workspace) PushTag(name string) []Result {
+	return w.forEach("tag push", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return r.PushTag(name)
+	})
+}
+
+// DeleteRemoteTag deletes a tag from the primary remote on all repos
+func (w *Workspace) DeleteRemoteTag(name string) []Result {
+	return w.forEach("tag delete remote", func(r *repo.Repo) error {
+		if !r.IsCloned() {
+			return notCloned(r)
+		}
+		return r.DeleteRemoteTag(name)
+	})
+}
+
+// TagResult represents the tags of a single repo
+type TagResult struct {
+	Repo  *repo.Repo
+	Tags  []string
+	Error error
+}
+
+// ListTags returns the local tags for all repos
+func (w *Workspace) ListTags() []TagResult {
+	results := make([]TagResult, len(w.Repos))
+
+	listTags := func(i int, r *repo.Repo) {
+		if !r.IsCloned() {
+			results[i] = TagResult{Repo: r, Error: notCloned(r)}
+			return
+		}
+		tags, err := r.ListTags()
+		results[i] = TagResult{Repo: r, Tags: tags, Error: err}
+	}
+
+	w.each("tag list", func(i int, r *repo.Repo) error {
+		listTags(i, r)
+		return results[i].Error
+	})
+
+	return results
+}
+
+// LatestTagResult represents the latest tag of a single repo
+type LatestTagResult struct {
+	Repo  *repo.Repo
+	Tag   string // "" if the repo has no tags
+	Error error
+}
+
+// LatestTags returns the highest version tag for all repos
+func (w *Workspace) LatestTags() []LatestTagResult {
+	results := make([]LatestTagResult, len(w.Repos))
+
+	w.each("tag latest", func(i int, r *repo.Repo) error {
+		if !r.IsCloned() {
+			results[i] = LatestTagResult{Repo: r, Error: notCloned(r)}
+			return results[i].Error
+		}
+		tag, err := r.LatestTag()
+		results[i] = LatestTagResult{Repo: r, Tag: tag, Error: err}
+		return err
+	})
+
+	return results
+}
+
+// CheckRemotes checks that every repo's URL is reachable. Repos do not need
+// to be cloned.
+func (w *Workspace) CheckRemotes() []Result {
+	return w.forEach("ls-remote", func(r *repo.Repo) error {
+		return r.CheckRemote()
+	})
+}
+
+// ValidateResult represents the validation issues of a single repo
+type ValidateResult struct {
+	Repo   *repo.Repo
+	Issues []repo.ValidationIssue
+}
+
+// Validate checks every repo against its config
+func (w *Workspace) Validate() []ValidateResult {
+	results := make([]ValidateResult, len(w.Repos))
+
+	w.each("validate", func(i int, r *repo.Repo) error {
+		results[i] = ValidateResult{Repo: r, Issues: r.Validate()}
+		if len(results[i].Issues) > 0 {
+			return fmt.Errorf("%d issue(s)", len(results[i].Issues))
+		}
+		return nil
+	})
+
+	return results
+}
+
+// CheckBranchConsistency checks if all repos are on the same branch. A
+// repo mid-rebase counts as on the branch being rebased; any other
+// detached HEAD is an error. Only the selected repos are checked, not those
+// in Unselected.
+func (w *Workspace) CheckBranchConsistency() (string, bool, error) {
+	var firstBranch string
+	consistent := true
+
+	for _, r := range w.Repos {
+		if !r.IsCloned() {
+			continue
+		}
+
+		branch, _, err := r.Branch()
+		var corrupt *git.CorruptError
+		if errors.As(err, &corrupt) {
+			continue // the operation reports it for this repo
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if branch == "" {
+			return "", false, fmt.Errorf("%s: HEAD is detached; check out a branch first", r.Name())
+		}
+
+		if firstBranch == "" {
+			firstBranch = branch
+		} else if branch != firstBranch {
+			consistent = false
+		}
+	}
+
+	return firstBranch, consistent, nil
+}
+
+// forEach runs an operation on all repos
+func (w *Workspace) forEach(op string, fn func(*repo.Repo) error) []Result {
+	results := make([]Result, len(w.Repos))
+
+	durations := w.each(op, func(i int, r *repo.Repo) error {
+		r.Attempts = 0
+		err := fn(r)
+		results[i] = Result{Repo: r, Error: err, Attempts: r.Attempts}
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// each calls fn for every repo, records the operation's timings under op,
+// and returns how long fn took in each repo.
+// Repos with parallel enabled run concurrently, up to maxJobs at once;
+// the rest run one at a time, alongside them.
+func (w *Workspace) each(op string, fn func(i int, r *repo.Repo) error) []time.Duration {
+	opStart := time.Now()
+	done := metrics.StartOperation(op)
+	timings := make([]metrics.RepoTiming, len(w.Repos))
+	slots := make(chan struct{}, w.maxJobs())
+	limitBefore := git.ProcessLimit()
+
+	run := func(i int, r *repo.Repo) {
+		slots <- struct{}{}
+		start := time.Now()
+		err := fn(i, r)
+		timings[i] = metrics.RepoTiming{Repo: r.Config.Path, Duration: time.Since(start), Err: err}
+		<-slots
+
+		jsonlog.Repo(op, r.Config.Path, timings[i].Duration, err)
+		if err == nil {
+			w.checkpoint.finished(r)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var serial []int
+	for i, r := range w.Repos {
+		if !r.Settings.Parallel {
+			serial = append(serial, i)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, r *repo.Repo) {
+			defer wg.Done()
+			run(i, r)
+		}(i, r)
+	}
+	for _, i := range serial {
+		run(i, w.Repos[i])
+	}
+	wg.Wait()
+
+	// git lowers the limit itself, retrying just the command that
+	// couldn't start
+	limit := git.ProcessLimit()
+	if limit == limitBefore {
+		limit = 0
+	}
+	done(timings, limit)
+
+	failed := 0
+	for _, t := range timings {
+		if t.Err != nil {
+			failed++
+		}
+	}
+	jsonlog.Operation(op, time.Since(opStart), len(timings)-failed, failed)
+
+	durations := make([]time.Duration, len(timings))
+	for i, t := range timings {
+		durations[i] = t.Duration
+	}
+	return durations
+}
+
+// HasErrors checks if any results have errors
+func HasErrors(results []Result) bool {
+	for _, r := range results {
+		if r.Error != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GitResult represents the result of a raw git command on a single repo
+type GitResult struct {
+	Repo     *repo.Repo
+	Stdout   string
+	Stderr   string
+	Error    error
+	Duration time.Duration // how long the command ran
+}
+
+// RunGit executes an arbitrary git command on all repos, with colored output
+// if color is set
+func (w *Workspace) RunGit(args []string, color bool) []GitResult {
+	results := make([]GitResult, len(w.Repos))
+
+	durations := w.each("git", func(i int, r *repo.Repo) error {
+		if !r.IsCloned() {
+			results[i] = GitResult{Repo: r, Error: notCloned(r)}
+			return results[i].Error
+		}
+		stdout, stderr, err := r.RunGit(color, args...)
+		results[i] = GitResult{Repo: r, Stdout: stdout, Stderr: stderr, Error: err}
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// DiffResult represents the diff of a single repo
+type DiffResult struct {
+	Repo  *repo.Repo
+	Diff  *git.Diff
+	Error error
+}
+
+// Diff returns diffs for all repos
+func (w *Workspace) Diff(opts git.DiffOptions) []DiffResult {
+	results := make([]DiffResult, len(w.Repos))
+
+	diff := func(i int, r *repo.Repo) {
+		if !r.IsCloned() {
+			results[i] = DiffResult{Repo: r, Error: notCloned(r)}
+			return
+		}
+		d, err := r.Diff(opts)
+		results[i] = DiffResult{Repo: r, Diff: d, Error: err}
+	}
+
+	w.each("diff", func(i int, r *repo.Repo) error {
+		diff(i, r)
+		return results[i].Error
+	})
+
+	return results
+}
+
+// ReplaceResult represents the proposed edits for a single repo
+type ReplaceResult struct {
+	Repo  *repo.Repo
+	Edits []*replace.FileEdit
+	Error error
+}
+
+// FindReplacements computes the edits rep would make in tracked files of all
+// repos, limited to the given glob paths. Nothing is written.
+func (w *Workspace) FindReplacements(rep *replace.Replacer, paths ...string) []ReplaceResult {
+	results := make([]ReplaceResult, len(w.Repos))
+
+	find := func(i int, r *repo.Repo) {
+		if !r.IsCloned() {
+			results[i] = ReplaceResult{Repo: r, Error: notCloned(r)}
+			return
+		}
+
+		// git grep narrows fixed-string searches; regex patterns use Go
+		// syntax, so every tracked file is a candidate
+		var files []string
+		var err error
+		if rep.Regex {
+			files, err = r.ListFiles(paths...)
+		} else {
+			files, err = r.GrepFiles(rep.Pattern, false, paths...)
+		}
+		if err != nil {
+			results[i] = ReplaceResult{Repo: r, Error: err}
+			return
+		}
+
+		result := ReplaceResult{Repo: r}
+		for _, f := range files {
+			edit, err := rep.EditFile(r.FullPath, f)
+			if err != nil {
+				result.Error = fmt.Errorf("%s: %w", f, err)
+				break
+			}
+			if edit != nil {
+				result.Edits = append(result.Edits, edit)
+			}
+		}
+		results[i] = result
+	}
+
+	w.each("replace", func(i int, r *repo.Repo) error {
+		find(i, r)
+		return results[i].Error
+	})
+
+	return results
+}
+
+// ApplyReplacements writes the edits found by FindReplacements and stages
+// the edited files. Repos without edits or with errors are skipped.
+func ApplyReplacements(results []ReplaceResult) []Result {
+	var applied []Result
+	for _, rr := range results {
+		if rr.Error != nil || len(rr.Edits) == 0 {
+			continue
+		}
+
+		var err error
+		paths := make([]string, 0, len(rr.Edits))
+		for _, e := range rr.Edits {
+			if err = e.Apply(); err != nil {
+				err = fmt.Errorf("%s: %w", e.Path, err)
+				break
+			}
+			paths = append(paths, e.Path)
+		}
+		if err == nil {
+			err = rr.Repo.Add(paths...)
+		}
+		applied = append(applied, Result{Repo: rr.Repo, Error: err})
+	}
+	return applied
+}
+
+// LogResult represents the commit log of a single repo
+type LogResult struct {
+	Repo    *repo.Repo
+	Commits []git.Commit
+	Error   error
+}
+
+// LogEntry is a commit tagged with the repo it belongs to
+type LogEntry struct {
+	Repo   *repo.Repo
+	Commit git.Commit
+}
+
+// Log returns commit logs for all repos. With branchOnly, each repo's log
+// is limited to commits not on its default base branch.
+func (w *Workspace) Log(opts git.LogOptions, branchOnly bool) []LogResult {
+	results := make([]LogResult, len(w.Repos))
+
+	log := func(i int, r *repo.Repo) {
+		if !r.IsCloned() {
+			results[i] = LogResult{Repo: r, Error: notCloned(r)}
+			return
+		}
+
+		o := opts
+		if branchOnly {
+			base, err := r.DefaultBase()
+			if err != nil {
+				results[i] = LogResult{Repo: r, Error: err}
+				return
+			}
+			head := o.Ref
+			if head == "" {
+				head = "HEAD"
+			}
+			o.Ref = base + ".." + head
+		}
+
+		commits, err := r.Log(o)
+		results[i] = LogResult{Repo: r, Commits: commits, Error: err}
+	}
+
+	w.each("log", func(i int, r *repo.Repo) error {
+		log(i, r)
+		return results[i].Error
+	})
+
+	return results
+}
+
+// MergeLogs interleaves the commits of all successful results, newest first.
+// Commits with the same time keep repo order.
+func MergeLogs(results []LogResult) []LogEntry {
+	var entries []LogEntry
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		for _, c := range r.Commits {
+			entries = append(entries, LogEntry{Repo: r.Repo, Commit: c})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Commit.Time.After(entries[j].Commit.Time)
+	})
+
+	return entries
+}
+
+// PRResult represents the result of a PR operation on a single repo
+type PRResult struct {
+	Repo     *repo.Repo
+	PR       *git.PRInfo
+	Existed  bool          // true if PR already existed (not newly created)
+	Pushed   bool          // the branch had no upstream and was pushed before creating the PR
+	NoPRs    bool          // the repo has no PR provider and was skipped
+	Skipped  string        // why the repo was skipped, if it was, e.g. "already a draft"
+	Checks   []git.PRCheck // CI checks of PR, filled in by GetPRChecks
+	Error    error
+	Duration time.Duration // how long the operation took in this repo
+}
+
+// GetPRs returns PR status for all repos
+func (w *Workspace) GetPRs() []PRResult {
+	results := make([]PRResult, len(w.Repos))
+
+	durations := w.each("pr status", func(i int, r *repo.Repo) error {
+		if !r.Config.HasPRs() {
+			results[i] = PRResult{Repo: r, NoPRs: true}
+			return nil
+		}
+		if !r.IsCloned() {
+			results[i] = PRResult{Repo: r, Error: notCloned(r)}
+			return results[i].Error
+		}
+		pr, err := r.GetPR()
+		results[i] = PRResult{Repo: r, PR: pr, Error: err}
+		return err
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// PRListResult holds the PRs of a single repo
+type PRListResult struct {
+	Repo     *repo.Repo
+	PRs      []git.PRInfo
+	NoPRs    bool // the repo has no PR provider and was skipped
+	Error    error
+	Duration time.Duration
+}
+
+// ListPRs lists the PRs in state, e.g. "open" or "all", of every repo,
+// whatever their branch
+func (w *Workspace) ListPRs(state string) []PRListResult {
+	results := make([]PRListResult, len(w.Repos))
+
+	durations := w.each("pr list", func(i int, r *repo.Repo) error {
+		results[i] = PRListResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			results[i].NoPRs = true
+		case !r.IsCloned():
+			results[i].Error = notCloned(r)
+		default:
+			results[i].PRs, results[i].Error = r.ListPRs(state)
+		}
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// GetPRChecks returns the PR for the current branch of every repo with its
+// CI checks
+func (w *Workspace) GetPRChecks() []PRResult {
+	results := make([]PRResult, len(w.Repos))
+
+	durations := w.each("pr checks", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		*res = PRResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			res.NoPRs = true
+			return nil
+		case !r.IsCloned():
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		res.PR, res.Error = r.GetPR()
+		if res.Error == nil && res.PR != nil {
+			res.Checks, res.Error = r.GetPRChecks()
+		}
+		return res.Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// ResolveBase returns the branch PRs for r should target: the explicit
+// override, the repo's pr_base, or the remote's default branch, in that
+// order. It returns "" if none is known, e.g. when the remote's HEAD was
+// never fetched, leaving the choice to the provider.
+func (w *Workspace) ResolveBase(r *repo.Repo, override string) string {
+	if override != "" {
+		return override
+	}
+	if r.Config.PRBase != "" {
+		return r.Config.PRBase
+	}
+	return r.LocalRemoteHead(r.Remote())
+}
+
+// CheckBaseConsistency checks if all cloned repos with PRs resolve to the
+// same PR base. It returns the repos grouped by base. Repos whose base
+// isn't known aren't compared.
+func (w *Workspace) CheckBaseConsistency(override string) (map[string][]*repo.Repo, bool) {
+	groups := make(map[string][]*repo.Repo)
+	for _, r := range w.Repos {
+		if !r.IsCloned() || !r.Config.HasPRs() {
+			continue
+		}
+		if base := w.ResolveBase(r, override); base != "" {
+			groups[base] = append(groups[base], r)
+		}
+	}
+	return groups, len(groups) <= 1
+}
+
+// MixedBaseError is returned by CreatePRs when repos would target
+// different base branches. No PR is created.
+type MixedBaseError struct {
+	Groups map[string][]*repo.Repo // repos by PR base
+}
+
+func (e *MixedBaseError) Error() string {
+	return "repositories target different base branches"
+}
+
+// PRMetadata adds the reviewers, assignees, and labels given for one
+// command to the defaults in settings.pr
+func (w *Workspace) PRMetadata(reviewers, assignees, labels []string) git.PRMetadata {
+	defaults := w.Config.Settings.PR
+	return git.PRMetadata{
+		Reviewers: mergeUnique(defaults.Reviewers, reviewers),
+		Assignees: mergeUnique(defaults.Assignees, assignees),
+		Labels:    mergeUnique(defaults.Labels, labels),
+	}
+}
+
+// mergeUnique returns the values of a followed by those of b, without
+// duplicates or empty values
+func mergeUnique(a, b []string) []string {
+	var merged []string
+	seen := make(map[string]bool)
+	for _, v := range append(append([]string{}, a...), b...) {
+		if v = strings.TrimSpace(v); v != "" && !seen[v] {
+			seen[v] = true
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// PRCreateOptions controls CreatePRs
+type PRCreateOptions struct {
+	Base           string // base branch for every repo, instead of ResolveBase's
+	Draft          bool
+	All            bool // open PRs in repos with no commits ahead of the base too
+	AllowMixedBase bool // let repos target different base branches
+	Meta           git.PRMetadata
+}
+
+// CreatePRs creates PRs for all repos on the current branch, skipping repos
+// that already have a PR or, unless opts.All is set, have no commits ahead
+// of the base. body returns each repo's PR body. A branch without an
+// upstream is pushed first, so that gh can find it. Unless
+// opts.AllowMixedBase is set, it returns a *MixedBaseError and creates
+// nothing if the repos would target different bases.
+func (w *Workspace) CreatePRs(title string, body func(*repo.Repo) string, opts PRCreateOptions) ([]PRResult, error) {
+	if groups, consistent := w.CheckBaseConsistency(opts.Base); !consistent && !opts.AllowMixedBase {
+		return nil, &MixedBaseError{Groups: groups}
+	}
+
+	results := make([]PRResult, len(w.Repos))
+
+	createPR := func(i int, r *repo.Repo) {
+		if !r.Config.HasPRs() {
+			results[i] = PRResult{Repo: r, NoPRs: true}
+			return
+		}
+		if !r.IsCloned() {
+			results[i] = PRResult{Repo: r, Error: notCloned(r)}
+			return
+		}
+
+		// Check if PR already exists
+		existingPR, err := r.GetPR()
+		if err != nil {
+			results[i] = PRResult{Repo: r, Error: fmt.Errorf("checking existing PR: %w", err)}
+			return
+		}
+		if existingPR != nil {
+			// PR already exists, return it without error
+			results[i] = PRResult{Repo: r, PR: existingPR, Existed: true, Error: nil}
+			return
+		}
+
+		// Skip repos the branch doesn't change. If the base isn't known
+		// locally, leave it to gh.
+		prBase := w.ResolveBase(r, opts.Base)
+		if !opts.All && prBase != "" {
+			commits, err := r.GetBranchCommits(r.Remote() + "/" + prBase)
+			if err == nil && len(commits) == 0 {
+				results[i] = PRResult{Repo: r, Skipped: "no commits ahead of " + prBase}
+				return
+			}
+		}
+
+		pushed := false
+		if !r.HasUpstream() {
+			if err := r.PushSetUpstream(false); err != nil {
+				results[i] = PRResult{Repo: r, Error: fmt.Errorf("pushing branch: %w", err)}
+				return
+			}
+			pushed = true
+		}
+
+		// Create new PR
+		pr, err := r.CreatePR(title, body(r), prBase, opts.Draft, opts.Meta)
+		results[i] = PRResult{Repo: r, PR: pr, Pushed: pushed, Error: err}
+	}
+
+	durations := w.each("pr create", func(i int, r *repo.Repo) error {
+		createPR(i, r)
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results, nil
+}
+
+// ClosePRs closes PRs for all repos on the current branch
+func (w *Workspace) ClosePRs() []PRResult {
+	results := make([]PRResult, len(w.Repos))
+
+	durations := w.each("pr close", func(i int, r *repo.Repo) error {
+		results[i] = PRResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			results[i].NoPRs = true
+		case !r.IsCloned():
+			results[i].Error = notCloned(r)
+		default:
+			results[i].Error = r.ClosePR()
+		}
+		return results[i].Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// MarkPRsDraft converts the open pull request for the current branch of
+// every repo to a draft. Repos without an open PR, or whose PR already is
+// one, are skipped, with the reason in Skipped.
+func (w *Workspace) MarkPRsDraft() []PRResult {
+	return w.setPRsDraft("pr draft", true)
+}
+
+// MarkPRsReady marks the draft pull request for the current branch of every
+// repo as ready for review. Repos without an open draft PR are skipped,
+// with the reason in Skipped.
+func (w *Workspace) MarkPRsReady() []PRResult {
+	return w.setPRsDraft("pr ready", false)
+}
+
+// setPRsDraft makes the open PR of every repo a draft or ready for review
+func (w *Workspace) setPRsDraft(op string, draft bool) []PRResult {
+	results := make([]PRResult, len(w.Repos))
+
+	durations := w.each(op, func(i int, r *repo.Repo) error {
+		res := &results[i]
+		*res = PRResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			res.NoPRs = true
+			return nil
+		case !r.IsCloned():
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		res.PR, res.Error = r.GetPR()
+		switch {
+		case res.Error != nil:
+		case res.PR == nil:
+			res.Skipped = "no PR"
+		case res.PR.State != "OPEN":
+			res.Skipped = "PR is " + strings.ToLower(res.PR.State)
+		case res.PR.Draft == draft && draft:
+			res.Skipped = "already a draft"
+		case res.PR.Draft == draft:
+			res.Skipped = "already ready for review"
+		default:
+			if draft {
+				res.Error = r.MarkPRDraft()
+			} else {
+				res.Error = r.MarkPRReady()
+			}
+			if res.Error == nil {
+				res.PR.Draft = draft
+			}
+		}
+		return res.Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// UpdatePRs replaces the title and body of the open pull request for the
+// current branch of every repo; an empty title or body is left as it is.
+// With appendBody, body is added to the end of the existing body instead.
+// A replaced body keeps the link to the umbrella issue. Repos without an
+// open PR are skipped, with the reason in Skipped.
+func (w *Workspace) UpdatePRs(title, body string, appendBody bool) []PRResult {
+	results := make([]PRResult, len(w.Repos))
+
+	durations := w.each("pr update", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		*res = PRResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			res.NoPRs = true
+			return nil
+		case !r.IsCloned():
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		res.PR, res.Error = r.GetPR()
+		switch {
+		case res.Error != nil:
+		case res.PR == nil:
+			res.Skipped = "no PR"
+		case res.PR.State != "OPEN":
+			res.Skipped = "PR is " + strings.ToLower(res.PR.State)
+		default:
+			res.Error = updatePR(r, title, body, appendBody)
+			if res.Error == nil && title != "" {
+				res.PR.Title = title
+			}
+		}
+		return res.Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// PRLabelResult is the outcome of EditPRLabels in one repo
+type PRLabelResult struct {
+	Repo     *repo.Repo
+	PR       *git.PRInfo
+	NoPRs    bool     // the repo has provider: none
+	Skipped  string   // why the PR wasn't edited, e.g. "no PR"
+	Added    []string // labels the PR didn't have before
+	Removed  []string // labels the PR had before
+	Error    error
+	Duration time.Duration
+}
+
+// EditPRLabels adds and removes labels on the open PR of every repo. Labels
+// a PR already has aren't added again, and labels it lacks aren't removed.
+func (w *Workspace) EditPRLabels(add, remove []string) []PRLabelResult {
+	results := make([]PRLabelResult, len(w.Repos))
+
+	durations := w.each("pr labels", func(i int, r *repo.Repo) error {
+		res := &results[i]
+		*res = PRLabelResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			res.NoPRs = true
+			return nil
+		case !r.IsCloned():
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		res.PR, res.Error = r.GetPR()
+		switch {
+		case res.Error != nil:
+			return res.Error
+		case res.PR == nil:
+			res.Skipped = "no PR"
+			return nil
+		case res.PR.State != "OPEN":
+			res.Skipped = "PR is " + strings.ToLower(res.PR.State)
+			return nil
+		}
+
+		has := make(map[string]bool)
+		for _, l := range res.PR.Labels {
+			has[l] = true
+		}
+		for _, l := range add {
+			if !has[l] {
+				res.Added = append(res.Added, l)
+			}
+		}
+		for _, l := range remove {
+			if has[l] {
+				res.Removed = append(res.Removed, l)
+			}
+		}
+		if len(res.Added) == 0 && len(res.Removed) == 0 {
+			return nil
+		}
+		if res.Error = r.EditPRLabels(res.Added, res.Removed); res.Error != nil {
+			res.Added, res.Removed = nil, nil
+		}
+		return res.Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// PRReviewResult is the review status of the PR in one repo, and the
+// reviewers RequestReviewers asked for
+type PRReviewResult struct {
+	Repo      *repo.Repo
+	PR        *git.PRInfo
+	NoPRs     bool   // the repo has provider: none
+	Skipped   string // why the PR wasn't looked at, e.g. "no PR"
+	Requested []string
+	Reviews   *git.PRReviews // set by GetPRReviews
+	Error     error
+	Duration  time.Duration
+}
+
+// RequestReviewers asks users and teams (org/team) to review the open PR of
+// every repo. Reviewers already requested aren't asked again.
+func (w *Workspace) RequestReviewers(users, teams []string) []PRReviewResult {
+	return w.forEachReview("pr reviewers", func(r *repo.Repo, res *PRReviewResult) error {
+		requested := make(map[string]bool)
+		for _, name := range res.PR.RequestedReviewers {
+			requested[strings.ToLower(name)] = true
+		}
+		var newUsers, newTeams []string
+		for _, u := range users {
+			if !requested[strings.ToLower(u)] {
+				newUsers = append(newUsers, u)
+			}
+		}
+		for _, t := range teams {
+			if !requested[strings.ToLower(t)] {
+				newTeams = append(newTeams, t)
+			}
+		}
+		if len(newUsers) == 0 && len(newTeams) == 0 {
+			return nil
+		}
+		if err := r.RequestReviewers(newUsers, newTeams); err != nil {
+			return err
+		}
+		res.Requested = append(newUsers, newTeams...)
+		return nil
+	})
+}
+
+// GetPRReviews returns the review status of the open PR of every repo
+func (w *Workspace) GetPRReviews() []PRReviewResult {
+	return w.forEachReview("pr reviews", func(r *repo.Repo, res *PRReviewResult) error {
+		var err error
+		res.Reviews, err = r.GetPRReviews()
+		return err
+	})
+}
+
+// forEachReview calls fn for every repo with an open PR on the current
+// branch, skipping the others with the reason in Skipped
+func (w *Workspace) forEachReview(op string, fn func(*repo.Repo, *PRReviewResult) error) []PRReviewResult {
+	results := make([]PRReviewResult, len(w.Repos))
+
+	durations := w.each(op, func(i int, r *repo.Repo) error {
+		res := &results[i]
+		*res = PRReviewResult{Repo: r}
+		switch {
+		case !r.Config.HasPRs():
+			res.NoPRs = true
+			return nil
+		case !r.IsCloned():
+			res.Error = notCloned(r)
+			return res.Error
+		}
+
+		res.PR, res.Error = r.GetPR()
+		switch {
+		case res.Error != nil:
+		case res.PR == nil:
+			res.Skipped = "no PR"
+		case res.PR.State != "OPEN":
+			res.Skipped = "PR is " + strings.ToLower(res.PR.State)
+		default:
+			res.Error = fn(r, res)
+		}
+		return res.Error
+	})
+	for i, d := range durations {
+		results[i].Duration = d
+	}
+
+	return results
+}
+
+// updatePR updates the title and body of r's pull request for UpdatePRs
+func updatePR(r *repo.Repo, title, body string, appendBody bool) error {
+	if body != "" {
+		old, err := r.PRBody()
+		if err != nil {
+			return err
+		}
+		if appendBody && strings.TrimSpace(old) != "" {
+			body = strings.TrimRight(old, "\n") + "\n\n" + body
+		} else {
+			for _, name := range []string{relatedSection, relatedPRsSection} {
+				if content, ok := section(old, name); ok {
+					body = setSection(body, name, content)
+				}
+			}
+		}
+	}
+	return r.UpdatePR(title, body)
+}
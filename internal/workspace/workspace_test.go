@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/willnewby/mergeish/internal/config"
+	"github.com/willnewby/mergeish/internal/git"
+	"github.com/willnewby/mergeish/internal/repo"
+)
+
+// testWorkspace creates a workspace of new repos with the given paths, each
+// with one commit on main pushed to its own bare origin, isolated from the
+// user's git config. The origins' HEAD isn't fetched.
+func testWorkspace(t *testing.T, paths ...string) *Workspace {
+	t.Helper()
+	home := t.TempDir()
+	gitconfig := filepath.Join(home, ".gitconfig")
+	if err := os.WriteFile(gitconfig, []byte("[user]\n\tname = Test\n\temail = test@example.com\n[commit]\n\tgpgsign = false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gitconfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	root := t.TempDir()
+	cfg := config.DefaultConfig()
+	remotes := t.TempDir()
+	for _, p := range paths {
+		remote := filepath.Join(remotes, p+".git")
+		dir := filepath.Join(root, p)
+		runGit(t, root, "init", "-q", "--bare", "-b", "main", remote)
+		runGit(t, root, "clone", "-q", remote, dir)
+		runGit(t, dir, "checkout", "-q", "-b", "main")
+		runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "initial")
+		runGit(t, dir, "push", "-q", "-u", "origin", "main")
+		rc := config.RepoConfig{URL: remote, Path: p}
+		rc.Provider = config.ProviderGitHub // for fakeGH
+		cfg.Repos = append(cfg.Repos, rc)
+	}
+	return New(cfg, root)
+}
+
+// runGit runs git in dir, failing the test on error, and returns its
+// trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// fakeGH puts a gh on PATH that logs its arguments, one call per line, to
+// the file it returns. pr create records a PR that pr list then reports.
+func fakeGH(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := `#!/bin/sh
+echo "$*" >> ` + log + `
+state=` + dir + `/created-$(basename "$PWD")
+case "$1 $2" in
+"pr list") if [ -f "$state" ]; then echo '[{"number":1,"title":"T","url":"https://example.com/1","state":"OPEN"}]'; else echo '[]'; fi;;
+"pr create") touch "$state"; echo https://example.com/1;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// ghCalls returns the calls logged by the gh of fakeGH
+func ghCalls(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// writeFile writes content to path under the repo at dir
+func writeFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEachRunsOnceOnResourceExhausted(t *testing.T) {
+	w := testWorkspace(t, "api")
+
+	calls := 0
+	w.each("test", func(i int, r *repo.Repo) error {
+		calls++
+		return fmt.Errorf("git status: %w", git.ErrResourceExhausted)
+	})
+	if calls != 1 {
+		t.Errorf("fn called %d times, want once: git retries the command itself", calls)
+	}
+}